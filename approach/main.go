@@ -3,9 +3,11 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"image/png"
-	"log"
+	"log/slog"
 	"os"
 	"strings"
 	"sync"
@@ -14,31 +16,61 @@ import (
 	"github.com/gen2brain/go-fitz"
 	"github.com/joho/godotenv"
 	"google.golang.org/genai"
+
+	"llm-pdf-app/geminicost"
+	"llm-pdf-app/geminiquota"
+	"llm-pdf-app/logging"
 )
 
+const modelName = "gemini-2.5-flash-lite"
+
+// pageTokenEstimate is roughly the input tokens of a page rendered as PNG and its prompt, for pacing to the tokens-per-minute quota
+const pageTokenEstimate = 5000
+
 type PageResult struct {
 	PageNumber int
 	Summary    string
+	Usage      geminicost.Usage
+	Resumed    bool // Kept from an earlier run; its usage was paid then
 	Error      error
 }
 
 func main() {
+	logging.Setup()
+	if err := run(os.Args[1:]); err != nil {
+		logging.Fatal(err)
+	}
+}
+
+// run summarizes the first pages of the PDF, each rendered as PNG
+func run(args []string) error {
 	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error: Could not load .env file. Make sure it exists!")
+		return fmt.Errorf("could not load .env file, make sure it exists")
 	}
 
 	apiKey := os.Getenv("GEMINI_API_KEY")
 	if apiKey == "" {
-		log.Fatal("Error: GEMINI_API_KEY not found in .env file")
+		return fmt.Errorf("GEMINI_API_KEY not found in .env file")
 	}
 
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: go run approach/main.go <pdf-file>")
+	fs := flag.NewFlagSet("approach", flag.ExitOnError)
+	tier := fs.String("tier", os.Getenv("GEMINI_TIER"), "Gemini API tier whose quotas to pace requests to: free or paid (default free)")
+	resume := fs.Bool("resume", false, "keep the pages summarized by an earlier run that hit the daily quota and send only the rest")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		return fmt.Errorf("usage: go run approach/main.go [-tier free|paid] [-resume] <pdf-file>")
+	}
+	if *tier == "" {
+		*tier = geminiquota.TierFree
+	}
+	profile, err := geminiquota.GetProfile(*tier, modelName)
+	if err != nil {
+		return err
 	}
 
-	pdfPath := os.Args[1]
+	pdfPath := fs.Arg(0)
 	if _, err := os.Stat(pdfPath); os.IsNotExist(err) {
-		log.Fatalf("Error: PDF file not found: %s", pdfPath)
+		return fmt.Errorf("PDF file not found: %s", pdfPath)
 	}
 
 	fmt.Printf("📄 Processing PDF: %s\n", pdfPath)
@@ -46,7 +78,7 @@ func main() {
 
 	doc, err := fitz.New(pdfPath)
 	if err != nil {
-		log.Fatalf("Error opening PDF: %v", err)
+		return fmt.Errorf("error opening PDF: %v", err)
 	}
 	defer doc.Close()
 
@@ -56,12 +88,29 @@ func main() {
 		maxPages = totalPages
 	}
 
-	fmt.Printf("📊 Total pages: %d (processing first %d pages)\n\n", totalPages, maxPages)
+	fmt.Printf("📊 Total pages: %d (processing first %d pages)\n", totalPages, maxPages)
+	geminicost.PrintPricing(modelName)
+	pacer := geminiquota.NewPacer(profile, modelName, geminiquota.DefaultStatePath())
+	fmt.Printf("🚦 Quota (%s tier): %d requests/min, %d tokens/min", *tier, profile.RPM, profile.TPM)
+	if used, perDay := pacer.Used(); perDay > 0 {
+		fmt.Printf(", %d of %d requests/day used", used, perDay)
+	}
+	fmt.Println()
+
+	progressPath := geminiquota.ProgressPath(pdfPath)
+	var done map[int]geminiquota.Page
+	if *resume {
+		if done, err = geminiquota.LoadProgress(progressPath); err != nil {
+			return err
+		}
+		fmt.Printf("♻️  Resuming: %d page(s) summarized by an earlier run\n", len(done))
+	}
+	fmt.Println()
 
 	ctx := context.Background()
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: apiKey})
 	if err != nil {
-		log.Fatalf("Error creating Gemini client: %v", err)
+		return fmt.Errorf("error creating Gemini client: %v", err)
 	}
 
 	results := make([]PageResult, maxPages)
@@ -87,6 +136,13 @@ func main() {
 				defer wg.Done()
 
 				pageNum := pageIndex + 1
+				if page, ok := done[pageNum]; ok {
+					mu.Lock()
+					results[pageIndex] = PageResult{PageNumber: pageNum, Summary: page.Summary, Usage: page.Usage, Resumed: true}
+					mu.Unlock()
+					fmt.Printf("  ♻️  Page %d: kept from the earlier run\n", pageNum)
+					return
+				}
 				fmt.Printf("  🔄 Processing page %d...\n", pageNum)
 
 				var pageResult PageResult
@@ -121,13 +177,17 @@ func main() {
 							},
 						}
 
-						result, err := client.Models.GenerateContent(ctx, "gemini-2.5-flash-lite", content, nil)
-						if err != nil {
+						result, err := generate(ctx, client, pacer, content)
+						if geminiquota.IsDailyQuota(err) {
+							pageResult.Error = geminiquota.ErrDailyQuota
+							fmt.Printf("  📅 Page %d: daily quota exhausted, not sent\n", pageNum)
+						} else if err != nil {
 							pageResult.Error = fmt.Errorf("API error: %v", err)
 							fmt.Printf("  ❌ Page %d: API error\n", pageNum)
 						} else {
 							pageResult.Summary = result.Text()
-							fmt.Printf("  ✅ Page %d: Summary received\n", pageNum)
+							pageResult.Usage = geminicost.UsageOf(modelName, result)
+							fmt.Printf("  ✅ Page %d: Summary received (%s)\n", pageNum, pageResult.Usage)
 						}
 					}
 				}
@@ -149,11 +209,48 @@ func main() {
 	fmt.Println("📋 SUMMARIES")
 	fmt.Println(strings.Repeat("=", 50) + "\n")
 
+	var total geminicost.Usage
+	var pages []geminiquota.Page
+	notSent := 0
 	for _, result := range results {
+		if !result.Resumed {
+			total.Add(result.Usage)
+		}
 		if result.Error != nil {
+			if errors.Is(result.Error, geminiquota.ErrDailyQuota) {
+				notSent++
+			}
 			fmt.Printf("Page %d: ❌ Error - %v\n\n", result.PageNumber, result.Error)
 		} else {
+			pages = append(pages, geminiquota.Page{Number: result.PageNumber, Summary: result.Summary, Usage: result.Usage})
 			fmt.Printf("Page %d:\n%s\n\n", result.PageNumber, result.Summary)
 		}
 	}
+	geminicost.PrintSummary(total)
+
+	// Pages the daily quota stopped are sent by a later run with -resume
+	if notSent > 0 {
+		if err := geminiquota.SaveProgress(progressPath, pages); err != nil {
+			slog.Warn("Could not save progress", "path", progressPath, "error", err)
+		}
+		reset := geminiquota.NextReset(time.Now())
+		fmt.Printf("\n📅 Daily quota exhausted: %d page(s) not sent. The quota resets at %s (in %v);\n", notSent, reset.Local().Format("15:04 MST"), time.Until(reset).Round(time.Minute))
+		fmt.Printf("   run again with -resume to summarize only the remaining pages (progress saved to %s)\n", progressPath)
+	} else if *resume {
+		os.Remove(progressPath)
+	}
+	return nil
+}
+
+// generate sends a page request once the quota pacer lets it through. A 429
+// for the daily quota stops all later requests of the run.
+func generate(ctx context.Context, client *genai.Client, pacer *geminiquota.Pacer, content []*genai.Content) (*genai.GenerateContentResponse, error) {
+	if err := pacer.Wait(ctx, pageTokenEstimate); err != nil {
+		return nil, err
+	}
+	result, err := client.Models.GenerateContent(ctx, modelName, content, nil)
+	if geminiquota.IsDailyQuota(err) {
+		pacer.Exhausted()
+	}
+	return result, err
 }
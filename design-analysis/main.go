@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -32,38 +32,45 @@ type DesignAnalysisResult struct {
 }
 
 func main() {
+	setupLogging()
+	if err := run(os.Args[1:]); err != nil {
+		fatal(err)
+	}
+}
+
+// run analyzes the whole PDF in one request and saves the formatted result
+func run(args []string) error {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		// Try loading from parent directory
 		if err := godotenv.Load("../.env"); err != nil {
-			log.Println("Warning: Could not load .env file. Using environment variables.")
+			slog.Warn("Could not load .env file, using environment variables")
 		}
 	}
 
 	// Parse command line arguments
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: go run main.go <pdf-file> [output-level]\n" +
-			"Output levels: executive (default), technical, detailed")
+	if len(args) < 1 {
+		return fmt.Errorf("usage: go run main.go <pdf-file> [output-level] (output levels: executive (default), technical, detailed)")
 	}
 
 	config := &Config{
 		APIKey:      os.Getenv("GEMINI_API_KEY"),
 		ModelName:   "gemini-2.5-flash-lite", // Using stable, free-tier compatible model
-		PDFPath:     os.Args[1],
+		PDFPath:     args[0],
 		OutputLevel: "executive",
 	}
 
 	if config.APIKey == "" {
-		log.Fatal("Error: GEMINI_API_KEY not found in environment variables")
+		return fmt.Errorf("GEMINI_API_KEY not found in environment variables")
 	}
 
-	if len(os.Args) >= 3 {
-		config.OutputLevel = os.Args[2]
+	if len(args) >= 2 {
+		config.OutputLevel = args[1]
 	}
 
 	// Validate PDF file
 	if _, err := os.Stat(config.PDFPath); os.IsNotExist(err) {
-		log.Fatalf("Error: PDF file not found: %s", config.PDFPath)
+		return fmt.Errorf("PDF file not found: %s", config.PDFPath)
 	}
 
 	fmt.Println(strings.Repeat("=", 62))
@@ -71,7 +78,9 @@ func main() {
 	fmt.Println(strings.Repeat("=", 62))
 	fmt.Printf("\n📄 Processing: %s\n", filepath.Base(config.PDFPath))
 	fmt.Printf("📊 Output Level: %s\n", config.OutputLevel)
-	fmt.Printf("🤖 Model: %s\n\n", config.ModelName)
+	fmt.Printf("🤖 Model: %s\n", config.ModelName)
+	pricing := GetPricing(config.ModelName)
+	fmt.Printf("💰 Model Pricing: $%.3f/M input, $%.2f/M output\n\n", pricing.InputPricePerMTokens, pricing.OutputPricePerMTokens)
 
 	startTime := time.Now()
 
@@ -79,7 +88,7 @@ func main() {
 	fmt.Println("📖 Reading PDF file...")
 	pdfBytes, err := os.ReadFile(config.PDFPath)
 	if err != nil {
-		log.Fatalf("Error reading PDF file: %v", err)
+		return fmt.Errorf("error reading PDF file: %v", err)
 	}
 	fmt.Printf("✅ PDF loaded: %d bytes\n\n", len(pdfBytes))
 
@@ -87,7 +96,7 @@ func main() {
 	ctx := context.Background()
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: config.APIKey})
 	if err != nil {
-		log.Fatalf("Error creating Gemini client: %v", err)
+		return fmt.Errorf("error creating Gemini client: %v", err)
 	}
 
 	// Generate comprehensive prompt based on output level
@@ -117,14 +126,17 @@ func main() {
 	apiStartTime := time.Now()
 	result, err := client.Models.GenerateContent(ctx, config.ModelName, content, nil)
 	if err != nil {
-		log.Fatalf("❌ API Error: %v", err)
+		return fmt.Errorf("API error: %v", err)
 	}
 
 	apiDuration := time.Since(apiStartTime)
 	totalDuration := time.Since(startTime)
 
+	usage := usageOf(config.ModelName, result)
+
 	fmt.Printf("✅ Analysis completed in: %v\n", apiDuration)
-	fmt.Printf("⏱️  Total time: %v\n\n", totalDuration)
+	fmt.Printf("⏱️  Total time: %v\n", totalDuration)
+	fmt.Printf("💰 Input Tokens: %d, Output Tokens: %d, Cost: $%.6f\n\n", usage.InputTokens, usage.OutputTokens, usage.TotalCost)
 
 	// Format and display results
 	analysis := result.Text()
@@ -139,10 +151,11 @@ func main() {
 	// Save to file
 	outputFile := generateOutputFilename(config.PDFPath, config.OutputLevel)
 	if err := os.WriteFile(outputFile, []byte(formattedOutput), 0644); err != nil {
-		log.Printf("Warning: Could not save output to file: %v", err)
+		slog.Warn("Could not save output to file", "path", outputFile, "error", err)
 	} else {
 		fmt.Printf("\n💾 Results saved to: %s\n", outputFile)
 	}
+	return nil
 }
 
 // generateOutputFilename creates an output filename based on input PDF
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"google.golang.org/genai"
+)
+
+// GeminiPricing holds pricing information for a Gemini model
+type GeminiPricing struct {
+	InputPricePerMTokens  float64 // Price per million input tokens (text, image, and PDF)
+	OutputPricePerMTokens float64 // Price per million output tokens, thinking included
+}
+
+// ModelPricing holds the paid-tier prices of the Gemini models, for prompts
+// up to 200k tokens. Keep in sync with geminicost in the root module.
+var ModelPricing = map[string]GeminiPricing{
+	"gemini-2.5-flash-lite": {
+		InputPricePerMTokens:  0.10, // $0.10 per million input tokens
+		OutputPricePerMTokens: 0.40, // $0.40 per million output tokens
+	},
+	"gemini-2.5-flash": {
+		InputPricePerMTokens:  0.30,
+		OutputPricePerMTokens: 2.50,
+	},
+	"gemini-2.5-pro": {
+		InputPricePerMTokens:  1.25,
+		OutputPricePerMTokens: 10.00,
+	},
+	"gemini-2.0-flash": {
+		InputPricePerMTokens:  0.10,
+		OutputPricePerMTokens: 0.40,
+	},
+	"gemini-2.0-flash-lite": {
+		InputPricePerMTokens:  0.075,
+		OutputPricePerMTokens: 0.30,
+	},
+}
+
+// GetPricing returns pricing for a given model name
+func GetPricing(modelName string) GeminiPricing {
+	if pricing, ok := ModelPricing[modelName]; ok {
+		return pricing
+	}
+	// Default to Flash-Lite pricing if model not found
+	return ModelPricing["gemini-2.5-flash-lite"]
+}
+
+// Usage is the token count and cost of a request
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+	InputCost    float64
+	OutputCost   float64
+	TotalCost    float64
+}
+
+// usageOf reads the usage metadata of a response and prices it for the
+// model. Thinking tokens are billed as output.
+func usageOf(modelName string, resp *genai.GenerateContentResponse) Usage {
+	if resp == nil || resp.UsageMetadata == nil {
+		return Usage{}
+	}
+	meta := resp.UsageMetadata
+	pricing := GetPricing(modelName)
+	u := Usage{
+		InputTokens:  int(meta.PromptTokenCount + meta.ToolUsePromptTokenCount),
+		OutputTokens: int(meta.CandidatesTokenCount + meta.ThoughtsTokenCount),
+	}
+	u.InputCost = float64(u.InputTokens) / 1_000_000 * pricing.InputPricePerMTokens
+	u.OutputCost = float64(u.OutputTokens) / 1_000_000 * pricing.OutputPricePerMTokens
+	u.TotalCost = u.InputCost + u.OutputCost
+	return u
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"design-ant/internal/tempfiles"
+	"design-ant/pkg/pdfanalysis"
+)
+
+// spendAlerts posts to a webhook when the running cost of a run crosses one
+// of its thresholds, so a long unattended run cannot run up a bill unnoticed.
+// The payload's text field makes it work as a Slack incoming webhook; other
+// receivers can use the structured fields.
+type spendAlerts struct {
+	url        string
+	document   string
+	project    string
+	thresholds []float64 // Ascending
+	next       int       // First threshold not yet crossed
+	wg         sync.WaitGroup
+}
+
+// spendAlert is the webhook payload
+type spendAlert struct {
+	Text       string  `json:"text"`
+	Document   string  `json:"document"`
+	Project    string  `json:"project,omitempty"`
+	Threshold  float64 `json:"threshold"`
+	Cost       float64 `json:"cost"`
+	PagesDone  int     `json:"pages_done"`
+	PagesTotal int     `json:"pages_total"`
+}
+
+// parseThresholds parses a comma-separated list of dollar amounts
+func parseThresholds(s string) ([]float64, error) {
+	var thresholds []float64
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimPrefix(strings.TrimSpace(field), "$")
+		if field == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("invalid -alert-at %q: must be positive dollar amounts, e.g. 1,5,20", s)
+		}
+		thresholds = append(thresholds, value)
+	}
+	sort.Float64s(thresholds)
+	return thresholds, nil
+}
+
+// newSpendAlerts returns the alerts of a run, or nil without -alert-webhook
+func newSpendAlerts(config *pdfanalysis.Config) *spendAlerts {
+	if config.AlertWebhook == "" {
+		return nil
+	}
+	return &spendAlerts{
+		url:        config.AlertWebhook,
+		document:   filepath.Base(config.DocumentPath()),
+		project:    config.Project,
+		thresholds: config.AlertThresholds,
+	}
+}
+
+// observe checks the running cost of the run and sends one alert for the
+// highest threshold it crossed since the last call. It is called from one
+// goroutine only; the webhook is posted in the background.
+func (a *spendAlerts) observe(cost float64, done, total int) {
+	if a == nil {
+		return
+	}
+	crossed := a.next
+	for crossed < len(a.thresholds) && cost >= a.thresholds[crossed] {
+		crossed++
+	}
+	if crossed == a.next {
+		return
+	}
+	a.next = crossed
+	threshold := a.thresholds[crossed-1]
+
+	alert := spendAlert{
+		Text: fmt.Sprintf("design-ant: %s has spent $%.2f, over the $%.2f alert threshold (%d of %d pages done)",
+			a.document, cost, threshold, done, total),
+		Document:   a.document,
+		Project:    a.project,
+		Threshold:  threshold,
+		Cost:       cost,
+		PagesDone:  done,
+		PagesTotal: total,
+	}
+	if a.project != "" {
+		alert.Text += fmt.Sprintf(" [project %s]", a.project)
+	}
+	pdfanalysis.Logf("  🔔 Spend alert: $%.2f passed the $%.2f threshold\n", cost, threshold)
+	a.wg.Add(1)
+	go func() {
+		defer tempfiles.CleanupOnPanic()
+		defer a.wg.Done()
+		if err := postAlert(a.url, alert); err != nil {
+			pdfanalysis.Logf("  ⚠️  Spend alert not sent: %v\n", err)
+		}
+	}()
+}
+
+// wait waits for the alerts still being sent
+func (a *spendAlerts) wait() {
+	if a != nil {
+		a.wg.Wait()
+	}
+}
+
+// postAlert posts an alert to the webhook
+func postAlert(url string, alert spendAlert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	resp, err := pdfanalysis.QuickClient.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
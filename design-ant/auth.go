@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Roles of the server's callers. Admin includes the other two.
+const (
+	roleRead   = "read"   // List jobs, follow their progress, see tenant usage
+	roleSubmit = "submit" // Queue jobs
+	roleAdmin  = "admin"  // Purge jobs, and act for any tenant with X-Tenant
+)
+
+// principal is an authenticated caller
+type principal struct {
+	name   string
+	roles  []string
+	tenant string // Tenant the caller acts for (empty = none)
+}
+
+// has reports whether the caller holds role
+func (p *principal) has(role string) bool {
+	return slices.Contains(p.roles, role) || slices.Contains(p.roles, roleAdmin)
+}
+
+type principalKey struct{}
+
+// principalFrom returns the caller of a request, nil when auth is off
+func principalFrom(ctx context.Context) *principal {
+	p, _ := ctx.Value(principalKey{}).(*principal)
+	return p
+}
+
+// staticToken is one entry of the -tokens file. Only the SHA-256 of the
+// token is stored, so the file holds no secrets.
+type staticToken struct {
+	Name   string   `json:"name"`
+	SHA256 string   `json:"sha256"` // Hex SHA-256 of the token, e.g. from `printf %s "$TOKEN" | sha256sum`
+	Roles  []string `json:"roles"`
+	Tenant string   `json:"tenant,omitempty"`
+}
+
+// authenticator checks the bearer tokens of requests against static tokens,
+// an OIDC issuer, or both
+type authenticator struct {
+	tokens map[string]*principal // By hex SHA-256 of the token
+	oidc   *oidcVerifier
+}
+
+// loadTokens reads a -tokens file
+func loadTokens(path string) (map[string]*principal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var list []staticToken
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("error parsing tokens %s: %v", path, err)
+	}
+	tokens := make(map[string]*principal, len(list))
+	for _, token := range list {
+		hash := strings.ToLower(token.SHA256)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != 64 {
+			return nil, fmt.Errorf("error parsing tokens %s: %s needs the hex SHA-256 of its token", path, token.Name)
+		}
+		if err := checkRoles(token.Roles); err != nil {
+			return nil, fmt.Errorf("error parsing tokens %s: %s: %v", path, token.Name, err)
+		}
+		tokens[hash] = &principal{name: token.Name, roles: token.Roles, tenant: token.Tenant}
+	}
+	return tokens, nil
+}
+
+// checkRoles rejects unknown role names
+func checkRoles(roles []string) error {
+	for _, role := range roles {
+		if role != roleRead && role != roleSubmit && role != roleAdmin {
+			return fmt.Errorf("unknown role %q: use read, submit, or admin", role)
+		}
+	}
+	return nil
+}
+
+// authenticate returns the caller of a request. The token comes from the
+// Authorization header; event streams also accept ?access_token=, since
+// browsers cannot set headers on an EventSource.
+func (a *authenticator) authenticate(r *http.Request) (*principal, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok && strings.HasSuffix(r.URL.Path, "/events") {
+		token = r.URL.Query().Get("access_token")
+	}
+	return a.verify(r.Context(), token)
+}
+
+// verify returns the caller a bearer token belongs to
+func (a *authenticator) verify(ctx context.Context, token string) (*principal, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, fmt.Errorf("missing bearer token")
+	}
+	sum := sha256.Sum256([]byte(token))
+	if p := a.tokens[hex.EncodeToString(sum[:])]; p != nil {
+		return p, nil
+	}
+	if a.oidc != nil && strings.Count(token, ".") == 2 {
+		return a.oidc.verify(ctx, token)
+	}
+	return nil, fmt.Errorf("invalid token")
+}
+
+// require wraps a handler so only callers holding role reach it. Without
+// an authenticator every request does.
+func (s *server) require(role string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.auth == nil {
+			handler(w, r)
+			return
+		}
+		p, err := s.auth.authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="design-ant"`)
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+		if !p.has(role) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("%s lacks the %s role", p.name, role))
+			return
+		}
+		handler(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+	}
+}
+
+// isLoopback reports whether a listen host only accepts local connections;
+// an empty host listens on every interface
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
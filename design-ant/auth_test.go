@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTokens writes a -tokens file with one token per role, named after
+// the role, and returns its path
+func writeTokens(t *testing.T, roles ...string) string {
+	t.Helper()
+	var list []staticToken
+	for _, role := range roles {
+		sum := sha256.Sum256([]byte(role + "-token"))
+		list = append(list, staticToken{Name: role, SHA256: hex.EncodeToString(sum[:]), Roles: []string{role}})
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadTokens(t *testing.T) {
+	if _, err := loadTokens(writeTokens(t, roleRead, roleSubmit, roleAdmin)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadTokens(writeTokens(t, "root")); err == nil {
+		t.Error("token with an unknown role loaded")
+	}
+}
+
+func TestRequireRoles(t *testing.T) {
+	s := testServer(t)
+	tokens, err := loadTokens(writeTokens(t, roleRead, roleSubmit, roleAdmin))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.auth = &authenticator{tokens: tokens}
+	handler := s.routes()
+
+	tests := []struct {
+		method, path, token string
+		want                int
+	}{
+		{"GET", "/jobs", "", http.StatusUnauthorized},
+		{"GET", "/jobs", "forged-token", http.StatusUnauthorized},
+		{"GET", "/jobs", "read-token", http.StatusOK},
+		{"GET", "/jobs", "submit-token", http.StatusForbidden},
+		{"GET", "/jobs", "admin-token", http.StatusOK},
+		// Past the role check, the missing form or job fails the request
+		{"POST", "/jobs", "read-token", http.StatusForbidden},
+		{"POST", "/jobs", "submit-token", http.StatusBadRequest},
+		{"POST", "/jobs", "admin-token", http.StatusBadRequest},
+		{"DELETE", "/jobs/missing", "read-token", http.StatusForbidden},
+		{"DELETE", "/jobs/missing", "submit-token", http.StatusForbidden},
+		{"DELETE", "/jobs/missing", "admin-token", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s with %q: status %d, want %d (%s)", tt.method, tt.path, tt.token, rec.Code, tt.want, rec.Body)
+		}
+	}
+}
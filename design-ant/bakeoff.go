@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"design-ant/internal/tempfiles"
+	"design-ant/pkg/pdfanalysis"
+)
+
+// BakeoffReport compares models on the same sample pages of a document
+type BakeoffReport struct {
+	PDFPath     string                    `json:"pdf_path"`
+	Pages       []int                     `json:"pages"`     // Sampled page numbers
+	Reference   string                    `json:"reference"` // Model whose outputs the others are compared with
+	Models      []BakeoffResult           `json:"models"`
+	Pricing     *pdfanalysis.PricingTable `json:"pricing"`
+	GeneratedAt time.Time                 `json:"generated_at"`
+	Build       *pdfanalysis.BuildInfo    `json:"build,omitempty"` // design-ant build that ran the bake-off
+}
+
+// BakeoffResult is one model's run over the sample pages
+type BakeoffResult struct {
+	Model        string        `json:"model"`
+	Provider     string        `json:"provider"`
+	FailedPages  int           `json:"failed_pages,omitempty"`
+	InputTokens  int           `json:"input_tokens"`
+	OutputTokens int           `json:"output_tokens"`
+	TotalCost    float64       `json:"total_cost"`
+	CostPerPage  float64       `json:"cost_per_page"`
+	Latency      string        `json:"latency"`              // Mean time per page
+	Similarity   float64       `json:"similarity,omitempty"` // Mean word overlap with the reference, 0-1
+	Outputs      []BakeoffPage `json:"outputs"`
+}
+
+// BakeoffPage is one model's output for one sample page
+type BakeoffPage struct {
+	Page       int     `json:"page"`
+	Analysis   string  `json:"analysis,omitempty"`
+	Similarity float64 `json:"similarity,omitempty"`
+	Latency    string  `json:"latency"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// runBakeoff sends a sample of pages to several models with the analysis
+// prompt and reports their cost, latency, and how closely each output
+// matches the reference model's, to find the cheapest model that is good
+// enough for a document type
+func runBakeoff(args []string) error {
+	fs := flag.NewFlagSet("bakeoff", flag.ContinueOnError)
+	models := fs.String("models", "claude-3-5-sonnet-20241022,claude-3-5-haiku-20241022,gemini-2.5-flash", "comma-separated models to compare; the first is the reference unless -reference is set")
+	reference := fs.String("reference", "", "model whose outputs the others are compared with (default: the first of -models)")
+	sample := fs.Int("n", 3, "number of pages to sample, spread evenly over the document")
+	minSimilarity := fs.Float64("min-similarity", 0.5, "recommend the cheapest model whose mean similarity to the reference is at least this")
+	chunkTimeout := fs.Duration("chunk-timeout", pdfanalysis.DefaultRequestTimeout, "time limit for one attempt of a page request")
+	pricingFile := fs.String("pricing-file", "", "read model prices from this JSON file instead of the built-in pricing.json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *sample < 1 {
+		return fmt.Errorf("usage: go run . bakeoff [-models a,b,c] [-n 3] <pdf-file>")
+	}
+	pdfPath := fs.Arg(0)
+	if *pricingFile != "" {
+		if err := pdfanalysis.LoadPricingFile(*pricingFile); err != nil {
+			return err
+		}
+	}
+
+	var names []string
+	for _, name := range strings.Split(*models, ",") {
+		if name = strings.TrimSpace(name); name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("-models lists no models")
+	}
+	if *reference == "" {
+		*reference = names[0]
+	} else if !slices.Contains(names, *reference) {
+		names = append([]string{*reference}, names...)
+	}
+	apiKeys := map[string]string{
+		pdfanalysis.ProviderAnthropic: os.Getenv("ANTHROPIC_API_KEY"),
+		pdfanalysis.ProviderGemini:    os.Getenv("GEMINI_API_KEY"),
+	}
+	for _, name := range names {
+		if provider := pdfanalysis.ModelProvider(name); apiKeys[provider] == "" {
+			return fmt.Errorf("%s needs %s_API_KEY", name, strings.ToUpper(provider))
+		}
+	}
+
+	totalPages, err := pdfanalysis.GetPageCount(pdfPath)
+	if err != nil {
+		return err
+	}
+	pages := samplePages(totalPages, *sample)
+	temp, err := tempfiles.New("pdf-bakeoff")
+	if err != nil {
+		return err
+	}
+	defer temp.Remove()
+	paths := make(map[int]string)
+	for _, page := range pages {
+		if paths[page], err = pdfanalysis.ExtractPage(pdfPath, temp.Path(), page); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("🥊 Bake-off: %d model(s) on page(s) %s of %s\n", len(names), joinInts(pages), filepath.Base(pdfPath))
+	report := BakeoffReport{PDFPath: pdfPath, Pages: pages, Reference: *reference, Pricing: pdfanalysis.PricingSnapshot(), GeneratedAt: time.Now(), Build: pdfanalysis.Build()}
+	ctx := context.Background()
+	for _, name := range names {
+		fmt.Printf("  🔄 %s...\n", name)
+		result := BakeoffResult{Model: name, Provider: pdfanalysis.ModelProvider(name)}
+		var elapsed time.Duration
+		for _, page := range pages {
+			start := time.Now()
+			analysis, inputTokens, outputTokens, err := bakeoffRequest(ctx, result.Provider, apiKeys[result.Provider], name, paths[page], pdfanalysis.GenerateAnalysisPrompt(page), *chunkTimeout)
+			took := time.Since(start)
+			elapsed += took
+			result.InputTokens += inputTokens
+			result.OutputTokens += outputTokens
+			output := BakeoffPage{Page: page, Analysis: analysis, Latency: took.Round(time.Millisecond).String()}
+			if err != nil {
+				result.FailedPages++
+				output.Error = err.Error()
+				fmt.Printf("  ❌ %s, page %d: %v\n", name, page, err)
+			}
+			result.Outputs = append(result.Outputs, output)
+		}
+		pricing := pdfanalysis.GetPricing(name)
+		result.TotalCost = float64(result.InputTokens)/1_000_000*pricing.InputPricePerMTokens +
+			float64(result.OutputTokens)/1_000_000*pricing.OutputPricePerMTokens
+		result.CostPerPage = result.TotalCost / float64(len(pages))
+		result.Latency = (elapsed / time.Duration(len(pages))).Round(time.Millisecond).String()
+		report.Models = append(report.Models, result)
+	}
+	scoreSimilarity(&report)
+
+	printBakeoff(os.Stdout, &report, *minSimilarity)
+	reportFile := pdfanalysis.GenerateOutputFilename(pdfPath, "bakeoff.json")
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(reportFile, data, 0644); err != nil {
+		return fmt.Errorf("error writing bake-off report: %v", err)
+	}
+	fmt.Printf("\n💾 Outputs and costs saved to: %s\n", reportFile)
+	return nil
+}
+
+// samplePages returns n page numbers spread evenly over the document, each
+// in the middle of its share of the pages
+func samplePages(totalPages, n int) []int {
+	n = min(n, totalPages)
+	pages := make([]int, n)
+	for i := range pages {
+		pages[i] = (2*i+1)*totalPages/(2*n) + 1
+	}
+	return pages
+}
+
+// bakeoffRequest sends one page to a model, retrying like the analysis does
+func bakeoffRequest(ctx context.Context, provider, apiKey, model, path, prompt string, timeout time.Duration) (string, int, int, error) {
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		var text string
+		var inputTokens, outputTokens int
+		var err error
+		if provider == pdfanalysis.ProviderGemini {
+			text, inputTokens, outputTokens, err = pdfanalysis.AnalyzeChunkGemini(attemptCtx, apiKey, model, path, prompt)
+		} else {
+			text, inputTokens, outputTokens, err = pdfanalysis.AnalyzeChunk(attemptCtx, apiKey, model, path, prompt)
+		}
+		cancel()
+		if err == nil {
+			return text, inputTokens, outputTokens, nil
+		}
+		waitTime, retry := pdfanalysis.RetryDelay(err, attempt)
+		if !retry {
+			return text, inputTokens, outputTokens, err
+		}
+		fmt.Printf("  ⚠️  Request failed (%s), retrying in %v...\n", pdfanalysis.ClassifyError(err), waitTime.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return "", 0, 0, ctx.Err()
+		case <-time.After(waitTime):
+		}
+	}
+}
+
+// scoreSimilarity compares every page output with the reference model's
+// output for the same page. The score is the overlap of their word sets
+// (Jaccard), a cheap proxy for whether a model found the same part
+// numbers, dimensions, and notes.
+func scoreSimilarity(report *BakeoffReport) {
+	var reference *BakeoffResult
+	for i := range report.Models {
+		if report.Models[i].Model == report.Reference {
+			reference = &report.Models[i]
+		}
+	}
+	if reference == nil {
+		return
+	}
+	for i := range report.Models {
+		result := &report.Models[i]
+		if result == reference {
+			continue
+		}
+		var total float64
+		var scored int
+		for j := range result.Outputs {
+			output, want := &result.Outputs[j], reference.Outputs[j]
+			if output.Error != "" || want.Error != "" {
+				continue
+			}
+			output.Similarity = wordOverlap(output.Analysis, want.Analysis)
+			total += output.Similarity
+			scored++
+		}
+		if scored > 0 {
+			result.Similarity = total / float64(scored)
+		}
+	}
+}
+
+// wordOverlap returns the Jaccard similarity of the word sets of two texts
+func wordOverlap(a, b string) float64 {
+	words := func(text string) map[string]bool {
+		set := make(map[string]bool)
+		for _, word := range questionTerm.FindAllString(strings.ToLower(text), -1) {
+			set[word] = true
+		}
+		return set
+	}
+	setA, setB := words(a), words(b)
+	if len(setA) == 0 && len(setB) == 0 {
+		return 1
+	}
+	shared := 0
+	for word := range setA {
+		if setB[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(setA)+len(setB)-shared)
+}
+
+// printBakeoff prints the comparison table and the cheapest model whose
+// similarity to the reference reaches minSimilarity
+func printBakeoff(w io.Writer, report *BakeoffReport, minSimilarity float64) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, strings.Repeat("=", 100))
+	fmt.Fprintf(w, "%-30s %6s %10s %10s %11s %11s %10s %10s\n", "MODEL", "FAILED", "INPUT", "OUTPUT", "COST", "COST/PAGE", "LATENCY", "SIMILARITY")
+	fmt.Fprintln(w, strings.Repeat("-", 100))
+	var best *BakeoffResult
+	for i := range report.Models {
+		result := &report.Models[i]
+		similarity := fmt.Sprintf("%.2f", result.Similarity)
+		if result.Model == report.Reference {
+			similarity = "reference"
+		}
+		fmt.Fprintf(w, "%-30s %6d %10d %10d %11s %11s %10s %10s\n",
+			pdfanalysis.Truncate(result.Model, 30), result.FailedPages, result.InputTokens, result.OutputTokens,
+			fmt.Sprintf("$%.4f", result.TotalCost), fmt.Sprintf("$%.4f", result.CostPerPage), result.Latency, similarity)
+
+		good := result.FailedPages == 0 && (result.Model == report.Reference || result.Similarity >= minSimilarity)
+		if good && (best == nil || result.CostPerPage < best.CostPerPage) {
+			best = result
+		}
+	}
+	fmt.Fprintln(w, strings.Repeat("=", 100))
+	if best != nil {
+		fmt.Fprintf(w, "💡 Cheapest model with similarity ≥ %.2f to %s: %s ($%.4f per page)\n", minSimilarity, report.Reference, best.Model, best.CostPerPage)
+	}
+}
+
+// joinInts formats page numbers as "1, 5, 9"
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, ", ")
+}
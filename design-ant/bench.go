@@ -0,0 +1,239 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/jpeg"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"time"
+
+	"design-ant/internal/tempfiles"
+	"design-ant/pkg/pdfanalysis"
+	"github.com/gen2brain/go-fitz"
+)
+
+// benchStage is one local step of the page pipeline
+type benchStage struct {
+	name string
+	run  func(b *benchRun) error
+}
+
+// benchRun is the state shared by the stages of one iteration
+type benchRun struct {
+	pdfPath    string
+	temp       *tempfiles.Dir
+	chunkSize  int
+	dpi        float64
+	totalPages int
+	chunks     []pdfanalysis.ChunkInfo
+	bytes      int64 // Output of the current stage, for the throughput column
+}
+
+// benchStages are the steps a run takes before its first API request, in order
+var benchStages = []benchStage{
+	{"page count", func(b *benchRun) error {
+		var err error
+		b.totalPages, err = pdfanalysis.GetPageCount(b.pdfPath)
+		return err
+	}},
+	{"fingerprint", func(b *benchRun) error {
+		_, err := pdfanalysis.PageFingerprints(b.pdfPath, b.totalPages)
+		return err
+	}},
+	{"route", func(b *benchRun) error {
+		routes, err := pdfanalysis.ClassifyPages(b.pdfPath, b.totalPages)
+		for _, route := range routes {
+			b.bytes += int64(len(route.Text))
+		}
+		return err
+	}},
+	{"split", func(b *benchRun) error {
+		var err error
+		b.chunks, err = pdfanalysis.SplitPDFIntoChunks(b.pdfPath, b.temp.Path(), b.chunkSize, b.totalPages)
+		if err != nil {
+			return err
+		}
+		b.bytes, err = b.temp.Size()
+		return err
+	}},
+	{"render", func(b *benchRun) error {
+		doc, err := fitz.New(b.pdfPath)
+		if err != nil {
+			return fmt.Errorf("error opening PDF: %v", err)
+		}
+		defer doc.Close()
+		counter := &countingWriter{}
+		for page := 0; page < b.totalPages; page++ {
+			img, err := doc.ImageDPI(page, b.dpi)
+			if err != nil {
+				return fmt.Errorf("error rendering page %d: %v", page+1, err)
+			}
+			if err := jpeg.Encode(counter, img, &jpeg.Options{Quality: 85}); err != nil {
+				return err
+			}
+		}
+		b.bytes = counter.n
+		return nil
+	}},
+	{"encode", func(b *benchRun) error {
+		// The request body exactly as a page request streams it
+		for _, chunk := range b.chunks {
+			content, err := pdfanalysis.ChunkContent(chunk.Path, "")
+			if err != nil {
+				return err
+			}
+			body, err := pdfanalysis.NewRequestBody(map[string]interface{}{
+				"messages": []map[string]interface{}{{"role": "user", "content": content}},
+			})
+			if err != nil {
+				return err
+			}
+			r := body.Reader()
+			n, err := io.Copy(io.Discard, r)
+			r.Close()
+			if err != nil {
+				return err
+			}
+			b.bytes += n
+		}
+		return nil
+	}},
+}
+
+// benchResult accumulates one stage over all iterations
+type benchResult struct {
+	name   string
+	times  []time.Duration
+	bytes  int64
+	allocs uint64 // Bytes allocated, summed over iterations
+}
+
+// runBench times the local pipeline stages on a sample PDF without any API
+// calls, so regressions in splitting, rendering, or encoding show up before
+// they are multiplied by API latency
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	iterations := fs.Int("n", 3, "number of times to run each stage")
+	chunkSize := fs.Int("chunk-size", 1, "pages per chunk when splitting")
+	dpi := fs.Float64("dpi", pdfanalysis.AnnotatedPageDPI, "render resolution for the render stage")
+	cpuProfile := fs.String("cpuprofile", "", "write a CPU profile of all iterations to this file (go tool pprof)")
+	memProfile := fs.String("memprofile", "", "write a heap allocation profile to this file after the last iteration")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: go run . bench [-n 3] [-chunk-size 1] [-dpi 110] [-cpuprofile cpu.out] [-memprofile mem.out] <sample.pdf>")
+	}
+	if *iterations < 1 {
+		return fmt.Errorf("invalid -n %d: must be at least 1", *iterations)
+	}
+	if *chunkSize < 1 {
+		return fmt.Errorf("invalid -chunk-size %d: must be at least 1", *chunkSize)
+	}
+	if *dpi <= 0 {
+		return fmt.Errorf("invalid -dpi %v: must be positive", *dpi)
+	}
+	pdfPath := fs.Arg(0)
+
+	if *cpuProfile != "" {
+		file, err := os.Create(*cpuProfile)
+		if err != nil {
+			return fmt.Errorf("error creating CPU profile: %v", err)
+		}
+		defer file.Close()
+		if err := pprof.StartCPUProfile(file); err != nil {
+			return fmt.Errorf("error starting CPU profile: %v", err)
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	fmt.Printf("⏱️  Benchmarking %s: %d iteration(s), no API calls\n\n", pdfPath, *iterations)
+	results := make([]benchResult, len(benchStages))
+	for i, stage := range benchStages {
+		results[i].name = stage.name
+	}
+	for iter := 0; iter < *iterations; iter++ {
+		temp, err := tempfiles.New("pdf-chunks")
+		if err != nil {
+			return err
+		}
+		run := &benchRun{pdfPath: pdfPath, temp: temp, chunkSize: *chunkSize, dpi: *dpi}
+		for i, stage := range benchStages {
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			run.bytes = 0
+			start := time.Now()
+			err := stage.run(run)
+			elapsed := time.Since(start)
+			runtime.ReadMemStats(&after)
+			if err != nil {
+				temp.Remove()
+				return fmt.Errorf("%s: %v", stage.name, err)
+			}
+			results[i].times = append(results[i].times, elapsed)
+			results[i].bytes = run.bytes
+			results[i].allocs += after.TotalAlloc - before.TotalAlloc
+		}
+		temp.Remove()
+	}
+
+	if *memProfile != "" {
+		file, err := os.Create(*memProfile)
+		if err != nil {
+			return fmt.Errorf("error creating memory profile: %v", err)
+		}
+		defer file.Close()
+		if err := pprof.Lookup("allocs").WriteTo(file, 0); err != nil {
+			return fmt.Errorf("error writing memory profile: %v", err)
+		}
+	}
+
+	printBenchTable(results, *iterations)
+	if *cpuProfile != "" {
+		fmt.Printf("\n💾 CPU profile saved to: %s\n", *cpuProfile)
+	}
+	if *memProfile != "" {
+		fmt.Printf("💾 Memory profile saved to: %s\n", *memProfile)
+	}
+	return nil
+}
+
+// printBenchTable prints the fastest and mean time of each stage, the size
+// of what it produced, and its allocations per iteration
+func printBenchTable(results []benchResult, iterations int) {
+	fmt.Printf("%-12s %12s %12s %12s %12s %12s\n", "STAGE", "MIN", "MEAN", "OUTPUT", "MB/S", "ALLOC/OP")
+	fmt.Println(strings.Repeat("-", 78))
+	var total time.Duration
+	for _, r := range results {
+		fastest, sum := r.times[0], time.Duration(0)
+		for _, t := range r.times {
+			fastest = min(fastest, t)
+			sum += t
+		}
+		mean := sum / time.Duration(len(r.times))
+		total += mean
+		output, throughput := "-", "-"
+		if r.bytes > 0 && fastest > 0 {
+			output = pdfanalysis.FormatMB(r.bytes)
+			throughput = fmt.Sprintf("%.1f", float64(r.bytes)/pdfanalysis.BytesPerMB/fastest.Seconds())
+		}
+		fmt.Printf("%-12s %12v %12v %12s %12s %12s\n", r.name, fastest.Round(time.Microsecond), mean.Round(time.Microsecond),
+			output, throughput, pdfanalysis.FormatMB(int64(r.allocs/uint64(iterations))))
+	}
+	fmt.Println(strings.Repeat("-", 78))
+	fmt.Printf("%-12s %12s %12v\n", "total", "", total.Round(time.Microsecond))
+}
+
+// countingWriter discards what is written and counts the bytes
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"design-ant/pkg/pdfanalysis"
+)
+
+// budgetWarnFraction is the share of a monthly budget from which runs of the project warn
+const budgetWarnFraction = 0.8
+
+// untaggedProject labels ledger runs without a -project in the spend report
+const untaggedProject = "(none)"
+
+// budgetsPath returns budgets.json next to the ledger, so a team sharing a
+// ledger also shares its budgets
+func budgetsPath(ledgerPath string) string {
+	return filepath.Join(filepath.Dir(ledgerPath), "budgets.json")
+}
+
+// loadBudgets reads the monthly budgets in dollars keyed by project. A
+// missing file means no budgets.
+func loadBudgets(path string) (map[string]float64, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]float64{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	budgets := map[string]float64{}
+	if err := json.Unmarshal(data, &budgets); err != nil {
+		return nil, fmt.Errorf("error parsing budgets %s: %v", path, err)
+	}
+	return budgets, nil
+}
+
+// saveBudgets writes the budgets file, creating its directory if needed
+func saveBudgets(path string, budgets map[string]float64) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(budgets, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// ledgerMonth returns the calendar month of t in local time, e.g. "2025-01"
+func ledgerMonth(t time.Time) string {
+	return t.Local().Format("2006-01")
+}
+
+// recordProject returns the project of a ledger record, untaggedProject when it has none
+func recordProject(record RunRecord) string {
+	if record.Project == "" {
+		return untaggedProject
+	}
+	return record.Project
+}
+
+// warnBudget warns when a project has spent budgetWarnFraction or more of
+// its budget for the current month. Budgets only warn; runs are never stopped.
+func warnBudget(ledgerPath, project string) {
+	budgets, err := loadBudgets(budgetsPath(ledgerPath))
+	if err != nil {
+		slog.Warn("Could not read budgets", "error", err)
+		return
+	}
+	budget, ok := budgets[project]
+	if !ok || budget <= 0 {
+		return
+	}
+	records, err := readRunRecords(ledgerPath)
+	if err != nil {
+		slog.Warn("Could not read run ledger", "path", ledgerPath, "error", err)
+		return
+	}
+
+	month := ledgerMonth(time.Now())
+	var spent float64
+	for _, record := range records {
+		if record.Project == project && ledgerMonth(record.StartedAt) == month {
+			spent += record.TotalCost
+		}
+	}
+	switch {
+	case spent >= budget:
+		slog.Warn("Project is over its monthly budget", "project", project, "month", month, "budget", budget, "spent", spent)
+	case spent >= budget*budgetWarnFraction:
+		slog.Warn("Project is close to its monthly budget", "project", project, "month", month, "budget", budget, "spent", spent, "used_percent", int(spent/budget*100))
+	}
+}
+
+// projectSpend is one row of the spend report
+type projectSpend struct {
+	project      string
+	runs         int
+	pages        int
+	inputTokens  int
+	outputTokens int
+	cost         float64
+}
+
+// runSpend prints the spend of each project for a month from the ledger, or
+// sets a project's monthly budget
+func runSpend(args []string) error {
+	fs := flag.NewFlagSet("spend", flag.ContinueOnError)
+	ledgerPath := fs.String("ledger", defaultLedgerPath(), "path to the run ledger; budgets are kept in budgets.json next to it")
+	month := fs.String("month", ledgerMonth(time.Now()), "month to report, e.g. 2025-01 (\"all\" = every month)")
+	project := fs.String("project", "", "only report this project")
+	budget := fs.Float64("budget", -1, "with -project, set its monthly budget in dollars instead of reporting (0 = remove)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *month != "all" {
+		if _, err := time.Parse("2006-01", *month); err != nil {
+			return fmt.Errorf("invalid -month %q: use YYYY-MM or all", *month)
+		}
+	}
+
+	path := budgetsPath(*ledgerPath)
+	budgets, err := loadBudgets(path)
+	if err != nil {
+		return err
+	}
+	if *budget >= 0 {
+		if *project == "" {
+			return fmt.Errorf("-budget needs -project")
+		}
+		if *budget == 0 {
+			delete(budgets, *project)
+		} else {
+			budgets[*project] = *budget
+		}
+		if err := saveBudgets(path, budgets); err != nil {
+			return fmt.Errorf("error saving budgets: %v", err)
+		}
+		fmt.Printf("✅ Budget for %s: $%.2f per month (%s)\n", *project, *budget, path)
+		return nil
+	}
+
+	records, err := readRunRecords(*ledgerPath)
+	if err != nil {
+		return fmt.Errorf("error reading ledger: %v", err)
+	}
+	byProject := make(map[string]*projectSpend)
+	for _, record := range records {
+		name := recordProject(record)
+		if *month != "all" && ledgerMonth(record.StartedAt) != *month {
+			continue
+		}
+		if *project != "" && name != *project {
+			continue
+		}
+		spend := byProject[name]
+		if spend == nil {
+			spend = &projectSpend{project: name}
+			byProject[name] = spend
+		}
+		spend.runs++
+		spend.pages += record.Pages
+		spend.inputTokens += record.InputTokens
+		spend.outputTokens += record.OutputTokens
+		spend.cost += record.TotalCost
+	}
+	// Projects with a budget are listed even before their first run of the month
+	for name := range budgets {
+		if byProject[name] == nil && (*project == "" || name == *project) {
+			byProject[name] = &projectSpend{project: name}
+		}
+	}
+	if len(byProject) == 0 {
+		fmt.Printf("No runs recorded in %s for %s\n", *ledgerPath, *month)
+		return nil
+	}
+
+	rows := make([]*projectSpend, 0, len(byProject))
+	for _, spend := range byProject {
+		rows = append(rows, spend)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].cost != rows[j].cost {
+			return rows[i].cost > rows[j].cost
+		}
+		return rows[i].project < rows[j].project
+	})
+
+	period := *month
+	if period == "all" {
+		period = "all months"
+	}
+	fmt.Printf("Spend for %s (%s)\n", period, *ledgerPath)
+	fmt.Println(strings.Repeat("=", 100))
+	fmt.Printf("%-24s %5s %7s %12s %12s %11s %11s %6s\n", "PROJECT", "RUNS", "PAGES", "INPUT", "OUTPUT", "COST", "BUDGET", "USED")
+	fmt.Println(strings.Repeat("-", 100))
+	var total float64
+	for _, spend := range rows {
+		total += spend.cost
+		budgetText, used := "-", "-"
+		if b, ok := budgets[spend.project]; ok && b > 0 && *month != "all" {
+			budgetText = fmt.Sprintf("$%.2f", b)
+			used = fmt.Sprintf("%.0f%%", spend.cost/b*100)
+		}
+		fmt.Printf("%-24s %5d %7d %12d %12d %11s %11s %6s\n",
+			pdfanalysis.Truncate(spend.project, 24), spend.runs, spend.pages, spend.inputTokens, spend.outputTokens,
+			fmt.Sprintf("$%.4f", spend.cost), budgetText, used)
+	}
+	fmt.Println(strings.Repeat("=", 100))
+	fmt.Printf("Total: $%.6f\n", total)
+	return nil
+}
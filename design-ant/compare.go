@@ -0,0 +1,415 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"design-ant/pkg/pdfanalysis"
+)
+
+// ChangeLog is the structured revision comparison of two drawing PDFs
+type ChangeLog struct {
+	OldPDF         string                 `json:"old_pdf"`
+	NewPDF         string                 `json:"new_pdf"`
+	Summary        string                 `json:"summary"`
+	Changes        []Change               `json:"changes"`
+	UnchangedPages []int                  `json:"unchanged_pages,omitempty"` // New pages identical to an old page
+	InputTokens    int                    `json:"input_tokens"`
+	OutputTokens   int                    `json:"output_tokens"`
+	TotalCost      float64                `json:"total_cost"` // Change log requests only, not the page analyses
+	Error          string                 `json:"error,omitempty"`
+	GeneratedAt    time.Time              `json:"generated_at"`
+	Build          *pdfanalysis.BuildInfo `json:"build,omitempty"` // design-ant build that wrote the change log
+}
+
+// Change is one entry of the change log
+type Change struct {
+	Category    string `json:"category"` // See changeCategories
+	OldPage     int    `json:"old_page,omitempty"`
+	NewPage     int    `json:"new_page,omitempty"`
+	Item        string `json:"item,omitempty"` // Part number, feature, or note affected
+	OldValue    string `json:"old_value,omitempty"`
+	NewValue    string `json:"new_value,omitempty"`
+	Description string `json:"description"`
+}
+
+// changeCategories are the change log categories, in report order
+var changeCategories = []string{
+	"revision", "dimension", "tolerance", "part_added", "part_removed", "part_changed",
+	"quantity", "material", "note", "title_block", "page_added", "page_removed", "other",
+}
+
+// pagePair is an old page and the new page it most likely corresponds to;
+// either side is nil for removed or added pages
+type pagePair struct {
+	old, new *pdfanalysis.ChunkAnalysis
+}
+
+// runCompare analyzes two revisions of a drawing package and writes a change
+// log. Both analyses reuse earlier results of the same file, and the new
+// revision also reuses the old one's analyses for identical pages, so only
+// changed pages are sent to the API.
+func runCompare(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: go run . compare [analysis flags] <old.pdf> <new.pdf>")
+	}
+	flagArgs, oldPDF, newPDF := args[:len(args)-2], args[len(args)-2], args[len(args)-1]
+	if pdfanalysis.GenerateOutputFilename(oldPDF, "json") == pdfanalysis.GenerateOutputFilename(newPDF, "json") {
+		return fmt.Errorf("%s and %s have the same file name, so their results would overwrite each other; rename one", oldPDF, newPDF)
+	}
+
+	oldConfig, err := parseFlags(append(append([]string{}, flagArgs...), oldPDF))
+	if err != nil {
+		return err
+	}
+	if err := reuseEarlierResult(oldConfig); err != nil {
+		return err
+	}
+	oldResult, err := runAnalysis(oldConfig)
+	if err != nil {
+		return fmt.Errorf("analyzing %s: %v", oldPDF, err)
+	}
+
+	newConfig, err := parseFlags(append(append([]string{}, flagArgs...), newPDF))
+	if err != nil {
+		return err
+	}
+	if err := reuseEarlierResult(newConfig); err != nil {
+		return err
+	}
+	// The old revision's result as saved, wherever -result-store keeps it
+	newConfig.ReuseResults = append(newConfig.ReuseResults, oldResult)
+	newResult, err := runAnalysis(newConfig)
+	if err != nil {
+		return fmt.Errorf("analyzing %s: %v", newPDF, err)
+	}
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println("  REVISION COMPARISON")
+	fmt.Println(strings.Repeat("=", 70))
+	changeLog, err := compareResults(context.Background(), newConfig, oldResult, newResult)
+	if err != nil {
+		// Keep whatever batches succeeded; the error is recorded in the change log
+		fmt.Printf("⚠️  Change log incomplete: %v\n", err)
+	}
+
+	jsonFile := pdfanalysis.GenerateOutputFilename(newConfig.DocumentPath(), "changes.json")
+	data, err := json.MarshalIndent(changeLog, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding change log: %v", err)
+	}
+	if err := os.WriteFile(jsonFile, data, 0644); err != nil {
+		return fmt.Errorf("error writing %s: %v", jsonFile, err)
+	}
+	mdFile := pdfanalysis.GenerateOutputFilename(newConfig.DocumentPath(), "changes.md")
+	if err := os.WriteFile(mdFile, []byte(renderChangeLog(changeLog)), 0644); err != nil {
+		return fmt.Errorf("error writing %s: %v", mdFile, err)
+	}
+
+	fmt.Printf("📝 %d change(s), %d unchanged page(s); change log cost $%.6f\n",
+		len(changeLog.Changes), len(changeLog.UnchangedPages), changeLog.TotalCost)
+	fmt.Printf("💾 Change log saved to: %s and %s\n", jsonFile, mdFile)
+	return nil
+}
+
+// reuseEarlierResult reuses the result of an earlier run on the same
+// document, if any: from -result-store when it is set, otherwise the result
+// file in the working directory
+func reuseEarlierResult(config *pdfanalysis.Config) error {
+	if config.StoreURI == "" {
+		for _, suffix := range []string{"", ".gz", ".zst"} {
+			filename := pdfanalysis.GenerateOutputFilename(config.DocumentPath(), "json") + suffix
+			if _, err := os.Stat(filename); err == nil {
+				config.ReuseFrom = append(config.ReuseFrom, filename)
+				return nil
+			}
+		}
+		return nil
+	}
+	store, err := openResultStore(config.StoreURI, ".")
+	if err != nil {
+		return err
+	}
+	defer closeResultStore(store)
+	result, err := store.Load(context.Background(), resultName(config))
+	if errors.Is(err, pdfanalysis.ErrResultNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error loading the earlier result of %s: %v", config.DocumentPath(), err)
+	}
+	config.ReuseResults = append(config.ReuseResults, result)
+	return nil
+}
+
+// compareResults pairs the pages of both revisions, skips pages whose
+// fingerprint is unchanged, and asks the model for the changes of the rest in
+// batches that fit the consolidation budget
+func compareResults(ctx context.Context, config *pdfanalysis.Config, oldResult, newResult *pdfanalysis.FullAnalysisResult) (*ChangeLog, error) {
+	changeLog := &ChangeLog{
+		OldPDF:      filepath.Base(oldResult.PDFPath),
+		NewPDF:      filepath.Base(newResult.PDFPath),
+		Changes:     []Change{},
+		GeneratedAt: time.Now(),
+		Build:       pdfanalysis.Build(),
+	}
+	pairs, unchanged := pairPages(oldResult.Chunks, newResult.Chunks)
+	changeLog.UnchangedPages = unchanged
+	if len(pairs) == 0 {
+		changeLog.Summary = "No changes: every page of the new revision is identical to a page of the old one."
+		return changeLog, nil
+	}
+
+	pricing := pdfanalysis.GetPricing(config.ModelName)
+	var summaries []string
+	for i, batch := range batchPairs(pairs, pdfanalysis.ConsolidateBudgetChars) {
+		fmt.Printf("  🔄 Comparing batch %d (%d changed page pair(s))...\n", i+1, len(batch))
+		prompt := changeLogPrompt(changeLog.OldPDF, changeLog.NewPDF, batch)
+		text, inputTokens, outputTokens, err := pdfanalysis.SendWithRetry(ctx, config, prompt)
+		changeLog.InputTokens += inputTokens
+		changeLog.OutputTokens += outputTokens
+		changeLog.TotalCost += float64(inputTokens)/1_000_000*pricing.InputPricePerMTokens +
+			float64(outputTokens)/1_000_000*pricing.OutputPricePerMTokens
+		if err == nil {
+			var parsed struct {
+				Summary string   `json:"summary"`
+				Changes []Change `json:"changes"`
+			}
+			var block string
+			if block, _, err = pdfanalysis.SplitJSONBlock(text); err == nil {
+				if err = json.Unmarshal([]byte(block), &parsed); err != nil {
+					err = fmt.Errorf("invalid json block: %v", err)
+				}
+			}
+			if err == nil {
+				summaries = append(summaries, strings.TrimSpace(parsed.Summary))
+				changeLog.Changes = append(changeLog.Changes, parsed.Changes...)
+				continue
+			}
+		}
+		changeLog.Error = fmt.Sprintf("batch %d: %v", i+1, err)
+		changeLog.Summary = strings.Join(summaries, "\n\n")
+		return changeLog, fmt.Errorf("error comparing batch %d: %v", i+1, err)
+	}
+
+	sort.SliceStable(changeLog.Changes, func(i, j int) bool {
+		return changeCategoryRank(changeLog.Changes[i].Category) < changeCategoryRank(changeLog.Changes[j].Category)
+	})
+	changeLog.Summary = strings.Join(summaries, "\n\n")
+	return changeLog, nil
+}
+
+// pairPages matches new pages to old pages. Pages with identical fingerprints
+// are unchanged and left out. The remaining pages are paired by drawing
+// number where structured metadata is available, then in page order.
+func pairPages(oldChunks, newChunks []pdfanalysis.ChunkAnalysis) ([]pagePair, []int) {
+	oldHashes := make(map[string]bool)
+	for _, chunk := range oldChunks {
+		if chunk.PageHash != "" {
+			oldHashes[chunk.PageHash] = true
+		}
+	}
+	newHashes := make(map[string]bool)
+	var unchanged []int
+	var newLeft []*pdfanalysis.ChunkAnalysis
+	for i := range newChunks {
+		chunk := &newChunks[i]
+		newHashes[chunk.PageHash] = chunk.PageHash != ""
+		if chunk.PageHash != "" && oldHashes[chunk.PageHash] {
+			unchanged = append(unchanged, chunk.StartPage)
+			continue
+		}
+		newLeft = append(newLeft, chunk)
+	}
+	var oldLeft []*pdfanalysis.ChunkAnalysis
+	for i := range oldChunks {
+		if chunk := &oldChunks[i]; chunk.PageHash == "" || !newHashes[chunk.PageHash] {
+			oldLeft = append(oldLeft, chunk)
+		}
+	}
+
+	var pairs []pagePair
+	drawing := func(chunk *pdfanalysis.ChunkAnalysis) string {
+		if chunk.Metadata == nil {
+			return ""
+		}
+		return strings.ToUpper(strings.TrimSpace(chunk.Metadata.DrawingNumber))
+	}
+	usedOld := make(map[*pdfanalysis.ChunkAnalysis]bool)
+	var unpairedNew []*pdfanalysis.ChunkAnalysis
+	for _, n := range newLeft {
+		var match *pdfanalysis.ChunkAnalysis
+		if d := drawing(n); d != "" {
+			for _, o := range oldLeft {
+				if !usedOld[o] && drawing(o) == d {
+					match = o
+					break
+				}
+			}
+		}
+		if match == nil {
+			unpairedNew = append(unpairedNew, n)
+			continue
+		}
+		usedOld[match] = true
+		pairs = append(pairs, pagePair{old: match, new: n})
+	}
+	var unpairedOld []*pdfanalysis.ChunkAnalysis
+	for _, o := range oldLeft {
+		if !usedOld[o] {
+			unpairedOld = append(unpairedOld, o)
+		}
+	}
+	for i := 0; i < max(len(unpairedOld), len(unpairedNew)); i++ {
+		var pair pagePair
+		if i < len(unpairedOld) {
+			pair.old = unpairedOld[i]
+		}
+		if i < len(unpairedNew) {
+			pair.new = unpairedNew[i]
+		}
+		pairs = append(pairs, pair)
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].sortPage() < pairs[j].sortPage()
+	})
+	return pairs, unchanged
+}
+
+// sortPage orders pairs by new page, removed pages by their old page
+func (p pagePair) sortPage() int {
+	if p.new != nil {
+		return p.new.StartPage
+	}
+	return p.old.StartPage
+}
+
+// pairText is the prompt text of one pair
+func (p pagePair) text() string {
+	var b strings.Builder
+	b.WriteString("<pair>\n")
+	for _, side := range []struct {
+		name  string
+		chunk *pdfanalysis.ChunkAnalysis
+	}{{"OLD", p.old}, {"NEW", p.new}} {
+		switch {
+		case side.chunk == nil:
+			fmt.Fprintf(&b, "<%s>(no corresponding page)</%s>\n", side.name, side.name)
+		case side.chunk.Error != "":
+			fmt.Fprintf(&b, "<%s page=\"%d\">(analysis failed: %s)</%s>\n", side.name, side.chunk.StartPage, side.chunk.Error, side.name)
+		default:
+			fmt.Fprintf(&b, "<%s page=\"%d\">\n%s\n</%s>\n", side.name, side.chunk.StartPage, strings.TrimSpace(side.chunk.Analysis), side.name)
+		}
+	}
+	b.WriteString("</pair>\n\n")
+	return b.String()
+}
+
+// batchPairs groups consecutive pairs into batches that fit the budget; an
+// oversized pair forms its own batch
+func batchPairs(pairs []pagePair, budget int) [][]pagePair {
+	var batches [][]pagePair
+	var current []pagePair
+	size := 0
+	for _, pair := range pairs {
+		n := len(pair.text())
+		if len(current) > 0 && size+n > budget {
+			batches = append(batches, current)
+			current, size = nil, 0
+		}
+		current = append(current, pair)
+		size += n
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+// changeCategoryRank orders changes by category for the report
+func changeCategoryRank(category string) int {
+	for i, c := range changeCategories {
+		if c == category {
+			return i
+		}
+	}
+	return len(changeCategories)
+}
+
+// changeLogPrompt asks for the structured changes between paired pages
+func changeLogPrompt(oldName, newName string, pairs []pagePair) string {
+	var b strings.Builder
+	for _, pair := range pairs {
+		b.WriteString(pair.text())
+	}
+	return fmt.Sprintf(`The following are analyses of pages from two revisions of an engineering drawing package:
+OLD (%s) and NEW (%s). Each <pair> holds an old page and the new page it most likely corresponds to.
+A pair without an old page is an added page, a pair without a new page is a removed page. If the two
+pages of a pair describe different drawings, report the old one as removed and the new one as added.
+Pages not listed are identical in both revisions.
+
+Produce the change log for the change control board as a single JSON code block:
+`+"```json"+`
+{
+  "summary": "2-4 sentences on what changed and why it matters for manufacturing",
+  "changes": [
+    {"category": "dimension", "old_page": 3, "new_page": 3, "item": "Bore A", "old_value": "Ø20 H7", "new_value": "Ø22 H7", "description": "Bore enlarged"}
+  ]
+}
+`+"```"+`
+
+RULES:
+- category is one of: %s
+- One entry per changed value; use exact values, units, tolerances, and part numbers from the analyses
+- Leave old_page or new_page out for added or removed pages, old_value or new_value out for added or removed items
+- Ignore differences in wording that do not change a value
+- Do not report unchanged items
+
+%s`, oldName, newName, strings.Join(changeCategories, ", "), b.String())
+}
+
+// renderChangeLog formats the change log as markdown grouped by category
+func renderChangeLog(changeLog *ChangeLog) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Change Log: %s → %s\n\n", changeLog.OldPDF, changeLog.NewPDF)
+	if changeLog.Error != "" {
+		fmt.Fprintf(&b, "> **Incomplete:** %s\n\n", pdfanalysis.EscapeInline(changeLog.Error))
+	}
+	if changeLog.Summary != "" {
+		fmt.Fprintf(&b, "%s\n\n", changeLog.Summary)
+	}
+	if len(changeLog.UnchangedPages) > 0 {
+		pages := make([]string, len(changeLog.UnchangedPages))
+		for i, p := range changeLog.UnchangedPages {
+			pages[i] = fmt.Sprintf("%d", p)
+		}
+		fmt.Fprintf(&b, "Unchanged pages (new revision): %s\n\n", strings.Join(pages, ", "))
+	}
+	if len(changeLog.Changes) == 0 {
+		b.WriteString("No changes found.\n")
+		return b.String()
+	}
+
+	b.WriteString("| Category | Old page | New page | Item | Old | New | Description |\n")
+	b.WriteString("| --- | --- | --- | --- | --- | --- | --- |\n")
+	page := func(p int) string {
+		if p == 0 {
+			return "—"
+		}
+		return fmt.Sprintf("%d", p)
+	}
+	for _, c := range changeLog.Changes {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s |\n", pdfanalysis.EscapeInline(c.Category), page(c.OldPage), page(c.NewPage),
+			pdfanalysis.EscapeInline(c.Item), pdfanalysis.EscapeInline(c.OldValue), pdfanalysis.EscapeInline(c.NewValue), pdfanalysis.EscapeInline(c.Description))
+	}
+	return b.String()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"design-ant/pkg/pdfanalysis"
+)
+
+func TestReuseEarlierResult(t *testing.T) {
+	config := &pdfanalysis.Config{PDFPath: "drawing.pdf", StoreURI: "sqlite:" + filepath.Join(t.TempDir(), "results.db")}
+	if err := reuseEarlierResult(config); err != nil || len(config.ReuseResults) != 0 {
+		t.Fatalf("empty store: %d results, %v", len(config.ReuseResults), err)
+	}
+
+	store, err := openResultStore(config.StoreURI, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeResultStore(store)
+	result := pdfanalysis.FullAnalysisResult{PDFPath: "drawing.pdf", TotalPages: 1,
+		Chunks: []pdfanalysis.ChunkAnalysis{{ChunkNumber: 1, StartPage: 1, EndPage: 1, PageHash: "abc", Analysis: "A bracket."}}}
+	if err := store.Save(context.Background(), resultName(config), result); err != nil {
+		t.Fatal(err)
+	}
+	if err := reuseEarlierResult(config); err != nil {
+		t.Fatal(err)
+	}
+	if len(config.ReuseResults) != 1 || config.ReuseResults[0].Chunks[0].PageHash != "abc" || len(config.ReuseFrom) != 0 {
+		t.Errorf("reused %d stored results and files %q, want the stored result", len(config.ReuseResults), config.ReuseFrom)
+	}
+}
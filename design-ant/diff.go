@@ -0,0 +1,337 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"design-ant/pkg/pdfanalysis"
+)
+
+// runDiff compares two result files page by page and prints a change report
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	outFile := fs.String("o", "", "write the report to this file instead of stdout")
+	showText := fs.Bool("text", true, "include line-level diffs of the analysis text")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: go run . diff [-o report.md] [-text=false] <old.json> <new.json>")
+	}
+
+	oldResult, err := pdfanalysis.LoadResult(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	newResult, err := pdfanalysis.LoadResult(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	report := diffResults(oldResult, newResult, *showText)
+
+	var out io.Writer = os.Stdout
+	if *outFile != "" {
+		file, err := os.Create(*outFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	if _, err := io.WriteString(out, report); err != nil {
+		return err
+	}
+	if *outFile != "" {
+		fmt.Printf("💾 Change report saved to: %s\n", *outFile)
+	}
+	return nil
+}
+
+// generatedBy names the build that wrote a result, for the report header;
+// results written before builds were recorded have none
+func generatedBy(result *pdfanalysis.FullAnalysisResult) string {
+	if result.Build == nil {
+		return ""
+	}
+	return " by design-ant " + result.Build.String()
+}
+
+// diffResults builds a markdown change report between two analysis results
+func diffResults(oldResult, newResult *pdfanalysis.FullAnalysisResult, showText bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Change Report\n\n")
+	fmt.Fprintf(&b, "- Old: `%s` (%d pages, generated %s%s)\n", oldResult.PDFPath, oldResult.TotalPages, oldResult.GeneratedAt.Format("2006-01-02 15:04"), generatedBy(oldResult))
+	fmt.Fprintf(&b, "- New: `%s` (%d pages, generated %s%s)\n\n", newResult.PDFPath, newResult.TotalPages, newResult.GeneratedAt.Format("2006-01-02 15:04"), generatedBy(newResult))
+
+	oldChunks := chunksByPage(oldResult.Chunks)
+	newChunks := chunksByPage(newResult.Chunks)
+
+	var pages []int
+	for page := range oldChunks {
+		pages = append(pages, page)
+	}
+	for page := range newChunks {
+		if _, ok := oldChunks[page]; !ok {
+			pages = append(pages, page)
+		}
+	}
+	sort.Ints(pages)
+
+	var added, removed, changed, unchanged int
+	var details strings.Builder
+	for _, page := range pages {
+		oldChunk, inOld := oldChunks[page]
+		newChunk, inNew := newChunks[page]
+		switch {
+		case !inOld:
+			added++
+			fmt.Fprintf(&details, "## Page %d — added\n\n", page)
+		case !inNew:
+			removed++
+			fmt.Fprintf(&details, "## Page %d — removed\n\n", page)
+		default:
+			section := diffChunk(oldChunk, newChunk, showText)
+			if section == "" {
+				unchanged++
+				continue
+			}
+			changed++
+			fmt.Fprintf(&details, "## Page %d — changed\n\n%s", page, section)
+		}
+	}
+
+	fmt.Fprintf(&b, "| Changed | Added | Removed | Unchanged |\n|---|---|---|---|\n| %d | %d | %d | %d |\n\n",
+		changed, added, removed, unchanged)
+	if changed+added+removed == 0 {
+		b.WriteString("No differences found.\n")
+	}
+	b.WriteString(details.String())
+	return b.String()
+}
+
+// chunksByPage indexes chunks by their first page
+func chunksByPage(chunks []pdfanalysis.ChunkAnalysis) map[int]pdfanalysis.ChunkAnalysis {
+	m := make(map[int]pdfanalysis.ChunkAnalysis, len(chunks))
+	for _, chunk := range chunks {
+		m[chunk.StartPage] = chunk
+	}
+	return m
+}
+
+// diffChunk returns the markdown description of all changes on a page, or "" if none
+func diffChunk(oldChunk, newChunk pdfanalysis.ChunkAnalysis, showText bool) string {
+	var b strings.Builder
+
+	if oldChunk.Error != newChunk.Error {
+		fmt.Fprintf(&b, "- Error: `%s` → `%s`\n", oldChunk.Error, newChunk.Error)
+	}
+
+	b.WriteString(diffMetadata(oldChunk.Metadata, newChunk.Metadata))
+	b.WriteString(diffBOM(oldChunk.BOMItems, newChunk.BOMItems))
+	b.WriteString(diffDimensions(oldChunk.Dimensions, newChunk.Dimensions))
+
+	if showText && oldChunk.Analysis != newChunk.Analysis {
+		b.WriteString("\n```diff\n")
+		b.WriteString(diffLines(oldChunk.Analysis, newChunk.Analysis))
+		b.WriteString("```\n")
+	} else if oldChunk.Analysis != newChunk.Analysis && b.Len() == 0 {
+		b.WriteString("- Analysis text changed\n")
+	}
+
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// diffMetadata reports changed title block fields
+func diffMetadata(oldMeta, newMeta *pdfanalysis.DrawingMetadata) string {
+	if oldMeta == nil && newMeta == nil {
+		return ""
+	}
+	var o, n pdfanalysis.DrawingMetadata
+	if oldMeta != nil {
+		o = *oldMeta
+	}
+	if newMeta != nil {
+		n = *newMeta
+	}
+
+	fields := []struct {
+		name     string
+		old, new string
+	}{
+		{"Drawing number", o.DrawingNumber, n.DrawingNumber},
+		{"Title", o.Title, n.Title},
+		{"Revision", o.Revision, n.Revision},
+		{"Drawn by", o.DrawnBy, n.DrawnBy},
+		{"Checked by", o.CheckedBy, n.CheckedBy},
+		{"Approved by", o.ApprovedBy, n.ApprovedBy},
+		{"Date", o.Date, n.Date},
+		{"Scale", o.Scale, n.Scale},
+		{"Projection", o.Projection, n.Projection},
+		{"Material", o.Material, n.Material},
+	}
+
+	var b strings.Builder
+	for _, f := range fields {
+		if f.old != f.new {
+			fmt.Fprintf(&b, "- %s: `%s` → `%s`\n", f.name, f.old, f.new)
+		}
+	}
+	return b.String()
+}
+
+// bomKey identifies a BOM row across revisions
+func bomKey(item pdfanalysis.BOMItem) string {
+	if item.PartNumber != "" {
+		return strings.ToUpper(strings.TrimSpace(item.PartNumber))
+	}
+	return "item " + strings.TrimSpace(item.ItemNumber)
+}
+
+// bomLabel formats a BOM row's key and description for reports
+func bomLabel(key string, item pdfanalysis.BOMItem) string {
+	return strings.TrimSpace(fmt.Sprintf("**%s** %s", key, item.Description))
+}
+
+// diffBOM compares BOM rows item by item
+func diffBOM(oldItems, newItems []pdfanalysis.BOMItem) string {
+	oldByKey := make(map[string]pdfanalysis.BOMItem)
+	for _, item := range oldItems {
+		oldByKey[bomKey(item)] = item
+	}
+	newByKey := make(map[string]pdfanalysis.BOMItem)
+	for _, item := range newItems {
+		newByKey[bomKey(item)] = item
+	}
+
+	var lines []string
+	for key, o := range oldByKey {
+		n, ok := newByKey[key]
+		if !ok {
+			lines = append(lines, fmt.Sprintf("- BOM removed: %s (qty %g)", bomLabel(key, o), o.Quantity))
+			continue
+		}
+		var changes []string
+		if o.Quantity != n.Quantity {
+			changes = append(changes, fmt.Sprintf("qty %g → %g", o.Quantity, n.Quantity))
+		}
+		if o.Material != n.Material {
+			changes = append(changes, fmt.Sprintf("material `%s` → `%s`", o.Material, n.Material))
+		}
+		if o.Finish != n.Finish {
+			changes = append(changes, fmt.Sprintf("finish `%s` → `%s`", o.Finish, n.Finish))
+		}
+		if o.Description != n.Description {
+			changes = append(changes, fmt.Sprintf("description `%s` → `%s`", o.Description, n.Description))
+		}
+		if len(changes) > 0 {
+			lines = append(lines, fmt.Sprintf("- BOM changed: **%s** %s", key, strings.Join(changes, ", ")))
+		}
+	}
+	for key, n := range newByKey {
+		if _, ok := oldByKey[key]; !ok {
+			lines = append(lines, fmt.Sprintf("- BOM added: %s (qty %g)", bomLabel(key, n), n.Quantity))
+		}
+	}
+
+	sort.Strings(lines)
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// diffDimensions compares dimensions keyed by feature and type
+func diffDimensions(oldDims, newDims []pdfanalysis.Dimension) string {
+	key := func(d pdfanalysis.Dimension) string { return d.Feature + " (" + d.Type + ")" }
+	format := func(d pdfanalysis.Dimension) string {
+		s := strings.TrimSpace(d.Value + " " + d.Unit)
+		if d.Tolerance != "" {
+			s += " " + d.Tolerance
+		}
+		return s
+	}
+
+	oldByKey := make(map[string]pdfanalysis.Dimension)
+	for _, d := range oldDims {
+		oldByKey[key(d)] = d
+	}
+	newByKey := make(map[string]pdfanalysis.Dimension)
+	for _, d := range newDims {
+		newByKey[key(d)] = d
+	}
+
+	var lines []string
+	for k, o := range oldByKey {
+		n, ok := newByKey[k]
+		switch {
+		case !ok:
+			lines = append(lines, fmt.Sprintf("- Dimension removed: %s `%s`", k, format(o)))
+		case format(o) != format(n):
+			lines = append(lines, fmt.Sprintf("- Dimension changed: %s `%s` → `%s`", k, format(o), format(n)))
+		}
+	}
+	for k, n := range newByKey {
+		if _, ok := oldByKey[k]; !ok {
+			lines = append(lines, fmt.Sprintf("- Dimension added: %s `%s`", k, format(n)))
+		}
+	}
+
+	sort.Strings(lines)
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// diffLines produces a line-based diff (longest common subsequence) with
+// "-"/"+" markers, omitting unchanged lines
+func diffLines(oldText, newText string) string {
+	a := strings.Split(oldText, "\n")
+	c := strings.Split(newText, "\n")
+
+	// lcs[i][j] is the LCS length of a[i:] and c[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(c)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(c) - 1; j >= 0; j-- {
+			if a[i] == c[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var b strings.Builder
+	i, j := 0, 0
+	for i < len(a) && j < len(c) {
+		switch {
+		case a[i] == c[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			fmt.Fprintf(&b, "- %s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&b, "+ %s\n", c[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		fmt.Fprintf(&b, "- %s\n", a[i])
+	}
+	for ; j < len(c); j++ {
+		fmt.Fprintf(&b, "+ %s\n", c[j])
+	}
+	return b.String()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"design-ant/pkg/pdfanalysis"
+	"github.com/philippgille/chromem-go"
+)
+
+// Embedding providers for the semantic index
+const (
+	EmbedderGemini = "gemini"
+	EmbedderOpenAI = "openai"
+)
+
+// defaultEmbeddingModels is the model used by each provider when -embed-model is not set
+var defaultEmbeddingModels = map[string]string{
+	EmbedderGemini: "gemini-embedding-001",
+	EmbedderOpenAI: string(chromem.EmbeddingModelOpenAI3Small),
+}
+
+// embedder creates document and query embeddings with one provider and model.
+// Gemini embeds documents and queries differently, so they are separate funcs.
+type embedder struct {
+	name     string // provider/model, also names the index collection
+	document chromem.EmbeddingFunc
+	query    chromem.EmbeddingFunc
+}
+
+// newEmbedder configures the provider; the API key comes from
+// GEMINI_API_KEY or OPENAI_API_KEY
+func newEmbedder(provider, model string) (*embedder, error) {
+	if model == "" {
+		model = defaultEmbeddingModels[provider]
+	}
+	switch provider {
+	case EmbedderGemini:
+		apiKey := os.Getenv("GEMINI_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("GEMINI_API_KEY not found in environment variables")
+		}
+		return &embedder{
+			name:     provider + "/" + model,
+			document: geminiEmbeddingFunc(apiKey, model, "RETRIEVAL_DOCUMENT"),
+			query:    geminiEmbeddingFunc(apiKey, model, "RETRIEVAL_QUERY"),
+		}, nil
+	case EmbedderOpenAI:
+		apiKey := os.Getenv("OPENAI_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("OPENAI_API_KEY not found in environment variables")
+		}
+		embed := chromem.NewEmbeddingFuncOpenAI(apiKey, chromem.EmbeddingModelOpenAI(model))
+		return &embedder{name: provider + "/" + model, document: embed, query: embed}, nil
+	default:
+		return nil, fmt.Errorf("invalid -embedder %q: must be %s or %s", provider, EmbedderGemini, EmbedderOpenAI)
+	}
+}
+
+// geminiEmbeddingFunc embeds text with the Gemini embedContent endpoint
+func geminiEmbeddingFunc(apiKey, model, taskType string) chromem.EmbeddingFunc {
+	return func(ctx context.Context, text string) ([]float32, error) {
+		return pdfanalysis.EmbedGemini(ctx, apiKey, model, taskType, text)
+	}
+}
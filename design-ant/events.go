@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"design-ant/pkg/pdfanalysis"
+)
+
+// Progress event types written by -events
+const (
+	eventStart    = "start"    // Pages were planned; Total is set
+	eventPage     = "page"     // A page finished or failed
+	eventFinished = "finished" // The result was written; Cost is the run total
+)
+
+// progressEvent is one line of the -events file. A job's progress stream in
+// server mode is this file, so a web UI gets pages done, cost so far, and page
+// errors without parsing the console output.
+type progressEvent struct {
+	Type  string    `json:"type"`
+	Page  int       `json:"page,omitempty"`
+	Done  int       `json:"done"`
+	Total int       `json:"total"`
+	Cost  float64   `json:"cost"` // Dollars spent so far
+	Error string    `json:"error,omitempty"`
+	Time  time.Time `json:"time"`
+
+	// Set on finished events
+	Output   string `json:"output,omitempty"`   // Absolute path of the JSON result
+	Partial  bool   `json:"partial,omitempty"`  // The run stopped early; -resume with Output continues it
+	Deadline bool   `json:"deadline,omitempty"` // It stopped at its -run-deadline
+}
+
+// eventLog appends progress events to a file; a nil log drops them
+type eventLog struct {
+	mu    sync.Mutex
+	file  *os.File
+	total int
+}
+
+// openEventLog opens the -events file for appending, so the attempts of a
+// job follow each other in one stream
+func openEventLog(path string) (*eventLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &eventLog{file: file}, nil
+}
+
+// emit writes one event. A failed write is logged and never stops the run.
+func (l *eventLog) emit(event progressEvent) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if event.Type == eventStart {
+		l.total = event.Total
+	}
+	event.Total = l.total
+	event.Time = time.Now()
+	data, err := json.Marshal(event)
+	if err == nil {
+		_, err = l.file.Write(append(data, '\n'))
+	}
+	if err != nil {
+		slog.Warn("Could not write progress event", "path", l.file.Name(), "error", err)
+	}
+}
+
+// page records a finished page and the cost of the run so far
+func (l *eventLog) page(chunk pdfanalysis.ChunkAnalysis, done int, cost float64) {
+	l.emit(progressEvent{Type: eventPage, Page: chunk.StartPage, Done: done, Cost: cost, Error: chunk.Error})
+}
+
+// Close closes the file
+func (l *eventLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// attemptOutcome reads the events an attempt appended to the file after
+// offset, returning what it spent, the cost of its last event, and its
+// finished event, nil if it wrote none
+func attemptOutcome(path string, offset int64) (float64, *progressEvent) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, nil
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return 0, nil
+	}
+	var cost float64
+	var finished *progressEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event progressEvent
+		if json.Unmarshal(scanner.Bytes(), &event) == nil {
+			cost = max(cost, event.Cost)
+			if event.Type == eventFinished {
+				finished = &event
+			}
+		}
+	}
+	return cost, finished
+}
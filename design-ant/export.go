@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"design-ant/pkg/pdfanalysis"
+)
+
+// runExport renders an existing result file as markdown for a wiki
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", pdfanalysis.MarkdownConfluence, "markdown flavor: confluence, notion, or github")
+	outFile := fs.String("o", "", "output file (default {pdf-name}_analysis.md)")
+	rules := fs.String("redact", "", "mask terms and patterns from this rules file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: go run . export [-format confluence|notion|github] [-redact rules.txt] [-o out.md] <result.json>")
+	}
+	if _, ok := pdfanalysis.MarkdownFlavors[*format]; !ok {
+		return fmt.Errorf("invalid -format %q: must be confluence, notion, or github", *format)
+	}
+
+	result, err := pdfanalysis.LoadResult(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	filename := *outFile
+	if filename == "" {
+		filename = pdfanalysis.GenerateOutputFilename(result.PDFPath, "md")
+	}
+	if *rules != "" {
+		redact, err := pdfanalysis.LoadRedactor(*rules)
+		if err != nil {
+			return err
+		}
+		*result = redact.RedactResult(*result)
+		fmt.Printf("🔒 Redacted %d occurrence(s)\n", redact.Count)
+	}
+	if err := pdfanalysis.SaveMarkdownExport(filename, *result, *format); err != nil {
+		return fmt.Errorf("error writing %s: %v", filename, err)
+	}
+	fmt.Printf("💾 Markdown export saved to: %s\n", filename)
+	return nil
+}
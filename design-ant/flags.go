@@ -0,0 +1,223 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"design-ant/pkg/pdfanalysis"
+)
+
+// parseFlags parses command line arguments into a Config.
+// Flags must come before the input file, e.g. `go run . -max-pages 50 drawing.pdf`.
+func parseFlags(args []string) (*pdfanalysis.Config, error) {
+	fs := flag.NewFlagSet("design-ant", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	config := pdfanalysis.DefaultConfig()
+
+	fs.IntVar(&config.Limits.MaxPages, "max-pages", 0, "refuse documents with more pages than this (0 = no limit)")
+	fs.Int64Var(&config.Limits.MaxChunkMB, "max-chunk-mb", config.Limits.MaxChunkMB, "maximum encoded size of a single chunk in MB")
+	fs.Int64Var(&config.Limits.MaxTotalMB, "max-total-mb", 0, "maximum encoded size of all chunks combined in MB (0 = no limit)")
+
+	fs.IntVar(&config.TokensPerMinute, "tpm", config.TokensPerMinute, "input tokens per minute allowed by your API tier; page requests are paced to it using pre-flight token counts (0 = fixed 4 concurrent pages)")
+
+	fs.IntVar(&config.Workers, "workers", 0, "concurrent page requests (0 = 16 with -tpm, 4 without)")
+	fs.DurationVar(&config.ChunkTimeout, "chunk-timeout", config.ChunkTimeout, "time limit for one attempt of a page request, e.g. 90s; timed-out attempts are retried")
+	fs.DurationVar(&config.RunDeadline, "run-deadline", 0, "stop starting requests after this long, e.g. 45m, and write a partial result for -resume (0 = no deadline)")
+	fs.Float64Var(&config.MaxCost, "max-cost", 0, "stop starting pages once the run has cost this many dollars and write a partial result for -resume (0 = no limit)")
+	fs.BoolVar(&config.NoProgress, "no-progress", false, "print one line per page instead of the progress bar (the bar is only shown on a terminal)")
+	fs.BoolVar(&config.Reproducible, "reproducible", false, "write the same timestamp (SOURCE_DATE_EPOCH or 1970) and zero durations to the outputs, so runs over the same cached pages give byte-identical JSON")
+
+	fs.StringVar(&config.InputMode, "input-mode", config.InputMode, "how pages are submitted: pdf, text, or auto (text layer for text-only pages, PDF otherwise)")
+
+	fs.BoolVar(&config.Structured, "structured", false, "also extract typed metadata, BOM items, dimensions, and notes as JSON")
+	fs.BoolVar(&config.Welds, "welds", false, "also extract weld symbols and surface finish callouts for fabrication planning (implies -structured)")
+	fs.StringVar(&config.Units, "units", "", "with -structured, normalize dimensions and tolerances to metric (mm) or imperial (in) and record conversions")
+	fs.BoolVar(&config.TwoStage, "two-stage", false, "classify each page (assembly, part, bom, schematic, text) with a cheap call first, then extract with a prompt specialized to its type")
+	promptPack := fs.String("prompt-pack", config.PromptPack.Name, "prompt and -structured sections for the kind of drawings: "+strings.Join(pdfanalysis.PromptPackNames(), ", "))
+	promptTemplate := fs.String("prompt-template", "", "analyze pages with this Go text/template prompt instead of the pack's (see pkg/pdfanalysis/prompts/mechanical.tmpl)")
+	fs.Func("prompt-var", "set {{.Vars.name}} in the prompt template, e.g. -prompt-var customer=ACME (repeatable)", func(assignment string) error {
+		name, value, ok := strings.Cut(assignment, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("must be name=value")
+		}
+		if config.PromptVars == nil {
+			config.PromptVars = make(map[string]string)
+		}
+		config.PromptVars[strings.TrimSpace(name)] = value
+		return nil
+	})
+	fs.Func("context-file", "add this text file to the prompt, e.g. a spec excerpt or the part numbering scheme; prefix pages to add it to those only, e.g. -context-file 3-5,9:spec.txt (repeatable)", func(value string) error {
+		context, err := parseContextFile(value)
+		if err != nil {
+			return err
+		}
+		config.Context = append(config.Context, context)
+		return nil
+	})
+	systemAddendum := fs.String("system-addendum", "", "append this text file to the system prompt of the page requests, e.g. company drafting conventions or part numbering")
+	examples := fs.String("examples", "", "send the example pages in this directory (page.pdf or page.png with the expected analysis in page.md) ahead of every page as few-shot examples")
+	fs.StringVar(&config.ClassifyModel, "classify-model", "", "model for the -two-stage classification call (default: the analysis model)")
+	fs.BoolVar(&config.Consolidate, "consolidate", false, "summarize all page analyses into one document summary (map-reduce for long documents)")
+	fs.BoolVar(&config.Summary, "summary", false, "write a one-page executive summary (key components, total parts, critical notes) to {pdf-name}_analysis.summary.md")
+	fs.StringVar(&config.SummaryModel, "summary-model", "", "model for -summary (default: the analysis model)")
+	fs.IntVar(&config.FanIn, "fan-in", 0, "with -consolidate, summarize at most this many sections per group at each level, e.g. 10 for 200+ page manuals (0 = group by size only)")
+	fs.BoolVar(&config.AnnotatedPDF, "annotated-pdf", false, "write {pdf-name}_analysis.pdf with each original page followed by its analysis")
+	fs.StringVar(&config.EventsPath, "events", "", "append progress events (start, each page with the cost so far, finished) to this file as JSON lines")
+	fs.StringVar(&config.CaptureDir, "capture-dir", "", "write the exact API request and response of every chunk to this directory (API keys redacted)")
+	fs.StringVar(&config.Markdown, "markdown", "", "also write {pdf-name}_analysis.md for pasting into a wiki: confluence, notion, or github")
+	fs.StringVar(&config.RedactRules, "redact", "", "mask terms and patterns from this rules file in reports and write {pdf-name}_analysis.redacted.json (the main JSON stays unredacted)")
+	fs.StringVar(&config.PriceList, "prices", "", "price the master BOM from this CSV or JSON price list keyed by part number (implies -structured)")
+	fs.StringVar(&config.PricingFile, "pricing-file", "", "read model prices from this JSON file instead of the built-in pricing.json; models it lists replace or add to the built-in ones")
+	fs.StringVar(&config.EscalateModel, "escalate-model", "", "rerun pages whose output looks incomplete (no structured data, BOM table without rows, -validate findings) with this model, e.g. claude-3-5-sonnet-20241022")
+	fs.StringVar(&config.ValidatorsPath, "validate", "", "check that values matched in each page's text layer appear in the output, using this validators file")
+	fs.BoolVar(&config.NeutralRetry, "neutral-retry", false, "send a page the model refuses or a safety filter blocks once more, with instructions to describe only what is printed on it")
+	fs.BoolVar(&config.Ground, "ground", false, "send each page's text layer with it, have the model mark part numbers and dimensions it cannot find as unverified, and move those and values missing from the text layer into a separate section")
+	fs.StringVar(&config.RulesPath, "rules", "", "check each page's title block, notes, and tolerances against this rules file (implies -structured)")
+	fs.StringVar(&config.QuestionsPath, "questions", "", "answer each question in this file (one per line) from the pages that mention it, with page citations, instead of analyzing every page")
+	fs.StringVar(&config.TemplatePath, "template", "", "render the result with this Go text/template file")
+	fs.StringVar(&config.TemplateOut, "template-out", "", "output file for -template (default {pdf-name}_report.{ext})")
+	fs.StringVar(&config.Compression, "compress", config.Compression, "compress the JSON result: none, gzip (.json.gz), or zstd (.json.zst)")
+	fs.StringVar(&config.OutputURI, "output-uri", "", "also upload the output files to this s3://, gs://, or az:// prefix, with credentials from the provider's SDK chain")
+	fs.StringVar(&config.PostHook, "post-hook", "", "run this shell command once the outputs are written, e.g. \"upload {{.OutputJSON}}\"; fields are shell-quoted, and also come as DESIGN_ANT_* variables")
+	fs.StringVar(&config.PageHook, "page-hook", "", "run this shell command after each page, with the page result as JSON on stdin and {{.Page}} set")
+	fs.Func("stage", "run this shell command on each page after extraction, with the page as JSON on stdin; the page it prints on stdout replaces it (repeatable, run in order)", func(command string) error {
+		if strings.TrimSpace(command) == "" {
+			return fmt.Errorf("empty command")
+		}
+		config.Stages = append(config.Stages, pdfanalysis.NewExecStage(command))
+		return nil
+	})
+	fs.StringVar(&config.Project, "project", os.Getenv("DESIGN_ANT_PROJECT"), "attribute this run's spend to a project in the ledger; see 'go run . spend'")
+	fs.StringVar(&config.LedgerPath, "ledger", defaultLedgerPath(), "append a summary of this run to this ledger file (empty = disabled)")
+	fs.StringVar(&config.OutputLang, "output-lang", "", "write the analysis in this language, e.g. de, fr, zh (default English)")
+	fs.StringVar(&config.LangMode, "lang-mode", config.LangMode, "how -output-lang is applied: prompt (model answers in the language) or translate (separate translation pass)")
+	fs.StringVar(&config.Terminology, "terminology", "", "drafting vocabulary of the analysis text: asme (BOM, feature control frame) or iso (parts list, tolerance frame)")
+	fs.StringVar(&config.UnitOrder, "unit-order", "", "which unit system the analysis puts first, e.g. for dual dimensions: metric-first or imperial-first")
+	fs.StringVar(&config.TranslateModel, "translate-model", "", "model for -lang-mode translate (default: the analysis model)")
+	fs.StringVar(&config.AlertWebhook, "alert-webhook", os.Getenv("DESIGN_ANT_ALERT_WEBHOOK"), "post to this webhook (e.g. a Slack incoming webhook) when the run's cost passes an -alert-at threshold")
+	alertAt := fs.String("alert-at", os.Getenv("DESIGN_ANT_ALERT_AT"), "comma-separated dollar amounts that trigger a spend alert mid-run, e.g. 1,5,20")
+	reuseFrom := fs.String("reuse", "", "comma-separated earlier result files; pages identical to one of their pages reuse its analysis")
+	fs.StringVar(&config.CacheDir, "cache", config.CacheDir, "cache page analyses in this directory and reuse them for identical pages, prompts, and models (empty = disabled)")
+	fs.StringVar(&config.ResumeFrom, "resume", "", "result of an interrupted run; its finished pages are kept and only the rest are analyzed (with -result-store, a name or location in the store)")
+	fs.StringVar(&config.StoreURI, "result-store", "", "keep the JSON result and page stream in this directory, SQLite database (sqlite:results.db), or s3://, gs://, az:// prefix instead of the current directory")
+	fs.BoolVar(&config.StreamJSONL, "jsonl", config.StreamJSONL, "stream each page result to {pdf-name}_analysis.jsonl as it completes")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("%v\n\n%s", err, usage(fs))
+	}
+	if fs.NArg() < 1 {
+		return nil, fmt.Errorf("%s", usage(fs))
+	}
+	config.PDFPath = fs.Arg(0)
+
+	pack, ok := pdfanalysis.PromptPacks[*promptPack]
+	if !ok {
+		return nil, fmt.Errorf("invalid -prompt-pack %q: must be %s", *promptPack, strings.Join(pdfanalysis.PromptPackNames(), ", "))
+	}
+	if *promptTemplate != "" {
+		// The template replaces the pack's prompt; its structured sections stay
+		custom, err := pdfanalysis.LoadPromptPack(*promptTemplate)
+		if err != nil {
+			return nil, err
+		}
+		pack.Name, pack.Version, pack.Hash, pack.Analysis = custom.Name, custom.Version, custom.Hash, custom.Analysis
+	}
+	config.PromptPack = pack
+	if *systemAddendum != "" {
+		text, err := os.ReadFile(*systemAddendum)
+		if err != nil {
+			return nil, fmt.Errorf("error reading -system-addendum: %v", err)
+		}
+		config.SystemAddendum = string(text)
+	}
+	if *examples != "" {
+		loaded, err := pdfanalysis.LoadExamples(*examples)
+		if err != nil {
+			return nil, err
+		}
+		config.Examples = loaded
+	}
+	for _, filename := range strings.Split(*reuseFrom, ",") {
+		if filename = strings.TrimSpace(filename); filename != "" {
+			config.ReuseFrom = append(config.ReuseFrom, filename)
+		}
+	}
+
+	thresholds, err := parseThresholds(*alertAt)
+	if err != nil {
+		return nil, err
+	}
+	config.AlertThresholds = thresholds
+	if config.AlertWebhook != "" && len(config.AlertThresholds) == 0 {
+		return nil, fmt.Errorf("-alert-webhook needs -alert-at thresholds, e.g. -alert-at 1,5,20")
+	}
+
+	for name, command := range map[string]string{"post-hook": config.PostHook, "page-hook": config.PageHook} {
+		if command == "" {
+			continue
+		}
+		if _, err := parseHook(name, command); err != nil {
+			return nil, err
+		}
+	}
+	if config.OutputURI != "" && !isRemoteURI(config.OutputURI) {
+		return nil, fmt.Errorf("invalid -output-uri %q: must start with s3://, gs://, or az://", config.OutputURI)
+	}
+	if config.StoreURI == "sqlite:" {
+		return nil, fmt.Errorf("invalid -result-store %q: use sqlite:path/to/results.db", config.StoreURI)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// contextPages matches the page list prefix of a -context-file value
+var contextPages = regexp.MustCompile(`^([0-9][0-9,\- ]*):(.+)$`)
+
+// parseContextFile reads a -context-file value, a file with an optional page
+// list in front of it: spec.txt, 7:spec.txt, or 3-5,9:spec.txt
+func parseContextFile(value string) (pdfanalysis.PageContext, error) {
+	var context pdfanalysis.PageContext
+	path := value
+	if m := contextPages.FindStringSubmatch(value); m != nil {
+		path = m[2]
+		for _, part := range strings.Split(m[1], ",") {
+			first, last, isRange := strings.Cut(strings.TrimSpace(part), "-")
+			from, err := strconv.Atoi(strings.TrimSpace(first))
+			to := from
+			if err == nil && isRange {
+				to, err = strconv.Atoi(strings.TrimSpace(last))
+			}
+			if err != nil || from < 1 || to < from {
+				return context, fmt.Errorf("invalid pages %q: must be like 3, 3-5, or 3-5,9", m[1])
+			}
+			for page := from; page <= to; page++ {
+				context.Pages = append(context.Pages, page)
+			}
+		}
+	}
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return context, fmt.Errorf("error reading context file: %v", err)
+	}
+	context.Text = string(text)
+	return context, nil
+}
+
+// usage builds the help text for the analysis command
+func usage(fs *flag.FlagSet) string {
+	var b strings.Builder
+	b.WriteString("Usage: go run . [flags] <pdf-file|docx-file|pptx-file|s3://, gs://, az:// URI>\n")
+	b.WriteString("Example: go run . ../design-analysis/v6truboEngine.pdf\n\nFlags:\n")
+	fs.SetOutput(&b)
+	fs.PrintDefaults()
+	fs.SetOutput(io.Discard)
+	return b.String()
+}
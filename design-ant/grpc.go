@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"design-ant/pkg/pdfanalysis"
+	"design-ant/proto/designantv1"
+)
+
+// grpcService is the DesignAnt service of proto/designant.proto. Analyze
+// queues a job like POST /jobs and streams its pages from the job's result
+// stream as the workers finish them.
+type grpcService struct {
+	designantv1.UnimplementedDesignAntServer
+	s *server
+}
+
+// newGRPCServer returns a gRPC server of the DesignAnt service that accepts
+// requests carrying documents up to the -max-upload-mb of s
+func newGRPCServer(s *server) *grpc.Server {
+	g := grpc.NewServer(grpc.MaxRecvMsgSize(int(s.maxUpload)+1<<20), grpc.StreamInterceptor(s.streamAuth))
+	designantv1.RegisterDesignAntServer(g, &grpcService{s: s})
+	return g
+}
+
+// metadataValue returns the first value of a metadata key of an incoming
+// call, or ""
+func metadataValue(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// streamAuth checks the bearer token of a gRPC call in its authorization
+// metadata as require does for HTTP: Analyze queues jobs, so the caller
+// needs the submit role
+func (s *server) streamAuth(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if s.auth == nil {
+		return handler(srv, stream)
+	}
+	token, _ := strings.CutPrefix(metadataValue(stream.Context(), "authorization"), "Bearer ")
+	p, err := s.auth.verify(stream.Context(), token)
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	if !p.has(roleSubmit) {
+		return status.Errorf(codes.PermissionDenied, "%s lacks the %s role", p.name, roleSubmit)
+	}
+	return handler(srv, &principalStream{ServerStream: stream, ctx: context.WithValue(stream.Context(), principalKey{}, p)})
+}
+
+// principalStream is a server stream whose context holds the caller
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (p *principalStream) Context() context.Context {
+	return p.ctx
+}
+
+// grpcCode returns the gRPC code of an HTTP status returned by queueUpload
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
+
+// Analyze queues the document of req as a job of the x-tenant metadata's
+// tenant and streams each page of its result once, in the order they are
+// finished, then a Summary. A client that disconnects leaves the job
+// running; its result stays available over HTTP.
+func (g *grpcService) Analyze(req *designantv1.AnalyzeRequest, stream designantv1.DesignAnt_AnalyzeServer) error {
+	ctx := stream.Context()
+	tenant, err := g.s.callerTenant(ctx, metadataValue(ctx, "x-tenant"))
+	if err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	if tenants, _ := g.s.store.tenants(); tenants != nil && tenant == nil {
+		return status.Error(codes.PermissionDenied, "missing x-tenant metadata")
+	}
+	job, err := g.queue(req, tenant)
+	if err != nil {
+		return err
+	}
+
+	// Pages are told apart by their first page, since a result read back
+	// from its stream numbers the chunks it has so far in page order
+	sent := make(map[int]bool)
+	for {
+		// The job is read before its result, so the result of a finished
+		// job is the final one
+		current, err := g.s.store.load(job.ID)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		result, err := g.s.jobResult(ctx, current)
+		if err != nil && !errors.Is(err, pdfanalysis.ErrResultNotFound) {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if result != nil {
+			for _, chunk := range result.Chunks {
+				if sent[chunk.StartPage] {
+					continue
+				}
+				if err := stream.Send(&designantv1.AnalyzeEvent{Event: &designantv1.AnalyzeEvent_Page{Page: pageResult(chunk)}}); err != nil {
+					return err
+				}
+				sent[chunk.StartPage] = true
+			}
+		}
+		if current.Status == JobDone || current.Status == JobFailed {
+			if result == nil {
+				return status.Errorf(codes.Aborted, "job %s failed without a result: %s", current.ID, current.Error)
+			}
+			summary, err := g.summary(current, result)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			return stream.Send(&designantv1.AnalyzeEvent{Event: &designantv1.AnalyzeEvent_Summary{Summary: summary}})
+		}
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-time.After(eventPollInterval):
+		}
+	}
+}
+
+// queue saves the document of req in a new upload and queues its job with
+// -jsonl, so its pages can be read while it runs
+func (g *grpcService) queue(req *designantv1.AnalyzeRequest, tenant *Tenant) (*Job, error) {
+	if req.GetUri() != "" {
+		return nil, status.Error(codes.InvalidArgument, "documents given as URIs are not accepted; send the PDF in pdf")
+	}
+	if len(req.GetPdf()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "missing pdf")
+	}
+	if int64(len(req.GetPdf())) > g.s.maxUpload {
+		return nil, status.Errorf(codes.InvalidArgument, "document larger than %d MB", g.s.maxUpload>>20)
+	}
+	if model := req.GetModel(); model != "" && model != pdfanalysis.DefaultModel {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported model %q: jobs run %s", model, pdfanalysis.DefaultModel)
+	}
+	name := "document.pdf"
+	if req.GetFilename() != "" {
+		name = filepath.Base(req.GetFilename())
+	}
+	if !strings.EqualFold(filepath.Ext(name), ".pdf") {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported filename %q: pdf holds a PDF", name)
+	}
+
+	upload, err := g.s.newUpload()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	document := filepath.Join(upload, name)
+	if err := os.WriteFile(document, req.GetPdf(), 0644); err != nil {
+		os.RemoveAll(upload)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	args := []string{"-jsonl"}
+	if req.GetStructured() {
+		args = append(args, "-structured")
+	}
+	if req.GetInputMode() != "" {
+		args = append(args, "-input-mode", req.GetInputMode())
+	}
+	if req.GetProject() != "" {
+		args = append(args, "-project", req.GetProject())
+	}
+	args = append(append(args, req.GetFlags()...), document)
+	jobReq := jobRequest{Args: args, Concurrency: int(req.GetWorkers()), Budget: req.GetMaxCost()}
+	job, httpStatus, err := g.s.queueUpload(jobReq, upload, tenant)
+	if err != nil {
+		os.RemoveAll(upload)
+		return nil, status.Error(grpcCode(httpStatus), err.Error())
+	}
+	return job, nil
+}
+
+// summary returns the Summary of a finished job's result
+func (g *grpcService) summary(job *Job, result *pdfanalysis.Result) (*designantv1.Summary, error) {
+	config, err := g.s.jobConfig(job)
+	if err != nil {
+		return nil, err
+	}
+	name := resultName(config)
+	location := resultLocation(config, name)
+	if config.StoreURI == "" {
+		location = filepath.Join(job.Dir, name)
+	}
+	failed := 0
+	for _, chunk := range result.Chunks {
+		if chunk.Error != "" {
+			failed++
+		}
+	}
+	return &designantv1.Summary{
+		TotalPages:        int32(result.TotalPages),
+		FailedPages:       int32(failed),
+		Interrupted:       result.Interrupted || job.Status == JobFailed,
+		TotalInputTokens:  int32(result.TotalInputTokens),
+		TotalOutputTokens: int32(result.TotalOutputTokens),
+		TotalCost:         result.TotalCost,
+		ProcessingTime:    result.ProcessingTime,
+		ResultUri:         location,
+	}, nil
+}
+
+// pageResult converts a chunk of the JSON result to its PageResult
+func pageResult(chunk pdfanalysis.ChunkAnalysis) *designantv1.PageResult {
+	page := &designantv1.PageResult{
+		ChunkNumber:  int32(chunk.ChunkNumber),
+		StartPage:    int32(chunk.StartPage),
+		EndPage:      int32(chunk.EndPage),
+		Analysis:     chunk.Analysis,
+		InputTokens:  int32(chunk.InputTokens),
+		OutputTokens: int32(chunk.OutputTokens),
+		TotalCost:    chunk.TotalCost,
+		InputMode:    chunk.InputMode,
+		Retries:      int32(chunk.Retries),
+		ReusedFrom:   chunk.ReusedFrom,
+		DuplicateOf:  int32(chunk.DuplicateOf),
+		Error:        chunk.Error,
+		ErrorClass:   chunk.ErrorClass,
+		Notes:        chunk.Notes,
+	}
+	if !chunk.Timestamp.IsZero() {
+		page.Timestamp = timestamppb.New(chunk.Timestamp)
+	}
+	if m := chunk.Metadata; m != nil {
+		page.Metadata = &designantv1.DrawingMetadata{
+			DrawingNumber: m.DrawingNumber, Title: m.Title, Revision: m.Revision, DrawnBy: m.DrawnBy,
+			CheckedBy: m.CheckedBy, ApprovedBy: m.ApprovedBy, Date: m.Date, Scale: m.Scale,
+			Projection: m.Projection, Material: m.Material,
+		}
+	}
+	for _, item := range chunk.BOMItems {
+		page.BomItems = append(page.BomItems, &designantv1.BOMItem{
+			ItemNumber: item.ItemNumber, PartNumber: item.PartNumber, Description: item.Description,
+			Quantity: item.Quantity, Material: item.Material, Finish: item.Finish,
+		})
+	}
+	for _, dim := range chunk.Dimensions {
+		page.Dimensions = append(page.Dimensions, &designantv1.Dimension{
+			Feature: dim.Feature, Type: dim.Type, Value: dim.Value, Unit: dim.Unit, Tolerance: dim.Tolerance,
+		})
+	}
+	return page
+}
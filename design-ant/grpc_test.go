@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"design-ant/pkg/pdfanalysis"
+	"design-ant/proto/designantv1"
+)
+
+// grpcClient serves s over an in-memory connection and returns a client of it
+func grpcClient(t *testing.T, s *server) designantv1.DesignAntClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	g := newGRPCServer(s)
+	go g.Serve(listener)
+	t.Cleanup(g.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return designantv1.NewDesignAntClient(conn)
+}
+
+func testServer(t *testing.T) *server {
+	dir := t.TempDir()
+	store, err := openJobStore(filepath.Join(dir, "jobs"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.close() })
+	return &server{store: store, workDir: dir, maxUpload: 1 << 20, maxJobWorkers: 16}
+}
+
+// finishJob plays the worker of the first job queued in s: it streams two
+// pages, then saves the result and marks the job done
+func finishJob(t *testing.T, s *server) {
+	var job *Job
+	for job == nil {
+		jobs, err := s.store.list()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if len(jobs) > 0 {
+			job = jobs[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	config, err := s.jobConfig(job)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !config.StreamJSONL || !config.Structured {
+		t.Errorf("job args %q lack -jsonl or -structured", job.Args)
+	}
+	store, err := openResultStore(config.StoreURI, job.Dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer closeResultStore(store)
+	name := resultName(config)
+	var chunks []pdfanalysis.ChunkAnalysis
+	for _, n := range []int{2, 1} {
+		chunk := pdfanalysis.ChunkAnalysis{ChunkNumber: n, StartPage: n, EndPage: n, Analysis: "A bracket.",
+			InputTokens: 1000, OutputTokens: 100, TotalCost: 0.01, Timestamp: time.Now()}
+		chunk.BOMItems = []pdfanalysis.BOMItem{{PartNumber: "P-1", Quantity: 2}}
+		if err := store.AppendPage(context.Background(), name, chunk); err != nil {
+			t.Error(err)
+			return
+		}
+		chunks = append(chunks, chunk)
+		time.Sleep(2 * eventPollInterval)
+	}
+	result := pdfanalysis.FullAnalysisResult{PDFPath: job.Document(), TotalPages: 2, Chunks: chunks}
+	result.SumTotals()
+	if err := store.Save(context.Background(), name, result); err != nil {
+		t.Error(err)
+		return
+	}
+	job.Status = JobDone
+	if err := s.store.save(job); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGRPCAnalyze(t *testing.T) {
+	s := testServer(t)
+	client := grpcClient(t, s)
+	go finishJob(t, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	stream, err := client.Analyze(ctx, &designantv1.AnalyzeRequest{
+		Document:   &designantv1.AnalyzeRequest_Pdf{Pdf: []byte("%PDF-1.7")},
+		Filename:   "bracket.pdf",
+		Structured: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pages []int
+	var summary *designantv1.Summary
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page := event.GetPage(); page != nil {
+			pages = append(pages, int(page.StartPage))
+			if len(page.BomItems) != 1 || page.BomItems[0].PartNumber != "P-1" || page.Timestamp == nil {
+				t.Errorf("page %d = %v", page.ChunkNumber, page)
+			}
+		}
+		if event.GetSummary() != nil {
+			summary = event.GetSummary()
+		}
+	}
+	if len(pages) != 2 || pages[0] != 2 || pages[1] != 1 {
+		t.Errorf("start pages %v, want [2 1] in completion order", pages)
+	}
+	if summary == nil {
+		t.Fatal("no summary")
+	}
+	if summary.TotalPages != 2 || summary.TotalInputTokens != 2000 || summary.Interrupted {
+		t.Errorf("summary = %v", summary)
+	}
+	if filepath.Base(summary.ResultUri) != "bracket_analysis.json" {
+		t.Errorf("result_uri = %q", summary.ResultUri)
+	}
+}
+
+func TestGRPCAnalyzeRejects(t *testing.T) {
+	s := testServer(t)
+	client := grpcClient(t, s)
+	pdf := &designantv1.AnalyzeRequest_Pdf{Pdf: []byte("%PDF-1.7")}
+	tests := []struct {
+		name string
+		req  *designantv1.AnalyzeRequest
+	}{
+		{"uri", &designantv1.AnalyzeRequest{Document: &designantv1.AnalyzeRequest_Uri{Uri: "s3://bucket/a.pdf"}}},
+		{"no document", &designantv1.AnalyzeRequest{}},
+		{"other model", &designantv1.AnalyzeRequest{Document: pdf, Model: "claude-3-5-sonnet-20241022"}},
+		{"not a pdf", &designantv1.AnalyzeRequest{Document: pdf, Filename: "notes.docx"}},
+		{"operator flag", &designantv1.AnalyzeRequest{Document: pdf, Flags: []string{"-capture-dir", "/tmp"}}},
+		{"second document", &designantv1.AnalyzeRequest{Document: pdf, Flags: []string{"/etc/passwd"}}},
+		{"negative workers", &designantv1.AnalyzeRequest{Document: pdf, Workers: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.Analyze(context.Background(), tt.req)
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("error %v, want InvalidArgument", err)
+			}
+		})
+	}
+	if jobs, _ := s.store.list(); len(jobs) != 0 {
+		t.Errorf("%d jobs queued", len(jobs))
+	}
+}
+
+func TestGRPCAuth(t *testing.T) {
+	s := testServer(t)
+	s.auth = &authenticator{tokens: make(map[string]*principal)}
+	for token, p := range map[string]*principal{
+		"reader":    {name: "dashboard", roles: []string{roleRead}},
+		"submitter": {name: "plm", roles: []string{roleSubmit}},
+	} {
+		sum := sha256.Sum256([]byte(token))
+		s.auth.tokens[hex.EncodeToString(sum[:])] = p
+	}
+	client := grpcClient(t, s)
+	for token, want := range map[string]codes.Code{
+		"":          codes.Unauthenticated,
+		"wrong":     codes.Unauthenticated,
+		"reader":    codes.PermissionDenied,
+		"submitter": codes.InvalidArgument, // Past auth, the empty request is rejected
+	} {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		}
+		stream, err := client.Analyze(ctx, &designantv1.AnalyzeRequest{})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != want {
+			t.Errorf("token %q: error %v, want %v", token, err, want)
+		}
+	}
+}
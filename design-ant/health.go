@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"design-ant/pkg/pdfanalysis"
+)
+
+// readyCacheTTL is how long a readiness result is reused, so frequent probes
+// do not each call the provider
+const readyCacheTTL = 30 * time.Second
+
+// readiness caches the last readiness check of the server
+type readiness struct {
+	mu      sync.Mutex
+	checked time.Time
+	ready   bool
+	checks  map[string]string
+}
+
+// healthz reports that the process serves requests
+func (s *server) healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "version": pdfanalysis.Build().String()})
+}
+
+// readyz reports whether the server can run jobs: the jobs directory is
+// writable, the queue answers, tenants.json parses, and every API key jobs
+// would use is accepted by the provider. It answers 503 with the failed
+// checks otherwise.
+func (s *server) readyz(w http.ResponseWriter, r *http.Request) {
+	s.readiness.mu.Lock()
+	if time.Since(s.readiness.checked) >= readyCacheTTL {
+		s.readiness.checks = s.checkReadiness(r.Context())
+		s.readiness.checked = time.Now()
+		s.readiness.ready = true
+		for _, result := range s.readiness.checks {
+			if result != "ok" {
+				s.readiness.ready = false
+			}
+		}
+	}
+	ready, checks := s.readiness.ready, s.readiness.checks
+	s.readiness.mu.Unlock()
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not ready", http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, map[string]interface{}{"status": status, "checks": checks})
+}
+
+// checkReadiness runs the readiness checks, returning "ok" or the problem
+// for each
+func (s *server) checkReadiness(ctx context.Context) map[string]string {
+	checks := make(map[string]string)
+	checks["jobs_dir"] = "ok"
+	if err := os.MkdirAll(s.store.dir, 0755); err != nil {
+		checks["jobs_dir"] = err.Error()
+	} else if file, err := os.CreateTemp(s.store.dir, ".ready-*"); err != nil {
+		checks["jobs_dir"] = err.Error()
+	} else {
+		file.Close()
+		os.Remove(file.Name())
+	}
+
+	checks["queue"] = "ok"
+	if err := s.store.queue.ping(ctx); err != nil {
+		checks["queue"] = err.Error()
+	}
+
+	// The runner's own key runs jobs without a tenant; with tenants it is
+	// only checked when set
+	tenants, err := s.store.tenants()
+	if err != nil {
+		checks["tenants"] = err.Error()
+	}
+	keys := make(map[string]string)
+	if key := os.Getenv("ANTHROPIC_API_KEY"); key != "" || tenants == nil {
+		keys["anthropic"] = key
+	}
+	for name, tenant := range tenants {
+		keys["tenant:"+name] = os.Getenv(tenant.APIKeyEnv)
+	}
+	for name, key := range keys {
+		checks[name] = "ok"
+		if err := checkAnthropicKey(ctx, key); err != nil {
+			checks[name] = err.Error()
+		}
+	}
+	return checks
+}
+
+// checkAnthropicKey lists one model with the key, which costs nothing and
+// fails with 401 for a missing or revoked key. A rate-limited or overloaded
+// answer still proves the key is accepted.
+func checkAnthropicKey(ctx context.Context, apiKey string) error {
+	if apiKey == "" {
+		return fmt.Errorf("no API key set")
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://api.anthropic.com/v1/models?limit=1", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-api-key", apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+	resp, err := pdfanalysis.QuickClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error reaching the API: %v", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusTooManyRequests, 529:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("API key rejected (status %d)", resp.StatusCode)
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
+
+	"design-ant/internal/tempfiles"
+	"design-ant/pkg/pdfanalysis"
+)
+
+// hookTimeout bounds one run of a hook command
+const hookTimeout = 5 * time.Minute
+
+// hookData is what -post-hook and -page-hook commands can use, as template
+// fields and as DESIGN_ANT_* environment variables
+type hookData struct {
+	Document    string  // Input document as given, a path or URI
+	OutputJSON  string  // Absolute path of the JSON result, or its location in -result-store
+	OutputJSONL string  // Absolute path or location of the JSONL stream (empty with -jsonl=false)
+	OutputURI   string  // -output-uri the outputs were uploaded to (empty = none)
+	Status      string  // complete or partial (-post-hook); ok or failed (-page-hook)
+	Pages       int     // Pages analyzed (-post-hook)
+	Cost        float64 // Dollars spent by the run, or by the page (-page-hook)
+	Page        int     // Page number (-page-hook)
+	Error       string  // Error of the page (-page-hook)
+}
+
+// hookFuncs are the template functions of hook commands. The fields are
+// quoted already, so quote is kept only for commands written before they were.
+var hookFuncs = template.FuncMap{"quote": func(s string) string { return s }}
+
+// shellQuote quotes s as one shell word, e.g. a path with spaces
+func shellQuote(s string) string {
+	if runtime.GOOS == "windows" {
+		return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// parseHook parses a hook command template and tries it on empty fields;
+// flag checks call it so a broken template or a misspelled field fails
+// before the run
+func parseHook(name, command string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(hookFuncs).Parse(command)
+	if err == nil {
+		err = tmpl.Execute(io.Discard, hookData{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid -%s: %v", name, err)
+	}
+	return tmpl, nil
+}
+
+// newHookData returns the fields shared by both hooks of a run
+func newHookData(config *pdfanalysis.Config) hookData {
+	data := hookData{Document: config.DocumentPath(), OutputURI: config.OutputURI}
+	data.OutputJSON = absPath(resultLocation(config, resultName(config)))
+	if config.StreamJSONL {
+		data.OutputJSONL = absPath(resultLocation(config, pdfanalysis.StreamName(resultName(config))))
+	}
+	return data
+}
+
+// quoted returns the fields with every string quoted as one shell word, so a
+// file name picked up by watch mode cannot inject commands into a hook
+func (d hookData) quoted() hookData {
+	for _, field := range []*string{&d.Document, &d.OutputJSON, &d.OutputJSONL, &d.OutputURI, &d.Status, &d.Error} {
+		*field = shellQuote(*field)
+	}
+	return d
+}
+
+// env returns the fields as DESIGN_ANT_* environment variables
+func (d hookData) env() []string {
+	return []string{
+		"DESIGN_ANT_DOCUMENT=" + d.Document,
+		"DESIGN_ANT_OUTPUT_JSON=" + d.OutputJSON,
+		"DESIGN_ANT_OUTPUT_JSONL=" + d.OutputJSONL,
+		"DESIGN_ANT_OUTPUT_URI=" + d.OutputURI,
+		"DESIGN_ANT_STATUS=" + d.Status,
+		fmt.Sprintf("DESIGN_ANT_PAGES=%d", d.Pages),
+		fmt.Sprintf("DESIGN_ANT_COST=%.6f", d.Cost),
+		fmt.Sprintf("DESIGN_ANT_PAGE=%d", d.Page),
+		"DESIGN_ANT_ERROR=" + d.Error,
+	}
+}
+
+// runHook renders a hook command with the quoted fields and runs it with
+// the shell, passing stdin to it. Its output goes to the console; a failure is a warning, since the
+// analysis itself succeeded.
+func runHook(name, command string, data hookData, stdin []byte) {
+	tmpl, err := parseHook(name, command)
+	if err != nil {
+		slog.Warn("Could not run hook", "hook", name, "error", err)
+		return
+	}
+	var line bytes.Buffer
+	if err := tmpl.Execute(&line, data.quoted()); err != nil {
+		slog.Warn("Could not run hook", "hook", name, "error", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", line.String())
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", line.String())
+	}
+	cmd.Env = append(os.Environ(), data.env()...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		slog.Warn("Hook failed", "hook", name, "command", line.String(), "error", err)
+	}
+}
+
+// pageHooks runs -page-hook for every finished page, one page at a time in
+// the background, so a slow hook never holds up the analysis
+type pageHooks struct {
+	command string
+	data    hookData
+	pages   chan pdfanalysis.ChunkAnalysis
+	done    chan struct{}
+}
+
+// newPageHooks starts the page hook runner of a run, or returns nil without
+// -page-hook
+func newPageHooks(config *pdfanalysis.Config, pages int) *pageHooks {
+	if config.PageHook == "" {
+		return nil
+	}
+	h := &pageHooks{command: config.PageHook, data: newHookData(config), pages: make(chan pdfanalysis.ChunkAnalysis, pages), done: make(chan struct{})}
+	go func() {
+		defer tempfiles.CleanupOnPanic()
+		defer close(h.done)
+		for chunk := range h.pages {
+			data := h.data
+			data.Page, data.Cost, data.Error, data.Status = chunk.StartPage, chunk.TotalCost, chunk.Error, "ok"
+			if chunk.Error != "" {
+				data.Status = "failed"
+			}
+			// The page result, as in the JSONL stream, on stdin
+			result, _ := json.Marshal(chunk)
+			runHook("page-hook", h.command, data, result)
+		}
+	}()
+	return h
+}
+
+// page queues the hook of a finished page
+func (h *pageHooks) page(chunk pdfanalysis.ChunkAnalysis) {
+	if h != nil {
+		h.pages <- chunk
+	}
+}
+
+// wait lets the queued page hooks finish
+func (h *pageHooks) wait() {
+	if h != nil {
+		close(h.pages)
+		<-h.done
+	}
+}
+
+// runPostHook runs -post-hook once the outputs of the document are written
+func runPostHook(config *pdfanalysis.Config, outputJSON string, pages int, cost float64, interrupted bool) {
+	if config.PostHook == "" {
+		return
+	}
+	data := newHookData(config)
+	data.OutputJSON = absPath(outputJSON)
+	data.Pages, data.Cost, data.Status = pages, cost, "complete"
+	if interrupted {
+		data.Status = "partial"
+	}
+	fmt.Printf("🪝 Running post-hook\n")
+	runHook("post-hook", config.PostHook, data, nil)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestRunHookQuotesFields(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks run with cmd /C on Windows")
+	}
+	dir := t.TempDir()
+	t.Chdir(dir)
+	// A file name dropped into a watched folder
+	document := "x'; touch injected; echo '$(touch injected).pdf"
+	for _, command := range []string{"printf %s {{.Document}} > out", "printf %s {{.Document | quote}} > out"} {
+		runHook("post-hook", command, hookData{Document: document}, nil)
+		out, err := os.ReadFile(filepath.Join(dir, "out"))
+		if err != nil || string(out) != document {
+			t.Errorf("%s: hook saw %q, %v; want the name as one word", command, out, err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "injected")); err == nil {
+			t.Fatalf("%s: the file name ran a command", command)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Package tempfiles manages the temporary files of a run: one directory per
+// run, predictable and unique names inside it, its size on disk, and its
+// removal, also when the process is stopped by a second Ctrl-C or a panic
+// in any goroutine, which skip deferred calls.
+package tempfiles
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// live holds the directories not removed yet, for RemoveAll
+var live = struct {
+	sync.Mutex
+	dirs map[*Dir]bool
+}{dirs: make(map[*Dir]bool)}
+
+// Dir is a temporary directory and the names handed out in it
+type Dir struct {
+	path string
+
+	mu    sync.Mutex
+	names map[string]bool // Names handed out by File and Sub
+}
+
+// New creates a directory named prefix-* in the system temp directory
+func New(prefix string) (*Dir, error) {
+	path, err := os.MkdirTemp("", prefix+"-*")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temp directory: %v", err)
+	}
+	d := &Dir{path: path, names: make(map[string]bool)}
+	live.Lock()
+	live.dirs[d] = true
+	live.Unlock()
+	return d, nil
+}
+
+// Path returns the directory
+func (d *Dir) Path() string {
+	return d.path
+}
+
+// File returns the path of a new file named name in the directory, e.g.
+// chunk_3.pdf. A name handed out before gets a number, chunk_3-2.pdf, so
+// two files never overwrite each other. The file is not created.
+func (d *Dir) File(name string) string {
+	return filepath.Join(d.path, d.reserve(name))
+}
+
+// Sub creates a new subdirectory named like File names files and returns
+// its path. It is removed with the directory.
+func (d *Dir) Sub(name string) (string, error) {
+	path := filepath.Join(d.path, d.reserve(name))
+	if err := os.Mkdir(path, 0755); err != nil {
+		return "", fmt.Errorf("error creating temp directory: %v", err)
+	}
+	return path, nil
+}
+
+// reserve returns name, or name with the first free number before its
+// extension
+func (d *Dir) reserve(name string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	unique := name
+	for n := 2; d.names[unique]; n++ {
+		unique = fmt.Sprintf("%s-%d%s", base, n, ext)
+	}
+	d.names[unique] = true
+	return unique
+}
+
+// Size returns the bytes of the files in the directory and its subdirectories
+func (d *Dir) Size() (int64, error) {
+	var size int64
+	err := filepath.WalkDir(d.path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// Remove deletes the directory and everything in it. It can be called
+// more than once, e.g. deferred and again by RemoveAll.
+func (d *Dir) Remove() error {
+	live.Lock()
+	delete(live.dirs, d)
+	live.Unlock()
+	return os.RemoveAll(d.path)
+}
+
+// RemoveAll deletes every directory not removed yet. It is for paths that
+// end the process without running deferred calls, e.g. before os.Exit.
+func RemoveAll() {
+	live.Lock()
+	dirs := make([]*Dir, 0, len(live.dirs))
+	for d := range live.dirs {
+		dirs = append(dirs, d)
+	}
+	live.Unlock()
+	for _, d := range dirs {
+		if err := d.Remove(); err != nil {
+			slog.Warn("Could not remove temp directory", "dir", d.path, "error", err)
+		}
+	}
+}
+
+// CleanupOnPanic removes every directory when the goroutine it is deferred
+// in panics, then panics again with the same value. A panic outside main
+// ends the process without the deferred calls of main, so goroutines that
+// run while temp files exist defer it first.
+func CleanupOnPanic() {
+	if r := recover(); r != nil {
+		RemoveAll()
+		panic(r)
+	}
+}
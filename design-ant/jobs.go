@@ -0,0 +1,861 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"design-ant/pkg/pdfanalysis"
+)
+
+// Job states
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// Job is one queued analysis. Jobs are kept in jobs.db in the jobs directory
+// or on the Redis server of -queue, so queued and running jobs survive a
+// restart of the runner.
+type Job struct {
+	ID          string    `json:"id"`
+	Status      string    `json:"status"`
+	Dir         string    `json:"dir"`                   // Working directory the job was added from; relative paths in Args resolve here
+	Args        []string  `json:"args"`                  // Analysis flags and the document, as on the command line
+	Concurrency int       `json:"concurrency,omitempty"` // Concurrent page requests of the job, passed as -workers (0 = default)
+	Budget      float64   `json:"budget,omitempty"`      // Dollars after which the job stops, passed as -max-cost (0 = no limit)
+	Priority    string    `json:"priority,omitempty"`    // Priority class: high, normal, or low (empty = normal)
+	Attempts    int       `json:"attempts"`
+	Runner      string    `json:"runner,omitempty"`    // Host and process ID of the runner that last claimed the job
+	Tenant      string    `json:"tenant,omitempty"`    // Tenant the job runs for, see tenants.json
+	Cost        float64   `json:"cost,omitempty"`      // Dollars spent by all attempts of the job
+	Upload      string    `json:"upload,omitempty"`    // Directory of a document uploaded to the server, which the job runs in
+	Outputs     []string  `json:"outputs,omitempty"`   // Files the job wrote, deleted by retention
+	PurgedAt    time.Time `json:"purged_at,omitempty"` // When retention deleted the job's files
+	Resume      string    `json:"resume,omitempty"`    // Partial result the next attempt continues, passed as -resume
+	Slices      int       `json:"slices,omitempty"`    // Attempts that ended at the runner's -slice and were requeued
+	CreatedAt   time.Time `json:"created_at"`
+	QueuedAt    time.Time `json:"queued_at,omitempty"` // When the job last joined the queue (zero = CreatedAt)
+	StartedAt   time.Time `json:"started_at,omitempty"`
+	FinishedAt  time.Time `json:"finished_at,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Document returns the document the job analyzes
+func (j *Job) Document() string {
+	if len(j.Args) == 0 {
+		return ""
+	}
+	return j.Args[len(j.Args)-1]
+}
+
+// defaultJobsDir returns DESIGN_ANT_JOBS or a jobs directory in the user config directory
+func defaultJobsDir() string {
+	if dir := os.Getenv("DESIGN_ANT_JOBS"); dir != "" {
+		return dir
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "jobs"
+	}
+	return filepath.Join(dir, "design-ant", "jobs")
+}
+
+// jobStore keeps the jobs of a queue. Several runners, on one machine or on
+// many sharing the Redis server of -queue, can work the same queue: a job is
+// claimed by taking its lock exclusively, and the runner holding it refreshes
+// the lock while the job runs. The mutex serializes the workers of one
+// runner. Logs, progress events, and tenants.json are always kept in the jobs
+// directory.
+type jobStore struct {
+	dir     string
+	runner  string   // Host and process ID of this runner, recorded in its locks
+	results string   // -result-store of every job, with absolute paths (empty = each job's own)
+	queue   jobQueue // jobs.db in dir, or the Redis server of -queue
+	mu      sync.Mutex
+}
+
+// jobQueue holds the jobs of a queue and the locks and heartbeats of the
+// runners working it
+type jobQueue interface {
+	save(job *Job) error
+	// load reads one job, with an error wrapping os.ErrNotExist when there
+	// is none
+	load(id string) (*Job, error)
+	list() ([]*Job, error)
+	// claim locks the first job pick returns from all jobs that is still
+	// pending and not locked, updates it with start, saves it, and returns
+	// it, or nil when there is none
+	claim(runner string, pick func(jobs []*Job) []*Job, start func(job *Job)) (*Job, error)
+	// release saves a claimed job and removes its lock in one step, but only
+	// while runner still holds the lock, and reports whether it did
+	release(job *Job, runner string) (bool, error)
+	// lock takes a lock for runner, reporting false when it is held
+	lock(name, runner string) (bool, error)
+	// refresh and unlock only touch a lock runner holds; refresh returns
+	// errLockLost when it holds it no longer
+	refresh(name, runner string) error
+	unlock(name, runner string)
+	// clearStale removes a lock that is stale, reporting whether the lock is
+	// gone
+	clearStale(name string) bool
+	// fresh reports whether a lock is held and was refreshed less than
+	// jobLockStale ago
+	fresh(name string) bool
+	// beat marks a runner active; runners counts the active ones
+	beat(runner string) error
+	endBeat(runner string)
+	runners() int
+	// location names the queue in messages
+	location() string
+	ping(ctx context.Context) error
+	close() error
+}
+
+// Job locks are refreshed every jobLockRefresh while the job runs. A running
+// job whose lock is older than jobLockStale lost its runner, which crashed or
+// was cut off from the queue, and is returned to the queue.
+const (
+	jobLockRefresh = 30 * time.Second
+	jobLockStale   = 3 * time.Minute
+)
+
+// errLockLost is returned by refresh when the lock is gone or another runner
+// took it over
+var errLockLost = errors.New("lock lost")
+
+// runnerID names this process in job locks and runner heartbeats
+func runnerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// openJobStore returns the store of a jobs directory, keeping its jobs in
+// jobs.db there or, when queue is set, on that Redis server
+func openJobStore(dir, queue string) (*jobStore, error) {
+	store := &jobStore{dir: dir}
+	if queue == "" {
+		sqliteQueue, err := openSQLiteQueue(dir)
+		if err != nil {
+			return nil, err
+		}
+		store.queue = sqliteQueue
+		return store, nil
+	}
+	if !strings.HasPrefix(queue, "redis://") && !strings.HasPrefix(queue, "rediss://") {
+		return nil, fmt.Errorf("invalid -queue %q: use redis://host:port or rediss://host:port", queue)
+	}
+	redisQueue, err := openRedisQueue(queue)
+	if err != nil {
+		return nil, err
+	}
+	store.queue = redisQueue
+	return store, nil
+}
+
+// close closes the connection to the queue
+func (s *jobStore) close() error {
+	return s.queue.close()
+}
+
+// location names where the jobs are kept in messages
+func (s *jobStore) location() string {
+	return s.queue.location()
+}
+
+// eventsPath returns the file the progress events of a job are appended to
+func (s *jobStore) eventsPath(id string) string {
+	return filepath.Join(s.dir, id+".events.jsonl")
+}
+
+// logPath returns the file the output of a job is written to
+func (s *jobStore) logPath(id string) string {
+	return filepath.Join(s.dir, id+".log")
+}
+
+// save writes a job
+func (s *jobStore) save(job *Job) error {
+	return s.queue.save(job)
+}
+
+// list reads all jobs, oldest first
+func (s *jobStore) list() ([]*Job, error) {
+	jobs, err := s.queue.list()
+	if err != nil {
+		return nil, err
+	}
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+// sortJobs sorts jobs oldest first
+func sortJobs(jobs []*Job) {
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+}
+
+// load reads one job
+func (s *jobStore) load(id string) (*Job, error) {
+	return s.queue.load(id)
+}
+
+// claim marks the next pending job in schedule order as running and returns
+// it, or nil when none is pending. A job another runner locked first is
+// skipped, and so is one whose tenant already runs its max_jobs.
+func (s *jobStore) claim() (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tenants, err := s.tenants()
+	if err != nil {
+		return nil, err
+	}
+	pick := func(jobs []*Job) []*Job {
+		sortJobs(jobs)
+		running := make(map[string]int)
+		for _, job := range jobs {
+			if job.Status == JobRunning {
+				running[job.Tenant]++
+			}
+		}
+		var picked []*Job
+		for _, job := range schedule(jobs) {
+			if tenant := tenants[job.Tenant]; tenant == nil || tenant.MaxJobs == 0 || running[job.Tenant] < tenant.MaxJobs {
+				picked = append(picked, job)
+			}
+		}
+		return picked
+	}
+	return s.queue.claim(s.runner, pick, func(job *Job) {
+		job.Status = JobRunning
+		job.Runner = s.runner
+		job.Attempts++
+		job.StartedAt = time.Now()
+		job.Error = ""
+	})
+}
+
+// release saves the outcome of a claimed job and removes its lock. A job
+// whose lock this runner lost was requeued and may run elsewhere now, so its
+// outcome is not saved over the new attempt's.
+func (s *jobStore) release(job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	released, err := s.queue.release(job, s.runner)
+	if err == nil && !released {
+		return fmt.Errorf("the job lost its lock and was requeued, so this attempt's outcome is dropped")
+	}
+	return err
+}
+
+// holdLock refreshes the lock of a running job until stop is closed, and
+// calls lost when the lock is gone, so the job stops before the runner that
+// requeued it runs it again
+func (s *jobStore) holdLock(id string, stop <-chan struct{}, lost func()) {
+	ticker := time.NewTicker(jobLockRefresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			err := s.queue.refresh(id, s.runner)
+			if errors.Is(err, errLockLost) {
+				slog.Warn("Job lost its lock; stopping it", "job", id)
+				lost()
+				return
+			}
+			if err != nil {
+				slog.Warn("Could not refresh job lock", "job", id, "error", err)
+			}
+		}
+	}
+}
+
+// requeueStale returns running jobs whose runner is gone, because it crashed,
+// was killed, or lost the queue, to pending. Their finished pages come from
+// the page cache when they run again.
+func (s *jobStore) requeueStale() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs, err := s.list()
+	if err != nil {
+		return 0, err
+	}
+	queue := s.queue
+	requeued := 0
+	for _, job := range jobs {
+		if job.Status != JobRunning || queue.fresh(job.ID) {
+			continue
+		}
+		// Of the runners finding the lock stale, only the one that takes
+		// the attempt's requeue lock, exclusively as claim does, requeues it
+		requeueLock := fmt.Sprintf("%s.requeue-%d", job.ID, job.Attempts)
+		// Left by a runner that stopped while requeueing
+		queue.clearStale(requeueLock)
+		locked, err := queue.lock(requeueLock, s.runner)
+		if err != nil {
+			return requeued, err
+		}
+		if !locked {
+			continue
+		}
+		ok, err := s.requeueAttempt(job)
+		queue.unlock(requeueLock, s.runner)
+		if err != nil {
+			return requeued, err
+		}
+		if ok {
+			requeued++
+		}
+	}
+	return requeued, nil
+}
+
+// requeueAttempt returns a job to pending if it still runs the attempt it
+// ran when listed and its lock is still stale, and reports whether it did.
+// Since the listing its runner may have refreshed the lock or finished the
+// job, or another runner requeued and claimed it again.
+func (s *jobStore) requeueAttempt(job *Job) (bool, error) {
+	current, err := s.load(job.ID)
+	if err != nil {
+		return false, err
+	}
+	queue := s.queue
+	if current.Status != JobRunning || current.Attempts != job.Attempts || queue.fresh(job.ID) {
+		return false, nil
+	}
+	// Nothing claims the job before it is saved pending. A lock refreshed
+	// since the check stays, and so does the job.
+	if !queue.clearStale(job.ID) {
+		return false, nil
+	}
+	current.Status = JobPending
+	return true, s.save(current)
+}
+
+// heartbeat marks this runner active until stop is closed, then removes its
+// entry
+func (s *jobStore) heartbeat(stop <-chan struct{}) {
+	queue := s.queue
+	touch := func() {
+		if err := queue.beat(s.runner); err != nil {
+			slog.Warn("Could not record runner heartbeat", "runner", s.runner, "error", err)
+		}
+	}
+	touch()
+	ticker := time.NewTicker(jobLockRefresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			queue.endBeat(s.runner)
+			return
+		case <-ticker.C:
+			touch()
+		}
+	}
+}
+
+// activeRunners counts the runners whose heartbeat is recent, at least 1
+func (s *jobStore) activeRunners() int {
+	return max(s.queue.runners(), 1)
+}
+
+// runJobs dispatches the jobs subcommands
+func runJobs(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: go run . jobs add|list|run [flags]")
+	}
+	switch args[0] {
+	case "add":
+		return runJobsAdd(args[1:])
+	case "list":
+		return runJobsList(args[1:])
+	case "run":
+		return runJobsRun(args[1:])
+	}
+	return fmt.Errorf("unknown jobs command %q: use add, list, or run", args[0])
+}
+
+// runJobsAdd queues an analysis. The analysis flags and document follow the
+// job's own flags, e.g. `jobs add -budget 5 -- -structured drawing.pdf`.
+func runJobsAdd(args []string) error {
+	fs := flag.NewFlagSet("jobs add", flag.ContinueOnError)
+	dir := fs.String("dir", defaultJobsDir(), "jobs directory")
+	queue := fs.String("queue", os.Getenv("DESIGN_ANT_QUEUE"), "keep the jobs on this Redis server, redis://host:port[/db], so runners on several machines work one queue (default: jobs.db in -dir)")
+	concurrency := fs.Int("concurrency", 0, "concurrent page requests of this job (0 = the analysis default)")
+	budget := fs.Float64("budget", 0, "stop this job once it has cost this many dollars (0 = no limit)")
+	tenant := fs.String("tenant", "", "tenant the job runs for, with its key and limits from tenants.json in the jobs directory")
+	priority := fs.String("priority", PriorityNormal, "priority class: high jobs start before normal ones, normal before low")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	job, err := newJob(wd, fs.Args(), *concurrency, *budget, *priority)
+	if err != nil {
+		return err
+	}
+	store, err := openJobStore(*dir, *queue)
+	if err != nil {
+		return err
+	}
+	defer store.close()
+	if *tenant != "" {
+		tenants, err := store.tenants()
+		if err != nil {
+			return err
+		}
+		if tenants[*tenant] == nil {
+			return fmt.Errorf("unknown tenant %q: add it to %s", *tenant, store.tenantsPath())
+		}
+		job.Tenant = *tenant
+	}
+	if err := store.save(job); err != nil {
+		return fmt.Errorf("error saving job: %v", err)
+	}
+	fmt.Printf("✅ Queued job %s: %s\n", job.ID, job.Document())
+	return nil
+}
+
+// newJob checks a job's settings and analysis flags, so bad ones fail when
+// the job is added rather than when it runs, and returns it pending
+func newJob(dir string, args []string, concurrency int, budget float64, priority string) (*Job, error) {
+	if concurrency < 0 || budget < 0 {
+		return nil, fmt.Errorf("-concurrency and -budget must not be negative")
+	}
+	if err := checkPriority(priority); err != nil {
+		return nil, err
+	}
+	if priority == PriorityNormal {
+		priority = ""
+	}
+	if _, err := parseFlags(args); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &Job{
+		ID:          now.UTC().Format("20060102T150405.000000000Z"),
+		Status:      JobPending,
+		Dir:         dir,
+		Args:        args,
+		Concurrency: concurrency,
+		Budget:      budget,
+		Priority:    priority,
+		CreatedAt:   now,
+	}, nil
+}
+
+// runJobsList prints the jobs of a directory
+func runJobsList(args []string) error {
+	fs := flag.NewFlagSet("jobs list", flag.ContinueOnError)
+	dir := fs.String("dir", defaultJobsDir(), "jobs directory")
+	queue := fs.String("queue", os.Getenv("DESIGN_ANT_QUEUE"), "keep the jobs on this Redis server, redis://host:port[/db], so runners on several machines work one queue (default: jobs.db in -dir)")
+	status := fs.String("status", "", "only list jobs in this state: pending, running, done, or failed")
+	tenant := fs.String("tenant", "", "only list the jobs of this tenant")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	store, err := openJobStore(*dir, *queue)
+	if err != nil {
+		return err
+	}
+	defer store.close()
+	jobs, err := store.list()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Jobs in %s\n", store.location())
+	fmt.Println(strings.Repeat("=", 105))
+	fmt.Printf("%-28s %-8s %-4s %-32s %4s %7s %8s  %s\n", "ID", "STATUS", "PRI", "DOCUMENT", "TRY", "WORKERS", "BUDGET", "ERROR")
+	fmt.Println(strings.Repeat("-", 105))
+	shown := 0
+	for _, job := range jobs {
+		if (*status != "" && job.Status != *status) || (*tenant != "" && job.Tenant != *tenant) {
+			continue
+		}
+		shown++
+		workers, budgetText, priority := "-", "-", "-"
+		if job.Concurrency > 0 {
+			workers = fmt.Sprint(job.Concurrency)
+		}
+		if job.Budget > 0 {
+			budgetText = fmt.Sprintf("$%.2f", job.Budget)
+		}
+		if job.Priority != "" {
+			priority = job.Priority
+		}
+		note := job.Error
+		switch {
+		case job.Status == JobRunning:
+			note = "on " + job.Runner
+		case job.Status == JobPending && job.Slices > 0:
+			note = fmt.Sprintf("resumes after %d slice(s)", job.Slices)
+		}
+		fmt.Printf("%-28s %-8s %-4s %-32s %4d %7s %8s  %s\n", job.ID, job.Status, priority, pdfanalysis.Truncate(filepath.Base(job.Document()), 32),
+			job.Attempts, workers, budgetText, pdfanalysis.Truncate(note, 40))
+	}
+	fmt.Println(strings.Repeat("=", 105))
+	fmt.Printf("%d job(s)\n", shown)
+	return nil
+}
+
+// runJobsRun works the queue with a pool of workers, each running one job
+// at a time as a child process. Ctrl-C stops the running jobs, which write
+// partial results, and returns them to the queue.
+func runJobsRun(args []string) error {
+	fs := flag.NewFlagSet("jobs run", flag.ContinueOnError)
+	dir := fs.String("dir", defaultJobsDir(), "jobs directory")
+	queue := fs.String("queue", os.Getenv("DESIGN_ANT_QUEUE"), "keep the jobs on this Redis server, redis://host:port[/db], so runners on several machines work one queue (default: jobs.db in -dir)")
+	workers := fs.Int("workers", 1, "jobs run at the same time")
+	poll := fs.Duration("poll", 0, "keep waiting for new jobs, checking this often, e.g. 10s (0 = stop once the queue is empty)")
+	tpm := fs.Int("tpm", 0, "input tokens per minute shared by all runners of the directory; each job gets an even share (0 = each job's own -tpm)")
+	slice := fs.Duration("slice", 0, "run each job for at most this long, e.g. 5m, then requeue it to resume after the other queued jobs (0 = run jobs to the end)")
+	results := fs.String("result-store", "", "keep the results of jobs in this directory, sqlite: database, or s3://, gs://, az:// prefix (default: each job's own, in its directory)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *slice < 0 {
+		return fmt.Errorf("invalid -slice %v: must not be negative", *slice)
+	}
+	if *workers < 1 {
+		return fmt.Errorf("invalid -workers %d: must be at least 1", *workers)
+	}
+	if *tpm < 0 {
+		return fmt.Errorf("invalid -tpm %d: must not be negative", *tpm)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("error locating the design-ant binary: %v", err)
+	}
+
+	if err := checkStorage(*results); err != nil {
+		return err
+	}
+	store, err := openJobStore(*dir, *queue)
+	if err != nil {
+		return err
+	}
+	defer store.close()
+	store.runner, store.results = runnerID(), absStoreURI(*results)
+	if err := requeueStale(store); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("🗂️  Working jobs in %s with %d worker(s) as runner %s\n", store.location(), *workers, store.runner)
+	if *tpm > 0 {
+		fmt.Printf("🚦 Sharing %d tokens/min with the other runners of the queue\n", *tpm)
+	}
+	if *slice > 0 {
+		fmt.Printf("🔄 Jobs take turns in slices of %v\n", *slice)
+	}
+	workQueue(ctx, store, exe, *workers, *poll, *tpm, *slice)
+	if ctx.Err() != nil {
+		fmt.Println("⏹️  Runner stopped; unfinished jobs stay queued")
+	}
+	return nil
+}
+
+// workQueue runs the queue's jobs with the given number of workers until ctx
+// is canceled or, with poll 0, the queue is empty. With a slice, each attempt
+// runs at most that long.
+func workQueue(ctx context.Context, store *jobStore, exe string, workers int, poll time.Duration, tpm int, slice time.Duration) {
+	beat := make(chan struct{})
+	go store.heartbeat(beat)
+	defer close(beat)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				job, err := store.claim()
+				if err != nil {
+					pdfanalysis.Logf("⚠️  Could not read the queue: %v\n", err)
+				}
+				if job == nil {
+					if poll == 0 {
+						return
+					}
+					select {
+					case <-ctx.Done():
+					case <-time.After(poll):
+						// Picks up the jobs of runners that died while this one waited
+						if err := requeueStale(store); err != nil {
+							pdfanalysis.Logf("⚠️  %v\n", err)
+						}
+					}
+					continue
+				}
+				runJob(ctx, store, exe, job, tokenShare(store, tpm, workers), slice)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// requeueStale returns the jobs of runners that are gone to the queue
+func requeueStale(store *jobStore) error {
+	requeued, err := store.requeueStale()
+	if err != nil {
+		return fmt.Errorf("error requeueing jobs: %v", err)
+	}
+	if requeued > 0 {
+		fmt.Printf("🔁 %d job(s) left running by a stopped runner returned to the queue\n", requeued)
+	}
+	return nil
+}
+
+// tokenShare splits a tokens-per-minute budget shared by all runners of the
+// queue evenly over their workers, counting the runners active right now.
+// It returns 0 when there is no shared budget.
+func tokenShare(store *jobStore, tpm, workers int) int {
+	if tpm == 0 {
+		return 0
+	}
+	share := tpm / (store.activeRunners() * workers)
+	if share < 1 {
+		share = 1
+	}
+	return share
+}
+
+// runJob runs one claimed job to completion, or for one slice, and records
+// its outcome. tpm is the job's share of the runners' token budget, 0 for
+// none; slice is the runner's -slice, 0 for none.
+func runJob(ctx context.Context, store *jobStore, exe string, job *Job, tpm int, slice time.Duration) {
+	fmt.Printf("▶️  Job %s: %s (attempt %d)\n", job.ID, job.Document(), job.Attempts)
+	// The job's own context stops it when its lock is lost
+	jobCtx, cancelJob := context.WithCancel(ctx)
+	defer cancelJob()
+	stopLock := make(chan struct{})
+	go store.holdLock(job.ID, stopLock, cancelJob)
+	slice = jobSlice(job, slice)
+	var finished *progressEvent
+	limits, err := jobLimits(store, job)
+	if err == nil {
+		var offset int64
+		if info, err := os.Stat(store.eventsPath(job.ID)); err == nil {
+			offset = info.Size()
+		}
+		err = execJob(jobCtx, store, exe, job, tpm, slice, limits)
+		var cost float64
+		cost, finished = attemptOutcome(store.eventsPath(job.ID), offset)
+		job.Cost += cost
+		job.Outputs = mergeOutputs(job.Outputs, outputFiles(job.Dir, job.Document(), job.StartedAt))
+	}
+	close(stopLock)
+	switch {
+	case ctx.Err() == nil && jobCtx.Err() != nil:
+		// Another runner requeued the job and owns it now
+		fmt.Printf("⚠️  Job %s lost its lock and was stopped\n", job.ID)
+		return
+	case ctx.Err() != nil:
+		// Stopped with the runner, not failed; the next attempt continues
+		// from the pages finished so far
+		job.Status = JobPending
+		if finished != nil && finished.Partial {
+			job.Resume = finished.Output
+		}
+		fmt.Printf("⏸️  Job %s returned to the queue\n", job.ID)
+	case slice > 0 && finished != nil && finished.Deadline:
+		endSlice(job, finished)
+		fmt.Printf("🔄 Job %s: slice over after %d of %d page(s), requeued\n", job.ID, finished.Done, finished.Total)
+	case err != nil:
+		job.Status = JobFailed
+		job.Error = err.Error()
+		fmt.Printf("❌ Job %s failed: %v (log: %s)\n", job.ID, err, store.logPath(job.ID))
+	default:
+		job.Status = JobDone
+		job.Resume = ""
+		fmt.Printf("✅ Job %s done (log: %s)\n", job.ID, store.logPath(job.ID))
+	}
+	job.FinishedAt = time.Now()
+	if err := store.release(job); err != nil {
+		pdfanalysis.Logf("⚠️  Could not save job %s: %v\n", job.ID, err)
+	}
+}
+
+// jobLimits returns the key and limits of the job's tenant, or none for a
+// job without a tenant
+func jobLimits(store *jobStore, job *Job) (*tenantLimits, error) {
+	if job.Tenant == "" {
+		return nil, nil
+	}
+	tenants, err := store.tenants()
+	if err != nil {
+		return nil, err
+	}
+	tenant := tenants[job.Tenant]
+	if tenant == nil {
+		return nil, fmt.Errorf("unknown tenant %q", job.Tenant)
+	}
+	jobs, err := store.list()
+	if err != nil {
+		return nil, err
+	}
+	limits, err := limitsFor(tenant, tenantUsage(jobs, tenant, time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	return &limits, nil
+}
+
+// mergeOutputs adds the files of an attempt to the outputs of earlier ones
+func mergeOutputs(outputs, files []string) []string {
+	for _, file := range files {
+		if !slices.Contains(outputs, file) {
+			outputs = append(outputs, file)
+		}
+	}
+	return outputs
+}
+
+// execJob runs the analysis of a job as a child process, appending its
+// output to the job's log. A tenant's limits are passed after the job's own
+// flags, so the job cannot raise them.
+func execJob(ctx context.Context, store *jobStore, exe string, job *Job, tpm int, slice time.Duration, tenant *tenantLimits) error {
+	args := []string{"-no-progress", "-events", store.eventsPath(job.ID)}
+	if tpm > 0 {
+		// Before the job's own flags, so a -tpm given with the job wins
+		args = append(args, "-tpm", fmt.Sprint(tpm))
+	}
+	if slice > 0 {
+		args = append(args, "-run-deadline", slice.String())
+	}
+	if job.Concurrency > 0 {
+		args = append(args, "-workers", fmt.Sprint(job.Concurrency))
+	}
+	if job.Budget > 0 {
+		args = append(args, "-max-cost", fmt.Sprint(job.Budget))
+	}
+	if store.results != "" {
+		args = append(args, "-result-store", store.results)
+	}
+	args = append(args, job.Args[:len(job.Args)-1]...)
+	if job.Resume != "" {
+		args = append(args, "-resume", job.Resume)
+	}
+	var env []string
+	if tenant != nil {
+		env = []string{"ANTHROPIC_API_KEY=" + tenant.apiKey}
+		// Only limits stricter than the job's are added
+		own, err := parseFlags(append(append([]string{}, args...), job.Document()))
+		if err != nil {
+			return err
+		}
+		args = append(args, "-project", job.Tenant)
+		if tenant.maxCost > 0 && (own.MaxCost == 0 || tenant.maxCost < own.MaxCost) {
+			args = append(args, "-max-cost", fmt.Sprint(tenant.maxCost))
+		}
+		if tenant.tpm > 0 && (own.TokensPerMinute == 0 || tenant.tpm < own.TokensPerMinute) {
+			args = append(args, "-tpm", fmt.Sprint(tenant.tpm))
+		}
+	}
+	args = append(args, job.Document())
+	header := fmt.Sprintf("=== Attempt %d, %s", job.Attempts, time.Now().Format(time.RFC3339))
+	return runAnalysisProcess(ctx, exe, job.Dir, args, env, store.logPath(job.ID), header)
+}
+
+// runAnalysisProcess runs an analysis as a child process in dir, with env
+// added to this process's environment, appending a header line and its
+// output to logPath. On failure the last line of the log, which holds the
+// analysis error, is returned. Canceling ctx interrupts the analysis, which
+// still writes its partial result.
+func runAnalysisProcess(ctx context.Context, exe, dir string, args, env []string, logPath, header string) error {
+	logFile, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error creating log: %v", err)
+	}
+	defer logFile.Close()
+	fmt.Fprintln(logFile, header)
+
+	cmd := exec.CommandContext(ctx, exe, args...)
+	cmd.Dir = dir
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = time.Minute
+	if err := cmd.Run(); err != nil {
+		if line := lastLogLine(logPath); line != "" {
+			return fmt.Errorf("%s", logMessage(line))
+		}
+		return err
+	}
+	return nil
+}
+
+// logMessage returns the message of a line logged by setupLogging's text or
+// JSON handler, or the line itself
+func logMessage(line string) string {
+	var record struct {
+		Msg string `json:"msg"`
+	}
+	if json.Unmarshal([]byte(line), &record) == nil && record.Msg != "" {
+		return record.Msg
+	}
+	i := strings.Index(line, " msg=")
+	if i < 0 {
+		return line
+	}
+	msg := line[i+len(" msg="):]
+	if quoted, err := strconv.QuotedPrefix(msg); err == nil {
+		if unquoted, err := strconv.Unquote(quoted); err == nil {
+			return unquoted
+		}
+	}
+	if end := strings.IndexByte(msg, ' '); end >= 0 {
+		msg = msg[:end]
+	}
+	return msg
+}
+
+// lastLogLine returns the last non-empty line of a job log
+func lastLogLine(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	var last string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			last = line
+		}
+	}
+	return last
+}
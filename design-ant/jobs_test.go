@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// testQueues returns, for SQLite and for Redis, a function that opens a
+// store of one shared queue, as each runner of the queue does
+func testQueues(t *testing.T) map[string]func() *jobStore {
+	dir := t.TempDir()
+	server := miniredis.RunT(t)
+	open := func(dir, queue string) *jobStore {
+		store, err := openJobStore(dir, queue)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { store.close() })
+		return store
+	}
+	return map[string]func() *jobStore{
+		"sqlite": func() *jobStore { return open(dir, "") },
+		"redis":  func() *jobStore { return open(t.TempDir(), "redis://"+server.Addr()) },
+	}
+}
+
+// expireLock makes a lock look like its runner stopped refreshing it
+func expireLock(t *testing.T, store *jobStore, name string) {
+	t.Helper()
+	switch queue := store.queue.(type) {
+	case *redisQueue:
+		queue.client.Del(context.Background(), queue.lockKey(name))
+	case *sqliteQueue:
+		then := time.Now().Add(-2 * jobLockStale)
+		if _, err := queue.db.Exec("UPDATE locks SET refreshed = ? WHERE name = ?", then.UnixNano(), name); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// runningJob saves a job claimed by runner gone-1, whose lock is stale when
+// stale is set
+func runningJob(t *testing.T, store *jobStore, id string, stale bool) *Job {
+	t.Helper()
+	job := &Job{ID: id, Status: JobRunning, Args: []string{"drawing.pdf"}, Attempts: 1, Runner: "gone-1", CreatedAt: time.Now()}
+	if err := store.save(job); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := store.queue.lock(id, "gone-1"); !ok || err != nil {
+		t.Fatalf("lock = %v, %v", ok, err)
+	}
+	if stale {
+		expireLock(t, store, id)
+	}
+	return job
+}
+
+func TestRequeueStaleOnce(t *testing.T) {
+	for name, open := range testQueues(t) {
+		t.Run(name, func(t *testing.T) {
+			runningJob(t, open(), "stale", true)
+			runningJob(t, open(), "fresh", false)
+
+			// Runners sharing the queue, each with its own mutex
+			const runners = 8
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			total := 0
+			for i := range runners {
+				store := open()
+				store.runner = fmt.Sprintf("runner-%d", i)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					requeued, err := store.requeueStale()
+					if err != nil {
+						t.Error(err)
+					}
+					mu.Lock()
+					total += requeued
+					mu.Unlock()
+				}()
+			}
+			wg.Wait()
+			if total != 1 {
+				t.Errorf("requeued %d times, want once", total)
+			}
+			store := open()
+			if job, err := store.load("stale"); err != nil || job.Status != JobPending {
+				t.Errorf("stale job = %+v, %v; want pending", job, err)
+			}
+			if job, err := store.load("fresh"); err != nil || job.Status != JobRunning {
+				t.Errorf("fresh job = %+v, %v; want running", job, err)
+			}
+			if !store.queue.fresh("fresh") {
+				t.Error("lock of the fresh job removed")
+			}
+			if store.queue.fresh("stale.requeue-1") {
+				t.Error("requeue lock left behind")
+			}
+		})
+	}
+}
+
+func TestRequeueAttempt(t *testing.T) {
+	for name, open := range testQueues(t) {
+		t.Run(name, func(t *testing.T) {
+			store := open()
+			store.runner = "runner-1"
+			listed := runningJob(t, store, "job", true)
+
+			// Requeued and claimed again by another runner since the listing
+			current := *listed
+			current.Attempts = 2
+			current.Runner = "runner-2"
+			if err := store.save(&current); err != nil {
+				t.Fatal(err)
+			}
+			if ok, err := store.requeueAttempt(listed); ok || err != nil {
+				t.Errorf("requeueAttempt of a newer attempt = %v, %v; want false", ok, err)
+			}
+
+			// The runner refreshed its lock since the listing
+			current.Attempts = 1
+			if err := store.save(&current); err != nil {
+				t.Fatal(err)
+			}
+			if store.queue.refresh("job", "gone-1") != nil {
+				// An expired Redis lock is gone, so the runner takes it anew
+				store.queue.lock("job", "gone-1")
+			}
+			if ok, err := store.requeueAttempt(listed); ok || err != nil {
+				t.Errorf("requeueAttempt with a fresh lock = %v, %v; want false", ok, err)
+			}
+
+			// A requeue lock left by a runner that stopped is taken over
+			expireLock(t, store, "job")
+			if ok, _ := store.queue.lock("job.requeue-1", "runner-3"); !ok {
+				t.Fatal("requeue lock not taken")
+			}
+			expireLock(t, store, "job.requeue-1")
+			if requeued, err := store.requeueStale(); requeued != 1 || err != nil {
+				t.Errorf("requeueStale = %d, %v; want 1", requeued, err)
+			}
+		})
+	}
+}
+
+func TestQueueClaim(t *testing.T) {
+	for name, open := range testQueues(t) {
+		t.Run(name, func(t *testing.T) {
+			first, second := open(), open()
+			first.runner, second.runner = "runner-1", "runner-2"
+			job := &Job{ID: "job-1", Status: JobPending, Dir: t.TempDir(), Args: []string{"drawing.pdf"}, CreatedAt: time.Now()}
+			if err := first.save(job); err != nil {
+				t.Fatal(err)
+			}
+			claimed, err := first.claim()
+			if err != nil || claimed == nil || claimed.Runner != "runner-1" || claimed.Attempts != 1 {
+				t.Fatalf("claim = %+v, %v", claimed, err)
+			}
+			if again, err := second.claim(); again != nil || err != nil {
+				t.Errorf("second claim = %+v, %v; want none", again, err)
+			}
+			if jobs, err := second.list(); err != nil || len(jobs) != 1 || jobs[0].Status != JobRunning {
+				t.Errorf("list = %v, %v", jobs, err)
+			}
+			claimed.Status = JobDone
+			if err := first.release(claimed); err != nil {
+				t.Fatal(err)
+			}
+			if second.queue.fresh(job.ID) {
+				t.Error("lock held after release")
+			}
+			if _, err := second.load("missing"); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("load of a missing job = %v, want os.ErrNotExist", err)
+			}
+
+			beat := make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				first.heartbeat(beat)
+				close(done)
+			}()
+			deadline := time.Now().Add(5 * time.Second)
+			for second.queue.runners() != 1 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if n := second.activeRunners(); n != 1 {
+				t.Errorf("activeRunners = %d, want 1", n)
+			}
+			close(beat)
+			<-done
+			if n := second.queue.runners(); n != 0 {
+				t.Errorf("%d runners after the heartbeat ended", n)
+			}
+		})
+	}
+}
+
+func TestOpenJobStore(t *testing.T) {
+	for _, queue := range []string{"amqp://localhost", "redis://127.0.0.1:1"} {
+		if _, err := openJobStore(t.TempDir(), queue); err == nil || !strings.Contains(err.Error(), "queue") {
+			t.Errorf("openJobStore(%q) = %v, want an error naming the queue", queue, err)
+		}
+	}
+}
+
+func TestQueueClaimRace(t *testing.T) {
+	for name, open := range testQueues(t) {
+		t.Run(name, func(t *testing.T) {
+			const jobs, runners = 20, 8
+			store := open()
+			for i := range jobs {
+				job := &Job{ID: fmt.Sprintf("job-%02d", i), Status: JobPending, Args: []string{"drawing.pdf"}, CreatedAt: time.Now()}
+				if err := store.save(job); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Runners sharing the queue claim until it is empty
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			claims := make(map[string]int)
+			for i := range runners {
+				store := open()
+				store.runner = fmt.Sprintf("runner-%d", i)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for {
+						job, err := store.claim()
+						if err != nil {
+							t.Error(err)
+							return
+						}
+						if job == nil {
+							return
+						}
+						mu.Lock()
+						claims[job.ID]++
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+			if len(claims) != jobs {
+				t.Errorf("%d of %d jobs claimed", len(claims), jobs)
+			}
+			for id, n := range claims {
+				if n != 1 {
+					t.Errorf("job %s claimed %d times", id, n)
+				}
+			}
+			listed, err := store.list()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, job := range listed {
+				if job.Status != JobRunning || job.Attempts != 1 {
+					t.Errorf("job %s is %s after %d attempt(s), want running after 1", job.ID, job.Status, job.Attempts)
+				}
+			}
+		})
+	}
+}
+
+func TestLockLost(t *testing.T) {
+	for name, open := range testQueues(t) {
+		t.Run(name, func(t *testing.T) {
+			first, second := open(), open()
+			first.runner, second.runner = "runner-1", "runner-2"
+			job := &Job{ID: "job", Status: JobPending, Args: []string{"drawing.pdf"}, CreatedAt: time.Now()}
+			if err := first.save(job); err != nil {
+				t.Fatal(err)
+			}
+			stalled, err := first.claim()
+			if err != nil || stalled == nil {
+				t.Fatalf("claim = %+v, %v", stalled, err)
+			}
+
+			// The first runner stalls past jobLockStale; the second requeues
+			// and claims the job
+			expireLock(t, first, "job")
+			if requeued, err := second.requeueStale(); requeued != 1 || err != nil {
+				t.Fatalf("requeueStale = %d, %v; want 1", requeued, err)
+			}
+			claimed, err := second.claim()
+			if err != nil || claimed == nil || claimed.Attempts != 2 {
+				t.Fatalf("claim after requeue = %+v, %v", claimed, err)
+			}
+
+			if err := first.queue.refresh("job", first.runner); !errors.Is(err, errLockLost) {
+				t.Errorf("refresh of a lost lock = %v, want errLockLost", err)
+			}
+			first.queue.unlock("job", first.runner)
+			stalled.Status = JobDone
+			if err := first.release(stalled); err == nil {
+				t.Error("release of a lost job succeeded")
+			}
+			if current, err := second.load("job"); err != nil || current.Status != JobRunning || current.Runner != "runner-2" {
+				t.Errorf("job = %+v, %v; want running on runner-2", current, err)
+			}
+			if !second.queue.fresh("job") {
+				t.Fatal("lock of the new runner removed")
+			}
+			if err := second.queue.refresh("job", second.runner); err != nil {
+				t.Errorf("refresh by the new runner: %v", err)
+			}
+			claimed.Status = JobDone
+			if err := second.release(claimed); err != nil {
+				t.Errorf("release by the new runner: %v", err)
+			}
+			if current, err := first.load("job"); err != nil || current.Status != JobDone || current.Attempts != 2 {
+				t.Errorf("job = %+v, %v; want done after 2 attempts", current, err)
+			}
+		})
+	}
+}
+
+func TestImportJobFiles(t *testing.T) {
+	dir := t.TempDir()
+	job := &Job{ID: "old", Status: JobRunning, Args: []string{"drawing.pdf"}, Attempts: 1, CreatedAt: time.Now()}
+	data, err := json.Marshal(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{"old.json": data, "tenants.json": []byte("{}")} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store, err := openJobStore(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.close()
+	if jobs, err := store.list(); err != nil || len(jobs) != 1 || jobs[0].ID != "old" {
+		t.Fatalf("list = %v, %v; want the imported job", jobs, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.json")); !os.IsNotExist(err) {
+		t.Error("job file left after the import")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tenants.json")); err != nil {
+		t.Errorf("tenants.json: %v", err)
+	}
+	// The job's runner held no lock in the database, so it is requeued
+	if requeued, err := store.requeueStale(); requeued != 1 || err != nil {
+		t.Errorf("requeueStale = %d, %v; want 1", requeued, err)
+	}
+}
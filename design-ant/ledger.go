@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"design-ant/pkg/pdfanalysis"
+)
+
+// RunRecord is one line of the append-only run ledger
+type RunRecord struct {
+	RunID        string    `json:"run_id"`
+	StartedAt    time.Time `json:"started_at"`
+	Project      string    `json:"project,omitempty"` // -project tag for spend attribution
+	Document     string    `json:"document"`
+	Model        string    `json:"model"`
+	Pages        int       `json:"pages"`
+	Chunks       int       `json:"chunks"`
+	FailedChunks int       `json:"failed_chunks"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	TotalCost    float64   `json:"total_cost"`
+	Duration     string    `json:"duration"`
+	OutputPath   string    `json:"output_path"`
+}
+
+// defaultLedgerPath returns DESIGN_ANT_LEDGER or runs.jsonl in the user config directory
+func defaultLedgerPath() string {
+	if path := os.Getenv("DESIGN_ANT_LEDGER"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "runs.jsonl"
+	}
+	return filepath.Join(dir, "design-ant", "runs.jsonl")
+}
+
+// newRunRecord summarizes a finished run for the ledger
+func newRunRecord(config *pdfanalysis.Config, result pdfanalysis.FullAnalysisResult, startTime time.Time, outputPath string) RunRecord {
+	failed := 0
+	for _, chunk := range result.Chunks {
+		if chunk.Error != "" {
+			failed++
+		}
+	}
+	return RunRecord{
+		RunID:        startTime.UTC().Format("20060102T150405.000Z"),
+		StartedAt:    startTime,
+		Project:      config.Project,
+		Document:     absPath(config.DocumentPath()),
+		Model:        config.ModelName,
+		Pages:        result.TotalPages,
+		Chunks:       result.TotalChunks,
+		FailedChunks: failed,
+		InputTokens:  result.TotalInputTokens,
+		OutputTokens: result.TotalOutputTokens,
+		TotalCost:    result.TotalCost,
+		Duration:     result.ProcessingTime,
+		OutputPath:   absPath(outputPath),
+	}
+}
+
+// appendRunRecord appends a record to the ledger, creating it if needed
+func appendRunRecord(ledgerPath string, record RunRecord) error {
+	if err := os.MkdirAll(filepath.Dir(ledgerPath), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(ledgerPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// readRunRecords reads all records from the ledger. Malformed lines are
+// skipped so a partially written line never blocks the history report.
+func readRunRecords(ledgerPath string) ([]RunRecord, error) {
+	file, err := os.Open(ledgerPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []RunRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record RunRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// runHistory prints past runs and cumulative spend from the ledger
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	ledgerPath := fs.String("ledger", defaultLedgerPath(), "path to the run ledger")
+	limit := fs.Int("n", 20, "number of most recent runs to list (0 = all)")
+	filter := fs.String("file", "", "only show runs whose document path contains this text")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	records, err := readRunRecords(*ledgerPath)
+	if err != nil {
+		return fmt.Errorf("error reading ledger: %v", err)
+	}
+
+	var matched []RunRecord
+	for _, record := range records {
+		if *filter == "" || strings.Contains(record.Document, *filter) {
+			matched = append(matched, record)
+		}
+	}
+	if len(matched) == 0 {
+		fmt.Printf("No runs recorded in %s\n", *ledgerPath)
+		return nil
+	}
+
+	var totalCost float64
+	var totalPages, totalInput, totalOutput int
+	for _, record := range matched {
+		totalCost += record.TotalCost
+		totalPages += record.Pages
+		totalInput += record.InputTokens
+		totalOutput += record.OutputTokens
+	}
+
+	shown := matched
+	if *limit > 0 && len(shown) > *limit {
+		shown = shown[len(shown)-*limit:]
+	}
+
+	fmt.Println(strings.Repeat("=", 110))
+	fmt.Printf("%-17s %-28s %-26s %6s %6s %11s  %s\n", "STARTED", "DOCUMENT", "MODEL", "PAGES", "FAILED", "COST", "OUTPUT")
+	fmt.Println(strings.Repeat("-", 110))
+	for _, record := range shown {
+		fmt.Printf("%-17s %-28s %-26s %6d %6d %11s  %s\n",
+			record.StartedAt.Local().Format("2006-01-02 15:04"),
+			pdfanalysis.Truncate(filepath.Base(record.Document), 28),
+			pdfanalysis.Truncate(record.Model, 26),
+			record.Pages, record.FailedChunks,
+			fmt.Sprintf("$%.4f", record.TotalCost),
+			record.OutputPath)
+	}
+	fmt.Println(strings.Repeat("=", 110))
+	fmt.Printf("Runs: %d (showing %d)  Pages: %d  Tokens: %d in / %d out  Cumulative cost: $%.6f\n",
+		len(matched), len(shown), totalPages, totalInput, totalOutput, totalCost)
+	return nil
+}
+
+// absPath returns an absolute path, falling back to the input on error;
+// cloud storage URIs and sqlite: result locations are kept as they are
+func absPath(path string) string {
+	if isRemoteURI(path) || strings.HasPrefix(path, "sqlite:") {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+
+	"design-ant/internal/tempfiles"
+)
+
+// setupLogging installs the default slog logger for warnings and errors,
+// configured by LOG_LEVEL (debug, info, warn, or error; default info) and
+// LOG_FORMAT (text or json; default text). The console report and progress
+// bar stay on stdout. The analyses run by jobs and watch inherit the
+// settings through the environment.
+func setupLogging() {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(os.Getenv("LOG_LEVEL"))); err != nil {
+		level = slog.LevelInfo
+	}
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if strings.EqualFold(os.Getenv("LOG_FORMAT"), "json") {
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, options)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// fatal logs err and exits with status 1; only main calls it. Exiting skips
+// deferred calls, so temp files left by a failed step are removed first.
+func fatal(err error) {
+	slog.Error(err.Error())
+	tempfiles.RemoveAll()
+	os.Exit(1)
+}
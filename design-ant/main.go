@@ -2,232 +2,317 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
 
+	"design-ant/internal/tempfiles"
+	"design-ant/pkg/pdfanalysis"
 	"github.com/joho/godotenv"
 )
 
-func main() {
-	// Load environment variables
+// loadEnv loads API keys from .env here or in the parent directory
+func loadEnv() {
 	if err := godotenv.Load(); err != nil {
 		if err := godotenv.Load("../.env"); err != nil {
-			log.Println("Warning: Could not load .env file. Using environment variables.")
+			slog.Warn("Could not load .env file, using environment variables")
 		}
 	}
+}
 
-	// Parse command line arguments
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: go run main.go <pdf-file>\n" +
-			"Example: go run main.go ../design-analysis/v6truboEngine.pdf")
+// interruptibleContext returns a context that is canceled by the first
+// SIGINT or SIGTERM. The signal handler is removed at that point, so a second
+// Ctrl-C terminates the process immediately.
+func interruptibleContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer signal.Stop(signals)
+		select {
+		case <-signals:
+			pdfanalysis.Logf("\n⏹️  Interrupted: waiting for in-flight pages, then writing a partial result (Ctrl-C again to quit now)\n")
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+		// A second signal quits without the deferred cleanup, so the temp
+		// files are removed here
+		<-signals
+		tempfiles.RemoveAll()
+		os.Exit(130)
+	}()
+	return ctx, cancel
+}
+
+func main() {
+	setupLogging()
+
+	// Subcommands operate on existing result files and need no API key
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "version", "-version", "--version":
+			build := pdfanalysis.Build()
+			fmt.Printf("design-ant %s %s\n", build, build.GoVersion)
+			return
+		case "diff":
+			if err := runDiff(os.Args[2:]); err != nil {
+				fatal(err)
+			}
+			return
+		case "history":
+			if err := runHistory(os.Args[2:]); err != nil {
+				fatal(err)
+			}
+			return
+		case "export":
+			if err := runExport(os.Args[2:]); err != nil {
+				fatal(err)
+			}
+			return
+		case "merge":
+			if err := runMerge(os.Args[2:]); err != nil {
+				fatal(err)
+			}
+			return
+		case "results":
+			if err := runResults(os.Args[2:]); err != nil {
+				fatal(err)
+			}
+			return
+		case "migrate":
+			if err := runMigrate(os.Args[2:]); err != nil {
+				fatal(err)
+			}
+			return
+		case "spend":
+			if err := runSpend(os.Args[2:]); err != nil {
+				fatal(err)
+			}
+			return
+		case "jobs":
+			// Loaded here so the analyses run as child processes inherit the keys
+			loadEnv()
+			if err := runJobs(os.Args[2:]); err != nil {
+				fatal(err)
+			}
+			return
+		case "serve":
+			loadEnv()
+			if err := runServe(os.Args[2:]); err != nil {
+				fatal(err)
+			}
+			return
+		case "watch":
+			loadEnv()
+			if err := runWatch(os.Args[2:]); err != nil {
+				fatal(err)
+			}
+			return
+		case "bench":
+			if err := runBench(os.Args[2:]); err != nil {
+				fatal(err)
+			}
+			return
+		case "compare":
+			loadEnv()
+			if err := runCompare(os.Args[2:]); err != nil {
+				fatal(err)
+			}
+			return
+		case "prompt-ab":
+			loadEnv()
+			if err := runPromptAB(os.Args[2:]); err != nil {
+				fatal(err)
+			}
+			return
+		case "bakeoff":
+			loadEnv()
+			if err := runBakeoff(os.Args[2:]); err != nil {
+				fatal(err)
+			}
+			return
+		case "index":
+			loadEnv()
+			if err := runSemanticIndex(os.Args[2:]); err != nil {
+				fatal(err)
+			}
+			return
+		case "query":
+			loadEnv()
+			if err := runQuery(os.Args[2:]); err != nil {
+				fatal(err)
+			}
+			return
+		}
 	}
 
-	config := &Config{
-		APIKey:    os.Getenv("ANTHROPIC_API_KEY"),
-		ModelName: "claude-3-5-haiku-20241022", // Using cheapest model
-		PDFPath:   os.Args[1],
+	loadEnv()
+
+	// Parse command line arguments
+	config, err := parseFlags(os.Args[1:])
+	if err != nil {
+		// The usage text, not a log record
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	// fatal skips deferred calls, so the spans are flushed explicitly
+	stopTracing := pdfanalysis.InitTracing()
+	if config.QuestionsPath != "" {
+		_, err = runQuestions(config)
+	} else {
+		_, err = runAnalysis(config)
+	}
+	stopTracing()
+	if err != nil {
+		fatal(err)
 	}
+}
 
+// runAnalysis analyzes config.PDFPath page by page, prints the summary, and
+// writes all configured outputs. The returned result is the unredacted one.
+func runAnalysis(config *pdfanalysis.Config) (*pdfanalysis.FullAnalysisResult, error) {
 	if config.APIKey == "" {
-		log.Fatal("Error: ANTHROPIC_API_KEY not found in environment variables")
+		return nil, fmt.Errorf("ANTHROPIC_API_KEY not found in environment variables")
+	}
+
+	// Load redaction rules up front so a bad rules file fails before any API cost
+	var redact *pdfanalysis.Redactor
+	if config.RedactRules != "" {
+		var err error
+		if redact, err = pdfanalysis.LoadRedactor(config.RedactRules); err != nil {
+			return nil, err
+		}
 	}
 
-	// Validate PDF file
-	if _, err := os.Stat(config.PDFPath); os.IsNotExist(err) {
-		log.Fatalf("Error: PDF file not found: %s", config.PDFPath)
+	// Model prices are loaded up front for the same reason
+	if config.PricingFile != "" {
+		if err := pdfanalysis.LoadPricingFile(config.PricingFile); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate input file
+	if _, err := os.Stat(config.PDFPath); os.IsNotExist(err) && !isRemoteURI(config.PDFPath) {
+		return nil, fmt.Errorf("PDF file not found: %s", config.PDFPath)
+	}
+	if err := checkStorage(config.PDFPath, config.OutputURI, config.StoreURI); err != nil {
+		return nil, err
 	}
 
 	fmt.Println(strings.Repeat("=", 70))
 	fmt.Println("  DESIGN PDF ANALYSIS TOOL (ANTHROPIC)")
 	fmt.Println(strings.Repeat("=", 70))
 	fmt.Printf("\n📄 Processing: %s\n", filepath.Base(config.PDFPath))
-	fmt.Printf("🤖 Model: %s\n", config.ModelName)
-	pricing := GetPricing(config.ModelName)
-	fmt.Printf("💰 Model Pricing: $%.2f/M input, $%.2f/M output\n\n",
-		pricing.InputPricePerMTokens,
-		pricing.OutputPricePerMTokens)
 
-	startTime := time.Now()
+	// Ctrl-C stops the run after the in-flight pages and keeps what is done.
+	// It is caught from here on, so a download or conversion it stops still
+	// removes the temporary directory.
+	ctx, cancel := interruptibleContext()
+	defer cancel()
 
-	// Get total page count
-	totalPages, err := getPageCount(config.PDFPath)
+	// Create temporary directory for downloaded and converted documents
+	temp, err := tempfiles.New("pdf-input")
 	if err != nil {
-		log.Fatalf("Error getting page count: %v", err)
+		return nil, err
 	}
+	defer temp.Remove()
 
-	fmt.Printf("📊 Total pages: %d\n", totalPages)
+	// Documents in cloud storage are copied into the temporary directory
+	if isRemoteURI(config.PDFPath) {
+		if err := fetchRemoteInput(ctx, config, temp.File("download")); err != nil {
+			return nil, err
+		}
+	}
+
+	// Office documents are converted here rather than by the analyzer, so the
+	// annotated PDF can be drawn on the converted pages
+	if pdfanalysis.IsOfficeDocument(config.PDFPath) {
+		fmt.Printf("🔁 Converting %s to PDF with LibreOffice...\n", filepath.Ext(config.PDFPath))
+		pdfPath, err := pdfanalysis.ConvertToPDF(ctx, config.PDFPath, temp.File("converted"))
+		if err != nil {
+			return nil, fmt.Errorf("error converting document: %v", err)
+		}
+		if config.SourcePath == "" {
+			config.SourcePath = config.PDFPath
+		}
+		config.PDFPath = pdfPath
+	}
+	fmt.Printf("🤖 Model: %s\n", config.ModelName)
+	if config.OutputLang != "" {
+		if config.LangMode == pdfanalysis.LangModeTranslate {
+			fmt.Printf("🌐 Output language: %s (translation pass with %s)\n", pdfanalysis.LanguageName(config.OutputLang), config.TranslateModel)
+		} else {
+			fmt.Printf("🌐 Output language: %s\n", pdfanalysis.LanguageName(config.OutputLang))
+		}
+	}
+	pricing := pdfanalysis.GetPricing(config.ModelName)
+	fmt.Printf("💰 Model Pricing: $%.2f/M input, $%.2f/M output\n",
+		pricing.InputPricePerMTokens,
+		pricing.OutputPricePerMTokens)
+	if config.PricingFile != "" {
+		fmt.Printf("💰 Pricing file: %s\n", config.PricingFile)
+	}
+	if config.Project != "" {
+		fmt.Printf("🏷️  Project: %s\n", config.Project)
+		if config.LedgerPath != "" {
+			warnBudget(config.LedgerPath, config.Project)
+		}
+	}
+	fmt.Println()
 
-	// Process each page individually for maximum detail extraction
-	chunkSize := 1
-	fmt.Printf("📦 Processing each page individually for complete data extraction\n\n")
+	startTime := time.Now()
 
-	// Create temporary directory for chunk PDFs
-	tempDir, err := os.MkdirTemp("", "pdf-chunks-*")
+	// The JSON result and the page stream go to -result-store, by default
+	// the current directory
+	store, err := openResultStore(config.StoreURI, ".")
 	if err != nil {
-		log.Fatalf("Error creating temp directory: %v", err)
+		return nil, err
+	}
+	defer closeResultStore(store)
+	if config.StoreURI != "" {
+		// -resume names a result in the store
+		config.ResumeStore = store
+	}
+	jsonName := resultName(config)
+	jsonFile := resultLocation(config, jsonName)
+
+	// Stream each completed chunk so partial progress survives a crash
+	var stream pdfanalysis.ResultStore
+	if config.StreamJSONL {
+		stream = store
+		fmt.Printf("📝 Streaming page results to: %s\n", resultLocation(config, pdfanalysis.StreamName(jsonName)))
 	}
-	defer os.RemoveAll(tempDir)
 
-	// Split PDF into chunks
-	chunks, err := splitPDFIntoChunks(config.PDFPath, tempDir, chunkSize, totalPages)
+	events, err := openEventLog(config.EventsPath)
 	if err != nil {
-		log.Fatalf("Error splitting PDF: %v", err)
+		return nil, fmt.Errorf("error creating progress events: %v", err)
 	}
+	defer events.Close()
 
-	if chunkSize == 1 {
-		fmt.Printf("✅ Created %d single-page PDF(s) for processing\n\n", len(chunks))
-	} else {
-		fmt.Printf("✅ Created %d chunk(s)\n\n", len(chunks))
-	}
-
-	// Process chunks with rate limiting
-	// Rate limit: 400,000 input tokens per minute
-	// Conservative estimate: ~80k tokens per single-page PDF (PDF + prompt)
-	// Safe concurrent limit: 4-5 pages at a time to stay well under limit
-	maxConcurrent := 4
-	fmt.Printf("🚀 Processing pages with rate limiting (max %d concurrent requests)...\n", maxConcurrent)
-	fmt.Println(strings.Repeat("-", 70))
-
-	ctx := context.Background()
-	results := make([]ChunkAnalysis, len(chunks))
-
-	// Create a semaphore to limit concurrent requests
-	semaphore := make(chan struct{}, maxConcurrent)
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-
-	for i, chunk := range chunks {
-		wg.Add(1)
-		go func(index int, path string, startPage, endPage int) {
-			defer wg.Done()
-
-			// Acquire semaphore (blocks if maxConcurrent requests are running)
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }() // Release semaphore when done
-
-			chunkStartTime := time.Now()
-			if startPage == endPage {
-				fmt.Printf("  🔄 Processing page %d...\n", startPage+1)
-			} else {
-				fmt.Printf("  🔄 Processing chunk %d (pages %d-%d)...\n", index+1, startPage+1, endPage+1)
-			}
-
-			// Retry logic for rate limit errors
-			var analysis string
-			var inputTokens, outputTokens int
-			var err error
-			maxRetries := 3
-			retryDelay := 2 * time.Second
-
-			for attempt := 0; attempt < maxRetries; attempt++ {
-				analysis, inputTokens, outputTokens, err = analyzeChunk(ctx, config.APIKey, config.ModelName, path, startPage+1)
-
-				if err == nil {
-					break // Success
-				}
-
-				// Check if it's a rate limit error
-				if strings.Contains(err.Error(), "rate_limit") || strings.Contains(err.Error(), "429") {
-					if attempt < maxRetries-1 {
-						waitTime := retryDelay * time.Duration(1<<attempt) // Exponential backoff
-						fmt.Printf("  ⚠️  Rate limit hit for page %d, retrying in %v...\n", startPage+1, waitTime)
-						time.Sleep(waitTime)
-						continue
-					}
-				} else {
-					break // Non-rate-limit error, don't retry
-				}
-			}
-
-			chunkDuration := time.Since(chunkStartTime)
-
-			mu.Lock()
-			pricing := GetPricing(config.ModelName)
-			inputCost := float64(inputTokens) / 1_000_000 * pricing.InputPricePerMTokens
-			outputCost := float64(outputTokens) / 1_000_000 * pricing.OutputPricePerMTokens
-
-			results[index] = ChunkAnalysis{
-				ChunkNumber:    index + 1,
-				StartPage:      startPage + 1,
-				EndPage:        endPage + 1,
-				Analysis:       analysis,
-				InputTokens:    inputTokens,
-				OutputTokens:   outputTokens,
-				InputCost:      inputCost,
-				OutputCost:     outputCost,
-				TotalCost:      inputCost + outputCost,
-				ProcessingTime: chunkDuration.String(),
-				Timestamp:      time.Now(),
-			}
-
-			if err != nil {
-				results[index].Error = err.Error()
-				if startPage == endPage {
-					fmt.Printf("  ❌ Page %d failed: %v\n", startPage+1, err)
-				} else {
-					fmt.Printf("  ❌ Chunk %d failed: %v\n", index+1, err)
-				}
-			} else {
-				if startPage == endPage {
-					fmt.Printf("  ✅ Page %d completed: %d input tokens, %d output tokens, $%.6f\n",
-						startPage+1, inputTokens, outputTokens, results[index].TotalCost)
-				} else {
-					fmt.Printf("  ✅ Chunk %d completed: %d input tokens, %d output tokens, $%.6f\n",
-						index+1, inputTokens, outputTokens, results[index].TotalCost)
-				}
-			}
-			mu.Unlock()
-		}(i, chunk.Path, chunk.StartPage, chunk.EndPage)
+	alerts := newSpendAlerts(config)
+	defer alerts.wait()
+	observer := &runObserver{config: config, stream: stream, name: jsonName, events: events, alerts: alerts}
+	analyzer, err := pdfanalysis.New(pdfanalysis.WithConfig(*config),
+		pdfanalysis.WithObserver(pdfanalysis.NewConsoleObserver()), pdfanalysis.WithObserver(observer))
+	if err != nil {
+		return nil, err
 	}
-
-	wg.Wait()
-
-	// Calculate chunk totals
-	var chunkInputTokens, chunkOutputTokens int
-	var chunkInputCost, chunkOutputCost float64
-
-	for _, result := range results {
-		chunkInputTokens += result.InputTokens
-		chunkOutputTokens += result.OutputTokens
-		chunkInputCost += result.InputCost
-		chunkOutputCost += result.OutputCost
+	result, runErr := analyzer.AnalyzeFile(ctx, config.PDFPath)
+	observer.hooks.wait()
+	if result == nil {
+		return nil, runErr
 	}
-
-	// Skip consolidation - use individual page analyses directly
-	fmt.Println()
-	fmt.Println(strings.Repeat("=", 70))
-	fmt.Println("  FINALIZING RESULTS")
-	fmt.Println(strings.Repeat("=", 70))
-	fmt.Println("✅ Using individual page analyses (no consolidation needed)")
-	fmt.Println("   All page-by-page details are preserved in the output")
-
-	totalDuration := time.Since(startTime)
-
-	// Calculate final totals (no consolidation costs)
-	totalInputTokens := chunkInputTokens
-	totalOutputTokens := chunkOutputTokens
-	totalInputCost := chunkInputCost
-	totalOutputCost := chunkOutputCost
-
-	// Create full result (no consolidated analysis - using individual page analyses)
-	fullResult := FullAnalysisResult{
-		PDFPath:           config.PDFPath,
-		TotalPages:        totalPages,
-		TotalChunks:       len(chunks),
-		Chunks:            results,
-		Consolidated:      nil, // No consolidation - all details in individual page analyses
-		TotalInputTokens:  totalInputTokens,
-		TotalOutputTokens: totalOutputTokens,
-		TotalInputCost:    totalInputCost,
-		TotalOutputCost:   totalOutputCost,
-		TotalCost:         totalInputCost + totalOutputCost,
-		ProcessingTime:    totalDuration.String(),
-		GeneratedAt:       time.Now(),
+	// The split, size limit, or cost limit error that ended the run early
+	stopped := runErr
+	if errors.Is(runErr, context.Canceled) || errors.Is(runErr, context.DeadlineExceeded) {
+		stopped = nil
 	}
 
 	// Output results
@@ -235,25 +320,234 @@ func main() {
 	fmt.Println(strings.Repeat("=", 70))
 	fmt.Println("  FINAL ANALYSIS SUMMARY")
 	fmt.Println(strings.Repeat("=", 70))
+	pageTotals := pdfanalysis.FullAnalysisResult{Chunks: result.Chunks}
+	pdfanalysis.RecomputeTotals(&pageTotals)
 	fmt.Printf("Page-by-Page Analysis:\n")
-	fmt.Printf("  - Input Tokens:  %d\n", chunkInputTokens)
-	fmt.Printf("  - Output Tokens: %d\n", chunkOutputTokens)
-	fmt.Printf("  - Cost:          $%.6f\n", chunkInputCost+chunkOutputCost)
+	fmt.Printf("  - Input Tokens:  %d\n", pageTotals.TotalInputTokens)
+	fmt.Printf("  - Output Tokens: %d\n", pageTotals.TotalOutputTokens)
+	fmt.Printf("  - Cost:          $%.6f\n", pageTotals.TotalCost)
+	if consolidated := result.Consolidated; consolidated != nil {
+		fmt.Printf("Consolidation:\n")
+		fmt.Printf("  - Input Tokens:  %d\n", consolidated.InputTokens)
+		fmt.Printf("  - Output Tokens: %d\n", consolidated.OutputTokens)
+		fmt.Printf("  - Cost:          $%.6f\n", consolidated.TotalCost)
+	}
+	if summary := result.ExecutiveSummary; summary != nil {
+		fmt.Printf("Executive Summary (%s):\n", summary.Model)
+		fmt.Printf("  - Input Tokens:  %d\n", summary.InputTokens)
+		fmt.Printf("  - Output Tokens: %d\n", summary.OutputTokens)
+		fmt.Printf("  - Cost:          $%.6f\n", summary.TotalCost)
+	}
 	fmt.Printf("TOTAL:\n")
-	fmt.Printf("  - Input Tokens:  %d\n", totalInputTokens)
-	fmt.Printf("  - Output Tokens: %d\n", totalOutputTokens)
-	fmt.Printf("  - Total Cost:    $%.6f\n", totalInputCost+totalOutputCost)
-	fmt.Printf("  - Processing Time: %s\n", totalDuration)
+	fmt.Printf("  - Input Tokens:  %d\n", result.TotalInputTokens)
+	fmt.Printf("  - Output Tokens: %d\n", result.TotalOutputTokens)
+	fmt.Printf("  - Total Cost:    $%.6f\n", result.TotalCost)
+	fmt.Printf("  - Processing Time: %s\n", result.ProcessingTime)
 	fmt.Println(strings.Repeat("=", 70))
+	pdfanalysis.WriteCostTable(os.Stdout, *result)
+	pdfanalysis.WriteAttribution(os.Stdout, result.InputBreakdown)
+	pdfanalysis.WriteSavings(os.Stdout, result.Savings)
+	if len(result.MasterBOM) > 0 {
+		conflicts := 0
+		for _, item := range result.MasterBOM {
+			if len(item.Conflicts) > 0 {
+				conflicts++
+			}
+		}
+		fmt.Printf("🧾 Master BOM: %d distinct parts, %d with conflicting data to review\n", len(result.MasterBOM), conflicts)
+	}
+	if cost := result.AssemblyCost; cost != nil {
+		fmt.Printf("💲 Estimated assembly cost: %s (%d of %d parts priced)\n",
+			pdfanalysis.FormatMoney(cost.Total, cost.Currency), len(cost.Lines)-cost.Unpriced, len(cost.Lines))
+		for _, line := range cost.Lines {
+			if !line.Priced {
+				fmt.Printf("  - no price for %s\n", pdfanalysis.MasterBOMLabel(pdfanalysis.MasterBOMItem{PartNumber: line.PartNumber, Description: line.Description}))
+			}
+		}
+	}
+	if len(result.Index) > 0 {
+		drawings := 0
+		for _, entry := range result.Index {
+			if entry.Kind == "drawing_number" {
+				drawings++
+			}
+		}
+		fmt.Printf("🗂️  Index: %d drawing numbers, %d part numbers\n", drawings, len(result.Index)-drawings)
+	}
+	if len(result.Standards) > 0 {
+		fmt.Printf("📚 Standards cited: %d\n", len(result.Standards))
+	}
+	if len(result.TitleBlockExceptions) > 0 {
+		fmt.Printf("🪪 %d page(s) with incomplete title blocks:\n", len(result.TitleBlockExceptions))
+		for _, e := range result.TitleBlockExceptions {
+			fmt.Printf("  - p. %d: missing %s\n", e.Page, strings.Join(e.Missing, ", "))
+		}
+	}
+	if len(result.Discrepancies) > 0 {
+		fmt.Printf("🔎 %d cross-page discrepancies to review:\n", len(result.Discrepancies))
+		for _, d := range result.Discrepancies {
+			fmt.Printf("  - [%s] %s\n", d.Kind, d.Message)
+		}
+	}
+
+	// The ledger keeps the real times even when the outputs are pinned
+	record := newRunRecord(config, *result, startTime, jsonFile)
+	if config.Reproducible {
+		at, err := pdfanalysis.ReproducibleTime()
+		if err != nil {
+			return nil, err
+		}
+		pdfanalysis.PinTimes(result, at)
+	}
 
 	// Save JSON output
-	jsonFile := generateOutputFilename(config.PDFPath, "json")
-	if err := saveJSONOutput(jsonFile, fullResult); err != nil {
-		log.Printf("Warning: Could not save JSON output: %v", err)
+	if err := store.Save(context.Background(), jsonName, *result); err != nil {
+		slog.Warn("Could not save JSON output", "path", jsonFile, "error", err)
 	} else {
 		fmt.Printf("\n💾 JSON results saved to: %s\n", jsonFile)
 	}
+	finished := progressEvent{Type: eventFinished, Done: len(result.Chunks), Cost: result.TotalCost, Partial: result.Interrupted,
+		Deadline: errors.Is(runErr, context.DeadlineExceeded)}
+	finished.Output = absPath(jsonFile)
+	events.emit(finished)
+
+	// Record the run in the ledger
+	if config.LedgerPath != "" {
+		if err := appendRunRecord(config.LedgerPath, record); err != nil {
+			slog.Warn("Could not update run ledger", "path", config.LedgerPath, "error", err)
+		} else if config.Project != "" {
+			warnBudget(config.LedgerPath, config.Project)
+		}
+	}
+
+	// Reports are rendered from a redacted copy when sharing rules are given
+	reportResult := *result
+	if redact != nil {
+		reportResult = redact.RedactResult(*result)
+		redactedFile := pdfanalysis.GenerateOutputFilename(config.DocumentPath(), "redacted.json")
+		if err := pdfanalysis.SaveJSONOutput(redactedFile, reportResult); err != nil {
+			slog.Warn("Could not save redacted JSON", "path", redactedFile, "error", err)
+		} else {
+			fmt.Printf("🔒 Redacted %d occurrence(s); shareable JSON saved to: %s\n", redact.Count, redactedFile)
+		}
+	}
+	// Save the executive summary next to the JSON
+	if summary := reportResult.ExecutiveSummary; summary != nil && summary.Error == "" {
+		summaryFile := pdfanalysis.GenerateOutputFilename(config.DocumentPath(), "summary.md")
+		if err := pdfanalysis.SaveExecutiveSummary(summaryFile, summary); err != nil {
+			slog.Warn("Could not save executive summary", "path", summaryFile, "error", err)
+		} else {
+			fmt.Printf("💾 Executive summary saved to: %s\n", summaryFile)
+		}
+	}
+
+	// Save annotated review PDF
+	if config.AnnotatedPDF {
+		pdfFile := pdfanalysis.GenerateOutputFilename(config.DocumentPath(), "pdf")
+		if redact != nil {
+			fmt.Println("⚠️  The annotated PDF embeds the original page images, which are not redacted")
+		}
+		if err := pdfanalysis.SaveAnnotatedPDF(pdfFile, config.PDFPath, reportResult); err != nil {
+			slog.Warn("Could not save annotated PDF", "path", pdfFile, "error", err)
+		} else {
+			fmt.Printf("💾 Annotated PDF saved to: %s\n", pdfFile)
+		}
+	}
+
+	// Write wiki-flavored markdown
+	if config.Markdown != "" {
+		mdFile := pdfanalysis.GenerateOutputFilename(config.DocumentPath(), "md")
+		if err := pdfanalysis.SaveMarkdownExport(mdFile, reportResult, config.Markdown); err != nil {
+			slog.Warn("Could not save markdown export", "path", mdFile, "error", err)
+		} else {
+			fmt.Printf("💾 Markdown export (%s) saved to: %s\n", config.Markdown, mdFile)
+		}
+	}
+
+	// Render custom template output
+	if config.TemplatePath != "" {
+		reportFile := config.TemplateOut
+		if reportFile == "" {
+			reportFile = pdfanalysis.TemplateOutputFilename(config.DocumentPath(), config.TemplatePath)
+		}
+		if err := pdfanalysis.RenderTemplate(config.TemplatePath, reportFile, reportResult); err != nil {
+			slog.Warn("Could not render template", "template", config.TemplatePath, "error", err)
+		} else {
+			fmt.Printf("💾 Template report saved to: %s\n", reportFile)
+		}
+	}
+
+	uploadOutputs(context.Background(), config, startTime)
+	runPostHook(config, jsonFile, len(result.Chunks), result.TotalCost, result.Interrupted)
+
+	// Suggest HTML viewer, which loads local files
+	if _, ok := store.(*pdfanalysis.FileStore); ok {
+		fmt.Printf("\n🌐 View results in HTML: Open viewer.html in your browser and load %s\n", jsonFile)
+	} else {
+		fmt.Printf("\n🌐 View results in HTML: save them with 'go run . results -store %s %s > %s', then open viewer.html and load it\n",
+			config.StoreURI, jsonName, pdfanalysis.GenerateOutputFilename(config.DocumentPath(), "json"))
+	}
+	if result.Interrupted {
+		if errors.Is(stopped, pdfanalysis.ErrBudgetExceeded) {
+			return result, fmt.Errorf("%v; %d of %d page(s) analyzed, continue with -resume %s and a higher -max-cost", stopped, len(result.Chunks), observer.planned, jsonFile)
+		}
+		if stopped != nil {
+			return result, fmt.Errorf("%v; %d of %d page(s) analyzed, continue with -resume %s once fixed", stopped, len(result.Chunks), observer.planned, jsonFile)
+		}
+		return result, fmt.Errorf("run interrupted with %d of %d page(s) analyzed; continue with -resume %s", len(result.Chunks), observer.planned, jsonFile)
+	}
+	return result, nil
+}
+
+// runObserver passes the pages of a run on to the stream of its result, the
+// -events file, the spend alerts, and the page hooks. Its Analyzer makes
+// only this run, so its state is not kept by run.
+type runObserver struct {
+	config  *pdfanalysis.Config
+	stream  pdfanalysis.ResultStore // nil with -jsonl=false
+	name    string                  // Name of the result in stream
+	events  *eventLog
+	alerts  *spendAlerts
+	hooks   *pageHooks
+	planned int
+	done    int
+	cost    float64
+}
+
+// begin starts the page hooks and the event stream with the first page
+func (o *runObserver) begin(total int) {
+	if o.planned > 0 {
+		return
+	}
+	o.planned = total
+	o.hooks = newPageHooks(o.config, total)
+	o.events.emit(progressEvent{Type: eventStart, Total: total})
+}
+
+func (o *runObserver) OnPageStart(run pdfanalysis.Run, total int, page pdfanalysis.ChunkAnalysis) {
+	o.begin(total)
+}
+
+func (o *runObserver) OnPageComplete(run pdfanalysis.Run, done, total int, page pdfanalysis.ChunkAnalysis) {
+	o.begin(total)
+	o.done = done
+	o.cost += page.TotalCost
+	o.events.page(page, done, o.cost)
+	if page.Error == pdfanalysis.ErrInterrupted {
+		return
+	}
+	if o.stream != nil {
+		if err := o.stream.AppendPage(context.Background(), o.name, page); err != nil {
+			slog.Warn("Could not stream page result", "page", page.StartPage, "error", err)
+		}
+	}
+	o.hooks.page(page)
+}
+
+func (o *runObserver) OnRetry(run pdfanalysis.Run, page pdfanalysis.ChunkAnalysis, attempt int, err error, delay time.Duration) {
+}
 
-	// Suggest HTML viewer
-	fmt.Printf("\n🌐 View results in HTML: Open viewer.html in your browser and load %s\n", jsonFile)
+// OnCostUpdate checks the spend alerts, also after the document summaries
+func (o *runObserver) OnCostUpdate(run pdfanalysis.Run, spent float64) {
+	o.alerts.observe(spent, o.done, o.planned)
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"design-ant/pkg/pdfanalysis"
+)
+
+// runMerge combines several partial result files into one consolidated result
+func runMerge(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	outFile := fs.String("o", "", "output file for the merged result (required; .gz/.zst to compress)")
+	prefer := fs.String("prefer", "newest", "which analysis wins when page results conflict: newest, first, or none (fail)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *outFile == "" || fs.NArg() < 2 {
+		return fmt.Errorf("usage: go run . merge -o merged.json [-prefer newest|first|none] <a.json> <b.json>...")
+	}
+	switch *prefer {
+	case "newest", "first", "none":
+	default:
+		return fmt.Errorf("invalid -prefer %q: must be newest, first, or none", *prefer)
+	}
+
+	var inputs []*pdfanalysis.FullAnalysisResult
+	for _, filename := range fs.Args() {
+		result, err := pdfanalysis.LoadResult(filename)
+		if err != nil {
+			return err
+		}
+		inputs = append(inputs, result)
+	}
+
+	merged, conflicts, err := pdfanalysis.MergeResults(inputs, *prefer)
+	if err != nil {
+		return err
+	}
+	for _, conflict := range conflicts {
+		fmt.Printf("⚠️  %s\n", conflict)
+	}
+	if len(conflicts) > 0 && *prefer == "none" {
+		return fmt.Errorf("%d conflicting page(s); rerun with -prefer newest or -prefer first", len(conflicts))
+	}
+
+	if err := pdfanalysis.SaveJSONOutput(*outFile, *merged); err != nil {
+		return fmt.Errorf("error writing %s: %v", *outFile, err)
+	}
+	fmt.Printf("✅ Merged %d result(s): %d chunks covering %d of %d pages, total cost $%.6f\n",
+		len(inputs), merged.TotalChunks, pdfanalysis.CoveredPages(merged.Chunks), merged.TotalPages, merged.TotalCost)
+	if merged.Interrupted {
+		fmt.Printf("⏹️  Pages are still missing from an interrupted run; -resume %s runs them\n", *outFile)
+	}
+	fmt.Printf("💾 Merged result saved to: %s\n", *outFile)
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+
+	"design-ant/pkg/pdfanalysis"
+)
+
+// runMigrate upgrades result files in place to the current schema version
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: go run . migrate <result.json>...")
+	}
+	for _, filename := range args {
+		result, err := pdfanalysis.LoadResult(filename)
+		if err != nil {
+			return err
+		}
+		if err := pdfanalysis.SaveJSONOutput(filename, *result); err != nil {
+			return fmt.Errorf("error writing %s: %v", filename, err)
+		}
+		fmt.Printf("✅ %s upgraded to schema version %d\n", filename, pdfanalysis.CurrentSchemaVersion)
+	}
+	return nil
+}
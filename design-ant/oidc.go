@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"design-ant/pkg/pdfanalysis"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v4"
+)
+
+// OIDC keys are fetched again after oidcKeysTTL, or sooner for an unknown
+// key ID but not more often than oidcRefetchMin. Token expiry allows
+// oidcLeeway of clock skew.
+const (
+	oidcKeysTTL    = time.Hour
+	oidcRefetchMin = time.Minute
+	oidcLeeway     = time.Minute
+)
+
+// oidcMinRSABits is the smallest RSA signing key accepted from an issuer
+const oidcMinRSABits = 2048
+
+// oidcAlgorithms are the token signature algorithms accepted; tokens with
+// any other alg, including none, are refused before their key is looked up
+var oidcAlgorithms = []jose.SignatureAlgorithm{jose.RS256, jose.ES256}
+
+// oidcVerifier checks ID and access tokens signed by an OpenID Connect
+// issuer (RS256 or ES256) and maps their claims to a principal
+type oidcVerifier struct {
+	verifier    *oidc.IDTokenVerifier
+	keys        *oidcKeySet
+	rolesClaim  string // Claim holding the roles, a list or a space-separated string
+	tenantClaim string // Claim holding the tenant
+}
+
+// newOIDCVerifier looks up the issuer's signing keys through its discovery
+// document, so a wrong issuer fails when the server starts. The audience is
+// required: without it, tokens the issuer signed for any other application
+// would be accepted.
+func newOIDCVerifier(ctx context.Context, issuer, audience, rolesClaim, tenantClaim string) (*oidcVerifier, error) {
+	if audience == "" {
+		return nil, fmt.Errorf("-oidc-issuer needs -oidc-audience, the audience tokens must be issued for")
+	}
+	discoveryCtx, cancel := context.WithTimeout(oidc.ClientContext(ctx, pdfanalysis.QuickClient), 10*time.Second)
+	defer cancel()
+	provider, err := oidc.NewProvider(discoveryCtx, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the OIDC discovery document of %s: %v", issuer, err)
+	}
+	var discovery struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := provider.Claims(&discovery); err != nil || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery document of %s has no jwks_uri", issuer)
+	}
+	keys := &oidcKeySet{url: discovery.JWKSURI}
+	if err := keys.fetch(ctx); err != nil {
+		return nil, err
+	}
+	config := &oidc.Config{
+		ClientID:             audience,
+		SupportedSigningAlgs: []string{string(jose.RS256), string(jose.ES256)},
+		Now:                  func() time.Time { return time.Now().Add(-oidcLeeway) },
+	}
+	return &oidcVerifier{verifier: oidc.NewVerifier(issuer, keys, config), keys: keys, rolesClaim: rolesClaim, tenantClaim: tenantClaim}, nil
+}
+
+// verify checks a token's signature, issuer, audience, and validity period
+// and returns its caller
+func (v *oidcVerifier) verify(ctx context.Context, token string) (*principal, error) {
+	idToken, err := v.verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %v", err)
+	}
+	p := &principal{roles: claimStrings(claims[v.rolesClaim])}
+	p.name, _ = claims["email"].(string)
+	if p.name == "" {
+		p.name = idToken.Subject
+	}
+	p.tenant, _ = claims[v.tenantClaim].(string)
+	return p, nil
+}
+
+// oidcKeySet is the issuer's JSON Web Key Set as an oidc.KeySet. Only RSA
+// keys of at least oidcMinRSABits and P-256 keys are kept, and a key is only
+// used with the algorithm of its type. The set is fetched again without
+// holding the lock, by one request at a time.
+type oidcKeySet struct {
+	url string
+
+	mu       sync.Mutex
+	keys     map[string]jose.JSONWebKey // By key ID
+	fetched  time.Time
+	fetching chan struct{} // Closed when the fetch in flight is done; nil when none is
+}
+
+// VerifySignature checks the signature of a token with the key its header
+// names and returns the payload
+func (s *oidcKeySet) VerifySignature(ctx context.Context, token string) ([]byte, error) {
+	jws, err := jose.ParseSigned(token, oidcAlgorithms)
+	if err != nil {
+		return nil, fmt.Errorf("malformed token: %v", err)
+	}
+	if len(jws.Signatures) != 1 {
+		return nil, fmt.Errorf("token has %d signatures, want 1", len(jws.Signatures))
+	}
+	header := jws.Signatures[0].Header
+	key, err := s.key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if keyAlgorithm(key) != jose.SignatureAlgorithm(header.Algorithm) {
+		return nil, fmt.Errorf("token signed with %s, but key %q is for %s", header.Algorithm, header.KeyID, keyAlgorithm(key))
+	}
+	return jws.Verify(&key)
+}
+
+// key returns the signing key with the given ID, fetching the key set again
+// when it is old or does not have the key
+func (s *oidcKeySet) key(ctx context.Context, kid string) (jose.JSONWebKey, error) {
+	s.mu.Lock()
+	key, ok := s.keys[kid]
+	age := time.Since(s.fetched)
+	s.mu.Unlock()
+	if (!ok && age >= oidcRefetchMin) || age >= oidcKeysTTL {
+		if err := s.fetch(ctx); err != nil && !ok {
+			return jose.JSONWebKey{}, err
+		}
+		s.mu.Lock()
+		key, ok = s.keys[kid]
+		s.mu.Unlock()
+	}
+	if !ok {
+		return jose.JSONWebKey{}, fmt.Errorf("token signed with unknown key %q", kid)
+	}
+	return key, nil
+}
+
+// fetch reads the key set, or waits for the fetch another request started
+func (s *oidcKeySet) fetch(ctx context.Context) error {
+	s.mu.Lock()
+	if s.fetching != nil {
+		done := s.fetching
+		s.mu.Unlock()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	done := make(chan struct{})
+	s.fetching = done
+	s.mu.Unlock()
+
+	keys, err := readKeySet(ctx, s.url)
+	s.mu.Lock()
+	if err == nil {
+		s.keys = keys
+	}
+	// A failed fetch is not tried again before oidcRefetchMin either
+	s.fetched, s.fetching = time.Now(), nil
+	s.mu.Unlock()
+	close(done)
+	return err
+}
+
+// readKeySet fetches a JSON Web Key Set and returns its usable signing keys
+func readKeySet(ctx context.Context, url string) (map[string]jose.JSONWebKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := pdfanalysis.QuickClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error reading OIDC keys: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error reading OIDC keys: status %d from %s", resp.StatusCode, url)
+	}
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("error reading OIDC keys: %v", err)
+	}
+	keys := make(map[string]jose.JSONWebKey)
+	for _, raw := range set.Keys {
+		// Keys of other types, uses, or sizes are skipped, not fatal
+		var key jose.JSONWebKey
+		if err := key.UnmarshalJSON(raw); err != nil || !key.IsPublic() || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		if alg := keyAlgorithm(key); alg != "" && (key.Algorithm == "" || key.Algorithm == string(alg)) {
+			keys[key.KeyID] = key
+		}
+	}
+	return keys, nil
+}
+
+// keyAlgorithm returns the signature algorithm a key is used with, or ""
+// for a key that is not accepted
+func keyAlgorithm(key jose.JSONWebKey) jose.SignatureAlgorithm {
+	switch k := key.Key.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() >= oidcMinRSABits {
+			return jose.RS256
+		}
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P256() {
+			return jose.ES256
+		}
+	}
+	return ""
+}
+
+// claimStrings returns a claim that is a list of strings or one
+// space-separated string
+func claimStrings(claim interface{}) []string {
+	switch claim := claim.(type) {
+	case string:
+		return strings.Fields(claim)
+	case []interface{}:
+		var values []string
+		for _, value := range claim {
+			if s, ok := value.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
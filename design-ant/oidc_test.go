@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testIssuer is an OIDC issuer serving the public keys of one RSA and one
+// EC P-256 key, and two keys that must not be used: a 1024-bit RSA key and
+// the RSA key again, labeled for ES256. Key set requests after the first
+// wait for hold to be closed, when it is set.
+type testIssuer struct {
+	url     string
+	rsaKey  *rsa.PrivateKey
+	weakKey *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+	fetches atomic.Int32
+	hold    chan struct{}
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &testIssuer{rsaKey: rsaKey, weakKey: weakKey, ecKey: ecKey}
+	encode := func(n *big.Int) string { return base64.RawURLEncoding.EncodeToString(n.Bytes()) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": issuer.url, "jwks_uri": issuer.url + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		if issuer.fetches.Add(1) > 1 && issuer.hold != nil {
+			<-issuer.hold
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kid": "rsa", "kty": "RSA", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+			{"kid": "weak", "kty": "RSA", "n": encode(weakKey.N), "e": encode(big.NewInt(int64(weakKey.E)))},
+			{"kid": "mislabeled", "kty": "RSA", "alg": "ES256", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+			{"kid": "ec", "kty": "EC", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+		}})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	issuer.url = server.URL
+	return issuer
+}
+
+// sign returns a token with the given header and claims, signed with the
+// key the header's kid names
+func (i *testIssuer) sign(t *testing.T, header, claims map[string]interface{}) string {
+	t.Helper()
+	segment := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := segment(header) + "." + segment(claims)
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	switch header["kid"] {
+	case "rsa", "weak", "mislabeled":
+		key := i.rsaKey
+		if header["kid"] == "weak" {
+			key = i.weakKey
+		}
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case "ec":
+		r, s, err := ecdsa.Sign(rand.Reader, i.ecKey, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// claims returns valid claims for the issuer, with overrides applied
+func (i *testIssuer) claims(overrides map[string]interface{}) map[string]interface{} {
+	claims := map[string]interface{}{
+		"iss":    i.url,
+		"aud":    "design-ant",
+		"sub":    "user-1",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"roles":  []string{"submit"},
+		"tenant": "acme",
+	}
+	for name, value := range overrides {
+		claims[name] = value
+	}
+	return claims
+}
+
+func TestOIDCRequiresAudience(t *testing.T) {
+	issuer := newTestIssuer(t)
+	if _, err := newOIDCVerifier(context.Background(), issuer.url, "", "roles", "tenant"); err == nil {
+		t.Fatal("verifier without an audience was created")
+	}
+}
+
+func TestOIDCVerify(t *testing.T) {
+	issuer := newTestIssuer(t)
+	v, err := newOIDCVerifier(context.Background(), issuer.url, "design-ant", "roles", "tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs256 := map[string]interface{}{"alg": "RS256", "kid": "rsa"}
+	es256 := map[string]interface{}{"alg": "ES256", "kid": "ec"}
+
+	for _, header := range []map[string]interface{}{rs256, es256} {
+		p, err := v.verify(context.Background(), issuer.sign(t, header, issuer.claims(nil)))
+		if err != nil {
+			t.Fatalf("%s: valid token refused: %v", header["alg"], err)
+		}
+		if p.name != "user-1" || p.tenant != "acme" || len(p.roles) != 1 || p.roles[0] != "submit" {
+			t.Errorf("%s: principal = %+v", header["alg"], p)
+		}
+	}
+
+	valid := issuer.sign(t, rs256, issuer.claims(nil))
+	parts := strings.Split(valid, ".")
+	tampered := issuer.claims(map[string]interface{}{"roles": []string{"admin"}})
+	tamperedClaims, _ := json.Marshal(tampered)
+	noneHeader, _ := json.Marshal(map[string]string{"alg": "none", "kid": "rsa"})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"bad signature", parts[0] + "." + base64.RawURLEncoding.EncodeToString(tamperedClaims) + "." + parts[2]},
+		{"alg none", base64.RawURLEncoding.EncodeToString(noneHeader) + "." + parts[1] + "."},
+		{"alg none with signature", base64.RawURLEncoding.EncodeToString(noneHeader) + "." + parts[1] + "." + parts[2]},
+		{"RS256 header on EC key", issuer.sign(t, map[string]interface{}{"alg": "RS256", "kid": "ec"}, issuer.claims(nil))},
+		{"unknown key", issuer.sign(t, map[string]interface{}{"alg": "RS256", "kid": "other"}, issuer.claims(nil))},
+		{"1024-bit RSA key", issuer.sign(t, map[string]interface{}{"alg": "RS256", "kid": "weak"}, issuer.claims(nil))},
+		{"RSA key labeled ES256", issuer.sign(t, map[string]interface{}{"alg": "RS256", "kid": "mislabeled"}, issuer.claims(nil))},
+		{"expired", issuer.sign(t, es256, issuer.claims(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}))},
+		{"no expiry", issuer.sign(t, rs256, issuer.claims(map[string]interface{}{"exp": nil}))},
+		{"not valid yet", issuer.sign(t, rs256, issuer.claims(map[string]interface{}{"nbf": time.Now().Add(time.Hour).Unix()}))},
+		{"wrong issuer", issuer.sign(t, rs256, issuer.claims(map[string]interface{}{"iss": "https://evil.example.com"}))},
+		{"wrong audience", issuer.sign(t, es256, issuer.claims(map[string]interface{}{"aud": "other-app"}))},
+		{"no audience", issuer.sign(t, rs256, issuer.claims(map[string]interface{}{"aud": nil}))},
+		{"malformed", "not-a-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if p, err := v.verify(context.Background(), tt.token); err == nil {
+				t.Errorf("token accepted as %+v", p)
+			}
+		})
+	}
+
+	p, err := v.verify(context.Background(), issuer.sign(t, rs256, issuer.claims(map[string]interface{}{"aud": []string{"other-app", "design-ant"}})))
+	if err != nil || p == nil {
+		t.Errorf("token with the audience in a list refused: %v", err)
+	}
+}
+
+func TestOIDCRefetchDoesNotBlock(t *testing.T) {
+	issuer := newTestIssuer(t)
+	v, err := newOIDCVerifier(context.Background(), issuer.url, "design-ant", "roles", "tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.hold = make(chan struct{})
+	defer close(issuer.hold)
+
+	// A token with an unknown key starts a refetch that hangs
+	v.keys.mu.Lock()
+	v.keys.fetched = time.Now().Add(-oidcRefetchMin)
+	v.keys.mu.Unlock()
+	go v.verify(context.Background(), issuer.sign(t, map[string]interface{}{"alg": "RS256", "kid": "rotated"}, issuer.claims(nil)))
+	for issuer.fetches.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Tokens signed with known keys are still checked meanwhile
+	verified := make(chan error, 1)
+	go func() {
+		_, err := v.verify(context.Background(), issuer.sign(t, map[string]interface{}{"alg": "ES256", "kid": "ec"}, issuer.claims(nil)))
+		verified <- err
+	}()
+	select {
+	case err := <-verified:
+		if err != nil {
+			t.Errorf("valid token refused during a refetch: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("verification waited for the key set refetch")
+	}
+}
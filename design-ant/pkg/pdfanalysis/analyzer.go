@@ -0,0 +1,647 @@
+// Package pdfanalysis analyzes design and engineering PDFs page by page with
+// Anthropic's models. It is the engine of the design-ant command, which adds
+// the flags, outputs, ledger, jobs, and server around it.
+package pdfanalysis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"design-ant/internal/tempfiles"
+)
+
+// Result is the analysis of a document, as written to {pdf-name}_analysis.json
+type Result = FullAnalysisResult
+
+// Analyzer analyzes documents page by page with one configuration
+type Analyzer struct {
+	config    Config
+	observers []ProgressObserver
+	group     *observerGroup // Shared by all runs, so concurrent runs take turns calling the observers
+}
+
+// Option configures an Analyzer
+type Option func(*Analyzer)
+
+// New returns an Analyzer. Without options it uses the defaults of the
+// command line (DefaultConfig), with the API key of the provider from
+// ANTHROPIC_API_KEY or GEMINI_API_KEY.
+func New(opts ...Option) (*Analyzer, error) {
+	config := DefaultConfig()
+	config.APIKey = ""
+	config.NoProgress = true
+	a := &Analyzer{config: *config}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if err := a.config.Validate(); err != nil {
+		return nil, err
+	}
+	if a.config.APIKey == "" {
+		a.config.APIKey = os.Getenv(providerKeys[a.config.Provider])
+	}
+	if a.config.APIKey == "" {
+		return nil, fmt.Errorf("no %s API key: set %s or use WithAPIKey", a.config.Provider, providerKeys[a.config.Provider])
+	}
+	if len(a.observers) > 0 {
+		a.group = &observerGroup{observers: a.observers}
+	}
+	return a, nil
+}
+
+// WithConfig replaces the whole configuration, e.g. one parsed from command
+// line flags. Options after it change the given configuration.
+func WithConfig(config Config) Option {
+	return func(a *Analyzer) { a.config = config }
+}
+
+// WithAPIKey sets the API key of the provider
+func WithAPIKey(key string) Option {
+	return func(a *Analyzer) { a.config.APIKey = key }
+}
+
+// WithProvider sets the API the model runs on, ProviderAnthropic or
+// ProviderGemini. It is told by the model name otherwise; with the default
+// model, the provider's own default model is used.
+func WithProvider(provider string) Option {
+	return func(a *Analyzer) { a.config.Provider = provider }
+}
+
+// WithModel sets the model that analyzes the pages, DefaultModel otherwise.
+// The classification, translation, and summary passes use it too unless
+// they have their own.
+func WithModel(model string) Option {
+	return func(a *Analyzer) { a.config.ModelName = model }
+}
+
+// WithConcurrency sets the number of pages analyzed at the same time. The
+// default is 16, paced by the token rate limit, or 4 without one.
+func WithConcurrency(workers int) Option {
+	return func(a *Analyzer) { a.config.Workers = workers }
+}
+
+// WithPromptPack sets the analysis prompt, MechanicalPromptPack otherwise
+func WithPromptPack(pack PromptPack) Option {
+	return func(a *Analyzer) { a.config.PromptPack = pack }
+}
+
+// WithBudget stops starting pages once a run has cost this many dollars
+// (0 = no limit). The run then returns the pages finished so far.
+func WithBudget(dollars float64) Option {
+	return func(a *Analyzer) { a.config.MaxCost = dollars }
+}
+
+// WithCache keeps page analyses in dir and reuses them for identical pages,
+// prompts, and models, across runs and processes. An empty dir disables the
+// cache, which is on by default in the user cache directory.
+func WithCache(dir string) Option {
+	return func(a *Analyzer) { a.config.CacheDir = dir }
+}
+
+// WithObserver reports the pages of every run to o, and the other lines of
+// the runs when o is a MessageObserver. Without observers, both are printed
+// on stdout as the command does (NewConsoleObserver); pass that too to keep
+// it alongside your own. The Analyzer itself never prints. It may run
+// several documents at once; their calls to o still come one at a time.
+func WithObserver(o ProgressObserver) Option {
+	return func(a *Analyzer) { a.observers = append(a.observers, o) }
+}
+
+// WithStage runs s on every analyzed page after extraction, after the
+// stages given before it; NewExecStage runs a command as a stage
+func WithStage(s PageStage) Option {
+	return func(a *Analyzer) { a.config.Stages = append(a.config.Stages, s) }
+}
+
+// observer returns what the pages of a run are reported to. The observers
+// given to New are called through one group for every run of the Analyzer,
+// so runs in parallel never call them at the same time.
+func (a *Analyzer) observer() ProgressObserver {
+	if a.group == nil {
+		return &observerGroup{observers: []ProgressObserver{NewConsoleObserver()}}
+	}
+	return a.group
+}
+
+// AnalyzeFile analyzes every page of a PDF, or of an Office document after
+// converting it with LibreOffice. A run stopped early by ctx, the cost
+// limit, or the run deadline returns the pages finished so far, marked
+// Interrupted, together with the cause.
+func (a *Analyzer) AnalyzeFile(ctx context.Context, path string) (*Result, error) {
+	config := a.config
+	config.PDFPath = path
+	observer, run := a.observer(), newRun(&config)
+	ctx = withRunMessages(ctx, run, observer)
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("PDF file not found: %s", path)
+	}
+	if IsOfficeDocument(path) {
+		temp, err := tempfiles.New("pdf-convert")
+		if err != nil {
+			return nil, err
+		}
+		defer temp.Remove()
+		logf(ctx, "🔁 Converting %s to PDF with LibreOffice...\n", filepath.Ext(path))
+		if config.PDFPath, err = ConvertToPDF(ctx, path, temp.Path()); err != nil {
+			return nil, fmt.Errorf("error converting document: %v", err)
+		}
+		if config.SourcePath == "" {
+			config.SourcePath = path
+		}
+	}
+	return analyzeDocument(ctx, &config, fileSource(config.PDFPath), observer, run)
+}
+
+// AnalyzePages analyzes the given pages (1-based) of a PDF read from r, or
+// every page when pages is empty. The document is read into memory; use
+// AnalyzeReader when it is already held in an io.ReaderAt.
+func (a *Analyzer) AnalyzePages(ctx context.Context, r io.Reader, pages []int) (*Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading PDF: %v", err)
+	}
+	return a.AnalyzeBytes(ctx, data, pages)
+}
+
+// AnalyzeBytes analyzes the given pages (1-based) of a PDF in memory, or
+// every page when pages is empty
+func (a *Analyzer) AnalyzeBytes(ctx context.Context, data []byte, pages []int) (*Result, error) {
+	return a.AnalyzeReader(ctx, bytes.NewReader(data), int64(len(data)), pages)
+}
+
+// AnalyzeReader analyzes the given pages (1-based) of a PDF of size bytes
+// read from r, or every page when pages is empty. The document is read in
+// place, e.g. from an upload or an object store, without a copy on disk;
+// only the chunks sent to the API are written to a temp directory. The
+// result is named after SourcePath of the configuration, "document.pdf"
+// when it is empty.
+func (a *Analyzer) AnalyzeReader(ctx context.Context, r io.ReaderAt, size int64, pages []int) (*Result, error) {
+	config := a.config
+	if config.SourcePath == "" {
+		config.SourcePath = "document.pdf"
+	}
+	config.PDFPath = config.SourcePath
+	config.Pages = pages
+	return analyzeDocument(ctx, &config, readerSource(r, size), a.observer(), newRun(&config))
+}
+
+// analyzeDocument analyzes the document of src page by page and runs the
+// configured passes over the pages: validation, escalation, consolidation,
+// unit normalization, compliance, and the executive summary. It returns the
+// result, and the cause when the run stopped early. Its lines are reported
+// to observer as those of run.
+func analyzeDocument(ctx context.Context, config *Config, src pdfSource, observer ProgressObserver, run Run) (*Result, error) {
+	ctx = withRunMessages(ctx, run, observer)
+	// Rules and lists are loaded up front so a bad file fails before any API cost
+	var rules []complianceRule
+	if config.RulesPath != "" {
+		var err error
+		if rules, err = loadRules(config.RulesPath); err != nil {
+			return nil, err
+		}
+	}
+
+	var validators []validator
+	if config.ValidatorsPath != "" {
+		var err error
+		if validators, err = loadValidators(config.ValidatorsPath); err != nil {
+			return nil, err
+		}
+		for _, v := range validators {
+			if v.target != "analysis" && v.target != "any" && !config.Structured {
+				return nil, fmt.Errorf("validator %q checks %s, which needs -structured", v.name, v.target)
+			}
+		}
+	}
+
+	var prices *priceList
+	if config.PriceList != "" {
+		var err error
+		if prices, err = loadPriceList(config.PriceList); err != nil {
+			return nil, err
+		}
+	}
+
+	// Earlier analyses of identical pages are reused instead of sent again
+	var reuse reuseCache
+	if len(config.ReuseFrom) > 0 || len(config.ReuseResults) > 0 {
+		var err error
+		if reuse, err = loadReuseCache(config); err != nil {
+			return nil, err
+		}
+	}
+
+	// Pages finished by an interrupted run are kept as they are
+	var resumed map[int]ChunkAnalysis
+	if config.ResumeFrom != "" {
+		var err error
+		if resumed, err = loadResume(ctx, config); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create temporary directory for chunk PDFs
+	temp, err := tempfiles.New("pdf-chunks")
+	if err != nil {
+		return nil, err
+	}
+	defer temp.Remove()
+
+	startTime := time.Now()
+
+	// Get total page count
+	totalPages, err := getPageCount(src)
+	if err != nil {
+		return nil, err
+	}
+
+	logf(ctx, "📊 Total pages: %d\n", totalPages)
+	if err := CheckPageLimit(config.Limits, totalPages); err != nil {
+		return nil, err
+	}
+
+	// Fingerprints identify unchanged pages for -reuse and compare
+	fingerprints, err := pageFingerprints(ctx, src, totalPages)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		slog.Warn("Could not fingerprint pages, analyses cannot be reused", "error", err)
+	}
+	if resumed != nil {
+		logf(ctx, "⏩ Resuming %s: %d page(s) already analyzed\n", config.ResumeFrom, len(resumed))
+	}
+	if reuse != nil {
+		logf(ctx, "♻️  %d reusable page analyses from %d earlier result(s)\n", len(reuse), len(config.ReuseFrom)+len(config.ReuseResults))
+	}
+
+	// Process each page individually for maximum detail extraction
+	chunkSize := 1
+	logf(ctx, "📦 Processing each page individually for complete data extraction\n\n")
+	plan, err := selectPages(planChunks(totalPages, chunkSize), config.Pages, totalPages)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decide per page whether the text layer is enough or the full PDF page is needed
+	var pageRoutes []PageRoute
+	if config.InputMode != InputModePDF {
+		pageRoutes, err = classifyPages(ctx, src, totalPages)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error classifying pages: %v", err)
+		}
+		textPages := 0
+		for i := range pageRoutes {
+			if config.InputMode == InputModeText {
+				pageRoutes[i].Mode = InputModeText
+				pageRoutes[i].Reason = "forced by -input-mode text"
+			}
+			if pageRoutes[i].Mode == InputModeText {
+				textPages++
+			}
+			logf(ctx, "  🧭 Page %d: %s (%s)\n", pageRoutes[i].Page, pageRoutes[i].Mode, pageRoutes[i].Reason)
+		}
+		logf(ctx, "🧭 Routing: %d page(s) via text layer, %d page(s) via PDF\n\n", textPages, len(pageRoutes)-textPages)
+	} else if config.Ground {
+		// -ground sends the text layer with the PDF pages
+		texts, err := pageTexts(src, totalPages)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the text layer for -ground: %v", err)
+		}
+		pageRoutes = make([]PageRoute, totalPages)
+		for i, text := range texts {
+			pageRoutes[i] = PageRoute{Page: i + 1, Mode: InputModePDF, Text: text}
+		}
+	}
+
+	// Process chunks with rate limiting. Page requests are paced by a shared
+	// input-tokens-per-minute bucket using pre-flight token counts, so small
+	// pages run in parallel and image-heavy pages wait. Without -tpm the old
+	// fixed limit of 4 concurrent pages applies. -workers overrides either.
+	workers := maxInFlightRequests
+	if config.TokensPerMinute == 0 {
+		workers = 4
+	}
+	if config.Workers > 0 {
+		workers = config.Workers
+	}
+	var bucket *tokenBucket
+	if config.TokensPerMinute > 0 {
+		bucket = newTokenBucket(config.TokensPerMinute)
+		logf(ctx, "🚀 Processing pages paced to %d input tokens/minute (%d workers)...\n", config.TokensPerMinute, workers)
+	} else {
+		logf(ctx, "🚀 Processing pages with rate limiting (%d workers)...\n", workers)
+	}
+	logf(ctx, "%s\n", strings.Repeat("-", 70))
+
+	if config.CaptureDir != "" {
+		if err := os.MkdirAll(config.CaptureDir, 0755); err != nil {
+			return nil, fmt.Errorf("error creating capture directory: %v", err)
+		}
+		logf(ctx, "🔍 Capturing raw API requests and responses in: %s\n", config.CaptureDir)
+	}
+
+	// Reaching -run-deadline stops the run like an interruption and keeps
+	// what is done
+	if config.RunDeadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, startTime.Add(config.RunDeadline))
+		defer cancel()
+		logf(ctx, "⏰ Run deadline: %v\n", config.RunDeadline)
+	}
+	if config.MaxCost > 0 {
+		logf(ctx, "💵 Cost limit: $%.2f\n", config.MaxCost)
+	}
+	ctx = withRateLimiter(ctx, bucket)
+	ctx, root := startSpan(ctx, "analysis", "document", filepath.Base(config.DocumentPath()), "model", config.ModelName, "pages", len(plan))
+	defer root.end(nil)
+
+	// Pages are split while earlier ones are analyzed. A split or size limit
+	// error, or reaching -max-cost, stops the run like an interruption,
+	// keeping the finished pages.
+	ctx, stopRun := context.WithCancelCause(ctx)
+	defer stopRun(nil)
+	splitter := &splitStage{source: src, temp: temp, limits: config.Limits, caps: config.Capabilities()}
+	split := make(chan ChunkInfo, workers)
+	go func() {
+		defer tempfiles.CleanupOnPanic()
+		// The cause is set before the queue sees the end of the input
+		defer close(split)
+		if err := splitter.run(ctx, plan, split); err != nil {
+			stopRun(err)
+		}
+	}()
+
+	// A fixed pool of workers takes pages from the queue; each finished page
+	// is reported as it completes so partial progress survives a crash
+	queue := &pageQueue{config: config, routes: pageRoutes, fingerprints: fingerprints, reuse: reuse, resumed: resumed,
+		source: filepath.Base(config.DocumentPath()), document: src, temp: temp, observer: observer, id: run, total: len(plan)}
+	if config.CacheDir != "" && fingerprints != nil {
+		queue.cache = &pageCache{dir: config.CacheDir}
+		logf(ctx, "💾 Page cache: %s\n", config.CacheDir)
+	}
+	progress := showProgress(config, observer)
+	if progress {
+		startProgress(len(plan), workers)
+	}
+	var runningCost float64
+	var done int
+	results := queue.run(ctx, split, workers, func(result ChunkAnalysis) {
+		done++
+		observer.OnPageComplete(run, done, len(plan), result)
+		if result.TotalCost > 0 {
+			runningCost += result.TotalCost
+			observer.OnCostUpdate(run, runningCost)
+		}
+		if config.MaxCost > 0 && runningCost >= config.MaxCost {
+			stopRun(fmt.Errorf("%w: cost limit of $%.2f reached ($%.4f spent)", ErrBudgetExceeded, config.MaxCost, runningCost))
+		}
+	})
+	if progress {
+		finishProgress()
+	}
+
+	// An interrupted run keeps the finished pages and skips the later API passes
+	interrupted := ctx.Err() != nil
+	var stopped error // Why the run ended early
+	if interrupted {
+		results = completedChunks(results)
+		stopped = context.Cause(ctx)
+		switch {
+		case errors.Is(stopped, context.DeadlineExceeded) && config.RunDeadline > 0:
+			logf(ctx, "\n⏰ Run deadline of %v reached after %d of %d page(s)\n", config.RunDeadline, len(results), len(plan))
+		case errors.Is(stopped, context.DeadlineExceeded):
+			logf(ctx, "\n⏰ Deadline reached after %d of %d page(s)\n", len(results), len(plan))
+		case errors.Is(stopped, context.Canceled):
+			logf(ctx, "\n⏹️  Run interrupted after %d of %d page(s)\n", len(results), len(plan))
+		default:
+			if len(results) == 0 {
+				return nil, stopped
+			}
+			logf(ctx, "\n❌ Run stopped after %d of %d page(s): %v\n", len(results), len(plan), stopped)
+		}
+	}
+
+	// Text layers for -validate; routed runs already extracted them
+	var texts []string
+	if validators != nil {
+		if pageRoutes != nil {
+			for _, route := range pageRoutes {
+				texts = append(texts, route.Text)
+			}
+		} else if texts, err = pageTexts(src, totalPages); err != nil {
+			slog.Warn("Could not read the text layer, skipping validation", "error", err)
+		}
+	}
+	if texts != nil {
+		flagged := validateOutput(results, texts, validators)
+		logf(ctx, "🧪 Validation: %d page(s) likely dropped data\n", flagged)
+	}
+
+	if config.EscalateModel != "" && !interrupted {
+		escalated, accepted := escalatePages(ctx, config, results, splitter.chunks, pageRoutes, texts, validators)
+		logf(ctx, "⏫ Escalation: %d page(s) rerun with %s, %d improved\n", escalated, config.EscalateModel, accepted)
+	}
+	if blocked, recovered := countBlocked(results); blocked > 0 && config.NeutralRetry {
+		logf(ctx, "🚫 %d page(s) refused or blocked, %d recovered with the neutral prompt\n", blocked, recovered)
+	} else if blocked > 0 {
+		logf(ctx, "🚫 %d page(s) refused or blocked; -neutral-retry sends them once more with a neutral prompt\n", blocked)
+	}
+	for _, chunk := range results {
+		for _, v := range chunk.Validation {
+			logf(ctx, "  - p. %d: %s missing from %s: %s\n", chunk.StartPage, v.Validator, v.Target, strings.Join(v.Missing, ", "))
+		}
+	}
+
+	logf(ctx, "\n%s\n  FINALIZING RESULTS\n%s\n", strings.Repeat("=", 70), strings.Repeat("=", 70))
+	ctx, finalize := startSpan(ctx, "finalize")
+	defer finalize.end(nil)
+
+	var consolidated *ConsolidatedAnalysis
+	if config.Consolidate && !interrupted {
+		consolidated, err = consolidate(ctx, config, results)
+		if err != nil {
+			// A failed pass still carries the cost of the calls it made
+			slog.Warn("Consolidation failed, keeping page analyses only", "error", err)
+		} else {
+			logf(ctx, "✅ Document summary: %d input tokens, %d output tokens, $%.6f\n",
+				consolidated.InputTokens, consolidated.OutputTokens, consolidated.TotalCost)
+		}
+	} else if !config.Consolidate {
+		logf(ctx, "✅ Using individual page analyses (run with -consolidate for a document summary)\n")
+		logf(ctx, "   All page-by-page details are preserved in the output\n")
+	}
+
+	if config.Units != "" {
+		normalized, converted := normalizeUnits(results, config.Units)
+		logf(ctx, "📏 Units: %d dimension(s) normalized to %s, %d converted\n", normalized, config.Units, converted)
+	}
+
+	if rules != nil {
+		checked, passed := checkCompliance(results, rules)
+		logf(ctx, "📋 Compliance: %d of %d page(s) pass all %d rule(s)\n", passed, checked, len(rules))
+		for _, chunk := range results {
+			for _, r := range chunk.Compliance {
+				if !r.Passed {
+					logf(ctx, "  - p. %d: %s: %s\n", chunk.StartPage, r.Rule, r.Message)
+				}
+			}
+		}
+	}
+
+	fullResult := FullAnalysisResult{
+		SchemaVersion:  CurrentSchemaVersion,
+		Build:          Build(),
+		Prompt:         config.PromptPack.Info(),
+		PDFPath:        config.DocumentPath(),
+		TotalPages:     totalPages,
+		Interrupted:    interrupted,
+		Chunks:         results,
+		Consolidated:   consolidated,
+		Pricing:        PricingSnapshot(),
+		ProcessingTime: time.Since(startTime).String(),
+		GeneratedAt:    time.Now(),
+	}
+	recomputeDerived(&fullResult)
+	if prices != nil {
+		fullResult.AssemblyCost = prices.estimate(fullResult.MasterBOM)
+	}
+	if config.Summary && !interrupted {
+		fullResult.ExecutiveSummary, err = generateExecutiveSummary(ctx, config, fullResult)
+		if err != nil {
+			slog.Warn("Executive summary failed", "error", err)
+		} else {
+			logf(ctx, "✅ Executive summary: %d input tokens, %d output tokens, $%.6f\n",
+				fullResult.ExecutiveSummary.InputTokens, fullResult.ExecutiveSummary.OutputTokens, fullResult.ExecutiveSummary.TotalCost)
+		}
+		RecomputeTotals(&fullResult)
+	}
+	if fullResult.TotalCost > runningCost {
+		// Escalation, consolidation, and the executive summary
+		observer.OnCostUpdate(run, fullResult.TotalCost)
+	}
+	root.set("cost", fullResult.TotalCost)
+	root.set("input_tokens", fullResult.TotalInputTokens)
+	root.set("output_tokens", fullResult.TotalOutputTokens)
+	return &fullResult, stopped
+}
+
+// selectPages keeps the planned pages listed in pages (1-based), or all of
+// them when pages is empty
+func selectPages(plan []ChunkInfo, pages []int, totalPages int) ([]ChunkInfo, error) {
+	if len(pages) == 0 {
+		return plan, nil
+	}
+	for _, page := range pages {
+		if page < 1 || page > totalPages {
+			return nil, fmt.Errorf("page %d is not in the document, which has %d pages", page, totalPages)
+		}
+	}
+	var selected []ChunkInfo
+	for _, chunk := range plan {
+		if slices.Contains(pages, chunk.StartPage+1) {
+			selected = append(selected, chunk)
+		}
+	}
+	return selected, nil
+}
+
+// pageReply is the outcome of a page's analysis request
+type pageReply struct {
+	Analysis     string
+	InputTokens  int
+	OutputTokens int
+	Continued    int             // Continuation requests after the answer hit max_tokens
+	Truncated    bool            // Still cut off at max_tokens after maxContinuations
+	Extraction   *toolExtraction // nil without -structured
+}
+
+// analyzePage runs one analysis of a chunk with the configured model: with
+// the extraction tool for -structured, otherwise as PDF or text layer
+// depending on the route. When the context carries a token bucket, the
+// request waits for its pre-flight token count first.
+func analyzePage(ctx context.Context, config *Config, route PageRoute, path string, pageNumber int, prompt string) (pageReply, error) {
+	bucket := rateLimiterFrom(ctx)
+	if bucket == nil {
+		return sendPageRequest(ctx, config, route, path, pageNumber, prompt)
+	}
+	reserved := preflightTokens(ctx, config, route, path, pageNumber, prompt)
+	_, wait := startSpan(ctx, "rate_limit_wait", "tokens", reserved)
+	err := bucket.wait(ctx, reserved)
+	wait.end(err)
+	if err != nil {
+		return pageReply{}, err
+	}
+	reply, err := sendPageRequest(ctx, config, route, path, pageNumber, prompt)
+	if err == nil || reply.InputTokens > 0 {
+		// Failed requests keep their reservation, which also backs off after a 429
+		bucket.settle(reserved, reply.InputTokens)
+	}
+	return reply, err
+}
+
+// sendPageRequest sends the analysis request for analyzePage within
+// -chunk-timeout, which also covers the repair turns of -structured and the
+// continuations of an answer that hit max_tokens (Anthropic only)
+func sendPageRequest(ctx context.Context, config *Config, route PageRoute, path string, pageNumber int, prompt string) (pageReply, error) {
+	if config.ChunkTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.ChunkTimeout)
+		defer cancel()
+	}
+	if config.Structured {
+		extraction, err := analyzeStructured(ctx, config, route, path, pageNumber, prompt)
+		if extraction == nil {
+			return pageReply{}, err
+		}
+		if err == nil && extraction.Analysis == "" && extraction.DataError != "" {
+			err = &ResponseError{Provider: ProviderAnthropic, Reason: ResponseEmpty, Detail: "no text and no valid tool call"}
+		}
+		return pageReply{
+			Analysis:     extraction.Analysis,
+			InputTokens:  extraction.InputTokens,
+			OutputTokens: extraction.OutputTokens,
+			Continued:    extraction.Continued,
+			Truncated:    extraction.Truncated,
+			Extraction:   extraction,
+		}, err
+	}
+	content, err := pageContent(config, route, path, pageNumber, prompt)
+	if err != nil {
+		return pageReply{}, err
+	}
+	var reply pageReply
+	if config.Provider == ProviderGemini {
+		reply.Analysis, reply.InputTokens, reply.OutputTokens, err = sendGemini(ctx, config.APIKey, config.ModelName, systemPrompt(config), content)
+		return reply, err
+	}
+	messages := userMessage(content)
+	extra := systemField(systemPrompt(config))
+	resp, err := sendMessages(ctx, config.APIKey, config.ModelName, messages, extra)
+	if err != nil {
+		if resp != nil {
+			reply.InputTokens, reply.OutputTokens = resp.Usage.InputTokens, resp.Usage.OutputTokens
+		}
+		return reply, err
+	}
+	resp, reply.Continued, err = continueAnswer(ctx, config.APIKey, config.ModelName, messages, extra, resp)
+	reply.InputTokens, reply.OutputTokens = resp.Usage.InputTokens, resp.Usage.OutputTokens
+	if err != nil {
+		return reply, err
+	}
+	reply.Truncated = resp.StopReason == "max_tokens"
+	reply.Analysis, err = resp.answer()
+	return reply, err
+}
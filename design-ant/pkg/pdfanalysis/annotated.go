@@ -0,0 +1,235 @@
+package pdfanalysis
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gen2brain/go-fitz"
+	"github.com/go-pdf/fpdf"
+)
+
+// AnnotatedPageDPI is the render resolution of original pages in the annotated PDF
+const AnnotatedPageDPI = 110
+
+// SaveAnnotatedPDF writes a review document containing a cover page, then each
+// original page (rendered by go-fitz) followed by its extracted analysis
+func SaveAnnotatedPDF(filename, pdfPath string, result FullAnalysisResult) error {
+	doc, err := fitz.New(pdfPath)
+	if err != nil {
+		return fmt.Errorf("error opening PDF: %v", err)
+	}
+	defer doc.Close()
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 15)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	writeCoverPage(pdf, tr, result)
+	writeCostPage(pdf, tr, result)
+	if c := result.Consolidated; c != nil {
+		writeAnalysisPages(pdf, tr, "Document summary", ChunkAnalysis{
+			Analysis:       c.Analysis,
+			Error:          c.Error,
+			InputTokens:    c.InputTokens,
+			OutputTokens:   c.OutputTokens,
+			TotalCost:      c.TotalCost,
+			ProcessingTime: c.ProcessingTime,
+		})
+	}
+
+	for _, chunk := range result.Chunks {
+		for page := chunk.StartPage; page <= chunk.EndPage; page++ {
+			if err := writeRenderedPage(pdf, doc, page); err != nil {
+				return fmt.Errorf("error rendering page %d: %v", page, err)
+			}
+		}
+		writeAnalysisPages(pdf, tr, "Analysis of "+PageRangeLabel(chunk.StartPage, chunk.EndPage), chunk)
+	}
+
+	return pdf.OutputFileAndClose(filename)
+}
+
+// writeCoverPage adds the run summary and a sign-off block
+func writeCoverPage(pdf *fpdf.Fpdf, tr func(string) string, result FullAnalysisResult) {
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.MultiCell(0, 10, tr("Design Analysis Review"), "", "L", false)
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "", 11)
+	rows := [][2]string{
+		{"Document", filepath.Base(result.PDFPath)},
+		{"Pages", fmt.Sprintf("%d", result.TotalPages)},
+		{"Input tokens", fmt.Sprintf("%d", result.TotalInputTokens)},
+		{"Output tokens", fmt.Sprintf("%d", result.TotalOutputTokens)},
+		{"Total cost", fmt.Sprintf("$%.6f", result.TotalCost)},
+		{"Processing time", result.ProcessingTime},
+		{"Generated", result.GeneratedAt.Format(time.RFC1123)},
+	}
+	for _, row := range rows {
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.CellFormat(45, 7, tr(row[0]), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.CellFormat(0, 7, tr(row[1]), "", 1, "L", false, 0, "")
+	}
+
+	var failed []string
+	for _, chunk := range result.Chunks {
+		if chunk.Error != "" {
+			failed = append(failed, PageRangeLabel(chunk.StartPage, chunk.EndPage))
+		}
+	}
+	if len(failed) > 0 {
+		pdf.Ln(4)
+		pdf.SetTextColor(180, 0, 0)
+		pdf.MultiCell(0, 6, tr("Not analyzed: "+strings.Join(failed, ", ")), "", "L", false)
+		pdf.SetTextColor(0, 0, 0)
+	}
+
+	pdf.Ln(16)
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(0, 8, "Sign-off", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 11)
+	for _, role := range []string{"Reviewed by", "Approved by"} {
+		pdf.Ln(6)
+		pdf.CellFormat(35, 8, role+":", "", 0, "L", false, 0, "")
+		pdf.CellFormat(80, 8, "", "B", 0, "L", false, 0, "")
+		pdf.CellFormat(15, 8, "Date:", "", 0, "R", false, 0, "")
+		pdf.CellFormat(40, 8, "", "B", 1, "L", false, 0, "")
+	}
+}
+
+// writeCostPage adds the per-page cost table with run totals
+func writeCostPage(pdf *fpdf.Fpdf, tr func(string) string, result FullAnalysisResult) {
+	pdf.AddPageFormat("P", pdf.GetPageSizeStr("A4"))
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(0, 10, "Cost Breakdown", "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+
+	headers := []string{"Section", "Input tokens", "Output tokens", "Cost", "Duration", "Retries"}
+	widths := []float64{40, 28, 28, 30, 32, 22}
+	row := func(cells []string, style string, fill bool) {
+		pdf.SetFont("Helvetica", style, 9)
+		for i, cell := range cells {
+			align := "R"
+			if i == 0 {
+				align = "L"
+			}
+			pdf.CellFormat(widths[i], 6, tr(cell), "1", 0, align, fill, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	pdf.SetFillColor(235, 235, 235)
+	row(headers, "B", true)
+	table := costBreakdown(result)
+	for _, r := range table.Rows {
+		label := r.Label
+		if r.Failed {
+			label += " (failed)"
+		}
+		row([]string{label, fmt.Sprintf("%d", r.InputTokens), fmt.Sprintf("%d", r.OutputTokens),
+			fmt.Sprintf("$%.6f", r.Cost), r.Duration, fmt.Sprintf("%d", r.Retries)}, "", false)
+	}
+	t := table.Total
+	row([]string{t.Label, fmt.Sprintf("%d", t.InputTokens), fmt.Sprintf("%d", t.OutputTokens),
+		fmt.Sprintf("$%.6f", t.Cost), t.Duration, fmt.Sprintf("%d", t.Retries)}, "B", true)
+}
+
+// writeRenderedPage renders a 1-indexed original page and places it on its own
+// page, oriented to match the source
+func writeRenderedPage(pdf *fpdf.Fpdf, doc *fitz.Document, page int) error {
+	img, err := doc.ImageDPI(page-1, AnnotatedPageDPI)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("page-%d", page)
+	opts := fpdf.ImageOptions{ImageType: "JPG"}
+	info := pdf.RegisterImageOptionsReader(name, opts, &buf)
+	if info == nil {
+		return pdf.Error()
+	}
+
+	orientation := "P"
+	if info.Width() > info.Height() {
+		orientation = "L"
+	}
+	pdf.AddPageFormat(orientation, pdf.GetPageSizeStr("A4"))
+
+	// Fit the image inside the margins, preserving its aspect ratio
+	pageW, pageH := pdf.GetPageSize()
+	left, top, right, bottom := pdf.GetMargins()
+	maxW, maxH := pageW-left-right, pageH-top-bottom-8
+	w, h := maxW, maxW*info.Height()/info.Width()
+	if h > maxH {
+		w, h = maxH*info.Width()/info.Height(), maxH
+	}
+
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.CellFormat(0, 6, fmt.Sprintf("Original page %d", page), "", 1, "L", false, 0, "")
+	pdf.ImageOptions(name, left+(maxW-w)/2, top+8, w, h, false, opts, 0, "")
+	return nil
+}
+
+// writeAnalysisPages renders a chunk's markdown analysis as text pages under
+// a header line naming what was analyzed
+func writeAnalysisPages(pdf *fpdf.Fpdf, tr func(string) string, title string, chunk ChunkAnalysis) {
+	pdf.AddPageFormat("P", pdf.GetPageSizeStr("A4"))
+
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.CellFormat(0, 5, tr(fmt.Sprintf("%s  |  %d input / %d output tokens  |  $%.6f  |  %s",
+		title, chunk.InputTokens, chunk.OutputTokens, chunk.TotalCost, chunk.ProcessingTime)), "", 1, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(2)
+
+	if chunk.Error != "" {
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.SetTextColor(180, 0, 0)
+		pdf.MultiCell(0, 6, tr("Analysis failed: "+chunk.Error), "", "L", false)
+		pdf.SetTextColor(0, 0, 0)
+		return
+	}
+
+	for _, line := range strings.Split(chunk.Analysis, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			pdf.Ln(2)
+		case strings.HasPrefix(trimmed, "# "):
+			pdf.SetFont("Helvetica", "B", 15)
+			pdf.MultiCell(0, 8, tr(stripMarkdown(trimmed[2:])), "", "L", false)
+		case strings.HasPrefix(trimmed, "## "):
+			pdf.SetFont("Helvetica", "B", 13)
+			pdf.MultiCell(0, 7, tr(stripMarkdown(trimmed[3:])), "", "L", false)
+		case strings.HasPrefix(trimmed, "### "):
+			pdf.SetFont("Helvetica", "B", 11)
+			pdf.MultiCell(0, 6, tr(stripMarkdown(trimmed[4:])), "", "L", false)
+		case strings.HasPrefix(trimmed, "|"):
+			// Tables are kept as monospaced rows; separator rows are dropped
+			if strings.Trim(trimmed, "|-: ") == "" {
+				continue
+			}
+			pdf.SetFont("Courier", "", 7)
+			pdf.MultiCell(0, 3.5, tr(stripMarkdown(trimmed)), "", "L", false)
+		default:
+			pdf.SetFont("Helvetica", "", 9.5)
+			pdf.MultiCell(0, 4.8, tr(stripMarkdown(line)), "", "L", false)
+		}
+	}
+}
+
+// stripMarkdown removes inline emphasis markers that have no meaning in the PDF
+func stripMarkdown(text string) string {
+	return strings.NewReplacer("**", "", "__", "", "`", "").Replace(text)
+}
@@ -0,0 +1,333 @@
+package pdfanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// AnalyzeChunk sends a PDF chunk to Anthropic API and returns analysis
+func AnalyzeChunk(ctx context.Context, apiKey, modelName, chunkPath, prompt string) (string, int, int, error) {
+	content, err := ChunkContent(chunkPath, prompt)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	return SendMessage(ctx, apiKey, modelName, content)
+}
+
+// ChunkContent builds the message content for a PDF chunk and its prompt,
+// or for a page that was rendered as images because its PDF is too large
+func ChunkContent(chunkPath, prompt string) ([]map[string]interface{}, error) {
+	// The file is base64-encoded while the request is sent
+	info, err := os.Stat(chunkPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading PDF chunk: %v", err)
+	}
+	if info.IsDir() {
+		return renderedContent(chunkPath, prompt)
+	}
+
+	return []map[string]interface{}{
+		{
+			"type": "document",
+			"source": map[string]interface{}{
+				"type":       "base64",
+				"media_type": "application/pdf",
+				"data":       FileData{Path: chunkPath},
+			},
+		},
+		{
+			"type": "text",
+			"text": prompt,
+		},
+	}, nil
+}
+
+// textPageContent builds the message content for a page's text layer and its prompt
+func textPageContent(text string, pageNumber int, prompt string) []map[string]interface{} {
+	return []map[string]interface{}{
+		{
+			"type": "text",
+			"text": fmt.Sprintf("The following is the extracted text layer of PDF page %d. The page contains no drawing geometry or images.\n\n<page_text>\n%s\n</page_text>", pageNumber, text),
+		},
+		{
+			"type": "text",
+			"text": prompt,
+		},
+	}
+}
+
+// contentBlock is one block of a Messages API response
+type contentBlock struct {
+	Type  string          `json:"type"`
+	Text  string          `json:"text,omitempty"`
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+// messageResponse is the parsed Messages API response
+type messageResponse struct {
+	Content    []contentBlock `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// text joins the text blocks of the response
+func (r *messageResponse) text() string {
+	var parts []string
+	for _, block := range r.Content {
+		if block.Type == "text" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// check returns a *ResponseError when the response cannot hold an answer:
+// the model refused, or the body is not a Messages API response at all
+func (r *messageResponse) check() error {
+	switch {
+	case r.StopReason == "refusal":
+		return &ResponseError{Provider: ProviderAnthropic, Reason: ResponseRefused, Detail: "stop_reason refusal"}
+	case r.StopReason == "" && len(r.Content) == 0:
+		return &ResponseError{Provider: ProviderAnthropic, Reason: ResponseMalformed, Detail: "no content and no stop_reason"}
+	}
+	return nil
+}
+
+// ResponseError is a successful API response without a usable answer, so a
+// page fails with a reason instead of being kept with an empty analysis.
+// Refused and blocked responses are not retried; empty and malformed ones
+// are retried once.
+type ResponseError struct {
+	Provider string // ProviderAnthropic or ProviderGemini
+	Reason   string // ResponseRefused, ResponseBlocked, ResponseEmpty, or ResponseMalformed
+	Detail   string // The provider's stop, finish, or block reason, or the parse error
+}
+
+// Reasons of a ResponseError
+const (
+	ResponseRefused   = "refused"   // The model declined to answer
+	ResponseBlocked   = "blocked"   // A safety filter blocked the prompt or the answer
+	ResponseEmpty     = "empty"     // No text, e.g. only thinking blocks or a stop before any text
+	ResponseMalformed = "malformed" // The body could not be parsed as a response
+)
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s response %s: %s", e.Provider, e.Reason, e.Detail)
+}
+
+// APIError is a non-200 Messages API response. RetryAfter is the server's
+// retry-after hint, zero when the header is missing.
+type APIError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// SendMessage posts a single user message to the Messages API and returns
+// the response text with input and output token counts. A refused or empty
+// answer is returned as a *ResponseError with the tokens it was billed for.
+func SendMessage(ctx context.Context, apiKey, modelName string, content []map[string]interface{}) (string, int, int, error) {
+	resp, err := sendMessages(ctx, apiKey, modelName, userMessage(content), nil)
+	if resp == nil {
+		return "", 0, 0, err
+	}
+	text := ""
+	if err == nil {
+		text, err = resp.answer()
+	}
+	return text, resp.Usage.InputTokens, resp.Usage.OutputTokens, err
+}
+
+// userMessage returns the messages of a conversation with a single user turn
+func userMessage(content []map[string]interface{}) []map[string]interface{} {
+	return []map[string]interface{}{
+		{
+			"role":    "user",
+			"content": content,
+		},
+	}
+}
+
+// answer returns the response text, or a *ResponseError when it has none
+func (r *messageResponse) answer() (string, error) {
+	text := r.text()
+	if strings.TrimSpace(text) == "" {
+		return "", &ResponseError{Provider: ProviderAnthropic, Reason: ResponseEmpty, Detail: "no text, stop_reason " + r.StopReason}
+	}
+	return text, nil
+}
+
+// maxContinuations is how often an answer that hit max_tokens is continued
+const maxContinuations = 3
+
+// continueAnswer completes a response that stopped at max_tokens. The text so
+// far is sent back as the start of the assistant turn, so the model picks up
+// where it stopped, until it ends on its own or after maxContinuations
+// requests. The returned response holds the stitched text, the other blocks
+// (e.g. a tool call) of the last part, and the tokens of all requests. API
+// errors are returned with the response so far.
+func continueAnswer(ctx context.Context, apiKey, modelName string, messages []map[string]interface{}, extra map[string]interface{}, resp *messageResponse) (*messageResponse, int, error) {
+	continued := 0
+	for resp.StopReason == "max_tokens" && continued < maxContinuations {
+		// The API rejects a prefill that ends in whitespace
+		text := strings.TrimRightFunc(resp.text(), unicode.IsSpace)
+		if text == "" {
+			break
+		}
+		prefill := append(messages[:len(messages):len(messages)], map[string]interface{}{"role": "assistant", "content": text})
+		next, err := sendMessages(withCaptureSuffix(ctx, "continue", continued+1), apiKey, modelName, prefill, extra)
+		if err != nil {
+			if next != nil {
+				spent := *resp
+				spent.Usage.InputTokens += next.Usage.InputTokens
+				spent.Usage.OutputTokens += next.Usage.OutputTokens
+				return &spent, continued, err
+			}
+			return resp, continued, err
+		}
+		continued++
+
+		stitched := &messageResponse{StopReason: next.StopReason, Usage: resp.Usage}
+		stitched.Usage.InputTokens += next.Usage.InputTokens
+		stitched.Usage.OutputTokens += next.Usage.OutputTokens
+		stitched.Content = []contentBlock{{Type: "text", Text: text + next.text()}}
+		for _, block := range next.Content {
+			if block.Type != "text" {
+				stitched.Content = append(stitched.Content, block)
+			}
+		}
+		resp = stitched
+	}
+	return resp, continued, nil
+}
+
+// systemField returns the request field of a system prompt, nil without one
+func systemField(system string) map[string]interface{} {
+	if system == "" {
+		return nil
+	}
+	return map[string]interface{}{"system": system}
+}
+
+// sendMessages posts a conversation to the Messages API. Extra request fields
+// such as tools and tool_choice are merged into the request body. A response
+// without an answer is returned along with its *ResponseError, for its usage.
+func sendMessages(ctx context.Context, apiKey, modelName string, messages []map[string]interface{}, extra map[string]interface{}) (*messageResponse, error) {
+	requestBody := map[string]interface{}{
+		"model":      modelName,
+		"max_tokens": providerCapabilities[ProviderAnthropic].MaxOutputTokens,
+		"messages":   messages,
+	}
+	for key, value := range extra {
+		requestBody[key] = value
+	}
+
+	reqBody, err := NewRequestBody(requestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set("x-api-key", apiKey)
+	header.Set("anthropic-version", "2023-06-01")
+	body, err := postModelRequest(ctx, MessageClient, "https://api.anthropic.com/v1/messages", reqBody, header, anthropicBreaker)
+	if err != nil {
+		return nil, err
+	}
+	return parseMessageResponse(body)
+}
+
+// postModelRequest posts a request body to a model API the way every page
+// and summary request is sent, whatever the provider: under
+// DefaultRequestTimeout unless ctx has a deadline, captured for
+// -capture-dir, paced by the rate limiter of the run, and held back by the
+// provider's circuit breaker. It returns the body of a 200 response, or an
+// *APIError.
+func postModelRequest(ctx context.Context, client *http.Client, url string, reqBody *requestBody, header http.Header, breaker *circuitBreaker) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultRequestTimeout)
+		defer cancel()
+	}
+
+	// Make HTTP request
+	req, err := reqBody.NewRequest(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	captureRequest(ctx, req, reqBody)
+
+	// A rate limit reported by an earlier response holds back every request
+	bucket := rateLimiterFrom(ctx)
+	if bucket != nil {
+		if err := bucket.waitForReset(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// A provider that keeps failing is given a rest instead of more requests
+	if err := breaker.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		err = fmt.Errorf("error making request: %w", err) // Wrapped so retries can tell timeouts from network errors
+		breaker.Record(ctx, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		err = fmt.Errorf("error reading response: %w", err)
+		breaker.Record(ctx, err)
+		return nil, err
+	}
+	captureResponse(ctx, resp, body)
+	if bucket != nil {
+		bucket.observe(resp.StatusCode, resp.Header)
+	}
+
+	if resp.StatusCode != 200 {
+		err := &APIError{StatusCode: resp.StatusCode, Body: string(body), RetryAfter: ParseRetryAfter(resp.Header.Get("retry-after"))}
+		breaker.Record(ctx, err)
+		return nil, err
+	}
+	breaker.Record(ctx, nil)
+	return body, nil
+}
+
+// parseMessageResponse parses the body of a successful Messages API
+// response, returning a *ResponseError when it holds no answer. A refused
+// response is returned with the error, since its tokens are billed.
+func parseMessageResponse(body []byte) (*messageResponse, error) {
+	var apiResponse messageResponse
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return nil, &ResponseError{Provider: ProviderAnthropic, Reason: ResponseMalformed, Detail: err.Error()}
+	}
+	if err := apiResponse.check(); err != nil {
+		return &apiResponse, err
+	}
+	return &apiResponse, nil
+}
@@ -0,0 +1,66 @@
+package pdfanalysis
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func FuzzParseMessageResponse(f *testing.F) {
+	for _, seed := range []string{
+		`{"content": [{"type": "text", "text": "A bracket."}], "stop_reason": "end_turn", "usage": {"input_tokens": 1000, "output_tokens": 100}}`,
+		`{"content": [{"type": "text", "text": "Part one"}, {"type": "text", "text": "part two"}], "stop_reason": "max_tokens"}`,
+		`{"content": [{"type": "tool_use", "id": "t1", "name": "record_page", "input": {"bom_items": []}}], "stop_reason": "tool_use"}`,
+		`{"content": [{"type": "thinking", "text": ""}], "stop_reason": "end_turn"}`,
+		`{"content": [], "stop_reason": "refusal"}`,
+		`{"content": null, "stop_reason": ""}`,
+		`{"content": [{"type": "text", "text": "   "}], "stop_reason": "end_turn"}`,
+		`{"type": "error", "error": {"type": "overloaded_error"}}`,
+		`{"content": [{"type": "text"}]`,
+		`[]`,
+		`null`,
+		``,
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, body []byte) {
+		resp, err := parseMessageResponse(body)
+		if err != nil {
+			var respErr *ResponseError
+			if !errors.As(err, &respErr) {
+				t.Fatalf("error %v is not a *ResponseError", err)
+			}
+			if respErr.Reason != ResponseRefused && respErr.Reason != ResponseMalformed {
+				t.Fatalf("parse failed with reason %q", respErr.Reason)
+			}
+			if respErr.Reason == ResponseRefused && resp == nil {
+				t.Fatal("refused response returned without its usage")
+			}
+			return
+		}
+		if resp.StopReason == "refusal" || (resp.StopReason == "" && len(resp.Content) == 0) {
+			t.Fatalf("response passed the check: %+v", resp)
+		}
+		text, err := resp.answer()
+		if err != nil {
+			var respErr *ResponseError
+			if !errors.As(err, &respErr) || respErr.Reason != ResponseEmpty {
+				t.Fatalf("answer error %v, want a ResponseEmpty *ResponseError", err)
+			}
+			if text != "" {
+				t.Fatalf("answer %q returned with an error", text)
+			}
+			return
+		}
+		if strings.TrimSpace(text) == "" {
+			t.Fatalf("blank answer %q returned without an error", text)
+		}
+	})
+}
+
+func TestRefusedResponseUsage(t *testing.T) {
+	resp, err := parseMessageResponse([]byte(`{"content": [], "stop_reason": "refusal", "usage": {"input_tokens": 1000, "output_tokens": 5}}`))
+	if err == nil || resp == nil || resp.Usage.InputTokens != 1000 || resp.Usage.OutputTokens != 5 {
+		t.Errorf("refusal = %+v, %v; want its usage with the error", resp, err)
+	}
+}
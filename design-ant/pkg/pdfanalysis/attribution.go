@@ -0,0 +1,89 @@
+package pdfanalysis
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// promptTokenRate is the tokens per character of the prompt, measured once
+// per model and request shape with the token counting endpoint. Page prompts
+// differ only in details such as the page number, so the rate of the first
+// prompt estimates all others without a counting request per page.
+var promptTokenRate = struct {
+	sync.Mutex
+	perChar map[string]float64
+}{perChar: make(map[string]float64)}
+
+// promptTokens estimates the input tokens of a prompt sent the way the
+// analysis sends it: alone with -structured's extraction tool, if any
+func promptTokens(ctx context.Context, config *Config, prompt string) int {
+	if prompt == "" {
+		return 0
+	}
+	key := fmt.Sprintf("%s structured=%t", config.ModelName, config.Structured)
+	promptTokenRate.Lock()
+	defer promptTokenRate.Unlock()
+	rate, ok := promptTokenRate.perChar[key]
+	if !ok {
+		var extra map[string]interface{}
+		if config.Structured {
+			extra = map[string]interface{}{"tools": []interface{}{extractionTool(config)}}
+		}
+		content := []map[string]interface{}{{"type": "text", "text": prompt}}
+		rate = 1.0 / 3 // The character estimate of preflightTokens
+		if config.Capabilities().TokenCounting {
+			if n, err := countTokens(ctx, config.APIKey, config.ModelName, content, extra); err == nil {
+				rate = float64(n) / float64(len(prompt))
+			}
+		}
+		promptTokenRate.perChar[key] = rate
+	}
+	return int(rate * float64(len(prompt)))
+}
+
+// attributeTokens splits the input tokens of a page's analysis, sent in the
+// given number of requests, into prompt and document. Each request carries
+// the system prompt, the prompt, and the few-shot examples again; everything
+// else is attributed to the document.
+func attributeTokens(ctx context.Context, config *Config, prompt string, inputTokens, requests int) *TokenAttribution {
+	if inputTokens <= 0 {
+		return nil
+	}
+	prompt = joinPrompt(systemPrompt(config), prompt)
+	pricing := GetPricing(config.ModelName)
+	promptCount := min((promptTokens(ctx, config, prompt)+exampleTokens(ctx, config))*requests, inputTokens)
+	return &TokenAttribution{
+		DocumentTokens: inputTokens - promptCount,
+		PromptTokens:   promptCount,
+		DocumentCost:   float64(inputTokens-promptCount) / 1_000_000 * pricing.InputPricePerMTokens,
+		PromptCost:     float64(promptCount) / 1_000_000 * pricing.InputPricePerMTokens,
+	}
+}
+
+// totalAttribution sums the attributions of the chunks; nil when none has one
+func totalAttribution(chunks []ChunkAnalysis) *TokenAttribution {
+	var total *TokenAttribution
+	for _, chunk := range chunks {
+		if chunk.InputBreakdown == nil {
+			continue
+		}
+		if total == nil {
+			total = &TokenAttribution{}
+		}
+		total.Add(*chunk.InputBreakdown)
+	}
+	return total
+}
+
+// WriteAttribution writes to w the share of the input tokens spent on the document and the prompt
+func WriteAttribution(w io.Writer, a *TokenAttribution) {
+	if a == nil || a.DocumentTokens+a.PromptTokens == 0 {
+		return
+	}
+	total := a.DocumentTokens + a.PromptTokens
+	fmt.Fprintf(w, "🧮 Page input tokens: %d document (%.0f%%, $%.6f), %d prompt (%.0f%%, $%.6f)\n",
+		a.DocumentTokens, float64(a.DocumentTokens)/float64(total)*100, a.DocumentCost,
+		a.PromptTokens, float64(a.PromptTokens)/float64(total)*100, a.PromptCost)
+}
@@ -0,0 +1,145 @@
+package pdfanalysis
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+)
+
+// FileData is the base64 data of a PDF or image file in message content.
+// The file is encoded while the request is sent, so a large document is
+// never held in memory, neither as bytes nor as a 1.37x base64 string.
+type FileData struct {
+	Path string
+}
+
+// MarshalJSON writes a marker naming the file; NewRequestBody replaces it
+// with the streamed base64 data
+func (d FileData) MarshalJSON() ([]byte, error) {
+	return json.Marshal("\x00file:" + hex.EncodeToString([]byte(d.Path)) + "\x00")
+}
+
+// fileMarkerPattern matches a marshaled FileData marker, quotes included
+var fileMarkerPattern = regexp.MustCompile(`"\\u0000file:([0-9a-f]*)\\u0000"`)
+
+// bodyPart is literal JSON or a file that is sent base64-encoded
+type bodyPart struct {
+	data []byte
+	path string
+}
+
+// requestBody is a JSON request whose PDF documents and images are streamed from disk
+type requestBody struct {
+	parts []bodyPart
+	size  int64
+}
+
+// NewRequestBody marshals v and splits it at its FileData markers. The files
+// must exist; they are read only when the body is sent.
+func NewRequestBody(v interface{}) (*requestBody, error) {
+	jsonData, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %v", err)
+	}
+
+	body := &requestBody{}
+	last := 0
+	for _, m := range fileMarkerPattern.FindAllSubmatchIndex(jsonData, -1) {
+		path, err := hex.DecodeString(string(jsonData[m[2]:m[3]]))
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling request: %v", err)
+		}
+		info, err := os.Stat(string(path))
+		if err != nil {
+			return nil, fmt.Errorf("error reading PDF chunk: %v", err)
+		}
+		// Keep the quotes around the data as literal JSON
+		body.add(bodyPart{data: jsonData[last : m[0]+1]})
+		body.add(bodyPart{path: string(path)})
+		body.size += int64(base64.StdEncoding.EncodedLen(int(info.Size())))
+		last = m[1] - 1
+	}
+	body.add(bodyPart{data: jsonData[last:]})
+	return body, nil
+}
+
+// NewRequest creates a POST request that streams the body
+func (b *requestBody) NewRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, b.Reader())
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = b.size
+	req.GetBody = func() (io.ReadCloser, error) { return b.Reader(), nil }
+	return req, nil
+}
+
+// add appends a part and counts literal JSON towards the size
+func (b *requestBody) add(part bodyPart) {
+	b.parts = append(b.parts, part)
+	b.size += int64(len(part.data))
+}
+
+// Reader returns a fresh reader over the whole body, so a request can be
+// sent again (http.Request.GetBody). Closing it stops the encoders.
+func (b *requestBody) Reader() io.ReadCloser {
+	r := &bodyReader{}
+	readers := make([]io.Reader, len(b.parts))
+	for i, part := range b.parts {
+		if part.path == "" {
+			readers[i] = bytes.NewReader(part.data)
+			continue
+		}
+		pr, pw := io.Pipe()
+		go encodeFile(part.path, pw)
+		readers[i] = pr
+		r.pipes = append(r.pipes, pr)
+	}
+	r.Reader = io.MultiReader(readers...)
+	return r
+}
+
+// bytes reads the whole body into memory, for -capture-dir
+func (b *requestBody) bytes() []byte {
+	r := b.Reader()
+	defer r.Close()
+	data, _ := io.ReadAll(r)
+	return data
+}
+
+// bodyReader reads a requestBody and closes its pipes when done
+type bodyReader struct {
+	io.Reader
+	pipes []*io.PipeReader
+}
+
+// Close ends the encoders of parts that were not read to the end
+func (r *bodyReader) Close() error {
+	for _, pipe := range r.pipes {
+		pipe.Close()
+	}
+	return nil
+}
+
+// encodeFile streams a file into w as base64
+func encodeFile(path string, w *io.PipeWriter) {
+	file, err := os.Open(path)
+	if err != nil {
+		w.CloseWithError(fmt.Errorf("error reading PDF chunk: %v", err))
+		return
+	}
+	defer file.Close()
+	enc := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err := io.Copy(enc, file); err != nil {
+		w.CloseWithError(err)
+		return
+	}
+	w.CloseWithError(enc.Close())
+}
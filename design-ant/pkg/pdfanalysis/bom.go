@@ -0,0 +1,155 @@
+package pdfanalysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// bomVariant is one spelling of a field value and the pages it appears on
+type bomVariant struct {
+	value string
+	pages []int
+}
+
+// bomField collects the distinct values of one BOM column for a part
+type bomField []bomVariant
+
+// add records value on page; spelling variants are folded together
+func (f *bomField) add(value string, page int) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	norm := normalizeBOMText(value)
+	for i, v := range *f {
+		if normalizeBOMText(v.value) == norm {
+			if v.pages[len(v.pages)-1] != page {
+				(*f)[i].pages = append(v.pages, page)
+			}
+			return
+		}
+	}
+	*f = append(*f, bomVariant{value: value, pages: []int{page}})
+}
+
+// first returns the first value seen, used as the master value
+func (f bomField) first() string {
+	if len(f) == 0 {
+		return ""
+	}
+	return f[0].value
+}
+
+// conflict describes differing values with their pages, or "" if they agree
+func (f bomField) conflict(name string) string {
+	if len(f) < 2 {
+		return ""
+	}
+	parts := make([]string, len(f))
+	for i, v := range f {
+		parts[i] = fmt.Sprintf("%q (%s)", v.value, pageRefs(v.pages))
+	}
+	return fmt.Sprintf("%s differs: %s", name, strings.Join(parts, " vs "))
+}
+
+// aggregateBOM merges the structured BOM rows of all pages into one master
+// BOM. Rows are deduplicated by part number (or by description when the row
+// has none), quantities are summed, and differing materials, finishes, or
+// descriptions are flagged for review.
+func aggregateBOM(chunks []ChunkAnalysis) []MasterBOMItem {
+	type part struct {
+		item                          MasterBOMItem
+		description, material, finish bomField
+	}
+	byKey := make(map[string]*part)
+	var order []*part
+
+	for _, chunk := range chunks {
+		for _, row := range chunk.BOMItems {
+			key := masterBOMKey(row)
+			if key == "" {
+				continue
+			}
+			p, ok := byKey[key]
+			if !ok {
+				p = &part{item: MasterBOMItem{PartNumber: strings.TrimSpace(row.PartNumber)}}
+				byKey[key] = p
+				order = append(order, p)
+			}
+			p.item.TotalQuantity += row.Quantity
+			p.item.Occurrences = append(p.item.Occurrences, BOMOccurrence{
+				Page:       chunk.StartPage,
+				ItemNumber: row.ItemNumber,
+				Quantity:   row.Quantity,
+			})
+			p.description.add(row.Description, chunk.StartPage)
+			p.material.add(row.Material, chunk.StartPage)
+			p.finish.add(row.Finish, chunk.StartPage)
+		}
+	}
+
+	items := make([]MasterBOMItem, 0, len(order))
+	for _, p := range order {
+		item := p.item
+		item.Description = p.description.first()
+		item.Material = p.material.first()
+		item.Finish = p.finish.first()
+		for _, conflict := range []string{p.material.conflict("material"), p.finish.conflict("finish"), p.description.conflict("description")} {
+			if conflict != "" {
+				item.Conflicts = append(item.Conflicts, conflict)
+			}
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return masterBOMSortKey(items[i]) < masterBOMSortKey(items[j])
+	})
+	return items
+}
+
+// masterBOMKey identifies a part across pages. Item numbers are page-local,
+// so rows without a part number fall back to their description.
+func masterBOMKey(row BOMItem) string {
+	if pn := strings.ToUpper(strings.TrimSpace(row.PartNumber)); pn != "" {
+		return "pn:" + pn
+	}
+	if desc := normalizeBOMText(row.Description); desc != "" {
+		return "desc:" + desc
+	}
+	return ""
+}
+
+// masterBOMSortKey orders parts by part number, then unnumbered parts by description
+func masterBOMSortKey(item MasterBOMItem) string {
+	if item.PartNumber != "" {
+		return "0" + strings.ToUpper(item.PartNumber)
+	}
+	return "1" + strings.ToUpper(item.Description)
+}
+
+// normalizeBOMText makes spelling variants compare equal ("BOLT, M6" == "Bolt M6")
+func normalizeBOMText(s string) string {
+	fields := strings.FieldsFunc(strings.ToUpper(s), func(r rune) bool {
+		return r == ' ' || r == ',' || r == ';' || r == '\t'
+	})
+	return strings.Join(fields, " ")
+}
+
+// markdownMasterBOM renders the master BOM as a markdown table
+func markdownMasterBOM(items []MasterBOMItem) string {
+	var b strings.Builder
+	b.WriteString("| Part number | Description | Total qty | Material | Finish | Pages | Review |\n")
+	b.WriteString("| --- | --- | --- | --- | --- | --- | --- |\n")
+	for _, item := range items {
+		pages := make([]string, 0, len(item.Occurrences))
+		for _, p := range item.Pages() {
+			pages = append(pages, fmt.Sprintf("%d", p))
+		}
+		fmt.Fprintf(&b, "| %s | %s | %g | %s | %s | %s | %s |\n",
+			EscapeInline(item.PartNumber), EscapeInline(item.Description), item.TotalQuantity,
+			EscapeInline(item.Material), EscapeInline(item.Finish), strings.Join(pages, ", "),
+			EscapeInline(strings.Join(item.Conflicts, "; ")))
+	}
+	return b.String()
+}
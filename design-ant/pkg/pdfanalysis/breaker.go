@@ -0,0 +1,125 @@
+package pdfanalysis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Circuit breaker settings shared by all providers
+const (
+	breakerThreshold   = 5                // Consecutive server-side failures that open the circuit
+	breakerCooldown    = 30 * time.Second // First pause; doubles while probes keep failing
+	breakerMaxCooldown = 5 * time.Minute
+	breakerPoll        = 500 * time.Millisecond // How often waiters check for the probe result
+)
+
+// Circuit states
+const (
+	circuitClosed   = "closed"    // Requests flow normally
+	circuitOpen     = "open"      // Requests wait for the cooldown
+	circuitHalfOpen = "half-open" // One probe request is testing the provider
+)
+
+// circuitBreaker stops all workers from hammering a provider that keeps
+// failing with 5xx, 529, or timeouts. After breakerThreshold consecutive
+// failures the circuit opens and every request waits for the cooldown; then
+// a single probe is let through, and its outcome closes the circuit or opens
+// it again for twice as long.
+type circuitBreaker struct {
+	name      string
+	mu        sync.Mutex
+	state     string
+	failures  int
+	cooldown  time.Duration
+	openUntil time.Time
+}
+
+// newCircuitBreaker creates a closed circuit for the named provider
+func newCircuitBreaker(name string) *circuitBreaker {
+	return &circuitBreaker{name: name, state: circuitClosed, cooldown: breakerCooldown}
+}
+
+// The circuits of the providers design-ant calls
+var (
+	anthropicBreaker = newCircuitBreaker("Anthropic API")
+	geminiBreaker    = newCircuitBreaker("Gemini API")
+)
+
+// Wait blocks while the circuit is open or another request is probing
+func (b *circuitBreaker) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		delay := breakerPoll
+		switch {
+		case b.state == circuitClosed:
+			b.mu.Unlock()
+			return nil
+		case b.state == circuitOpen && !time.Now().Before(b.openUntil):
+			// The cooldown is over and this request is the probe
+			b.state = circuitHalfOpen
+			b.mu.Unlock()
+			return nil
+		case b.state == circuitOpen:
+			delay = min(time.Until(b.openUntil), breakerPoll)
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Record updates the circuit with the outcome of a request of the run of
+// ctx, which reports the circuit opening or closing; err is nil for a
+// success. Client errors and rate limits count as successes, since the
+// provider answered.
+func (b *circuitBreaker) Record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		b.recordSuccess(ctx)
+		return
+	}
+	switch ClassifyError(err) {
+	case ErrorOverloaded, ErrorServer, ErrorTimeout, ErrorNetwork:
+	case ErrorCanceled:
+		if b.state == circuitHalfOpen {
+			// An interrupted probe proves nothing; let the next request probe
+			b.state, b.openUntil = circuitOpen, time.Now()
+		}
+		return
+	default:
+		b.recordSuccess(ctx)
+		return
+	}
+
+	b.failures++
+	switch {
+	case b.state == circuitHalfOpen:
+		b.cooldown = min(2*b.cooldown, breakerMaxCooldown)
+		b.open(ctx, fmt.Sprintf("still failing (%s)", ClassifyError(err)))
+	case b.state == circuitClosed && b.failures >= breakerThreshold:
+		b.open(ctx, fmt.Sprintf("%d consecutive failures, last: %s", b.failures, ClassifyError(err)))
+	}
+}
+
+// recordSuccess closes the circuit; mu must be held
+func (b *circuitBreaker) recordSuccess(ctx context.Context) {
+	if b.state != circuitClosed {
+		logf(ctx, "  🔌 %s recovered, resuming requests\n", b.name)
+	}
+	b.state, b.failures, b.cooldown = circuitClosed, 0, breakerCooldown
+}
+
+// open pauses all requests for the current cooldown; mu must be held
+func (b *circuitBreaker) open(ctx context.Context, reason string) {
+	b.state = circuitOpen
+	b.openUntil = time.Now().Add(b.cooldown)
+	logf(ctx, "  🔌 %s circuit open (%s), pausing requests for %v\n", b.name, reason, b.cooldown)
+}
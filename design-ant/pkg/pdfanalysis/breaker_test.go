@@ -0,0 +1,89 @@
+package pdfanalysis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// waitBriefly waits for the breaker, giving up after a short time
+func waitBriefly(b *circuitBreaker) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	return b.Wait(ctx)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	b := newCircuitBreaker("Test API")
+	serverErr := &APIError{StatusCode: http.StatusInternalServerError}
+
+	// Client errors and rate limits are answers, and reset the count
+	for i := 0; i < breakerThreshold-1; i++ {
+		b.Record(ctx, serverErr)
+	}
+	b.Record(ctx, &APIError{StatusCode: http.StatusTooManyRequests})
+	b.Record(ctx, &APIError{StatusCode: http.StatusBadRequest})
+	if b.state != circuitClosed || b.failures != 0 {
+		t.Fatalf("after a rate limit: %s with %d failures, want closed with 0", b.state, b.failures)
+	}
+
+	for i := 0; i < breakerThreshold; i++ {
+		if b.state != circuitClosed {
+			t.Fatalf("opened after %d failures, want %d", i, breakerThreshold)
+		}
+		b.Record(ctx, serverErr)
+	}
+	if b.state != circuitOpen {
+		t.Fatalf("state %s after %d failures, want open", b.state, breakerThreshold)
+	}
+	if err := waitBriefly(b); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait on an open circuit = %v, want a timeout", err)
+	}
+
+	// After the cooldown one probe goes through; the others keep waiting
+	b.openUntil = time.Now()
+	if err := waitBriefly(b); err != nil || b.state != circuitHalfOpen {
+		t.Fatalf("Wait after the cooldown = %v in state %s, want the probe in half-open", err, b.state)
+	}
+	if err := waitBriefly(b); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait during the probe = %v, want a timeout", err)
+	}
+
+	// A failed probe opens the circuit for twice as long
+	b.Record(ctx, serverErr)
+	if b.state != circuitOpen || b.cooldown != 2*breakerCooldown {
+		t.Fatalf("after a failed probe: %s for %v, want open for %v", b.state, b.cooldown, 2*breakerCooldown)
+	}
+
+	// An interrupted probe lets the next request probe at once
+	b.openUntil = time.Now()
+	if err := waitBriefly(b); err != nil {
+		t.Fatal(err)
+	}
+	b.Record(ctx, context.Canceled)
+	if b.state != circuitOpen || b.cooldown != 2*breakerCooldown {
+		t.Fatalf("after an interrupted probe: %s for %v, want open for %v", b.state, b.cooldown, 2*breakerCooldown)
+	}
+	if err := waitBriefly(b); err != nil || b.state != circuitHalfOpen {
+		t.Fatalf("Wait after an interrupted probe = %v in state %s, want a new probe", err, b.state)
+	}
+
+	// A successful probe closes the circuit and resets the cooldown
+	b.Record(ctx, nil)
+	if b.state != circuitClosed || b.failures != 0 || b.cooldown != breakerCooldown {
+		t.Fatalf("after a successful probe: %s, %d failures, cooldown %v", b.state, b.failures, b.cooldown)
+	}
+	if err := waitBriefly(b); err != nil {
+		t.Errorf("Wait on a closed circuit = %v", err)
+	}
+
+	// The cooldown doubles up to breakerMaxCooldown
+	b.state, b.cooldown = circuitHalfOpen, breakerMaxCooldown
+	b.Record(ctx, serverErr)
+	if b.cooldown != breakerMaxCooldown {
+		t.Errorf("cooldown %v, want at most %v", b.cooldown, breakerMaxCooldown)
+	}
+}
@@ -0,0 +1,120 @@
+package pdfanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// redactedHeaders are replaced before request/response headers are written to disk
+var redactedHeaders = map[string]bool{
+	"x-api-key":      true,
+	"authorization":  true,
+	"x-goog-api-key": true,
+}
+
+type captureKey struct{}
+
+// captureTarget identifies where the exchange of a single request is captured
+type captureTarget struct {
+	dir  string
+	name string
+}
+
+// ContextWithCapture returns a context that makes SendMessage write the exact request
+// and response to dir, using name as the file prefix
+func ContextWithCapture(ctx context.Context, dir, name string) context.Context {
+	if dir == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, captureKey{}, captureTarget{dir: dir, name: name})
+}
+
+// withCaptureSuffix gives follow-up turns of a conversation their own capture
+// files, e.g. chunk-003-repair-1; turn 0 keeps the original name
+func withCaptureSuffix(ctx context.Context, kind string, turn int) context.Context {
+	target, ok := ctx.Value(captureKey{}).(captureTarget)
+	if !ok || turn == 0 {
+		return ctx
+	}
+	return ContextWithCapture(ctx, target.dir, fmt.Sprintf("%s-%s-%d", target.name, kind, turn))
+}
+
+// capturedExchange is the on-disk format of a captured request or response
+type capturedExchange struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Method    string            `json:"method,omitempty"`
+	URL       string            `json:"url,omitempty"`
+	Status    int               `json:"status,omitempty"`
+	Headers   map[string]string `json:"headers"`
+	Body      json.RawMessage   `json:"body"`
+}
+
+// captureRequest writes the outgoing request if capture is enabled for ctx
+func captureRequest(ctx context.Context, req *http.Request, body *requestBody) {
+	target, ok := ctx.Value(captureKey{}).(captureTarget)
+	if !ok {
+		return
+	}
+	writeCapture(ctx, target, "request", capturedExchange{
+		Timestamp: time.Now(),
+		Method:    req.Method,
+		URL:       req.URL.String(),
+		Headers:   redactHeaders(req.Header),
+		Body:      rawJSON(body.bytes()),
+	})
+}
+
+// captureResponse writes the received response if capture is enabled for ctx
+func captureResponse(ctx context.Context, resp *http.Response, body []byte) {
+	target, ok := ctx.Value(captureKey{}).(captureTarget)
+	if !ok {
+		return
+	}
+	writeCapture(ctx, target, "response", capturedExchange{
+		Timestamp: time.Now(),
+		Status:    resp.StatusCode,
+		Headers:   redactHeaders(resp.Header),
+		Body:      rawJSON(body),
+	})
+}
+
+// writeCapture stores one side of an exchange; capture failures never fail the request
+func writeCapture(ctx context.Context, target captureTarget, kind string, exchange capturedExchange) {
+	data, err := json.MarshalIndent(exchange, "", "  ")
+	if err != nil {
+		logf(ctx, "  ⚠️  Could not encode %s capture for %s: %v\n", kind, target.name, err)
+		return
+	}
+	filename := filepath.Join(target.dir, fmt.Sprintf("%s.%s.json", target.name, kind))
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		logf(ctx, "  ⚠️  Could not write %s capture for %s: %v\n", kind, target.name, err)
+	}
+}
+
+// redactHeaders flattens headers and masks credentials
+func redactHeaders(header http.Header) map[string]string {
+	out := make(map[string]string, len(header))
+	for name, values := range header {
+		if redactedHeaders[strings.ToLower(name)] {
+			out[name] = "[REDACTED]"
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+// rawJSON keeps valid JSON bodies verbatim and wraps anything else as a string
+func rawJSON(body []byte) json.RawMessage {
+	if json.Valid(body) {
+		return body
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
@@ -0,0 +1,245 @@
+package pdfanalysis
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// calloutPattern finds fastener quantity callouts such as "8x M6", "8 × M6x20", or "4 off M8"
+var calloutPattern = regexp.MustCompile(`(?i)\b(\d{1,3})\s*(?:[x×]|off\b|pcs\.?)\s*(M\d{1,2})(?:\s*[x×]\s*\d+(?:\.\d+)?)?\b`)
+
+// checkConsistency cross-checks the structured data of all pages and returns
+// the discrepancies sorted by first page. It only flags candidates; deciding
+// which value is right is left to the reviewer.
+func checkConsistency(chunks []ChunkAnalysis, masterBOM []MasterBOMItem) []Discrepancy {
+	var found []Discrepancy
+	found = append(found, checkCallouts(chunks, masterBOM)...)
+	found = append(found, checkRevisions(chunks)...)
+	found = append(found, checkDimensions(chunks)...)
+	for _, item := range masterBOM {
+		for _, conflict := range item.Conflicts {
+			found = append(found, Discrepancy{
+				Kind:    "bom",
+				Pages:   item.Pages(),
+				Message: fmt.Sprintf("%s: %s", MasterBOMLabel(item), conflict),
+			})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Pages[0] < found[j].Pages[0]
+	})
+	return found
+}
+
+// checkCallouts compares quantity callouts in the analysis text and notes
+// ("8x M6") with the BOM rows for that size. A callout is flagged when no BOM
+// row and not the BOM total for the size matches its quantity.
+func checkCallouts(chunks []ChunkAnalysis, masterBOM []MasterBOMItem) []Discrepancy {
+	var found []Discrepancy
+	reported := make(map[string]bool)
+	for _, chunk := range chunks {
+		text := chunk.Analysis + "\n" + strings.Join(chunk.Notes, "\n")
+		for _, m := range calloutPattern.FindAllStringSubmatch(text, -1) {
+			quantity, _ := strconv.ParseFloat(m[1], 64)
+			size := strings.ToUpper(m[2])
+
+			sizePattern := regexp.MustCompile(`(?i)\b` + size + `(?:\b|[x×])`)
+			var matches []MasterBOMItem
+			for _, item := range masterBOM {
+				if sizePattern.MatchString(item.Description) || sizePattern.MatchString(item.PartNumber) {
+					matches = append(matches, item)
+				}
+			}
+			if len(matches) == 0 {
+				continue
+			}
+
+			var total float64
+			var listed []string
+			pages := []int{chunk.StartPage}
+			agrees := false
+			for _, item := range matches {
+				total += item.TotalQuantity
+				for _, o := range item.Occurrences {
+					agrees = agrees || o.Quantity == quantity
+					listed = append(listed, fmt.Sprintf("%g on p. %d", o.Quantity, o.Page))
+					pages = appendPage(pages, o.Page)
+				}
+			}
+			key := fmt.Sprintf("%d/%g/%s", chunk.StartPage, quantity, size)
+			if agrees || total == quantity || reported[key] {
+				continue
+			}
+			reported[key] = true
+			found = append(found, Discrepancy{
+				Kind:    "quantity",
+				Pages:   pages,
+				Message: fmt.Sprintf("p. %d calls out %q but the BOM lists %s for %s", chunk.StartPage, strings.TrimSpace(m[0]), strings.Join(listed, ", "), size),
+			})
+		}
+	}
+	return found
+}
+
+// checkRevisions flags drawing numbers that carry different revisions on different pages
+func checkRevisions(chunks []ChunkAnalysis) []Discrepancy {
+	type revision struct {
+		value string
+		pages []int
+	}
+	byDrawing := make(map[string][]revision)
+	var order []string
+	for _, chunk := range chunks {
+		m := chunk.Metadata
+		if m == nil || m.DrawingNumber == "" || m.Revision == "" {
+			continue
+		}
+		key := strings.ToUpper(strings.TrimSpace(m.DrawingNumber))
+		if _, ok := byDrawing[key]; !ok {
+			order = append(order, key)
+		}
+		revs := byDrawing[key]
+		i := 0
+		for i < len(revs) && !strings.EqualFold(revs[i].value, m.Revision) {
+			i++
+		}
+		if i == len(revs) {
+			revs = append(revs, revision{value: strings.TrimSpace(m.Revision)})
+		}
+		revs[i].pages = appendPage(revs[i].pages, chunk.StartPage)
+		byDrawing[key] = revs
+	}
+
+	var found []Discrepancy
+	for _, drawing := range order {
+		revs := byDrawing[drawing]
+		if len(revs) < 2 {
+			continue
+		}
+		var parts []string
+		var pages []int
+		for _, r := range revs {
+			parts = append(parts, fmt.Sprintf("rev %s (%s)", r.value, pageRefs(r.pages)))
+			for _, p := range r.pages {
+				pages = appendPage(pages, p)
+			}
+		}
+		sort.Ints(pages)
+		found = append(found, Discrepancy{
+			Kind:    "revision",
+			Pages:   pages,
+			Message: fmt.Sprintf("Drawing %s appears with different revisions: %s", drawing, strings.Join(parts, " vs ")),
+		})
+	}
+	return found
+}
+
+// checkDimensions flags features of the same drawing (multi-sheet drawings
+// share a drawing number) that are dimensioned differently on different sheets
+func checkDimensions(chunks []ChunkAnalysis) []Discrepancy {
+	type value struct {
+		text  string
+		pages []int
+	}
+	values := make(map[string][]value)
+	labels := make(map[string]string)
+	var order []string
+	for _, chunk := range chunks {
+		if chunk.Metadata == nil || chunk.Metadata.DrawingNumber == "" {
+			continue
+		}
+		drawing := strings.ToUpper(strings.TrimSpace(chunk.Metadata.DrawingNumber))
+		for _, d := range chunk.Dimensions {
+			if d.Feature == "" {
+				continue
+			}
+			key := drawing + "\x00" + normalizeBOMText(d.Feature) + "\x00" + strings.ToLower(d.Type)
+			text := strings.TrimSpace(strings.Join(strings.Fields(d.Value+" "+d.Unit+" "+d.Tolerance), " "))
+			if d.Normalized != nil {
+				// 1.000 in and 25.4 mm are the same dimension
+				text = d.Normalized.String()
+			}
+			if _, ok := values[key]; !ok {
+				order = append(order, key)
+				labels[key] = strings.TrimSpace(d.Feature)
+			}
+			vals := values[key]
+			i := 0
+			for i < len(vals) && vals[i].text != text {
+				i++
+			}
+			if i == len(vals) {
+				vals = append(vals, value{text: text})
+			}
+			vals[i].pages = appendPage(vals[i].pages, chunk.StartPage)
+			values[key] = vals
+		}
+	}
+
+	var found []Discrepancy
+	for _, key := range order {
+		vals := values[key]
+		if len(vals) < 2 {
+			continue
+		}
+		parts := strings.Split(key, "\x00")
+		var desc []string
+		var pages []int
+		for _, v := range vals {
+			desc = append(desc, fmt.Sprintf("%q (%s)", v.text, pageRefs(v.pages)))
+			for _, p := range v.pages {
+				pages = appendPage(pages, p)
+			}
+		}
+		sort.Ints(pages)
+		feature := labels[key]
+		if parts[2] != "" {
+			feature += " (" + parts[2] + ")"
+		}
+		found = append(found, Discrepancy{
+			Kind:    "dimension",
+			Pages:   pages,
+			Message: fmt.Sprintf("Drawing %s: %s is dimensioned differently: %s", parts[0], feature, strings.Join(desc, " vs ")),
+		})
+	}
+	return found
+}
+
+// MasterBOMLabel names a master BOM part for messages
+func MasterBOMLabel(item MasterBOMItem) string {
+	if item.PartNumber != "" {
+		return item.PartNumber
+	}
+	return item.Description
+}
+
+// appendPage adds page if it is not already present
+func appendPage(pages []int, page int) []int {
+	for _, p := range pages {
+		if p == page {
+			return pages
+		}
+	}
+	return append(pages, page)
+}
+
+// pageRefs formats pages as "p. 1, p. 4"
+func pageRefs(pages []int) string {
+	refs := make([]string, len(pages))
+	for i, p := range pages {
+		refs[i] = fmt.Sprintf("p. %d", p)
+	}
+	return strings.Join(refs, ", ")
+}
+
+// markdownDiscrepancies renders the discrepancies as a review checklist
+func markdownDiscrepancies(found []Discrepancy) string {
+	var b strings.Builder
+	for _, d := range found {
+		fmt.Fprintf(&b, "- [ ] **%s** (%s): %s\n", d.Kind, pageRefs(d.Pages), EscapeInline(d.Message))
+	}
+	return b.String()
+}
@@ -0,0 +1,114 @@
+package pdfanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Page types assigned by the -two-stage classification call
+const (
+	PageTypeAssembly  = "assembly"  // Assembly or exploded view, usually with a parts list
+	PageTypePart      = "part"      // Detail drawing of a single part
+	PageTypeBOM       = "bom"       // Parts list or BOM table without a drawing
+	PageTypeSchematic = "schematic" // Electrical, hydraulic, or pneumatic schematic
+	PageTypeText      = "text"      // Specifications, notes, or other running text
+	PageTypeOther     = "other"     // Cover sheets, indexes, and anything else
+)
+
+// pageTypeFocus specializes the extraction prompt for each page type
+var pageTypeFocus = map[string]string{
+	PageTypeAssembly: `- This is an assembly drawing: list EVERY balloon/item number with its part, quantity, and material
+- Describe the assembly sequence, fastening methods, and torque values
+- Capture fits and tolerances between mating parts`,
+	PageTypePart: `- This is a part drawing: extract EVERY dimension with its tolerance, including GD&T frames and datums
+- Capture threads, chamfers, radii, surface finishes, and heat treatment exactly
+- The BOM section is usually empty; do not invent parts`,
+	PageTypeBOM: `- This page is a parts list: extract EVERY row of every table, in order, with all columns
+- Keep item numbers, part numbers, quantities, materials, and finishes exactly as written
+- The DIMENSIONS and DRAWINGS sections are usually empty`,
+	PageTypeSchematic: `- This is a schematic: list EVERY component designator with its value or rating
+- Describe connections, signal or fluid paths, and connector pinouts
+- Record wire gauges, pressure ratings, and cable or hose specifications`,
+	PageTypeText: `- This page is mostly text: quote EVERY requirement, note, and specification verbatim
+- Keep numbered clauses and their numbering
+- The BOM, DIMENSIONS, and DRAWINGS sections are usually empty`,
+}
+
+// classificationPrompt asks for the page type and a short summary as JSON
+const classificationPrompt = `Classify this engineering document page. Reply with ONLY one JSON object, no other text:
+{"type": "<assembly|part|bom|schematic|text|other>", "summary": "<one sentence: what the page shows>"}
+
+Types:
+- assembly: assembly or exploded view, usually with a parts list
+- part: detail drawing of a single part with dimensions
+- bom: parts list or BOM table without a drawing
+- schematic: electrical, hydraulic, or pneumatic schematic
+- text: specifications, notes, or other running text
+- other: cover sheet, index, or anything else`
+
+// classifyPage runs the first -two-stage call with config.ClassifyModel. On
+// failure the returned classification has type "other", which selects the
+// generic prompt, and still carries the tokens of the call.
+func classifyPage(ctx context.Context, config *Config, route PageRoute, path string, pageNumber int) *PageClassification {
+	result := &PageClassification{Type: PageTypeOther, Model: config.ClassifyModel}
+
+	var content []map[string]interface{}
+	if route.Mode == InputModeText {
+		content = textPageContent(route.Text, pageNumber, classificationPrompt)
+	} else {
+		var err error
+		if content, err = ChunkContent(path, classificationPrompt); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+	classifyConfig := *config
+	classifyConfig.ModelName = config.ClassifyModel
+	text, inputTokens, outputTokens, err := SendContentWithRetry(ctx, &classifyConfig, content)
+
+	pricing := GetPricing(config.ClassifyModel)
+	result.InputTokens, result.OutputTokens = inputTokens, outputTokens
+	result.TotalCost = float64(inputTokens)/1_000_000*pricing.InputPricePerMTokens +
+		float64(outputTokens)/1_000_000*pricing.OutputPricePerMTokens
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	var parsed struct {
+		Type    string `json:"type"`
+		Summary string `json:"summary"`
+	}
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		result.Error = "no JSON object in classification response"
+		return result
+	}
+	if err := json.Unmarshal([]byte(text[start:end+1]), &parsed); err != nil {
+		result.Error = fmt.Sprintf("invalid classification response: %v", err)
+		return result
+	}
+	result.Summary = strings.TrimSpace(parsed.Summary)
+	if pageType := strings.ToLower(strings.TrimSpace(parsed.Type)); pageType == PageTypeOther || pageTypeFocus[pageType] != "" {
+		result.Type = pageType
+	} else {
+		result.Error = fmt.Sprintf("unknown page type %q", parsed.Type)
+	}
+	return result
+}
+
+// classificationInstructions is the prompt section for a classified page;
+// pages of type "other" get the generic prompt
+func classificationInstructions(c *PageClassification) string {
+	focus, ok := pageTypeFocus[c.Type]
+	if !ok {
+		return ""
+	}
+	summary := ""
+	if c.Summary != "" {
+		summary = fmt.Sprintf(" A first look summarized it as: %q", c.Summary)
+	}
+	return fmt.Sprintf("PAGE TYPE: %s.%s\nFOCUS:\n%s", c.Type, summary, focus)
+}
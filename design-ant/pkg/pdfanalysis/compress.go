@@ -0,0 +1,139 @@
+package pdfanalysis
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression formats for result files
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// CompressionSuffix returns the file extension appended for a compression format
+func CompressionSuffix(compression string) string {
+	switch compression {
+	case CompressionGzip:
+		return ".gz"
+	case CompressionZstd:
+		return ".zst"
+	}
+	return ""
+}
+
+// writeFileCompressed writes data to filename, compressing it according to
+// the file extension (.gz or .zst). The data goes to a temporary file that
+// replaces filename once complete and synced to disk, so a crash or power
+// loss never leaves a truncated result.
+func writeFileCompressed(filename string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if err := encodeCompressed(file, filename, data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err := os.Rename(file.Name(), filename); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return syncDir(filepath.Dir(filename))
+}
+
+// syncDir flushes a directory, so a file renamed into it survives a crash
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && runtime.GOOS != "windows" {
+		return err
+	}
+	return nil
+}
+
+// encodeCompressed writes data to w with the compression filename asks for
+func encodeCompressed(w io.Writer, filename string, data []byte) error {
+	var enc io.WriteCloser
+	switch {
+	case strings.HasSuffix(filename, ".gz"):
+		enc = gzip.NewWriter(w)
+	case strings.HasSuffix(filename, ".zst"):
+		var err error
+		if enc, err = zstd.NewWriter(w); err != nil {
+			return err
+		}
+	default:
+		_, err := w.Write(data)
+		return err
+	}
+
+	if _, err := enc.Write(data); err != nil {
+		enc.Close()
+		return err
+	}
+	return enc.Close()
+}
+
+// readFileDecompressed reads filename, transparently decompressing gzip or
+// zstd content detected by its magic bytes
+func readFileDecompressed(filename string) ([]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	br := bufio.NewReader(file)
+	header, _ := br.Peek(4)
+
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("error opening gzip stream: %v", err)
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	case bytes.HasPrefix(header, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("error opening zstd stream: %v", err)
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	}
+	return io.ReadAll(br)
+}
@@ -0,0 +1,91 @@
+package pdfanalysis
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// embeddedPricing is the default pricing file, compiled into the binary
+//
+//go:embed pricing.json
+var embeddedPricing []byte
+
+// ModelPricing holds pricing information for different Anthropic models,
+// read from pricing.json and any -pricing-file
+var ModelPricing = mustParsePricing(embeddedPricing, "embedded")
+
+// mustParsePricing parses the embedded pricing file, which is checked at build time
+func mustParsePricing(data []byte, source string) *PricingTable {
+	table, err := parsePricing(data, source)
+	if err != nil {
+		panic(err)
+	}
+	return table
+}
+
+// parsePricing parses a pricing file and checks that its prices are usable
+func parsePricing(data []byte, source string) (*PricingTable, error) {
+	var table PricingTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("error parsing pricing file %s: %v", source, err)
+	}
+	for model, pricing := range table.Models {
+		if pricing.InputPricePerMTokens < 0 || pricing.OutputPricePerMTokens < 0 {
+			return nil, fmt.Errorf("%s: negative price for %s", source, model)
+		}
+	}
+	table.Source = source
+	return &table, nil
+}
+
+// LoadPricingFile overrides the embedded prices with a pricing file. Models
+// in the file replace or add to the embedded ones, so a file only needs the
+// prices that changed; its default_model and updated fields, when set,
+// replace the embedded ones too.
+func LoadPricingFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading pricing file: %v", err)
+	}
+	override, err := parsePricing(data, path)
+	if err != nil {
+		return err
+	}
+
+	table := mustParsePricing(embeddedPricing, path)
+	for model, pricing := range override.Models {
+		table.Models[model] = pricing
+	}
+	if override.DefaultModel != "" {
+		table.DefaultModel = override.DefaultModel
+	}
+	if override.Updated != "" {
+		table.Updated = override.Updated
+	}
+	if _, ok := table.Models[table.DefaultModel]; !ok {
+		return fmt.Errorf("%s: default_model %s has no prices", path, table.DefaultModel)
+	}
+	ModelPricing = table
+	return nil
+}
+
+// GetPricing returns pricing for a given model name
+func GetPricing(modelName string) AnthropicPricing {
+	if pricing, ok := ModelPricing.Models[modelName]; ok {
+		return pricing
+	}
+	// Default to the table's default model (Haiku) if model not found
+	return ModelPricing.Models[ModelPricing.DefaultModel]
+}
+
+// PricingSnapshot returns a copy of the prices in effect, for recording in a result
+func PricingSnapshot() *PricingTable {
+	snapshot := *ModelPricing
+	snapshot.Models = make(map[string]AnthropicPricing, len(ModelPricing.Models))
+	for model, pricing := range ModelPricing.Models {
+		snapshot.Models[model] = pricing
+	}
+	return &snapshot
+}
@@ -0,0 +1,293 @@
+package pdfanalysis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"design-ant/internal/tempfiles"
+)
+
+// ConsolidateBudgetChars caps the page text sent in one consolidation call
+// (roughly 75k tokens). Larger documents are reduced in groups first.
+const ConsolidateBudgetChars = 300_000
+
+// maxConcurrentReduce limits parallel group summary requests, like page analysis
+const maxConcurrentReduce = 4
+
+// consolidationSection is one page range of text fed into a reduce step
+type consolidationSection struct {
+	startPage, endPage int
+	text               string
+}
+
+// label names the section's pages for prompts and console output
+func (s consolidationSection) label() string {
+	if s.startPage == s.endPage {
+		return fmt.Sprintf("Page %d", s.startPage)
+	}
+	return fmt.Sprintf("Pages %d-%d", s.startPage, s.endPage)
+}
+
+// consolidate runs the second-stage pass over the per-page analyses. When all
+// pages fit in one request they are summarized directly. Otherwise consecutive
+// sections are summarized in groups (map) and the group summaries are reduced
+// again until they fit (reduce). config.FanIn additionally caps the sections
+// per group, which gives a fixed hierarchy for very large documents (pages →
+// groups of 10 → groups of 100 → digest). Every intermediate level is kept
+// in the result. On failure the partial result is returned with the error.
+func consolidate(ctx context.Context, config *Config, chunks []ChunkAnalysis) (*ConsolidatedAnalysis, error) {
+	start := time.Now()
+	var sections []consolidationSection
+	for _, chunk := range chunks {
+		if chunk.Error != "" || strings.TrimSpace(chunk.Analysis) == "" {
+			continue
+		}
+		sections = append(sections, consolidationSection{
+			startPage: chunk.StartPage,
+			endPage:   chunk.EndPage,
+			text:      chunk.Analysis,
+		})
+	}
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("no successful page analyses to consolidate")
+	}
+
+	result := &ConsolidatedAnalysis{}
+	pricing := GetPricing(config.ModelName)
+	account := func(inputTokens, outputTokens int) float64 {
+		inputCost := float64(inputTokens) / 1_000_000 * pricing.InputPricePerMTokens
+		outputCost := float64(outputTokens) / 1_000_000 * pricing.OutputPricePerMTokens
+		result.InputTokens += inputTokens
+		result.OutputTokens += outputTokens
+		result.InputCost += inputCost
+		result.OutputCost += outputCost
+		return inputCost + outputCost
+	}
+	// Failed runs keep their partial token accounting so the run cost stays accurate
+	fail := func(err error) (*ConsolidatedAnalysis, error) {
+		result.Error = err.Error()
+		result.TotalCost = result.InputCost + result.OutputCost
+		result.ProcessingTime = time.Since(start).String()
+		result.Timestamp = time.Now()
+		return result, err
+	}
+
+	for level := 1; needsReduce(sections, config.FanIn); level++ {
+		groups := groupSections(sections, ConsolidateBudgetChars, config.FanIn)
+		if len(groups) == len(sections) && level > 1 {
+			return fail(fmt.Errorf("group summaries do not shrink below the consolidation budget"))
+		}
+		logf(ctx, "  🔄 Reduce level %d: summarizing %d sections in %d groups...\n", level, len(sections), len(groups))
+
+		summaries, err := summarizeGroups(ctx, config, groups)
+		reduceLevel := ReduceLevel{Level: level}
+		var reduced []consolidationSection
+		for _, summary := range summaries {
+			summary.TotalCost = account(summary.InputTokens, summary.OutputTokens)
+			if summary.Summary == "" {
+				continue
+			}
+			reduceLevel.Summaries = append(reduceLevel.Summaries, summary)
+			reduced = append(reduced, consolidationSection{startPage: summary.StartPage, endPage: summary.EndPage, text: summary.Summary})
+		}
+		result.Levels = append(result.Levels, reduceLevel)
+		if err != nil {
+			return fail(err)
+		}
+		sections = reduced
+	}
+
+	logf(ctx, "  🔄 Consolidating %d sections into the document summary...\n", len(sections))
+	prompt := consolidationPrompt(sections)
+	if style := styleInstructions(config); style != "" {
+		prompt += "\n\n" + style
+	}
+	if config.OutputLang != "" {
+		prompt += "\n\n" + OutputLanguageInstructions(config.OutputLang)
+	}
+	text, inputTokens, outputTokens, err := SendWithRetry(ctx, config, prompt)
+	account(inputTokens, outputTokens)
+	if err != nil {
+		return fail(fmt.Errorf("error consolidating: %v", err))
+	}
+
+	result.Analysis = text
+	result.TotalCost = result.InputCost + result.OutputCost
+	result.ProcessingTime = time.Since(start).String()
+	result.Timestamp = time.Now()
+	return result, nil
+}
+
+// needsReduce reports whether the sections must be grouped before the final call
+func needsReduce(sections []consolidationSection, fanIn int) bool {
+	return sectionsLength(sections) > ConsolidateBudgetChars || (fanIn > 1 && len(sections) > fanIn)
+}
+
+// summarizeGroups summarizes all groups of one level concurrently. Summaries
+// are returned in group order; failed groups have an empty Summary and the
+// first error is returned.
+func summarizeGroups(ctx context.Context, config *Config, groups [][]consolidationSection) ([]GroupSummary, error) {
+	summaries := make([]GroupSummary, len(groups))
+	errs := make([]error, len(groups))
+	semaphore := make(chan struct{}, maxConcurrentReduce)
+	var wg sync.WaitGroup
+	for i, group := range groups {
+		wg.Add(1)
+		go func(index int, group []consolidationSection) {
+			defer tempfiles.CleanupOnPanic()
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			first, last := group[0], group[len(group)-1]
+			span := consolidationSection{startPage: first.startPage, endPage: last.endPage}
+			text, inputTokens, outputTokens, err := SendWithRetry(ctx, config, groupSummaryPrompt(span.label(), group))
+			summaries[index] = GroupSummary{
+				StartPage:    span.startPage,
+				EndPage:      span.endPage,
+				InputTokens:  inputTokens,
+				OutputTokens: outputTokens,
+			}
+			if err != nil {
+				errs[index] = fmt.Errorf("error summarizing %s: %v", span.label(), err)
+				return
+			}
+			summaries[index].Summary = text
+			pagef(ctx, "  ✅ Summarized %s\n", span.label())
+		}(i, group)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return summaries, err
+		}
+	}
+	return summaries, nil
+}
+
+// SendWithRetry sends a text-only prompt, retrying failed requests with the
+// policy of their error class like the page analysis queue
+func SendWithRetry(ctx context.Context, config *Config, prompt string) (string, int, int, error) {
+	content := []map[string]interface{}{
+		{
+			"type": "text",
+			"text": prompt,
+		},
+	}
+	return SendContentWithRetry(ctx, config, content)
+}
+
+// SendContentWithRetry sends message content with the same retries. The
+// token counts are those of all attempts, failed ones included.
+func SendContentWithRetry(ctx context.Context, config *Config, content []map[string]interface{}) (string, int, int, error) {
+	spentInput, spentOutput := 0, 0
+	for attempt := 1; ; attempt++ {
+		text, inputTokens, outputTokens, err := sendAttempt(ctx, config, content)
+		spentInput += inputTokens
+		spentOutput += outputTokens
+		if err == nil {
+			return text, spentInput, spentOutput, nil
+		}
+		waitTime, retry := RetryDelay(err, attempt)
+		if !retry {
+			return text, spentInput, spentOutput, err
+		}
+		logf(ctx, "  ⚠️  Request failed (%s), retrying in %v...\n", ClassifyError(err), waitTime.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return "", spentInput, spentOutput, ctx.Err()
+		case <-time.After(waitTime):
+		}
+	}
+}
+
+// sendAttempt sends one attempt of SendContentWithRetry within -chunk-timeout
+func sendAttempt(ctx context.Context, config *Config, content []map[string]interface{}) (string, int, int, error) {
+	if config.ChunkTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.ChunkTimeout)
+		defer cancel()
+	}
+	return SendMessage(ctx, config.APIKey, config.ModelName, content)
+}
+
+// groupSections splits sections into consecutive groups that fit the budget
+// and hold at most fanIn sections (fanIn <= 1 means no count limit). A single
+// oversized section forms its own group.
+func groupSections(sections []consolidationSection, budget, fanIn int) [][]consolidationSection {
+	var groups [][]consolidationSection
+	var current []consolidationSection
+	size := 0
+	for _, s := range sections {
+		full := fanIn > 1 && len(current) == fanIn
+		if len(current) > 0 && (full || size+len(s.text) > budget) {
+			groups = append(groups, current)
+			current, size = nil, 0
+		}
+		current = append(current, s)
+		size += len(s.text)
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+	return groups
+}
+
+// sectionsLength is the total text size of the sections
+func sectionsLength(sections []consolidationSection) int {
+	n := 0
+	for _, s := range sections {
+		n += len(s.text)
+	}
+	return n
+}
+
+// formatSections joins sections with their labels for a prompt
+func formatSections(sections []consolidationSection) string {
+	var b strings.Builder
+	for _, s := range sections {
+		fmt.Fprintf(&b, "<section label=%q>\n%s\n</section>\n\n", s.label(), strings.TrimSpace(s.text))
+	}
+	return b.String()
+}
+
+// groupSummaryPrompt asks for a condensed but lossless-as-possible summary of a group
+func groupSummaryPrompt(label string, group []consolidationSection) string {
+	return fmt.Sprintf(`The following are page-by-page analyses of an engineering drawing package (%s).
+Condense them into one summary that a later step will merge with summaries of the other pages.
+
+RULES:
+- Keep every part number, drawing number, revision, material code, and standard exactly as written
+- Keep BOM quantities and key dimensions with units and tolerances
+- Note which page each drawing or assembly comes from
+- Drop repeated boilerplate (title block layout, general notes repeated on every page)
+- Start directly with the summary, no introductory phrases
+
+%s`, label, formatSections(group))
+}
+
+// consolidationPrompt asks for the whole-document summary
+func consolidationPrompt(sections []consolidationSection) string {
+	return fmt.Sprintf(`The following are analyses of all pages of an engineering drawing package.
+Write one coherent whole-document summary.
+
+OUTPUT FORMAT - START DIRECTLY (NO INTRODUCTORY PHRASES):
+# Document Summary
+
+1. **OVERVIEW**: What the document describes, product/assembly name, drawing numbers and revisions
+2. **STRUCTURE**: The assemblies and sub-assemblies and the pages they are on
+3. **MASTER BOM**: Every part number with description, total quantity, material, and pages
+4. **KEY SPECIFICATIONS**: Critical dimensions, tolerances, materials, finishes, standards
+5. **NOTES**: Manufacturing, quality, and inspection requirements that apply to the whole document
+6. **OPEN QUESTIONS**: Inconsistencies between pages or information that appears to be missing
+
+RULES:
+- Use exact values and codes from the analyses - no approximations
+- Reference pages as "p. N"
+
+%s`, formatSections(sections))
+}
@@ -0,0 +1,74 @@
+package pdfanalysis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// officeExtensions lists document types that are converted to PDF before analysis
+var officeExtensions = map[string]bool{
+	".doc":  true,
+	".docx": true,
+	".odt":  true,
+	".rtf":  true,
+	".ppt":  true,
+	".pptx": true,
+	".odp":  true,
+}
+
+// IsOfficeDocument reports whether the file needs conversion to PDF first
+func IsOfficeDocument(path string) bool {
+	return officeExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// findOfficeConverter locates the LibreOffice binary used for headless conversion.
+// LIBREOFFICE_PATH takes precedence over the binaries found on PATH.
+func findOfficeConverter() (string, error) {
+	if path := os.Getenv("LIBREOFFICE_PATH"); path != "" {
+		return path, nil
+	}
+	for _, name := range []string{"soffice", "libreoffice"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("LibreOffice not found: install it or set LIBREOFFICE_PATH to the soffice binary")
+}
+
+// ConvertToPDF converts an Office document to PDF using LibreOffice headless
+// and returns the path of the generated PDF inside outDir
+func ConvertToPDF(ctx context.Context, inputPath, outDir string) (string, error) {
+	converter, err := findOfficeConverter()
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return "", fmt.Errorf("error creating conversion directory: %v", err)
+	}
+
+	// A private profile directory avoids clashing with a running LibreOffice instance
+	profileDir := filepath.Join(outDir, "lo-profile")
+	cmd := exec.CommandContext(ctx, converter,
+		"-env:UserInstallation=file://"+filepath.ToSlash(profileDir),
+		"--headless",
+		"--convert-to", "pdf",
+		"--outdir", outDir,
+		inputPath,
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error converting %s to PDF: %v\n%s", filepath.Base(inputPath), err, strings.TrimSpace(string(output)))
+	}
+
+	base := filepath.Base(inputPath)
+	pdfPath := filepath.Join(outDir, strings.TrimSuffix(base, filepath.Ext(base))+".pdf")
+	if _, err := os.Stat(pdfPath); err != nil {
+		return "", fmt.Errorf("converted PDF not found at %s: %s", pdfPath, strings.TrimSpace(string(output)))
+	}
+	return pdfPath, nil
+}
@@ -0,0 +1,117 @@
+package pdfanalysis
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// costRow is one line of the per-page cost table shared by all renderers
+type costRow struct {
+	Label        string
+	InputTokens  int
+	OutputTokens int
+	Cost         float64
+	Duration     string
+	Retries      int
+	Failed       bool
+}
+
+// costTable is the per-page breakdown plus run totals
+type costTable struct {
+	Rows  []costRow
+	Total costRow
+}
+
+// costBreakdown builds the cost table for a result. The total row uses the
+// result totals and the wall-clock run time, since pages run concurrently.
+func costBreakdown(result FullAnalysisResult) costTable {
+	var table costTable
+	for _, chunk := range result.Chunks {
+		table.Rows = append(table.Rows, costRow{
+			Label:        chunkTitle(chunk),
+			InputTokens:  chunk.InputTokens,
+			OutputTokens: chunk.OutputTokens,
+			Cost:         chunk.TotalCost,
+			Duration:     chunk.ProcessingTime,
+			Retries:      chunk.Retries,
+			Failed:       chunk.Error != "",
+		})
+		table.Total.Retries += chunk.Retries
+	}
+	if c := result.Consolidated; c != nil {
+		table.Rows = append(table.Rows, costRow{
+			Label:        "Summary",
+			InputTokens:  c.InputTokens,
+			OutputTokens: c.OutputTokens,
+			Cost:         c.TotalCost,
+			Duration:     c.ProcessingTime,
+			Failed:       c.Error != "",
+		})
+	}
+	if s := result.ExecutiveSummary; s != nil {
+		table.Rows = append(table.Rows, costRow{
+			Label:        "Exec summary",
+			InputTokens:  s.InputTokens,
+			OutputTokens: s.OutputTokens,
+			Cost:         s.TotalCost,
+			Duration:     s.ProcessingTime,
+			Failed:       s.Error != "",
+		})
+	}
+	table.Total.Label = "Total"
+	table.Total.InputTokens = result.TotalInputTokens
+	table.Total.OutputTokens = result.TotalOutputTokens
+	table.Total.Cost = result.TotalCost
+	table.Total.Duration = result.ProcessingTime
+	return table
+}
+
+// WriteCostTable writes the per-page cost table to w, as the command prints it
+func WriteCostTable(w io.Writer, result FullAnalysisResult) {
+	table := costBreakdown(result)
+	line := func(r costRow) {
+		status := ""
+		if r.Failed {
+			status = "FAILED"
+		}
+		fmt.Fprintln(w, strings.TrimRight(fmt.Sprintf("%-16s %12d %12d %12s %14s %7d  %s",
+			Truncate(r.Label, 16), r.InputTokens, r.OutputTokens, fmt.Sprintf("$%.6f", r.Cost), r.Duration, r.Retries, status), " "))
+	}
+
+	fmt.Fprintln(w, "COST BREAKDOWN:")
+	fmt.Fprintf(w, "%-16s %12s %12s %12s %14s %7s\n", "SECTION", "INPUT", "OUTPUT", "COST", "DURATION", "RETRIES")
+	fmt.Fprintln(w, strings.Repeat("-", 78))
+	for _, r := range table.Rows {
+		line(r)
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 78))
+	line(table.Total)
+}
+
+// markdownCostTable renders the cost table as a markdown table
+func markdownCostTable(result FullAnalysisResult) string {
+	table := costBreakdown(result)
+	var b strings.Builder
+	b.WriteString("| Section | Input tokens | Output tokens | Cost | Duration | Retries |\n")
+	b.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+	for _, r := range table.Rows {
+		label := r.Label
+		if r.Failed {
+			label += " (failed)"
+		}
+		fmt.Fprintf(&b, "| %s | %d | %d | $%.6f | %s | %d |\n", label, r.InputTokens, r.OutputTokens, r.Cost, r.Duration, r.Retries)
+	}
+	t := table.Total
+	fmt.Fprintf(&b, "| **%s** | **%d** | **%d** | **$%.6f** | **%s** | **%d** |\n", t.Label, t.InputTokens, t.OutputTokens, t.Cost, t.Duration, t.Retries)
+	return b.String()
+}
+
+// Truncate shortens s to at most n characters for table output
+func Truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
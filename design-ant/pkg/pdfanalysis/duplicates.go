@@ -0,0 +1,71 @@
+package pdfanalysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+)
+
+// contentKey identifies a chunk's content for in-run deduplication: the page
+// fingerprint when there is one, otherwise a hash of the chunk file. Chunks
+// that cannot be read get no key and are never deduplicated.
+func contentKey(job *pageJob) string {
+	if job.pageHash != "" {
+		return job.pageHash
+	}
+	file, err := os.Open(job.chunk.Path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// duplicateResult copies the analysis of an identical page for a duplicate
+// job. The copy is deep, so later passes that modify one page in place
+// (such as unit normalization) never touch the other.
+func duplicateResult(original ChunkAnalysis, job *pageJob) ChunkAnalysis {
+	var chunk ChunkAnalysis
+	data, err := json.Marshal(original)
+	if err == nil {
+		err = json.Unmarshal(data, &chunk)
+	}
+	if err != nil {
+		chunk = original
+	}
+	page := job.chunk.StartPage + 1
+	chunk.Analysis = renumberPageHeading(chunk.Analysis, original.StartPage, page)
+	chunk.ChunkNumber = job.index + 1
+	chunk.StartPage, chunk.EndPage = page, job.chunk.EndPage+1
+	markCopied(&chunk)
+	chunk.DuplicateOf = original.StartPage
+	chunk.ProcessingTime = time.Duration(0).String()
+	chunk.Timestamp = time.Now()
+	return chunk
+}
+
+// duplicateSavings counts the pages copied from an identical page of the
+// same run and what analyzing them again would have cost
+func duplicateSavings(chunks []ChunkAnalysis) (int, float64) {
+	costs := make(map[int]float64)
+	for _, chunk := range chunks {
+		if chunk.DuplicateOf == 0 {
+			costs[chunk.StartPage] = chunk.TotalCost + chunk.SavedCost // A cached original saved its cost too
+		}
+	}
+	count, saved := 0, 0.0
+	for _, chunk := range chunks {
+		if chunk.DuplicateOf != 0 {
+			count++
+			saved += costs[chunk.DuplicateOf]
+		}
+	}
+	return count, saved
+}
@@ -0,0 +1,33 @@
+package pdfanalysis
+
+import (
+	"errors"
+
+	"design-ant/pkg/results"
+)
+
+// Errors of the pipeline, the providers, and the result stores, for errors.Is. A rate-limited
+// or oversized request is an *APIError, which also carries the status code
+// and the server's retry-after; a refused or blocked page is a
+// *ResponseError with the provider's reason.
+var (
+	ErrRateLimited     = results.ErrRateLimited
+	ErrPayloadTooLarge = results.ErrPayloadTooLarge
+	ErrBlocked         = results.ErrBlocked
+	ErrInvalidPDF      = errors.New("invalid PDF")
+	ErrBudgetExceeded  = errors.New("budget exceeded")
+	ErrResultNotFound  = errors.New("result not found")
+)
+
+// Is matches ErrRateLimited and ErrPayloadTooLarge by the error's class
+func (e *APIError) Is(target error) bool {
+	if target != ErrRateLimited && target != ErrPayloadTooLarge {
+		return false // ClassifyError itself asks for other targets
+	}
+	return results.ClassError(string(ClassifyError(e))) == target
+}
+
+// Is matches ErrBlocked for refused and blocked responses
+func (e *ResponseError) Is(target error) bool {
+	return target == ErrBlocked && ClassifyError(e) == ErrorBlocked
+}
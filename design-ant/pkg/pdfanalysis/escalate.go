@@ -0,0 +1,139 @@
+package pdfanalysis
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// bomTablePattern finds a markdown table with a quantity column, which the
+	// analysis prompt produces for BOM and parts list tables
+	bomTablePattern = regexp.MustCompile(`(?im)^\|.*\b(?:qty|quantity)\b.*\|`)
+	// titleBlockPattern finds a mention of the title block in the analysis
+	titleBlockPattern = regexp.MustCompile(`(?i)\btitle[ -]block\b`)
+)
+
+// qualityProblems lists signs that a page's output is incomplete: an answer
+// cut off at max_tokens, no valid structured data, a BOM table in the
+// analysis without extracted BOM rows, a title block without extracted
+// metadata, or -validate findings
+func qualityProblems(config *Config, chunk ChunkAnalysis) []string {
+	if chunk.Error != "" {
+		return nil
+	}
+	// The English analysis is checked, also after a translation pass
+	analysis := chunk.Analysis
+	if chunk.OriginalAnalysis != "" {
+		analysis = chunk.OriginalAnalysis
+	}
+	var problems []string
+	if chunk.Truncated {
+		problems = append(problems, "answer cut off at max_tokens")
+	}
+	if config.Structured {
+		data := chunk.StructuredData
+		switch {
+		case data.Metadata == nil && len(data.BOMItems) == 0 && len(data.Dimensions) == 0 && len(data.Notes) == 0 && len(packTables(data)) == 0:
+			problems = append(problems, "no structured data extracted")
+		default:
+			if len(data.BOMItems) == 0 && bomTablePattern.MatchString(analysis) {
+				problems = append(problems, "BOM table on the page but no BOM rows extracted")
+			}
+			if data.Metadata == nil && titleBlockPattern.MatchString(analysis) {
+				problems = append(problems, "title block on the page but no metadata extracted")
+			}
+		}
+	}
+	for _, v := range chunk.Validation {
+		problems = append(problems, fmt.Sprintf("%s missing from %s: %s", v.Validator, v.Target, strings.Join(v.Missing, ", ")))
+	}
+	return problems
+}
+
+// escalatePages analyzes pages with quality problems again with
+// config.EscalateModel. The escalated output replaces the first one when it
+// has fewer problems; either way its cost is added to the chunk and the
+// attempt is recorded in chunk.Escalation. texts and validators may be nil.
+// It returns the number of escalated and accepted pages.
+func escalatePages(ctx context.Context, config *Config, results []ChunkAnalysis, chunks []ChunkInfo, routes []PageRoute, texts []string, validators []validator) (escalated, accepted int) {
+	escalatedConfig := *config
+	escalatedConfig.ModelName = config.EscalateModel
+	pricing := GetPricing(config.EscalateModel)
+
+	for i := range results {
+		problems := qualityProblems(config, results[i])
+		if len(problems) == 0 {
+			continue
+		}
+		escalated++
+		page := results[i].StartPage
+		logf(ctx, "  ⏫ Page %d: %s, retrying with %s...\n", page, problems[0], config.EscalateModel)
+
+		start := time.Now()
+		record := &Escalation{FromModel: config.ModelName, Model: config.EscalateModel, Reasons: problems}
+		route := routeForChunk(routes, chunks[i])
+		captureCtx := ContextWithCapture(ctx, config.CaptureDir, fmt.Sprintf("chunk-%03d-escalate", i+1))
+		reply, err := analyzePage(captureCtx, &escalatedConfig, route, chunks[i].Path, page, buildPrompt(config, page, results[i].Classification))
+
+		inputTokens, outputTokens, extraction := reply.InputTokens, reply.OutputTokens, reply.Extraction
+		inputCost := float64(inputTokens) / 1_000_000 * pricing.InputPricePerMTokens
+		outputCost := float64(outputTokens) / 1_000_000 * pricing.OutputPricePerMTokens
+		record.InputTokens, record.OutputTokens, record.TotalCost = inputTokens, outputTokens, inputCost+outputCost
+
+		// The attempt is paid for whether or not its output is used
+		results[i].InputTokens += inputTokens
+		results[i].OutputTokens += outputTokens
+		results[i].InputCost += inputCost
+		results[i].OutputCost += outputCost
+		results[i].TotalCost = results[i].InputCost + results[i].OutputCost
+		results[i].Escalation = record
+		if err != nil {
+			record.Error = err.Error()
+			logf(ctx, "  ❌ Page %d: escalation failed, keeping the first analysis: %v\n", page, err)
+			continue
+		}
+
+		candidate := []ChunkAnalysis{results[i]}
+		candidate[0].Analysis, candidate[0].OriginalAnalysis = reply.Analysis, ""
+		candidate[0].Continued, candidate[0].Truncated = reply.Continued, reply.Truncated
+		candidate[0].StructuredData = StructuredData{}
+		if extraction != nil && extraction.DataError == "" {
+			candidate[0].StructuredData = extraction.Data
+		}
+		if config.Ground {
+			groundChunk(&candidate[0], route.Text)
+		}
+		if texts != nil {
+			validateOutput(candidate, texts, validators)
+		}
+		record.Remaining = qualityProblems(config, candidate[0])
+		if len(record.Remaining) >= len(problems) {
+			logf(ctx, "  ⚠️  Page %d: %s output is no better, keeping the first analysis\n", page, config.EscalateModel)
+			continue
+		}
+
+		chunk := &candidate[0]
+		if config.OutputLang != "" && config.LangMode == LangModeTranslate {
+			chunk.Language = ""
+			translateCtx := ContextWithCapture(ctx, config.CaptureDir, fmt.Sprintf("chunk-%03d-escalate-translate", i+1))
+			if err := applyTranslation(translateCtx, config, chunk); err != nil {
+				logf(ctx, "  ⚠️  Page %d: translation failed, keeping English analysis: %v\n", page, err)
+			}
+		}
+		record.Accepted = true
+		chunk.ProcessingTime = (parseDuration(chunk.ProcessingTime) + time.Since(start)).String()
+		results[i] = *chunk
+		accepted++
+		logf(ctx, "  ✅ Page %d: escalated analysis accepted (%d problem(s) left), $%.6f\n", page, len(record.Remaining), record.TotalCost)
+	}
+	return escalated, accepted
+}
+
+// parseDuration reads a stored processing time, treating bad values as zero
+func parseDuration(s string) time.Duration {
+	d, _ := time.ParseDuration(s)
+	return d
+}
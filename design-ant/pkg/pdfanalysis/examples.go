@@ -0,0 +1,185 @@
+package pdfanalysis
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Example is a page with the analysis expected of it, sent ahead of every
+// page as a few-shot example, e.g. for a title block layout the model keeps
+// misreading
+type Example struct {
+	Name     string // File name without extension, e.g. "title-block-a"
+	Path     string // One-page PDF, or a PNG or JPEG image of the page
+	Expected string // Markdown analysis the model should write for the page
+}
+
+// exampleMediaTypes are the page files of an examples directory
+var exampleMediaTypes = map[string]string{
+	".pdf":  "application/pdf",
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+}
+
+// LoadExamples reads an examples directory: each page file (.pdf, .png,
+// .jpg) next to a .md file of the same name with its expected analysis,
+// e.g. title-block-a.pdf and title-block-a.md. Examples are sorted by name.
+func LoadExamples(dir string) ([]Example, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading examples: %v", err)
+	}
+	var examples []Example
+	pages := make(map[string]bool)
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || exampleMediaTypes[ext] == "" {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if pages[name] {
+			return nil, fmt.Errorf("example %s has more than one page file", name)
+		}
+		pages[name] = true
+		path := filepath.Join(dir, entry.Name())
+		if ext == ".pdf" {
+			count, err := GetPageCount(path)
+			if err != nil {
+				return nil, fmt.Errorf("example %s: %v", name, err)
+			}
+			if count != 1 {
+				return nil, fmt.Errorf("example %s has %d pages: must be one page", name, count)
+			}
+		}
+		expected, err := os.ReadFile(filepath.Join(dir, name+".md"))
+		if err != nil {
+			return nil, fmt.Errorf("example %s has no expected analysis: %v", name, err)
+		}
+		if strings.TrimSpace(string(expected)) == "" {
+			return nil, fmt.Errorf("example %s: %s.md is empty", name, name)
+		}
+		examples = append(examples, Example{Name: name, Path: path, Expected: strings.TrimSpace(string(expected))})
+	}
+	if len(examples) == 0 {
+		return nil, fmt.Errorf("no examples in %s: expected page files (.pdf, .png, .jpg) with a .md of the same name", dir)
+	}
+	sort.Slice(examples, func(i, j int) bool { return examples[i].Name < examples[j].Name })
+	return examples, nil
+}
+
+// exampleContent returns the content blocks of the few-shot examples that go
+// before the page, each example page followed by its expected analysis
+func exampleContent(examples []Example) []map[string]interface{} {
+	if len(examples) == 0 {
+		return nil
+	}
+	var content []map[string]interface{}
+	for i, example := range examples {
+		mediaType := exampleMediaTypes[strings.ToLower(filepath.Ext(example.Path))]
+		blockType := "image"
+		if mediaType == "application/pdf" {
+			blockType = "document"
+		}
+		content = append(content,
+			map[string]interface{}{"type": "text", "text": fmt.Sprintf("Example %d of %d, a page with its correct analysis:", i+1, len(examples))},
+			map[string]interface{}{
+				"type": blockType,
+				"source": map[string]interface{}{
+					"type":       "base64",
+					"media_type": mediaType,
+					"data":       FileData{Path: example.Path},
+				},
+			},
+			map[string]interface{}{"type": "text", "text": "<expected_analysis>\n" + example.Expected + "\n</expected_analysis>"})
+	}
+	return append(content, map[string]interface{}{
+		"type": "text",
+		"text": "End of the examples. Analyze the next page the same way, reading title blocks and tables as the examples do; its content is its own, so never copy values from the examples.",
+	})
+}
+
+// pageContent builds the message content of a page's analysis request: the
+// few-shot examples, then the page as text layer or PDF with its prompt. With
+// -ground a PDF page also carries its text layer, just before the prompt.
+func pageContent(config *Config, route PageRoute, path string, pageNumber int, prompt string) ([]map[string]interface{}, error) {
+	var content []map[string]interface{}
+	if route.Mode == InputModeText {
+		content = textPageContent(route.Text, pageNumber, prompt)
+	} else {
+		var err error
+		if content, err = ChunkContent(path, prompt); err != nil {
+			return nil, err
+		}
+		if config.Ground && strings.TrimSpace(route.Text) != "" {
+			last := len(content) - 1
+			content = append(content[:last:last], groundingText(route.Text, pageNumber), content[last])
+		}
+	}
+	if len(config.Examples) == 0 {
+		return content, nil
+	}
+	return append(exampleContent(config.Examples), content...), nil
+}
+
+// hashExamples writes the files and expected analyses of the examples to a
+// hash, so the page cache does not reuse analyses made with other examples
+func hashExamples(h io.Writer, examples []Example) error {
+	for _, example := range examples {
+		data, err := os.ReadFile(example.Path)
+		if err != nil {
+			return fmt.Errorf("error reading example %s: %v", example.Name, err)
+		}
+		fmt.Fprintf(h, "example:%s\x00%x\x00%x\x00", example.Name, sha256.Sum256(data), sha256.Sum256([]byte(example.Expected)))
+	}
+	return nil
+}
+
+// examplePageTokens estimates the tokens of an example page that could not
+// be counted, a typical drawing page at the API's image resolution
+const examplePageTokens = 2_000
+
+// exampleTokenCount is the input tokens of the examples, counted once per
+// model and set of examples
+var exampleTokenCount = struct {
+	sync.Mutex
+	byKey map[string]int
+}{byKey: make(map[string]int)}
+
+// exampleTokens returns the input tokens every page request spends on the
+// examples, or an estimate when the provider cannot count tokens
+func exampleTokens(ctx context.Context, config *Config) int {
+	if len(config.Examples) == 0 {
+		return 0
+	}
+	estimate := 0
+	for _, example := range config.Examples {
+		estimate += examplePageTokens + len(example.Expected)/3
+	}
+	if !config.Capabilities().TokenCounting {
+		return estimate
+	}
+	names := make([]string, len(config.Examples))
+	for i, example := range config.Examples {
+		names[i] = example.Path
+	}
+	key := config.ModelName + "\x00" + strings.Join(names, "\x00")
+	exampleTokenCount.Lock()
+	defer exampleTokenCount.Unlock()
+	if n, ok := exampleTokenCount.byKey[key]; ok {
+		return n
+	}
+	n, err := countTokens(ctx, config.APIKey, config.ModelName, exampleContent(config.Examples), nil)
+	if err != nil {
+		return estimate
+	}
+	exampleTokenCount.byKey[key] = n
+	return n
+}
@@ -0,0 +1,129 @@
+package pdfanalysis
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// groundingInstructions asks the model to mark values it cannot find on the page
+const groundingInstructions = `GROUNDING:
+The extracted text layer of the page is sent with it (scanned pages have none). Report part numbers and dimensions exactly as they appear in the text layer or legibly in the image.
+Write any part number or dimension that is NOT in the text layer and NOT clearly legible in the image as [unverified: VALUE], e.g. [unverified: P-10234] - in the analysis and in the tool call alike. Never state a guessed or inferred value as fact.`
+
+// Reasons of an UnverifiedValue
+const (
+	unverifiedMarked = "marked by the model"
+	unverifiedNoText = "not in the text layer"
+)
+
+var (
+	// unverifiedPattern finds a value the model marked under -ground
+	unverifiedPattern = regexp.MustCompile(`\[unverified:\s*([^\]]*)\]`)
+	// groundNumberPattern finds the numbers of a dimension or text layer, e.g. 25.4, 0,5, or .75
+	groundNumberPattern = regexp.MustCompile(`\d*[.,]?\d+`)
+	// groundKeyPattern is what is dropped to compare part numbers: all but letters and digits
+	groundKeyPattern = regexp.MustCompile(`[^\pL\pN]+`)
+)
+
+// groundingText is the content block with the text layer of a page sent as
+// PDF or images, which -ground adds before the prompt
+func groundingText(text string, pageNumber int) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "text",
+		"text": fmt.Sprintf("The extracted text layer of PDF page %d, for checking part numbers and dimensions:\n\n<page_text>\n%s\n</page_text>", pageNumber, text),
+	}
+}
+
+// groundChunk moves the values of a page that cannot be verified out of its
+// output into chunk.Unverified: those the model marked [unverified: ...] in
+// the analysis (replaced by a numbered [unverified #N] reference) or in the
+// BOM part numbers and dimension values, and BOM part numbers and dimension
+// values that are not in the page's text layer. Pages without a text layer
+// (scans) only lose the marked values. It returns the number of values moved.
+func groundChunk(chunk *ChunkAnalysis, text string) int {
+	chunk.Unverified = nil
+	add := func(value, field, reason string) int {
+		chunk.Unverified = append(chunk.Unverified, UnverifiedValue{Value: strings.TrimSpace(value), Field: field, Reason: reason})
+		return len(chunk.Unverified)
+	}
+
+	chunk.Analysis = unverifiedPattern.ReplaceAllStringFunc(chunk.Analysis, func(marked string) string {
+		value := unverifiedPattern.FindStringSubmatch(marked)[1]
+		return fmt.Sprintf("[unverified #%d]", add(value, "analysis", unverifiedMarked))
+	})
+
+	checked := strings.TrimSpace(text) != ""
+	parts := groundKey(text)
+	numbers := make(map[string]bool)
+	for _, n := range groundNumberPattern.FindAllString(text, -1) {
+		numbers[normalizeGroundNumber(n)] = true
+	}
+
+	for i := range chunk.BOMItems {
+		item := &chunk.BOMItems[i]
+		field := fmt.Sprintf("bom_items[%d].part_number", i)
+		if m := unverifiedPattern.FindStringSubmatch(item.PartNumber); m != nil {
+			add(m[1], field, unverifiedMarked)
+			item.PartNumber = ""
+		} else if checked && item.PartNumber != "" && !strings.Contains(parts, groundKey(item.PartNumber)) {
+			add(item.PartNumber, field, unverifiedNoText)
+			item.PartNumber = ""
+		}
+	}
+
+	var dimensions []Dimension
+	for i, d := range chunk.Dimensions {
+		field := fmt.Sprintf("dimensions[%d]", i)
+		if m := unverifiedPattern.FindStringSubmatch(d.Value); m != nil {
+			add(dimensionLabel(d.Feature, m[1]), field, unverifiedMarked)
+			continue
+		}
+		if n := groundNumberPattern.FindString(d.Value); checked && n != "" && !numbers[normalizeGroundNumber(n)] {
+			add(dimensionLabel(d.Feature, d.Value), field, unverifiedNoText)
+			continue
+		}
+		dimensions = append(dimensions, d)
+	}
+	chunk.Dimensions = dimensions
+	return len(chunk.Unverified)
+}
+
+// groundKey reduces a part number or text to letters and digits in upper
+// case, so P-10234 matches "P 10234" in the text layer
+func groundKey(s string) string {
+	return strings.ToUpper(groundKeyPattern.ReplaceAllString(s, ""))
+}
+
+// normalizeGroundNumber writes a number the same way however it was typed,
+// e.g. 25.40 and 25,4 as 25.4
+func normalizeGroundNumber(n string) string {
+	n = strings.Replace(n, ",", ".", 1)
+	if f, err := strconv.ParseFloat(n, 64); err == nil {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return n
+}
+
+// dimensionLabel names a dimension in the unverified list, e.g. "bore: 25.4"
+func dimensionLabel(feature, value string) string {
+	if feature == "" {
+		return strings.TrimSpace(value)
+	}
+	return feature + ": " + strings.TrimSpace(value)
+}
+
+// markdownUnverified renders the values -ground moved out of a page's output
+func markdownUnverified(values []UnverifiedValue) string {
+	if len(values) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("**Unverified values** (not found on the page, removed from the output):\n\n")
+	for i, v := range values {
+		fmt.Fprintf(&b, "%d. %s (%s, %s)\n", i+1, EscapeInline(v.Value), v.Field, v.Reason)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
@@ -0,0 +1,39 @@
+package pdfanalysis
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// apiTransport is the connection pool shared by every API call of a run:
+// message requests, token counting, and embeddings. Keeping connections
+// alive between pages saves a TLS handshake per request, and HTTP/2 lets
+// the workers share a few connections per host.
+var apiTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   maxInFlightRequests, // One idle connection per worker
+	MaxConnsPerHost:       2 * maxInFlightRequests,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: 1 * time.Second,
+}
+
+// DefaultRequestTimeout bounds a Messages API request whose context has no
+// deadline; page requests get theirs from -chunk-timeout
+const DefaultRequestTimeout = 5 * time.Minute
+
+// Clients differ only in their overall timeout; all use apiTransport, with
+// a span per request when tracing is on
+var (
+	// MessageClient sends Messages API requests; their deadline comes from the context
+	MessageClient = &http.Client{Transport: tracingTransport{apiTransport}}
+	// QuickClient sends token counting and embedding requests
+	QuickClient = &http.Client{Transport: tracingTransport{apiTransport}, Timeout: 60 * time.Second}
+)
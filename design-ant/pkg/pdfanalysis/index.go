@@ -0,0 +1,96 @@
+package pdfanalysis
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// minIndexMentionLength keeps short identifiers ("1", "A") from matching
+// unrelated text when scanning the analyses for mentions
+const minIndexMentionLength = 3
+
+// buildIndex collects every part number (BOM rows) and drawing number with
+// revision (title blocks) from the structured data, then adds the pages whose
+// analysis text mentions the identifier, e.g. an assembly page referencing a
+// detail drawing. Entries are sorted by kind and value.
+func buildIndex(chunks []ChunkAnalysis) []IndexEntry {
+	byKey := make(map[string]*IndexEntry)
+	var entries []*IndexEntry
+	add := func(kind, value, revision string, page int) {
+		value, revision = strings.TrimSpace(value), strings.TrimSpace(revision)
+		if value == "" {
+			return
+		}
+		key := kind + "\x00" + strings.ToUpper(value) + "\x00" + strings.ToUpper(revision)
+		entry, ok := byKey[key]
+		if !ok {
+			entry = &IndexEntry{Kind: kind, Value: value, Revision: revision}
+			byKey[key] = entry
+			entries = append(entries, entry)
+		}
+		entry.Pages = appendPage(entry.Pages, page)
+	}
+
+	for _, chunk := range chunks {
+		if m := chunk.Metadata; m != nil {
+			add("drawing_number", m.DrawingNumber, m.Revision, chunk.StartPage)
+		}
+		for _, row := range chunk.BOMItems {
+			add("part_number", row.PartNumber, "", chunk.StartPage)
+		}
+	}
+
+	for _, entry := range entries {
+		if len(entry.Value) < minIndexMentionLength {
+			continue
+		}
+		mention := regexp.MustCompile(`(?i)(?:^|[^\w-])` + regexp.QuoteMeta(entry.Value) + `(?:$|[^\w-])`)
+		for _, chunk := range chunks {
+			if mention.MatchString(chunk.Analysis) {
+				entry.Pages = appendPage(entry.Pages, chunk.StartPage)
+			}
+		}
+	}
+
+	index := make([]IndexEntry, len(entries))
+	for i, entry := range entries {
+		sort.Ints(entry.Pages)
+		index[i] = *entry
+	}
+	sort.SliceStable(index, func(i, j int) bool {
+		if index[i].Kind != index[j].Kind {
+			return index[i].Kind == "drawing_number"
+		}
+		if a, b := strings.ToUpper(index[i].Value), strings.ToUpper(index[j].Value); a != b {
+			return a < b
+		}
+		return index[i].Revision < index[j].Revision
+	})
+	return index
+}
+
+// indexKindLabel is the display name of an index entry kind
+func indexKindLabel(kind string) string {
+	if kind == "drawing_number" {
+		return "Drawing"
+	}
+	return "Part"
+}
+
+// markdownIndex renders the index as a markdown table; link formats a page reference
+func markdownIndex(index []IndexEntry, link func(page int) string) string {
+	var b strings.Builder
+	b.WriteString("| Kind | Number | Revision | Pages |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+	for _, entry := range index {
+		pages := make([]string, len(entry.Pages))
+		for i, p := range entry.Pages {
+			pages[i] = link(p)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", indexKindLabel(entry.Kind),
+			EscapeInline(entry.Value), EscapeInline(entry.Revision), strings.Join(pages, ", "))
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package pdfanalysis
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInterrupted marks chunks that were not analyzed because the run was
+// interrupted; they are left out of the partial result so -resume runs them
+const ErrInterrupted = "interrupted"
+
+// completedChunks drops the chunks that were never analyzed because of an
+// interruption and renumbers the rest
+func completedChunks(chunks []ChunkAnalysis) []ChunkAnalysis {
+	var completed []ChunkAnalysis
+	for _, chunk := range chunks {
+		if chunk.Error == ErrInterrupted {
+			continue
+		}
+		chunk.ChunkNumber = len(completed) + 1
+		completed = append(completed, chunk)
+	}
+	return completed
+}
+
+// loadResume reads the pages an interrupted run already analyzed, keyed by
+// page number, from its result or its JSONL stream, or from ResumeStore.
+// Failed pages and pages analyzed with other options are run again.
+func loadResume(ctx context.Context, config *Config) (map[int]ChunkAnalysis, error) {
+	var chunks []ChunkAnalysis
+	if config.ResumeStore != nil {
+		result, err := config.ResumeStore.Load(ctx, config.ResumeFrom)
+		if err != nil {
+			return nil, err
+		}
+		// Results rebuilt from a stream do not record their document
+		if result.PDFPath != "" && filepath.Base(result.PDFPath) != filepath.Base(config.PDFPath) {
+			return nil, fmt.Errorf("cannot resume %s: it is the result of %s", config.ResumeFrom, filepath.Base(result.PDFPath))
+		}
+		chunks = result.Chunks
+	} else if strings.HasSuffix(config.ResumeFrom, ".jsonl") {
+		// The stream of a run that crashed before writing its JSON
+		if filepath.Base(config.ResumeFrom) != GenerateOutputFilename(config.PDFPath, "jsonl") {
+			return nil, fmt.Errorf("cannot resume %s: it is not the stream of %s", config.ResumeFrom, filepath.Base(config.PDFPath))
+		}
+		var err error
+		if chunks, err = loadChunkStream(config.ResumeFrom); err != nil {
+			return nil, err
+		}
+	} else {
+		result, err := LoadResult(config.ResumeFrom)
+		if err != nil {
+			return nil, err
+		}
+		if filepath.Base(result.PDFPath) != filepath.Base(config.PDFPath) {
+			return nil, fmt.Errorf("cannot resume %s: it is the result of %s", config.ResumeFrom, filepath.Base(result.PDFPath))
+		}
+		if !result.Interrupted {
+			logf(ctx, "ℹ️  %s is a complete result; failed pages are run again\n", config.ResumeFrom)
+		}
+		chunks = result.Chunks
+	}
+	done := make(map[int]ChunkAnalysis)
+	for _, chunk := range chunks {
+		if chunk.StartPage == chunk.EndPage && reusable(config, chunk) {
+			done[chunk.StartPage] = chunk
+		}
+	}
+	return done, nil
+}
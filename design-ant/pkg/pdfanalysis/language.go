@@ -0,0 +1,99 @@
+package pdfanalysis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Output language modes
+const (
+	LangModePrompt    = "prompt"    // Ask the analysis model to answer in the target language
+	LangModeTranslate = "translate" // Analyze in English, then translate each page in a separate pass
+)
+
+// languageNames maps common language codes to the names used in prompts.
+// Unknown values are passed to the model as given (e.g. "Brazilian Portuguese").
+var languageNames = map[string]string{
+	"cs": "Czech",
+	"de": "German",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"hu": "Hungarian",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pl": "Polish",
+	"pt": "Portuguese",
+	"ro": "Romanian",
+	"sv": "Swedish",
+	"tr": "Turkish",
+	"zh": "Simplified Chinese",
+}
+
+// LanguageName resolves a language code to its English name
+func LanguageName(lang string) string {
+	if name, ok := languageNames[strings.ToLower(lang)]; ok {
+		return name
+	}
+	return lang
+}
+
+// preservationRules lists what must stay verbatim in any language
+const preservationRules = `- Keep the "# Page N" heading exactly as written (do not translate it)
+- Keep part numbers, drawing numbers, material and finish codes, standards (ISO, DIN, ASME), dimensions, tolerances, and units exactly as they appear
+- Quote note text from the drawing in its original wording, followed by the translation in parentheses
+- Keep the markdown structure (headings, lists, tables) unchanged`
+
+// OutputLanguageInstructions is added to the analysis prompt in prompt mode
+func OutputLanguageInstructions(lang string) string {
+	return fmt.Sprintf(`OUTPUT LANGUAGE:
+Write the entire analysis in %s.
+%s
+- Copy values in tool calls exactly as written on the drawing`, LanguageName(lang), preservationRules)
+}
+
+// translationPrompt builds the request for the translation pass
+func translationPrompt(text, lang string) string {
+	return fmt.Sprintf(`Translate the following engineering drawing analysis into %s.
+%s
+- Output only the translation, with no introductory or closing remarks
+
+<analysis>
+%s
+</analysis>`, LanguageName(lang), preservationRules, text)
+}
+
+// applyTranslation translates a chunk's analysis in place. The English text is
+// kept as original_analysis and the translation tokens are added to the
+// chunk's totals so the run cost stays accurate.
+func applyTranslation(ctx context.Context, config *Config, chunk *ChunkAnalysis) error {
+	content := []map[string]interface{}{
+		{
+			"type": "text",
+			"text": translationPrompt(chunk.Analysis, config.OutputLang),
+		},
+	}
+	translated, inputTokens, outputTokens, err := SendMessage(ctx, config.APIKey, config.TranslateModel, content)
+
+	// A refused translation is paid for as well
+	pricing := GetPricing(config.TranslateModel)
+	chunk.InputTokens += inputTokens
+	chunk.OutputTokens += outputTokens
+	chunk.InputCost += float64(inputTokens) / 1_000_000 * pricing.InputPricePerMTokens
+	chunk.OutputCost += float64(outputTokens) / 1_000_000 * pricing.OutputPricePerMTokens
+	chunk.TotalCost = chunk.InputCost + chunk.OutputCost
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(translated) == "" {
+		return fmt.Errorf("empty translation")
+	}
+
+	chunk.OriginalAnalysis = chunk.Analysis
+	chunk.Analysis = translated
+	chunk.Language = config.OutputLang
+	return nil
+}
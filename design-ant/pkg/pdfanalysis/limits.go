@@ -0,0 +1,90 @@
+package pdfanalysis
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+)
+
+// anthropicMaxRequestMB is the maximum request size accepted by the Messages API
+const anthropicMaxRequestMB = 32
+
+// geminiMaxRequestMB is the largest request with inline data the Gemini API accepts
+const geminiMaxRequestMB = 20
+
+// BytesPerMB is the number of bytes in the megabytes (MiB) of the size limits and FormatMB.
+const BytesPerMB = 1024 * 1024
+
+// CheckPageLimit fails fast when the document has more pages than allowed
+func CheckPageLimit(limits Limits, totalPages int) error {
+	if limits.MaxPages > 0 && totalPages > limits.MaxPages {
+		return fmt.Errorf("document has %d pages, exceeds the limit of %d pages; raise -max-pages or split the document first",
+			totalPages, limits.MaxPages)
+	}
+	return nil
+}
+
+// checkChunkLimits verifies every chunk fits the per-request and total size
+// limits before anything is sent to the API: -max-chunk-mb or the
+// provider's request limit, whichever is lower. Sizes are measured after
+// base64 encoding, since that is what counts against the provider's limit.
+func checkChunkLimits(limits Limits, caps Capabilities, chunks []ChunkInfo) error {
+	maxChunkMB := caps.MaxRequestMB
+	if limits.MaxChunkMB > 0 {
+		maxChunkMB = min(maxChunkMB, limits.MaxChunkMB)
+	}
+	var totalBytes int64
+	for _, chunk := range chunks {
+		encodedBytes, err := chunkEncodedSize(chunk)
+		if err != nil {
+			return fmt.Errorf("error reading chunk %s: %v", chunk.Path, err)
+		}
+		totalBytes += encodedBytes
+
+		if encodedBytes > maxChunkMB*BytesPerMB {
+			if maxChunkMB < caps.MaxRequestMB {
+				return fmt.Errorf("%w: %s encodes to %s, exceeds the -max-chunk-mb limit of %dMB; raise -max-chunk-mb (the %s provider accepts up to %dMB)",
+					ErrPayloadTooLarge, describeChunk(chunk), FormatMB(encodedBytes), maxChunkMB, caps.Provider, caps.MaxRequestMB)
+			}
+			return fmt.Errorf("%w: %s encodes to %s, exceeds the %dMB request limit of the %s provider; reduce the resolution of embedded images (e.g. re-save the PDF with an optimizer)",
+				ErrPayloadTooLarge, describeChunk(chunk), FormatMB(encodedBytes), caps.MaxRequestMB, caps.Provider)
+		}
+	}
+
+	if limits.MaxTotalMB > 0 && totalBytes > limits.MaxTotalMB*BytesPerMB {
+		return fmt.Errorf("%w: all %d chunks encode to %s combined, exceeds the %dMB total limit; raise -max-total-mb or use -max-pages",
+			ErrPayloadTooLarge, len(chunks), FormatMB(totalBytes), limits.MaxTotalMB)
+	}
+	return nil
+}
+
+// chunkEncodedSize returns the base64-encoded size of a chunk's PDF, or of
+// its images when the page was rendered
+func chunkEncodedSize(chunk ChunkInfo) (int64, error) {
+	if isRendered(chunk.Path) {
+		return renderedSize(chunk.Path)
+	}
+	info, err := os.Stat(chunk.Path)
+	if err != nil {
+		return 0, err
+	}
+	return int64(base64.StdEncoding.EncodedLen(int(info.Size()))), nil
+}
+
+// describeChunk returns "page N" or "pages N-M" for messages
+func describeChunk(chunk ChunkInfo) string {
+	return PageRangeLabel(chunk.StartPage+1, chunk.EndPage+1)
+}
+
+// PageRangeLabel formats a 1-indexed page range as "page N" or "pages N-M"
+func PageRangeLabel(startPage, endPage int) string {
+	if startPage == endPage {
+		return fmt.Sprintf("page %d", startPage)
+	}
+	return fmt.Sprintf("pages %d-%d", startPage, endPage)
+}
+
+// FormatMB formats a byte count in megabytes
+func FormatMB(bytes int64) string {
+	return fmt.Sprintf("%.1fMB", float64(bytes)/BytesPerMB)
+}
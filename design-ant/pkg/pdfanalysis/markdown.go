@@ -0,0 +1,353 @@
+package pdfanalysis
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Markdown export flavors
+const (
+	MarkdownGitHub     = "github"
+	MarkdownConfluence = "confluence"
+	MarkdownNotion     = "notion"
+)
+
+// markdownFlavor captures how a target renders pasted markdown
+type markdownFlavor struct {
+	maxHeading int                       // Deepest heading level the target renders
+	anchor     func(title string) string // In-page link target for a heading, "" if unsupported
+}
+
+var MarkdownFlavors = map[string]markdownFlavor{
+	// GitHub-style slugs: lowercase, spaces to hyphens
+	MarkdownGitHub: {maxHeading: 6, anchor: func(title string) string {
+		return "#" + strings.ToLower(strings.ReplaceAll(title, " ", "-"))
+	}},
+	// Confluence Cloud keeps the heading case and replaces spaces with hyphens
+	MarkdownConfluence: {maxHeading: 6, anchor: func(title string) string {
+		return "#" + strings.ReplaceAll(title, " ", "-")
+	}},
+	// Notion only has three heading levels and drops in-page links on paste
+	MarkdownNotion: {maxHeading: 3, anchor: func(string) string { return "" }},
+}
+
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	separatorCell  = regexp.MustCompile(`^\s*:?-{3,}:?\s*$`)
+)
+
+// SaveMarkdownExport writes the result as markdown in the given flavor
+func SaveMarkdownExport(filename string, result FullAnalysisResult, format string) error {
+	return os.WriteFile(filename, []byte(renderMarkdown(result, format)), 0644)
+}
+
+// renderMarkdown builds a single document with a table of contents and one
+// level-2 section per page. Model headings are nested below the page heading
+// and tables are normalized so they survive pasting into wikis.
+func renderMarkdown(result FullAnalysisResult, format string) string {
+	flavor := MarkdownFlavors[format]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Design Analysis: %s\n\n", filepath.Base(result.PDFPath))
+	fmt.Fprintf(&b, "| Pages | Chunks | Total Cost | Generated |\n|---|---|---|---|\n| %d | %d | $%.6f | %s |\n\n",
+		result.TotalPages, result.TotalChunks, result.TotalCost, result.GeneratedAt.Format("2006-01-02 15:04"))
+	if result.Interrupted {
+		fmt.Fprintf(&b, "> **Partial result:** the run was interrupted after %d of %d page(s).\n\n", result.TotalChunks, result.TotalPages)
+	}
+
+	b.WriteString("## Contents\n\n")
+	if result.ExecutiveSummary != nil {
+		writeTOCEntry(&b, flavor, "Executive Summary", "")
+	}
+	if result.Consolidated != nil {
+		writeTOCEntry(&b, flavor, "Summary", "")
+	}
+	for _, chunk := range result.Chunks {
+		writeTOCEntry(&b, flavor, chunkTitle(chunk), chunkSubtitle(chunk))
+	}
+	if len(result.Discrepancies) > 0 {
+		writeTOCEntry(&b, flavor, "Discrepancies", "")
+	}
+	if len(result.TitleBlockExceptions) > 0 {
+		writeTOCEntry(&b, flavor, "Title Block Exceptions", "")
+	}
+	if len(result.Index) > 0 {
+		writeTOCEntry(&b, flavor, "Index", "")
+	}
+	if len(result.Standards) > 0 {
+		writeTOCEntry(&b, flavor, "Standards Cited", "")
+	}
+	if len(result.MasterBOM) > 0 {
+		writeTOCEntry(&b, flavor, "Master BOM", "")
+	}
+	if result.AssemblyCost != nil {
+		writeTOCEntry(&b, flavor, "Estimated Assembly Cost", "")
+	}
+	writeTOCEntry(&b, flavor, "Cost Breakdown", "")
+	b.WriteString("\n")
+
+	if s := result.ExecutiveSummary; s != nil {
+		b.WriteString("## Executive Summary\n\n")
+		if s.Error != "" {
+			fmt.Fprintf(&b, "> **Executive summary failed:** %s\n\n", EscapeInline(s.Error))
+		} else {
+			b.WriteString(normalizeMarkdown(s.Summary, flavor))
+			b.WriteString("\n\n")
+		}
+	}
+	if result.Consolidated != nil {
+		b.WriteString("## Summary\n\n")
+		if result.Consolidated.Error != "" {
+			fmt.Fprintf(&b, "> **Consolidation failed:** %s\n\n", EscapeInline(result.Consolidated.Error))
+		} else {
+			b.WriteString(normalizeMarkdown(result.Consolidated.Analysis, flavor))
+			b.WriteString("\n\n")
+		}
+	}
+	for _, chunk := range result.Chunks {
+		fmt.Fprintf(&b, "## %s\n\n", chunkTitle(chunk))
+		if subtitle := chunkSubtitle(chunk); subtitle != "" {
+			fmt.Fprintf(&b, "_%s_\n\n", subtitle)
+		}
+		if chunk.Error != "" {
+			fmt.Fprintf(&b, "> **Analysis failed:** %s\n\n", EscapeInline(chunk.Error))
+			continue
+		}
+		if c := chunk.Classification; c != nil && c.Error == "" {
+			fmt.Fprintf(&b, "_Page type: %s_\n\n", c.Type)
+		}
+		if s := chunk.Blocked; s != nil && s.Recovered {
+			fmt.Fprintf(&b, "_%s at first (%s), analyzed with the neutral prompt_\n\n", strings.ToUpper(s.Reason[:1])+s.Reason[1:], EscapeInline(s.Detail))
+		}
+		if e := chunk.Escalation; e != nil && e.Accepted {
+			fmt.Fprintf(&b, "_Reanalyzed with %s: %s_\n\n", e.Model, EscapeInline(strings.Join(e.Reasons, "; ")))
+		}
+		b.WriteString(markdownValidation(chunk.Validation))
+		b.WriteString(normalizeMarkdown(chunk.Analysis, flavor))
+		b.WriteString("\n\n")
+		b.WriteString(markdownUnverified(chunk.Unverified))
+		b.WriteString(markdownWelds(chunk.StructuredData))
+		b.WriteString(markdownPackTables(chunk.StructuredData))
+		b.WriteString(markdownCompliance(chunk.Compliance))
+	}
+	if len(result.Discrepancies) > 0 {
+		b.WriteString("## Discrepancies\n\n")
+		b.WriteString(markdownDiscrepancies(result.Discrepancies))
+		b.WriteString("\n")
+	}
+	if len(result.TitleBlockExceptions) > 0 {
+		b.WriteString("## Title Block Exceptions\n\n")
+		b.WriteString(markdownTitleBlockExceptions(result.TitleBlockExceptions, func(page int) string {
+			return pageLink(result.Chunks, flavor, page)
+		}))
+		b.WriteString("\n")
+	}
+	if len(result.Index) > 0 {
+		b.WriteString("## Index\n\n")
+		b.WriteString(markdownIndex(result.Index, func(page int) string {
+			return pageLink(result.Chunks, flavor, page)
+		}))
+		b.WriteString("\n")
+	}
+	if len(result.Standards) > 0 {
+		b.WriteString("## Standards Cited\n\n")
+		b.WriteString(markdownStandards(result.Standards, func(page int) string {
+			return pageLink(result.Chunks, flavor, page)
+		}))
+		b.WriteString("\n")
+	}
+	if len(result.MasterBOM) > 0 {
+		b.WriteString("## Master BOM\n\n")
+		b.WriteString(markdownMasterBOM(result.MasterBOM))
+		b.WriteString("\n")
+	}
+	if result.AssemblyCost != nil {
+		b.WriteString("## Estimated Assembly Cost\n\n")
+		b.WriteString(markdownAssemblyCost(result.AssemblyCost))
+		b.WriteString("\n")
+	}
+	b.WriteString("## Cost Breakdown\n\n")
+	b.WriteString(markdownCostTable(result))
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// chunkTitle is the section heading for a chunk; it must stay plain text so
+// the anchor derived from it is predictable
+func chunkTitle(chunk ChunkAnalysis) string {
+	if chunk.StartPage == chunk.EndPage {
+		return fmt.Sprintf("Page %d", chunk.StartPage)
+	}
+	return fmt.Sprintf("Pages %d to %d", chunk.StartPage, chunk.EndPage)
+}
+
+// pageLink formats a page number, linked to the section of the chunk that
+// contains it when the flavor supports anchors
+func pageLink(chunks []ChunkAnalysis, flavor markdownFlavor, page int) string {
+	for _, chunk := range chunks {
+		if page >= chunk.StartPage && page <= chunk.EndPage {
+			if anchor := flavor.anchor(chunkTitle(chunk)); anchor != "" {
+				return fmt.Sprintf("[%d](%s)", page, anchor)
+			}
+		}
+	}
+	return fmt.Sprintf("%d", page)
+}
+
+// chunkSubtitle describes the drawing on a chunk when structured data is available
+func chunkSubtitle(chunk ChunkAnalysis) string {
+	if chunk.Metadata == nil {
+		return ""
+	}
+	var parts []string
+	for _, s := range []string{chunk.Metadata.DrawingNumber, chunk.Metadata.Title} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	if chunk.Metadata.Revision != "" {
+		parts = append(parts, "Rev "+chunk.Metadata.Revision)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// writeTOCEntry writes one contents line, linked when the flavor supports anchors
+func writeTOCEntry(b *strings.Builder, flavor markdownFlavor, title, subtitle string) {
+	entry := title
+	if anchor := flavor.anchor(title); anchor != "" {
+		entry = fmt.Sprintf("[%s](%s)", title, anchor)
+	}
+	if subtitle != "" {
+		entry += " — " + EscapeInline(subtitle)
+	}
+	fmt.Fprintf(b, "- %s\n", entry)
+}
+
+// normalizeMarkdown adapts model output to the flavor: the leading "# Page N"
+// heading is dropped, other headings are nested below the section heading
+// (bold text where the flavor has no heading that deep),
+// and tables get blank lines around them, a separator row, and equal column counts
+func normalizeMarkdown(text string, flavor markdownFlavor) string {
+	lines := strings.Split(strings.ReplaceAll(strings.TrimSpace(text), "\r\n", "\n"), "\n")
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "# Page") {
+		lines = lines[1:]
+	}
+
+	var out []string
+	inFence := false
+	var table []string
+	flushTable := func() {
+		if len(table) == 0 {
+			return
+		}
+		if len(out) > 0 && out[len(out)-1] != "" {
+			out = append(out, "")
+		}
+		out = append(out, normalizeTable(table)...)
+		out = append(out, "")
+		table = nil
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			flushTable()
+			inFence = !inFence
+			out = append(out, line)
+			continue
+		}
+		if inFence {
+			out = append(out, line)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "|") {
+			table = append(table, trimmed)
+			continue
+		}
+		flushTable()
+
+		if m := headingPattern.FindStringSubmatch(trimmed); m != nil {
+			// Models use ## for the numbered sections; those become ###
+			heading := strings.Repeat("#", max(3, len(m[1])+1)) + " " + m[2]
+			if len(m[1])+1 > flavor.maxHeading {
+				heading = "**" + strings.Trim(m[2], "*") + "**"
+			}
+			if len(out) > 0 && out[len(out)-1] != "" {
+				out = append(out, "")
+			}
+			out = append(out, heading, "")
+			continue
+		}
+		if trimmed == "" && len(out) > 0 && out[len(out)-1] == "" {
+			continue
+		}
+		out = append(out, line)
+	}
+	flushTable()
+	if inFence {
+		out = append(out, "```")
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// normalizeTable makes every row of a pipe table have the header's column
+// count and inserts the separator row when the model omitted it
+func normalizeTable(rows []string) []string {
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+		cells[i] = strings.Split(row, "|")
+		for j := range cells[i] {
+			cells[i][j] = strings.TrimSpace(cells[i][j])
+		}
+	}
+
+	columns := len(cells[0])
+	hasSeparator := len(cells) > 1 && isSeparatorRow(cells[1])
+	if !hasSeparator {
+		separator := make([]string, columns)
+		for j := range separator {
+			separator[j] = "---"
+		}
+		cells = append([][]string{cells[0], separator}, cells[1:]...)
+	}
+
+	out := make([]string, 0, len(cells))
+	for i, row := range cells {
+		switch {
+		case len(row) < columns:
+			for len(row) < columns {
+				row = append(row, "")
+			}
+		case len(row) > columns:
+			// Extra cells usually come from an unescaped pipe; keep the text in the last column
+			row = append(row[:columns-1], strings.Join(row[columns-1:], " / "))
+		}
+		if i == 1 {
+			for j := range row {
+				if row[j] == "" {
+					row[j] = "---"
+				}
+			}
+		}
+		out = append(out, "| "+strings.Join(row, " | ")+" |")
+	}
+	return out
+}
+
+// isSeparatorRow reports whether a table row is the header separator
+func isSeparatorRow(row []string) bool {
+	for _, cell := range row {
+		if !separatorCell.MatchString(cell) {
+			return false
+		}
+	}
+	return true
+}
+
+// EscapeInline keeps free text from breaking table cells or links
+func EscapeInline(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
@@ -0,0 +1,130 @@
+package pdfanalysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// MergeResults combines chunks from all inputs keyed by page range. When the
+// same pages were analyzed more than once, successful analyses beat failed
+// ones; differing successful analyses are reported as conflicts and resolved
+// according to prefer. Inputs of different documents are an error. The
+// merged result is Interrupted when an input was and pages are still missing.
+func MergeResults(inputs []*FullAnalysisResult, prefer string) (*FullAnalysisResult, []string, error) {
+	type pageKey struct{ start, end int }
+	byPages := make(map[pageKey]ChunkAnalysis)
+	var conflicts []string
+
+	merged := &FullAnalysisResult{PDFPath: inputs[0].PDFPath, Build: Build()}
+	var duration time.Duration
+	interrupted := false
+	for i, input := range inputs {
+		// Runs are sequential, so their wall-clock times add up
+		if d, err := time.ParseDuration(input.ProcessingTime); err == nil {
+			duration += d
+		}
+		if filepath.Base(input.PDFPath) != filepath.Base(merged.PDFPath) {
+			return nil, nil, fmt.Errorf("input %d is for %s, expected %s", i+1, input.PDFPath, merged.PDFPath)
+		}
+		merged.TotalPages = max(merged.TotalPages, input.TotalPages)
+		interrupted = interrupted || input.Interrupted
+		switch {
+		case i == 0:
+			merged.Prompt = input.Prompt
+		case input.Prompt != nil && (merged.Prompt == nil || *input.Prompt != *merged.Prompt):
+			conflicts = append(conflicts, fmt.Sprintf("input %d was analyzed with prompt %s", i+1, input.Prompt))
+		}
+		if input.Pricing != nil {
+			merged.Pricing = input.Pricing // The latest run's prices
+		}
+
+		for _, chunk := range input.Chunks {
+			key := pageKey{chunk.StartPage, chunk.EndPage}
+			existing, ok := byPages[key]
+			switch {
+			case !ok:
+				byPages[key] = chunk
+			case existing.Error != "" && chunk.Error == "":
+				byPages[key] = chunk
+			case chunk.Error != "":
+				// Keep the existing result; a failure never replaces anything
+			case existing.Analysis == chunk.Analysis:
+				// Identical re-runs are not conflicts
+			default:
+				conflicts = append(conflicts, fmt.Sprintf("%s analyzed differently in input %d", PageRangeLabel(chunk.StartPage, chunk.EndPage), i+1))
+				if prefer == "newest" && chunk.Timestamp.After(existing.Timestamp) {
+					byPages[key] = chunk
+				}
+			}
+		}
+	}
+
+	for _, chunk := range byPages {
+		merged.Chunks = append(merged.Chunks, chunk)
+	}
+	sortChunks(merged.Chunks)
+	for i := range merged.Chunks {
+		// Chunks with different page ranges can still cover the same page
+		if i > 0 && merged.Chunks[i].StartPage <= merged.Chunks[i-1].EndPage {
+			prev, cur := merged.Chunks[i-1], merged.Chunks[i]
+			conflicts = append(conflicts, fmt.Sprintf("%s overlaps %s",
+				PageRangeLabel(cur.StartPage, cur.EndPage), PageRangeLabel(prev.StartPage, prev.EndPage)))
+		}
+	}
+
+	recomputeDerived(merged)
+	// A later run that analyzed the missing pages completes the result
+	merged.Interrupted = interrupted && CoveredPages(merged.Chunks) < merged.TotalPages
+	merged.ProcessingTime = duration.String()
+	merged.GeneratedAt = time.Now()
+	return merged, conflicts, nil
+}
+
+// RecomputeTotals derives all result totals from the chunks slice
+func RecomputeTotals(result *FullAnalysisResult) {
+	result.SumTotals()
+	_, result.DuplicateSavings = duplicateSavings(result.Chunks)
+	result.Savings = computeSavings(result)
+	result.InputBreakdown = totalAttribution(result.Chunks)
+}
+
+// sortChunks puts chunks in page order and numbers them. Ties are broken by
+// the end page, so the order never depends on how the chunks were collected.
+func sortChunks(chunks []ChunkAnalysis) {
+	sort.SliceStable(chunks, func(i, j int) bool {
+		if chunks[i].StartPage != chunks[j].StartPage {
+			return chunks[i].StartPage < chunks[j].StartPage
+		}
+		return chunks[i].EndPage < chunks[j].EndPage
+	})
+	for i := range chunks {
+		chunks[i].ChunkNumber = i + 1
+	}
+}
+
+// recomputeDerived rebuilds everything in a result that is derived from its
+// chunks: the totals and the cross-page aggregates. It works on the final
+// chunks in page order only, so it can be called again after any change and
+// always gives the same result for the same chunks.
+func recomputeDerived(result *FullAnalysisResult) {
+	sortChunks(result.Chunks)
+	RecomputeTotals(result)
+	result.MasterBOM = aggregateBOM(result.Chunks)
+	result.Discrepancies = checkConsistency(result.Chunks, result.MasterBOM)
+	result.TitleBlockExceptions = auditTitleBlocks(result.Chunks)
+	result.Index = buildIndex(result.Chunks)
+	result.Standards = extractStandards(result.Chunks)
+}
+
+// CoveredPages counts the distinct pages present in the chunks
+func CoveredPages(chunks []ChunkAnalysis) int {
+	pages := make(map[int]bool)
+	for _, chunk := range chunks {
+		for p := chunk.StartPage; p <= chunk.EndPage; p++ {
+			pages[p] = true
+		}
+	}
+	return len(pages)
+}
@@ -0,0 +1,36 @@
+package pdfanalysis
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeResults(t *testing.T) {
+	first := &FullAnalysisResult{PDFPath: "drawing.pdf", TotalPages: 3, Interrupted: true,
+		Chunks: []ChunkAnalysis{{StartPage: 1, EndPage: 1, Analysis: "Page one."}}}
+	second := &FullAnalysisResult{PDFPath: "drawing.pdf", TotalPages: 3,
+		Chunks: []ChunkAnalysis{{StartPage: 2, EndPage: 2, Analysis: "Page two."}}}
+
+	merged, _, err := MergeResults([]*FullAnalysisResult{first, second}, "newest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !merged.Interrupted {
+		t.Error("page 3 is missing, but the merged result is not interrupted")
+	}
+
+	third := &FullAnalysisResult{PDFPath: "drawing.pdf", TotalPages: 3,
+		Chunks: []ChunkAnalysis{{StartPage: 3, EndPage: 3, Analysis: "Page three."}}}
+	merged, _, err = MergeResults([]*FullAnalysisResult{first, second, third}, "newest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Interrupted || len(merged.Chunks) != 3 {
+		t.Errorf("merged %d chunks, interrupted %v; want 3 and complete", len(merged.Chunks), merged.Interrupted)
+	}
+
+	other := &FullAnalysisResult{PDFPath: "other.pdf", TotalPages: 1}
+	if _, _, err := MergeResults([]*FullAnalysisResult{first, other}, "newest"); err == nil || !strings.Contains(err.Error(), "other.pdf") {
+		t.Errorf("merging another document: error %v", err)
+	}
+}
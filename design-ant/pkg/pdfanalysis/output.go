@@ -0,0 +1,64 @@
+package pdfanalysis
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GenerateOutputFilename creates an output filename based on input PDF
+func GenerateOutputFilename(pdfPath, format string) string {
+	base := filepath.Base(pdfPath)
+	ext := filepath.Ext(base)
+	name := base[:len(base)-len(ext)]
+	return fmt.Sprintf("%s_analysis.%s", name, format)
+}
+
+// SaveJSONOutput saves results to JSON file, compressed if the name ends in .gz or .zst
+func SaveJSONOutput(filename string, result FullAnalysisResult) error {
+	result.SchemaVersion = CurrentSchemaVersion
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileCompressed(filename, jsonData)
+}
+
+// maxStreamLine bounds one JSONL line; a page analysis is far smaller
+const maxStreamLine = 64 << 20
+
+// loadChunkStream reads the chunks of a JSONL stream. A last line that does
+// not parse was cut off by a crash and is ignored.
+func loadChunkStream(filename string) ([]ChunkAnalysis, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var chunks []ChunkAnalysis
+	var pending error
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 1<<20), maxStreamLine)
+	for line := 1; scanner.Scan(); line++ {
+		if pending != nil {
+			return nil, pending
+		}
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var chunk ChunkAnalysis
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			pending = fmt.Errorf("%s line %d: %v", filename, line, err)
+			continue
+		}
+		chunks = append(chunks, chunk)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", filename, err)
+	}
+	return chunks, nil
+}
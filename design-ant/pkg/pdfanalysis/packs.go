@@ -0,0 +1,320 @@
+package pdfanalysis
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// packSchema is the structured data of a prompt pack: what the extraction
+// tool records, the sections the pack adds to extractionSchema, and the
+// checks of their rows beyond what decoding catches
+type packSchema struct {
+	records    string   // What the tool call holds, for the prompt and the tool description
+	properties string   // JSON schema properties added to extractionSchema
+	required   []string // Top-level sections the tool call must have
+	validate   func(StructuredData) []string
+}
+
+// mechanicalSchema is extractionSchema as it is, also for packs without a schema
+var mechanicalSchema = &packSchema{
+	records:  "title block,\nEVERY BOM row, EVERY dimension, and EVERY note",
+	required: []string{"metadata", "bom_items", "dimensions", "notes"},
+}
+
+//go:embed prompts/pid.tmpl
+var pidPrompt string
+
+//go:embed prompts/electrical.tmpl
+var electricalPrompt string
+
+//go:embed prompts/architectural.tmpl
+var architecturalPrompt string
+
+// Built-in packs for other kinds of drawings, each with its own structured
+// data sections for -structured
+var (
+	// PIDPromptPack reads P&IDs: instrument tags, line numbers, and equipment
+	PIDPromptPack = withSchema(mustPromptPack("pid", pidPrompt), pidSchema)
+	// ElectricalPromptPack reads schematics: reference designators and nets
+	ElectricalPromptPack = withSchema(mustPromptPack("electrical", electricalPrompt), electricalSchema)
+	// ArchitecturalPromptPack reads plans and schedules: rooms, doors, and windows
+	ArchitecturalPromptPack = withSchema(mustPromptPack("architectural", architecturalPrompt), architecturalSchema)
+)
+
+// PromptPacks are the built-in packs by name, for -prompt-pack
+var PromptPacks = map[string]PromptPack{
+	MechanicalPromptPack.Name:    MechanicalPromptPack,
+	PIDPromptPack.Name:           PIDPromptPack,
+	ElectricalPromptPack.Name:    ElectricalPromptPack,
+	ArchitecturalPromptPack.Name: ArchitecturalPromptPack,
+}
+
+// PromptPackNames returns the names of the built-in packs, sorted
+func PromptPackNames() []string {
+	names := make([]string, 0, len(PromptPacks))
+	for name := range PromptPacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// withSchema returns pack with its structured data schema
+func withSchema(pack PromptPack, schema *packSchema) PromptPack {
+	pack.schema = schema
+	return pack
+}
+
+// structuredSchema returns the structured data schema of the pack; packs
+// from a template alone extract the mechanical sections
+func (p PromptPack) structuredSchema() *packSchema {
+	if p.schema == nil {
+		return mechanicalSchema
+	}
+	return p.schema
+}
+
+// pidSchema holds instruments, lines, and equipment
+var pidSchema = &packSchema{
+	records:  "title block,\nEVERY instrument tag, EVERY line number, EVERY equipment item, and EVERY note",
+	required: []string{"metadata", "instruments", "lines", "equipment", "notes"},
+	properties: `{
+  "instruments": {
+    "type": "array",
+    "items": {
+      "type": "object",
+      "properties": {
+        "tag": {"type": "string"},
+        "function": {"type": "string"},
+        "loop": {"type": "string"},
+        "location": {"type": "string", "enum": ["field", "panel", "control_system", "local_panel"]},
+        "service": {"type": "string"},
+        "description": {"type": "string"}
+      },
+      "required": ["tag"]
+    }
+  },
+  "lines": {
+    "type": "array",
+    "items": {
+      "type": "object",
+      "properties": {
+        "line_number": {"type": "string"},
+        "size": {"type": "string"},
+        "service": {"type": "string"},
+        "spec": {"type": "string"},
+        "insulation": {"type": "string"},
+        "from": {"type": "string"},
+        "to": {"type": "string"}
+      },
+      "required": ["line_number"]
+    }
+  },
+  "equipment": {
+    "type": "array",
+    "items": {
+      "type": "object",
+      "properties": {
+        "tag": {"type": "string"},
+        "description": {"type": "string"},
+        "rating": {"type": "string"}
+      },
+      "required": ["tag"]
+    }
+  }
+}`,
+	validate: func(data StructuredData) []string {
+		var errs []string
+		for i, instrument := range data.Instruments {
+			if strings.TrimSpace(instrument.Tag) == "" {
+				errs = append(errs, fmt.Sprintf("instruments[%d].tag: required", i))
+			}
+			if instrument.Location != "" && !containsString([]string{"field", "panel", "control_system", "local_panel"}, instrument.Location) {
+				errs = append(errs, fmt.Sprintf("instruments[%d].location: %q must be field, panel, control_system, or local_panel", i, instrument.Location))
+			}
+		}
+		for i, line := range data.Lines {
+			if strings.TrimSpace(line.LineNumber) == "" {
+				errs = append(errs, fmt.Sprintf("lines[%d].line_number: required", i))
+			}
+		}
+		for i, equipment := range data.Equipment {
+			if strings.TrimSpace(equipment.Tag) == "" {
+				errs = append(errs, fmt.Sprintf("equipment[%d].tag: required", i))
+			}
+		}
+		return errs
+	},
+}
+
+// electricalSchema holds components and nets; a parts list on the sheet
+// goes to bom_items
+var electricalSchema = &packSchema{
+	records:  "title block,\nEVERY component, EVERY net with the pins it connects, and EVERY note",
+	required: []string{"metadata", "components", "nets", "notes"},
+	properties: `{
+  "components": {
+    "type": "array",
+    "items": {
+      "type": "object",
+      "properties": {
+        "designator": {"type": "string"},
+        "value": {"type": "string"},
+        "part_number": {"type": "string"},
+        "description": {"type": "string"}
+      },
+      "required": ["designator"]
+    }
+  },
+  "nets": {
+    "type": "array",
+    "items": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "nodes": {"type": "array", "items": {"type": "string"}}
+      },
+      "required": ["name", "nodes"]
+    }
+  }
+}`,
+	validate: func(data StructuredData) []string {
+		var errs []string
+		for i, component := range data.Components {
+			if strings.TrimSpace(component.Designator) == "" {
+				errs = append(errs, fmt.Sprintf("components[%d].designator: required", i))
+			}
+		}
+		for i, net := range data.Nets {
+			if strings.TrimSpace(net.Name) == "" {
+				errs = append(errs, fmt.Sprintf("nets[%d].name: required", i))
+			}
+		}
+		return errs
+	},
+}
+
+// architecturalSchema holds rooms and openings next to the dimensions
+var architecturalSchema = &packSchema{
+	records:  "title block,\nEVERY room, EVERY door and window, EVERY dimension, and EVERY note",
+	required: []string{"metadata", "rooms", "openings", "dimensions", "notes"},
+	properties: `{
+  "rooms": {
+    "type": "array",
+    "items": {
+      "type": "object",
+      "properties": {
+        "number": {"type": "string"},
+        "name": {"type": "string"},
+        "area": {"type": "string"},
+        "level": {"type": "string"},
+        "finishes": {"type": "string"}
+      }
+    }
+  },
+  "openings": {
+    "type": "array",
+    "items": {
+      "type": "object",
+      "properties": {
+        "mark": {"type": "string"},
+        "kind": {"type": "string", "enum": ["door", "window"]},
+        "size": {"type": "string"},
+        "type": {"type": "string"},
+        "fire_rating": {"type": "string"}
+      },
+      "required": ["mark", "kind"]
+    }
+  }
+}`,
+	validate: func(data StructuredData) []string {
+		var errs []string
+		for i, room := range data.Rooms {
+			if room.Number == "" && room.Name == "" {
+				errs = append(errs, fmt.Sprintf("rooms[%d]: needs a number or name", i))
+			}
+		}
+		for i, opening := range data.Openings {
+			if strings.TrimSpace(opening.Mark) == "" {
+				errs = append(errs, fmt.Sprintf("openings[%d].mark: required", i))
+			}
+			if opening.Kind != "door" && opening.Kind != "window" {
+				errs = append(errs, fmt.Sprintf("openings[%d].kind: %q must be door or window", i, opening.Kind))
+			}
+		}
+		return errs
+	},
+}
+
+// dataTable is a titled table of structured rows for the exports
+type dataTable struct {
+	title   string
+	headers []string
+	rows    [][]string
+}
+
+// packTables returns the tables of the pack sections a page has, in the
+// order of the packs
+func packTables(data StructuredData) []dataTable {
+	var tables []dataTable
+	add := func(title string, headers []string, count int, row func(i int) []string) {
+		if count == 0 {
+			return
+		}
+		table := dataTable{title: title, headers: headers}
+		for i := 0; i < count; i++ {
+			table.rows = append(table.rows, row(i))
+		}
+		tables = append(tables, table)
+	}
+	add("Instruments", []string{"Tag", "Function", "Loop", "Location", "Service", "Description"}, len(data.Instruments), func(i int) []string {
+		x := data.Instruments[i]
+		return []string{x.Tag, x.Function, x.Loop, x.Location, x.Service, x.Description}
+	})
+	add("Lines", []string{"Line number", "Size", "Service", "Spec", "Insulation", "From", "To"}, len(data.Lines), func(i int) []string {
+		x := data.Lines[i]
+		return []string{x.LineNumber, x.Size, x.Service, x.Spec, x.Insulation, x.From, x.To}
+	})
+	add("Equipment", []string{"Tag", "Description", "Rating"}, len(data.Equipment), func(i int) []string {
+		x := data.Equipment[i]
+		return []string{x.Tag, x.Description, x.Rating}
+	})
+	add("Components", []string{"Designator", "Value", "Part number", "Description"}, len(data.Components), func(i int) []string {
+		x := data.Components[i]
+		return []string{x.Designator, x.Value, x.PartNumber, x.Description}
+	})
+	add("Nets", []string{"Net", "Connections"}, len(data.Nets), func(i int) []string {
+		x := data.Nets[i]
+		return []string{x.Name, strings.Join(x.Nodes, ", ")}
+	})
+	add("Rooms", []string{"Number", "Name", "Area", "Level", "Finishes"}, len(data.Rooms), func(i int) []string {
+		x := data.Rooms[i]
+		return []string{x.Number, x.Name, x.Area, x.Level, x.Finishes}
+	})
+	add("Doors and windows", []string{"Mark", "Kind", "Size", "Type", "Fire rating"}, len(data.Openings), func(i int) []string {
+		x := data.Openings[i]
+		return []string{x.Mark, x.Kind, x.Size, x.Type, x.FireRating}
+	})
+	return tables
+}
+
+// markdownPackTables renders the pack sections of a page as tables
+func markdownPackTables(data StructuredData) string {
+	var b strings.Builder
+	for _, table := range packTables(data) {
+		fmt.Fprintf(&b, "**%s**\n\n", table.title)
+		b.WriteString("| " + strings.Join(table.headers, " | ") + " |\n")
+		b.WriteString(strings.Repeat("| --- ", len(table.headers)) + "|\n")
+		for _, row := range table.rows {
+			cells := make([]string, len(row))
+			for i, cell := range row {
+				cells[i] = EscapeInline(cell)
+			}
+			b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
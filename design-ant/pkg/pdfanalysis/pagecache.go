@@ -0,0 +1,143 @@
+package pdfanalysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultCacheDir returns DESIGN_ANT_CACHE or a pages directory in the user cache directory
+func defaultCacheDir() string {
+	if dir := os.Getenv("DESIGN_ANT_CACHE"); dir != "" {
+		return dir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "design-ant", "pages")
+}
+
+// cachedPage is one page analysis in the local cache
+type cachedPage struct {
+	Source string        `json:"source"` // Document and page the analysis was made for
+	Chunk  ChunkAnalysis `json:"chunk"`
+}
+
+// pageCache stores successful page analyses on disk, one file per key. The
+// key covers the page content, the prompt, the model, and every option that
+// changes the result, so a hit is exactly what the API would be asked again.
+type pageCache struct {
+	dir string
+}
+
+// cacheKey identifies the analysis of a page; pages without a fingerprint
+// are not cached. The prompt is hashed with a placeholder page number and
+// without the document name, so a page that moved in a new revision or was
+// copied into another document still hits; only its -context-file text is
+// that of its page number.
+func cacheKey(config *Config, pageHash string, pageNumber int, route PageRoute) string {
+	if pageHash == "" {
+		return ""
+	}
+	data := promptData(config, 0, nil)
+	data.Document = ""
+	data.Context = pageContext(config, pageNumber)
+	text, err := config.PromptPack.Render(data)
+	if err != nil {
+		return "" // Not cached; Validate already rejects templates that fail
+	}
+	prompt := sha256.Sum256([]byte(systemPrompt(config) + "\x00" + text))
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%x\x00%s\x00%s\x00", pageHash, prompt, config.ModelName, route.Mode)
+	if err := hashExamples(h, config.Examples); err != nil {
+		return "" // Not cached; the request fails on the same file
+	}
+	if config.TwoStage {
+		fmt.Fprintf(h, "two-stage:%s\x00", config.ClassifyModel)
+	}
+	if config.OutputLang != "" {
+		fmt.Fprintf(h, "lang:%s:%s:%s\x00", config.OutputLang, config.LangMode, config.TranslateModel)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// path returns the file of a key, sharded by its first two characters
+func (c *pageCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// lookup returns the cached analysis for a key, renumbered for the current
+// document. Cached pages cost nothing in this run; unreadable entries are
+// treated as misses.
+func (c *pageCache) lookup(key string, chunkNumber, page int) (ChunkAnalysis, bool) {
+	if c == nil || key == "" {
+		return ChunkAnalysis{}, false
+	}
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return ChunkAnalysis{}, false
+	}
+	var cached cachedPage
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return ChunkAnalysis{}, false
+	}
+	chunk := cached.Chunk
+	chunk.Analysis = renumberPageHeading(chunk.Analysis, chunk.StartPage, page)
+	chunk.ChunkNumber = chunkNumber
+	chunk.StartPage, chunk.EndPage = page, page
+	markCopied(&chunk)
+	chunk.ReusedFrom = "cache: " + cached.Source
+	return chunk, true
+}
+
+// store saves a successful analysis under its key. The file is written to a
+// temporary name first so concurrent runs never read a partial entry.
+func (c *pageCache) store(key, source string, chunk ChunkAnalysis) error {
+	if c == nil || key == "" {
+		return nil
+	}
+	data, err := json.Marshal(cachedPage{Source: source, Chunk: chunk})
+	if err != nil {
+		return fmt.Errorf("error marshaling cache entry: %v", err)
+	}
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating cache directory: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating cache entry: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing cache entry: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing cache entry: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing cache entry: %v", err)
+	}
+	return nil
+}
+
+// renumberPageHeading rewrites the leading "# Page N" heading of an analysis
+// made for another page number
+func renumberPageHeading(analysis string, from, to int) string {
+	heading := fmt.Sprintf("# Page %d", from)
+	if from == to || !strings.HasPrefix(analysis, heading) {
+		return analysis
+	}
+	if rest := analysis[len(heading):]; rest != "" && rest[0] >= '0' && rest[0] <= '9' {
+		return analysis // "# Page 12" when renumbering page 1
+	}
+	return fmt.Sprintf("# Page %d", to) + analysis[len(heading):]
+}
@@ -0,0 +1,105 @@
+package pdfanalysis
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// SplitPDFIntoChunks splits PDF into chunks and returns chunk file paths
+func SplitPDFIntoChunks(pdfPath, tempDir string, chunkSize, totalPages int) ([]ChunkInfo, error) {
+	var chunks []ChunkInfo
+	for _, chunk := range planChunks(totalPages, chunkSize) {
+		chunk, err := splitChunk(fileSource(pdfPath), filepath.Join(tempDir, chunkFileName(chunk)), chunk)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+// planChunks lists the page ranges of the chunks, without files
+func planChunks(totalPages, chunkSize int) []ChunkInfo {
+	var chunks []ChunkInfo
+	for startPage := 0; startPage < totalPages; startPage += chunkSize {
+		endPage := min(startPage+chunkSize, totalPages)
+		chunks = append(chunks, ChunkInfo{StartPage: startPage, EndPage: endPage - 1}) // 0-indexed
+	}
+	return chunks
+}
+
+// chunkFileName names the PDF of a chunk after its pages, chunk_3.pdf or
+// chunk_3-5.pdf
+func chunkFileName(chunk ChunkInfo) string {
+	if chunk.StartPage == chunk.EndPage {
+		return fmt.Sprintf("chunk_%d.pdf", chunk.StartPage+1)
+	}
+	return fmt.Sprintf("chunk_%d-%d.pdf", chunk.StartPage+1, chunk.EndPage+1)
+}
+
+// splitChunk writes the pages of a planned chunk to one PDF at path and
+// returns the chunk with its path
+func splitChunk(src pdfSource, path string, chunk ChunkInfo) (ChunkInfo, error) {
+	startPage, endPage := chunk.StartPage+1, chunk.EndPage+1
+	if err := extractPages(src, path, fmt.Sprintf("%d-%d", startPage, endPage)); err != nil {
+		return chunk, fmt.Errorf("error extracting pages %d-%d: %w: %v", startPage, endPage, ErrInvalidPDF, err)
+	}
+	chunk.Path = path
+	return chunk, nil
+}
+
+// extractPages writes the selected pages of a source, e.g. "3-5", to a new
+// PDF at path. pdfcpu's ExtractPages would write a file per page under
+// names of its own; Trim writes them all to the one file.
+func extractPages(src pdfSource, path, selection string) error {
+	file, closeFile, err := src.open()
+	if err != nil {
+		return err
+	}
+	defer closeFile()
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := api.Trim(file, out, []string{selection}, model.NewDefaultConfiguration()); err != nil {
+		out.Close()
+		os.Remove(path)
+		return err
+	}
+	return out.Close()
+}
+
+// GetPageCount returns the total number of pages in a PDF
+func GetPageCount(pdfPath string) (int, error) {
+	return getPageCount(fileSource(pdfPath))
+}
+
+// getPageCount returns the total number of pages of a source
+func getPageCount(src pdfSource) (int, error) {
+	file, closeFile, err := src.open()
+	if err != nil {
+		return 0, err
+	}
+	defer closeFile()
+
+	conf := model.NewDefaultConfiguration()
+	pages, err := api.PageCount(file, conf)
+	if err != nil {
+		return 0, fmt.Errorf("error getting page count: %w: %v", ErrInvalidPDF, err)
+	}
+	return pages, nil
+}
+
+// ExtractPage writes a single page (1-based) to its own PDF in tempDir
+func ExtractPage(pdfPath, tempDir string, page int) (string, error) {
+	path := filepath.Join(tempDir, fmt.Sprintf("page_%d.pdf", page))
+	if err := extractPages(fileSource(pdfPath), path, fmt.Sprintf("%d", page)); err != nil {
+		return "", fmt.Errorf("error extracting page %d: %v", page, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,111 @@
+package pdfanalysis
+
+import (
+	"context"
+	"fmt"
+
+	"design-ant/internal/tempfiles"
+)
+
+// pipelineDepth is how many chunks per worker may be split and not yet
+// finished. Splitting runs ahead of the API by this much and then waits, so
+// PDF work overlaps request latency while the number of chunk files stays
+// bounded on big documents.
+const pipelineDepth = 2
+
+// splitStage splits the document one chunk at a time for the page queue.
+// It is the first stage of the pipeline:
+//
+//	split → queue (dedup, resume, reuse, cache) → workers (encode, submit) → persist
+//
+// Each stage hands chunks to the next through a bounded channel, so a slow
+// API holds back splitting instead of piling up chunk files.
+type splitStage struct {
+	source pdfSource
+	temp   *tempfiles.Dir // Chunk PDFs and rendered pages of the run
+	limits Limits
+	caps   Capabilities // Of the provider the chunks are sent to
+	chunks []ChunkInfo  // Split so far; complete once the output channel is closed
+}
+
+// run splits the planned chunks in order into out. It stops early when ctx
+// is canceled; the caller closes out. Size limits are checked before a chunk
+// is handed on, so an oversized chunk is never sent; a -max-total-mb limit
+// needs every chunk, so with one set the whole document is split first.
+func (s *splitStage) run(ctx context.Context, plan []ChunkInfo, out chan<- ChunkInfo) error {
+	if s.limits.MaxTotalMB > 0 {
+		for _, chunk := range plan {
+			if ctx.Err() != nil {
+				return nil
+			}
+			chunk, err := s.split(ctx, chunk)
+			if err != nil {
+				return err
+			}
+			s.chunks = append(s.chunks, chunk)
+		}
+		if err := checkChunkLimits(s.limits, s.caps, s.chunks); err != nil {
+			return err
+		}
+		for _, chunk := range s.chunks {
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		return nil
+	}
+
+	for _, chunk := range plan {
+		if ctx.Err() != nil {
+			return nil
+		}
+		chunk, err := s.split(ctx, chunk)
+		if err != nil {
+			return err
+		}
+		s.chunks = append(s.chunks, chunk)
+		select {
+		case out <- chunk:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+// split writes one chunk and checks it against the per-chunk limit. A single
+// page over the limit is rendered as images instead, since it cannot be
+// split any further as a PDF; so is every page for a provider that takes
+// no PDFs.
+func (s *splitStage) split(ctx context.Context, chunk ChunkInfo) (ChunkInfo, error) {
+	_, span := startSpan(ctx, "split", "page", chunk.StartPage+1)
+	chunk, err := splitChunk(s.source, s.temp.File(chunkFileName(chunk)), chunk)
+	span.end(err)
+	if err != nil {
+		return chunk, fmt.Errorf("error splitting PDF: %w", err)
+	}
+	var limitErr error
+	if !s.caps.PDFInput {
+		limitErr = fmt.Errorf("the %s provider takes no PDF documents", s.caps.Provider)
+	} else if limitErr = checkChunkLimits(Limits{MaxChunkMB: s.limits.MaxChunkMB}, s.caps, []ChunkInfo{chunk}); limitErr == nil {
+		return chunk, nil
+	}
+	if chunk.StartPage != chunk.EndPage || !s.caps.ImageInput {
+		return chunk, limitErr
+	}
+	_, span = startSpan(ctx, "render", "page", chunk.StartPage+1)
+	rendered, err := renderOversizedPage(ctx, s.source, s.temp, chunk, s.limits, s.caps)
+	span.end(err)
+	if err != nil {
+		return chunk, fmt.Errorf("%w; rendering it as images failed: %v", limitErr, err)
+	}
+	if !s.caps.PDFInput {
+		pagef(ctx, "  🖼️  Page %d: the %s provider takes no PDFs, sending it %s\n", chunk.StartPage+1, s.caps.Provider, rendered.Rendered)
+		return rendered, nil
+	}
+	size, _ := chunkEncodedSize(chunk)
+	logf(ctx, "  🖼️  Page %d encodes to %s as PDF, sending it %s\n", chunk.StartPage+1, FormatMB(size), rendered.Rendered)
+	return rendered, nil
+}
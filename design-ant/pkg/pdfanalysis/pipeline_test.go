@@ -0,0 +1,252 @@
+package pdfanalysis
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// mockProvider answers Messages API and token counting requests without a
+// network, counting the page requests
+type mockProvider struct {
+	requests atomic.Int64
+}
+
+func (m *mockProvider) RoundTrip(req *http.Request) (*http.Response, error) {
+	io.Copy(io.Discard, req.Body)
+	req.Body.Close()
+	body := `{"content": [{"type": "text", "text": "A bracket with two holes."}], "stop_reason": "end_turn", "usage": {"input_tokens": 1000, "output_tokens": 100}}`
+	if strings.HasSuffix(req.URL.Path, "/count_tokens") {
+		body = `{"input_tokens": 1000}`
+	} else {
+		m.requests.Add(1)
+	}
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+}
+
+// useMockProvider sends the API requests of the test to a mockProvider
+func useMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	mock := &mockProvider{}
+	messageClient, quickClient := MessageClient, QuickClient
+	MessageClient, QuickClient = &http.Client{Transport: mock}, &http.Client{Transport: mock}
+	t.Cleanup(func() { MessageClient, QuickClient = messageClient, quickClient })
+	return mock
+}
+
+// testPDF returns a PDF with the given number of pages, each with its own text
+func testPDF(t *testing.T, pages int) []byte {
+	t.Helper()
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	for i := 1; i <= pages; i++ {
+		pdf.AddPage()
+		pdf.Cell(40, 10, fmt.Sprintf("Drawing sheet %d, part P-%04d", i, i))
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// recordingObserver checks the calls of every run it follows
+type recordingObserver struct {
+	t        *testing.T
+	inCall   atomic.Bool
+	started  map[runChunk]bool
+	done     map[int64]int
+	complete map[int64][]int // Chunk numbers by run, in completion order
+	spent    map[int64]float64
+}
+
+func newRecordingObserver(t *testing.T) *recordingObserver {
+	return &recordingObserver{t: t, started: make(map[runChunk]bool), done: make(map[int64]int),
+		complete: make(map[int64][]int), spent: make(map[int64]float64)}
+}
+
+// enter fails the test when calls overlap
+func (o *recordingObserver) enter() func() {
+	if !o.inCall.CompareAndSwap(false, true) {
+		o.t.Error("observer called while another call was running")
+	}
+	return func() { o.inCall.Store(false) }
+}
+
+func (o *recordingObserver) OnPageStart(run Run, total int, page ChunkAnalysis) {
+	defer o.enter()()
+	key := runChunk{run.ID, page.ChunkNumber}
+	if o.started[key] {
+		o.t.Errorf("run %d: chunk %d started twice", run.ID, page.ChunkNumber)
+	}
+	o.started[key] = true
+}
+
+func (o *recordingObserver) OnPageComplete(run Run, done, total int, page ChunkAnalysis) {
+	defer o.enter()()
+	if !o.started[runChunk{run.ID, page.ChunkNumber}] {
+		o.t.Errorf("run %d: chunk %d completed without starting", run.ID, page.ChunkNumber)
+	}
+	if o.done[run.ID]++; done != o.done[run.ID] {
+		o.t.Errorf("run %d: done = %d, want %d", run.ID, done, o.done[run.ID])
+	}
+	o.complete[run.ID] = append(o.complete[run.ID], page.ChunkNumber)
+}
+
+func (o *recordingObserver) OnRetry(run Run, page ChunkAnalysis, attempt int, err error, delay time.Duration) {
+	defer o.enter()()
+}
+
+func (o *recordingObserver) OnCostUpdate(run Run, spent float64) {
+	defer o.enter()()
+	if spent < o.spent[run.ID] {
+		o.t.Errorf("run %d: spend went down from %f to %f", run.ID, o.spent[run.ID], spent)
+	}
+	o.spent[run.ID] = spent
+}
+
+func TestConcurrentRuns(t *testing.T) {
+	mock := useMockProvider(t)
+	observer := newRecordingObserver(t)
+	analyzer, err := New(WithAPIKey("test"), WithCache(""), WithConcurrency(4), WithObserver(observer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const runs, pages = 4, 6
+	documents := make([][]byte, runs)
+	for i := range documents {
+		// Different documents, so no run copies another's pages
+		documents[i] = testPDF(t, pages+i)
+	}
+	results := make([]*Result, runs)
+	var wg sync.WaitGroup
+	for i := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := analyzer.AnalyzeBytes(context.Background(), documents[i], nil)
+			if err != nil {
+				t.Errorf("run %d: %v", i, err)
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	want := 0
+	for i, result := range results {
+		if result == nil {
+			continue
+		}
+		want += pages + i
+		if len(result.Chunks) != pages+i {
+			t.Errorf("run %d: %d chunks, want %d", i, len(result.Chunks), pages+i)
+		}
+		for _, chunk := range result.Chunks {
+			if chunk.Error != "" || chunk.Analysis == "" {
+				t.Errorf("run %d, chunk %d: error %q, analysis %q", i, chunk.ChunkNumber, chunk.Error, chunk.Analysis)
+			}
+		}
+	}
+	if got := mock.requests.Load(); got != int64(want) {
+		t.Errorf("%d page requests, want %d", got, want)
+	}
+	if len(observer.complete) != runs {
+		t.Fatalf("observer saw %d runs, want %d", len(observer.complete), runs)
+	}
+	totals := make(map[int]bool)
+	for id, chunks := range observer.complete {
+		seen := make(map[int]bool)
+		for _, chunk := range chunks {
+			if seen[chunk] {
+				t.Errorf("run %d: chunk %d completed twice", id, chunk)
+			}
+			seen[chunk] = true
+		}
+		totals[len(chunks)] = true
+		if observer.spent[id] <= 0 {
+			t.Errorf("run %d: no cost reported", id)
+		}
+	}
+	if len(totals) != runs {
+		t.Errorf("runs completed %v chunks, want one run of each size", totals)
+	}
+}
+
+// messageObserver records the lines of every run it follows
+type messageObserver struct {
+	*recordingObserver
+	messages map[int64][]string
+}
+
+func (o *messageObserver) OnMessage(run Run, message string, routine bool) {
+	defer o.enter()()
+	o.messages[run.ID] = append(o.messages[run.ID], message)
+}
+
+func TestRunMessages(t *testing.T) {
+	useMockProvider(t)
+	observer := &messageObserver{recordingObserver: newRecordingObserver(t), messages: make(map[int64][]string)}
+	analyzer, err := New(WithAPIKey("test"), WithCache(""), WithObserver(observer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the console observer nothing goes to stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	_, err = analyzer.AnalyzeBytes(context.Background(), testPDF(t, 2), nil)
+	os.Stdout = stdout
+	w.Close()
+	printed, _ := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(printed) > 0 {
+		t.Errorf("printed %q", printed)
+	}
+	if len(observer.messages) != 1 {
+		t.Fatalf("messages of %d runs, want 1", len(observer.messages))
+	}
+	for _, messages := range observer.messages {
+		if len(messages) == 0 || !strings.Contains(strings.Join(messages, ""), "Total pages: 2") {
+			t.Errorf("messages %q lack the page count", messages)
+		}
+	}
+}
+
+func TestConsoleObserverRuns(t *testing.T) {
+	o := NewConsoleObserver().(*consoleObserver)
+	first, second := Run{ID: 1, Document: "a.pdf"}, Run{ID: 2, Document: "b.pdf"}
+	page := singlePage(1)
+	o.OnPageStart(first, 2, page)
+	o.OnPageStart(second, 2, page)
+	o.OnPageComplete(first, 1, 2, page)
+	if !o.started[runChunk{second.ID, 1}] {
+		t.Error("finishing chunk 1 of one run forgot it was started in the other")
+	}
+	o.OnPageComplete(second, 1, 2, page)
+	if len(o.started) != 0 {
+		t.Errorf("started = %v after every chunk finished", o.started)
+	}
+}
+
+// singlePage returns chunk n of a run analyzing one page per chunk
+func singlePage(n int) ChunkAnalysis {
+	return ChunkAnalysis{ChunkNumber: n, StartPage: n, EndPage: n}
+}
@@ -0,0 +1,184 @@
+package pdfanalysis
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// priceEntry is one row of a price list
+type priceEntry struct {
+	PartNumber string  `json:"part_number"`
+	UnitPrice  float64 `json:"unit_price"`
+	Currency   string  `json:"currency,omitempty"`
+}
+
+// priceList maps normalized part numbers to their prices
+type priceList struct {
+	path     string
+	currency string
+	prices   map[string]float64
+}
+
+// loadPriceList reads a CSV or JSON price list keyed by part number.
+//
+// CSV files need a header row with a part_number column and a unit_price (or
+// price) column; an optional currency column applies to every row. JSON files
+// hold an array of {"part_number", "unit_price", "currency"} objects. All rows
+// must use the same currency.
+func loadPriceList(path string) (*priceList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening price list: %v", err)
+	}
+	defer file.Close()
+
+	var entries []priceEntry
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.NewDecoder(file).Decode(&entries); err != nil {
+			return nil, fmt.Errorf("error parsing price list %s: %v", path, err)
+		}
+	} else if entries, err = readPriceCSV(file); err != nil {
+		return nil, fmt.Errorf("error parsing price list %s: %v", path, err)
+	}
+
+	list := &priceList{path: path, prices: make(map[string]float64)}
+	for i, entry := range entries {
+		key := priceKey(entry.PartNumber)
+		if key == "" {
+			continue
+		}
+		if entry.UnitPrice < 0 {
+			return nil, fmt.Errorf("%s: row %d: negative price for %s", path, i+1, entry.PartNumber)
+		}
+		currency := strings.ToUpper(strings.TrimSpace(entry.Currency))
+		if currency != "" && list.currency != "" && currency != list.currency {
+			return nil, fmt.Errorf("%s: row %d: currency %s differs from %s; convert the list to one currency first", path, i+1, currency, list.currency)
+		}
+		if currency != "" {
+			list.currency = currency
+		}
+		list.prices[key] = entry.UnitPrice
+	}
+	if len(list.prices) == 0 {
+		return nil, fmt.Errorf("%s contains no prices", path)
+	}
+	return list, nil
+}
+
+// readPriceCSV reads the rows of a CSV price list
+func readPriceCSV(r io.Reader) ([]priceEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{"part_number": -1, "unit_price": -1, "currency": -1}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name == "price" {
+			name = "unit_price"
+		}
+		if _, ok := columns[name]; ok {
+			columns[name] = i
+		}
+	}
+	if columns["part_number"] < 0 || columns["unit_price"] < 0 {
+		return nil, fmt.Errorf("header needs part_number and unit_price columns")
+	}
+
+	column := func(record []string, name string) string {
+		if i := columns[name]; i >= 0 && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	var entries []priceEntry
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		raw := column(record, "unit_price")
+		if raw == "" {
+			continue
+		}
+		price, err := strconv.ParseFloat(strings.TrimLeft(strings.ReplaceAll(raw, ",", ""), "$€£¥ "), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid price %q", line, raw)
+		}
+		entries = append(entries, priceEntry{
+			PartNumber: column(record, "part_number"),
+			UnitPrice:  price,
+			Currency:   column(record, "currency"),
+		})
+	}
+}
+
+// priceKey normalizes part numbers so "p-01 " matches "P-01"
+func priceKey(partNumber string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(partNumber), ""))
+}
+
+// estimate prices the master BOM. Parts without a part number or without a
+// match in the list are kept as unpriced lines so they can be followed up.
+func (l *priceList) estimate(items []MasterBOMItem) *AssemblyCost {
+	cost := &AssemblyCost{PriceList: filepath.Base(l.path), Currency: l.currency}
+	for _, item := range items {
+		line := AssemblyCostLine{
+			PartNumber:  item.PartNumber,
+			Description: item.Description,
+			Quantity:    item.TotalQuantity,
+		}
+		if price, ok := l.prices[priceKey(item.PartNumber)]; ok && item.PartNumber != "" {
+			line.UnitPrice = price
+			line.ExtendedPrice = roundTo(price*item.TotalQuantity, 4)
+			line.Priced = true
+			cost.Total += line.ExtendedPrice
+		} else {
+			cost.Unpriced++
+		}
+		cost.Lines = append(cost.Lines, line)
+	}
+	cost.Total = roundTo(cost.Total, 4)
+	return cost
+}
+
+// FormatMoney formats an amount with the list currency when known
+func FormatMoney(amount float64, currency string) string {
+	if currency == "" {
+		return fmt.Sprintf("%.2f", amount)
+	}
+	return fmt.Sprintf("%.2f %s", amount, currency)
+}
+
+// markdownAssemblyCost renders the cost estimate as a markdown table
+func markdownAssemblyCost(cost *AssemblyCost) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Priced from `%s`. Unpriced parts are not included in the total.\n\n", cost.PriceList)
+	b.WriteString("| Part number | Description | Qty | Unit price | Extended |\n")
+	b.WriteString("| --- | --- | --- | --- | --- |\n")
+	for _, line := range cost.Lines {
+		unit, extended := FormatMoney(line.UnitPrice, cost.Currency), FormatMoney(line.ExtendedPrice, cost.Currency)
+		if !line.Priced {
+			unit, extended = "**no price**", ""
+		}
+		fmt.Fprintf(&b, "| %s | %s | %g | %s | %s |\n",
+			EscapeInline(line.PartNumber), EscapeInline(line.Description), line.Quantity, unit, extended)
+	}
+	fmt.Fprintf(&b, "| **Total** | | | | **%s** |\n", FormatMoney(cost.Total, cost.Currency))
+	if cost.Unpriced > 0 {
+		fmt.Fprintf(&b, "\n%d part(s) without a price match.\n", cost.Unpriced)
+	}
+	return b.String()
+}
@@ -0,0 +1,344 @@
+package pdfanalysis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// progressBarWidth is the number of cells of the bar
+const progressBarWidth = 30
+
+// progressLatencyWeight is the weight of the newest page in the moving
+// average of page latency
+const progressLatencyWeight = 0.2
+
+// progressBar is a single status line for the page queue: pages done, spend,
+// token throughput, and an ETA from the moving average of page latency.
+// While it is shown, routine per-page lines are hidden and warnings are
+// printed above it.
+type progressBar struct {
+	total    int
+	workers  int
+	started  time.Time
+	done     int
+	cost     float64
+	tokens   int
+	latency  time.Duration // Moving average over pages that were sent to the API
+	measured bool
+}
+
+// console serializes output while a progress bar is shown
+var console struct {
+	mu  sync.Mutex
+	bar *progressBar
+}
+
+// showProgress reports whether the progress bar should replace the per-page
+// lines: only for runs reported by the console observer, not with
+// -no-progress, and only when stdout is a terminal, so logs and CI output
+// keep one line per page
+func showProgress(config *Config, observer ProgressObserver) bool {
+	if config.NoProgress || !printsToConsole(observer) {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// printsToConsole reports whether observer is or includes the console observer
+func printsToConsole(observer ProgressObserver) bool {
+	if g, ok := observer.(*observerGroup); ok {
+		for _, o := range g.observers {
+			if printsToConsole(o) {
+				return true
+			}
+		}
+		return false
+	}
+	_, ok := observer.(*consoleObserver)
+	return ok
+}
+
+// startProgress shows a progress bar for total pages until finishProgress
+func startProgress(total, workers int) {
+	console.mu.Lock()
+	defer console.mu.Unlock()
+	console.bar = &progressBar{total: total, workers: workers, started: time.Now()}
+	console.bar.draw()
+}
+
+// finishProgress ends the bar line
+func finishProgress() {
+	console.mu.Lock()
+	defer console.mu.Unlock()
+	if console.bar != nil {
+		fmt.Println()
+		console.bar = nil
+	}
+}
+
+// recordProgress counts a finished chunk
+func recordProgress(chunk ChunkAnalysis) {
+	console.mu.Lock()
+	defer console.mu.Unlock()
+	bar := console.bar
+	if bar == nil {
+		return
+	}
+	bar.done++
+	bar.cost += chunk.TotalCost
+	bar.tokens += chunk.InputTokens + chunk.OutputTokens
+	// Copied pages (cache, reuse, duplicates) took no API time
+	if elapsed, err := time.ParseDuration(chunk.ProcessingTime); err == nil && chunk.InputTokens > 0 {
+		if !bar.measured {
+			bar.latency, bar.measured = elapsed, true
+		} else {
+			bar.latency = time.Duration(progressLatencyWeight*float64(elapsed) + (1-progressLatencyWeight)*float64(bar.latency))
+		}
+	}
+	bar.draw()
+}
+
+// draw rewrites the bar line; console.mu must be held
+func (b *progressBar) draw() {
+	filled := progressBarWidth * b.done / max(b.total, 1)
+	line := fmt.Sprintf("[%s%s] %d/%d pages · $%.6f", strings.Repeat("█", filled), strings.Repeat("░", progressBarWidth-filled), b.done, b.total, b.cost)
+	if minutes := time.Since(b.started).Minutes(); b.tokens > 0 && minutes > 0 {
+		line += fmt.Sprintf(" · %.0f tok/min", float64(b.tokens)/minutes)
+	}
+	if remaining := b.total - b.done; remaining > 0 && b.measured {
+		// The workers share the remaining pages
+		eta := b.latency * time.Duration(remaining) / time.Duration(min(b.workers, remaining))
+		line += fmt.Sprintf(" · ETA %v", eta.Round(time.Second))
+	}
+	fmt.Printf("\r\033[K%s", line)
+}
+
+// Logf prints a line of the command that is always shown, above the
+// progress bar if there is one. Runs print through the console observer.
+func Logf(format string, args ...interface{}) {
+	console.mu.Lock()
+	defer console.mu.Unlock()
+	if console.bar == nil {
+		fmt.Printf(format, args...)
+		return
+	}
+	fmt.Printf("\r\033[K"+format, args...)
+	console.bar.draw()
+}
+
+// printPage prints a routine per-page line, which the progress bar replaces
+func printPage(format string, args ...interface{}) {
+	console.mu.Lock()
+	defer console.mu.Unlock()
+	if console.bar == nil {
+		fmt.Printf(format, args...)
+	}
+}
+
+// Run identifies one run of an Analyzer, the analysis of one document, in
+// the calls to its observers
+type Run struct {
+	ID       int64  // Unique in the process, in the order the runs started
+	Document string // File name of the document
+}
+
+// runIDs numbers the runs of the process
+var runIDs atomic.Int64
+
+// newRun returns the identity of a new run of the document of config
+func newRun(config *Config) Run {
+	return Run{ID: runIDs.Add(1), Document: filepath.Base(config.DocumentPath())}
+}
+
+// ProgressObserver follows the pages of a run, e.g. to drive a UI, export
+// metrics, or cancel the run's context. Every call names its run, since one
+// observer can follow several runs of an Analyzer at once; state kept per
+// page should be keyed by run and chunk number. Pages are identified by a
+// ChunkAnalysis with ChunkNumber, StartPage, and EndPage set; total is the
+// number of chunks planned. The calls are made one at a time, also across
+// runs of one Analyzer in parallel, so an observer needs no locking but
+// should return quickly: the workers that start and retry pages wait for it.
+type ProgressObserver interface {
+	// OnPageStart is called before the first request for a chunk. Pages
+	// kept from a resumed run or copied from a cache or an identical page
+	// are never started.
+	OnPageStart(run Run, total int, page ChunkAnalysis)
+	// OnPageComplete is called for every finished chunk in completion order:
+	// analyzed, failed, copied, or interrupted (Error is ErrInterrupted)
+	OnPageComplete(run Run, done, total int, page ChunkAnalysis)
+	// OnRetry is called when a failed attempt is sent again after delay
+	OnRetry(run Run, page ChunkAnalysis, attempt int, err error, delay time.Duration)
+	// OnCostUpdate is called with what the run has spent whenever it grows,
+	// including the document summaries after the pages
+	OnCostUpdate(run Run, spent float64)
+}
+
+// MessageObserver is a ProgressObserver that also takes the lines a run
+// reports besides its pages: its settings, warnings, and what the passes
+// after the pages did. The package prints nothing itself; the console
+// observer prints these lines as the command does.
+type MessageObserver interface {
+	ProgressObserver
+	// OnMessage is called with a line of a run, ending in a newline.
+	// Routine lines, about a page going as planned, are those the progress
+	// bar hides.
+	OnMessage(run Run, message string, routine bool)
+}
+
+// runMessagesKey is the context key of the run whose lines are reported
+type runMessagesKey struct{}
+
+// runMessages is where the lines of a run go
+type runMessages struct {
+	run      Run
+	observer ProgressObserver
+}
+
+// withRunMessages returns ctx reporting the lines of run to observer
+func withRunMessages(ctx context.Context, run Run, observer ProgressObserver) context.Context {
+	return context.WithValue(ctx, runMessagesKey{}, &runMessages{run: run, observer: observer})
+}
+
+// logf reports a line of the run of ctx to its observers; outside a run,
+// or without a MessageObserver, the line is dropped
+func logf(ctx context.Context, format string, args ...interface{}) {
+	sendMessage(ctx, fmt.Sprintf(format, args...), false)
+}
+
+// pagef reports a routine per-page line of the run of ctx
+func pagef(ctx context.Context, format string, args ...interface{}) {
+	sendMessage(ctx, fmt.Sprintf(format, args...), true)
+}
+
+func sendMessage(ctx context.Context, message string, routine bool) {
+	m, _ := ctx.Value(runMessagesKey{}).(*runMessages)
+	if m == nil {
+		return
+	}
+	if o, ok := m.observer.(MessageObserver); ok {
+		o.OnMessage(m.run, message, routine)
+	}
+}
+
+// observerGroup passes every call on to each observer, one call at a time
+type observerGroup struct {
+	mu        sync.Mutex
+	observers []ProgressObserver
+}
+
+func (g *observerGroup) OnPageStart(run Run, total int, page ChunkAnalysis) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, o := range g.observers {
+		o.OnPageStart(run, total, page)
+	}
+}
+
+func (g *observerGroup) OnPageComplete(run Run, done, total int, page ChunkAnalysis) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, o := range g.observers {
+		o.OnPageComplete(run, done, total, page)
+	}
+}
+
+func (g *observerGroup) OnRetry(run Run, page ChunkAnalysis, attempt int, err error, delay time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, o := range g.observers {
+		o.OnRetry(run, page, attempt, err, delay)
+	}
+}
+
+func (g *observerGroup) OnCostUpdate(run Run, spent float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, o := range g.observers {
+		o.OnCostUpdate(run, spent)
+	}
+}
+
+func (g *observerGroup) OnMessage(run Run, message string, routine bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, o := range g.observers {
+		if m, ok := o.(MessageObserver); ok {
+			m.OnMessage(run, message, routine)
+		}
+	}
+}
+
+// consoleObserver prints a line per page as the command does, or advances
+// the progress bar while it is shown
+type consoleObserver struct {
+	started map[runChunk]bool // Chunks sent to the API and not finished yet
+}
+
+// runChunk identifies a chunk of one of the runs an observer follows
+type runChunk struct {
+	run   int64
+	chunk int
+}
+
+// NewConsoleObserver returns the observer the command reports pages and
+// the lines of its runs with; it is a MessageObserver
+func NewConsoleObserver() ProgressObserver {
+	return &consoleObserver{started: make(map[runChunk]bool)}
+}
+
+func (o *consoleObserver) OnPageStart(run Run, total int, page ChunkAnalysis) {
+	o.started[runChunk{run.ID, page.ChunkNumber}] = true
+	if page.StartPage == page.EndPage {
+		printPage("  🔄 Processing page %d...\n", page.StartPage)
+	} else {
+		printPage("  🔄 Processing chunk %d (pages %d-%d)...\n", page.ChunkNumber, page.StartPage, page.EndPage)
+	}
+}
+
+func (o *consoleObserver) OnPageComplete(run Run, done, total int, page ChunkAnalysis) {
+	recordProgress(page)
+	key := runChunk{run.ID, page.ChunkNumber}
+	if !o.started[key] {
+		// Copied pages were reported when they were found
+		return
+	}
+	delete(o.started, key)
+	switch {
+	case page.Error == ErrInterrupted:
+		Logf("  ⏹️  %s interrupted\n", chunkLabel(page))
+	case page.Error != "":
+		Logf("  ❌ %s failed: %s\n", chunkLabel(page), page.Error)
+	default:
+		printPage("  ✅ %s completed: %d input tokens, %d output tokens, $%.6f\n",
+			chunkLabel(page), page.InputTokens, page.OutputTokens, page.TotalCost)
+	}
+}
+
+func (o *consoleObserver) OnRetry(run Run, page ChunkAnalysis, attempt int, err error, delay time.Duration) {
+	Logf("  ⚠️  %s: %s, retrying in %v...\n", chunkLabel(page), ClassifyError(err), delay.Round(time.Millisecond))
+}
+
+func (o *consoleObserver) OnCostUpdate(run Run, spent float64) {}
+
+func (o *consoleObserver) OnMessage(run Run, message string, routine bool) {
+	if routine {
+		printPage("%s", message)
+	} else {
+		Logf("%s", message)
+	}
+}
+
+// chunkLabel names a chunk's pages for console output
+func chunkLabel(page ChunkAnalysis) string {
+	if page.StartPage == page.EndPage {
+		return fmt.Sprintf("Page %d", page.StartPage)
+	}
+	return fmt.Sprintf("Chunk %d", page.ChunkNumber)
+}
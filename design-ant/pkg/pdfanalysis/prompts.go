@@ -0,0 +1,258 @@
+package pdfanalysis
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// PromptPack is the analysis prompt for one kind of drawings
+type PromptPack struct {
+	Name    string // Recorded with the prompts, e.g. "mechanical"
+	Version string // Declared by the template as {{define "version"}}2{{end}} (empty = none)
+	Hash    string // SHA-256 of the template text, first 12 hex digits
+	// Analysis is the prompt for a page, executed with PromptData. A
+	// template it defines as "system" is the system prompt of the page
+	// requests, which carries the instructions that are the same on every page.
+	Analysis *template.Template
+
+	schema *packSchema // Structured data of the built-in packs
+}
+
+// PromptData holds the variables of a prompt template
+type PromptData struct {
+	Page        int               // Page number
+	Document    string            // File name of the document, e.g. pump.pdf
+	PageType    string            // -two-stage page type, e.g. "bom" (empty without it)
+	Focus       string            // Instructions for PageType (empty without it)
+	Context     string            // -context-file text for the page (empty without it)
+	Language    string            // -output-lang language the model writes, e.g. "German" (empty = English)
+	Terminology string            // -terminology style: asme or iso (empty without it)
+	UnitOrder   string            // -unit-order: metric-first or imperial-first (empty without it)
+	Vars        map[string]string // User values from -prompt-var, e.g. {{.Vars.customer}}
+	// Sections are the instructions of the enabled options (structured
+	// output, welds, grounding, terminology, unit order, output language),
+	// which go after the rules
+	Sections []string
+}
+
+// PageContext is supplemental text for the prompt of some or all pages, e.g.
+// a spec excerpt or the part numbering scheme, so the model resolves
+// abbreviations and references the page alone leaves ambiguous
+type PageContext struct {
+	Pages []int  // Pages (1-based) it is added to (empty = all)
+	Text  string // Text added to the prompt
+}
+
+//go:embed prompts/mechanical.tmpl
+var mechanicalPrompt string
+
+// MechanicalPromptPack is the built-in prompt, tuned for mechanical CAD
+// drawings with title blocks and BOMs; its template is
+// prompts/mechanical.tmpl, a starting point for -prompt-template
+var MechanicalPromptPack = withSchema(mustPromptPack("mechanical", mechanicalPrompt), mechanicalSchema)
+
+// NewPromptPack parses a prompt template. Referring to a -prompt-var that
+// is not set is an error when the prompt is rendered.
+func NewPromptPack(name, text string) (PromptPack, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return PromptPack{}, fmt.Errorf("error parsing prompt template: %v", err)
+	}
+	pack := PromptPack{Name: name, Analysis: tmpl, Hash: fmt.Sprintf("%x", sha256.Sum256([]byte(text)))[:12]}
+	if version := tmpl.Lookup("version"); version != nil {
+		var b strings.Builder
+		if err := version.Execute(&b, PromptData{}); err != nil {
+			return PromptPack{}, fmt.Errorf("error executing prompt template version: %v", err)
+		}
+		pack.Version = strings.TrimSpace(b.String())
+	}
+	return pack, nil
+}
+
+// Info returns the name, version, and hash of the pack for a result
+func (p PromptPack) Info() *PromptInfo {
+	return &PromptInfo{Pack: p.Name, Version: p.Version, Hash: p.Hash}
+}
+
+// LoadPromptPack reads a prompt template file, named after the file without
+// its extension, e.g. pid for pid.tmpl
+func LoadPromptPack(path string) (PromptPack, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return PromptPack{}, fmt.Errorf("error reading prompt template: %v", err)
+	}
+	base := filepath.Base(path)
+	return NewPromptPack(strings.TrimSuffix(base, filepath.Ext(base)), string(text))
+}
+
+// mustPromptPack parses a built-in prompt template
+func mustPromptPack(name, text string) PromptPack {
+	pack, err := NewPromptPack(name, text)
+	if err != nil {
+		panic(err)
+	}
+	return pack
+}
+
+// Render returns the prompt for a page
+func (p PromptPack) Render(data PromptData) (string, error) {
+	var b strings.Builder
+	if err := p.Analysis.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("error executing prompt template %s: %v", p.Name, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// System returns the system prompt, or "" when the template defines none
+func (p PromptPack) System(data PromptData) (string, error) {
+	tmpl := p.Analysis.Lookup("system")
+	if tmpl == nil {
+		return "", nil
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("error executing system prompt of %s: %v", p.Name, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// checkPrompt renders the system prompt and the prompt for every page type,
+// so a template error stops the run before any request instead of failing
+// each page
+func (c *Config) checkPrompt() error {
+	if _, err := c.PromptPack.System(systemData(c)); err != nil {
+		return err
+	}
+	pageTypes := []string{"", PageTypeOther}
+	for pageType := range pageTypeFocus {
+		pageTypes = append(pageTypes, pageType)
+	}
+	for _, pageType := range pageTypes {
+		data := promptData(c, 1, &PageClassification{Type: pageType})
+		if _, err := c.PromptPack.Render(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildPrompt assembles the analysis prompt for a page from the configured
+// options. classification is the -two-stage result for the page, or nil.
+func buildPrompt(config *Config, pageNumber int, classification *PageClassification) string {
+	prompt, err := config.PromptPack.Render(promptData(config, pageNumber, classification))
+	if err != nil {
+		// Validate rendered the template already, so this is not expected
+		slog.Warn("Prompt template failed, using the built-in prompt", "page", pageNumber, "error", err)
+		prompt, _ = MechanicalPromptPack.Render(promptData(config, pageNumber, classification))
+	}
+	return prompt
+}
+
+// systemPrompt returns the system prompt of the page requests: the pack's
+// system template followed by the -system-addendum text. It only depends on
+// the run's options, so it is a stable prefix of every page request.
+func systemPrompt(config *Config) string {
+	system, err := config.PromptPack.System(systemData(config))
+	if err != nil {
+		slog.Warn("System prompt template failed, using the built-in one", "error", err)
+		system, _ = MechanicalPromptPack.System(systemData(config))
+	}
+	return joinPrompt(system, strings.TrimSpace(config.SystemAddendum))
+}
+
+// systemData returns the template variables of the system prompt, those of
+// a page without its number and type
+func systemData(config *Config) PromptData {
+	return promptData(config, 0, nil)
+}
+
+// joinPrompt joins the non-empty parts of a prompt with blank lines
+func joinPrompt(parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "\n\n")
+}
+
+// promptData returns the template variables of a page
+func promptData(config *Config, pageNumber int, classification *PageClassification) PromptData {
+	data := PromptData{
+		Page:        pageNumber,
+		Document:    filepath.Base(config.DocumentPath()),
+		Vars:        config.PromptVars,
+		Context:     pageContext(config, pageNumber),
+		Terminology: config.Terminology,
+		UnitOrder:   config.UnitOrder,
+	}
+	if classification != nil {
+		data.PageType = classification.Type
+		data.Focus = classificationInstructions(classification)
+	}
+	if config.Structured {
+		data.Sections = append(data.Sections, structuredOutputInstructions(config.PromptPack))
+	}
+	if config.Welds {
+		data.Sections = append(data.Sections, weldInstructions)
+	}
+	if config.Ground {
+		data.Sections = append(data.Sections, groundingInstructions)
+	}
+	if config.Terminology != "" {
+		data.Sections = append(data.Sections, TerminologyInstructions(config.Terminology))
+	}
+	if config.UnitOrder != "" {
+		data.Sections = append(data.Sections, UnitOrderInstructions(config.UnitOrder))
+	}
+	if config.OutputLang != "" && config.LangMode == LangModePrompt {
+		data.Language = LanguageName(config.OutputLang)
+		data.Sections = append(data.Sections, OutputLanguageInstructions(config.OutputLang))
+	}
+	return data
+}
+
+// pageContext joins the -context-file texts for a page, those for all pages
+// first, in the order given. The system prompt (page 0) gets none.
+func pageContext(config *Config, pageNumber int) string {
+	if pageNumber == 0 {
+		return ""
+	}
+	var all, page []string
+	for _, context := range config.Context {
+		text := strings.TrimSpace(context.Text)
+		switch {
+		case text == "":
+		case len(context.Pages) == 0:
+			all = append(all, text)
+		case slices.Contains(context.Pages, pageNumber):
+			page = append(page, text)
+		}
+	}
+	return joinPrompt(append(all, page...)...)
+}
+
+// GenerateAnalysisPrompt creates the built-in prompt for design analysis as
+// one text, the system prompt followed by the page prompt. Extra sections are
+// inserted after the critical rules.
+func GenerateAnalysisPrompt(pageNumber int, extraSections ...string) string {
+	data := PromptData{Page: pageNumber, Sections: extraSections}
+	system, _ := MechanicalPromptPack.System(data)
+	prompt, _ := MechanicalPromptPack.Render(data)
+	return joinPrompt(system, prompt)
+}
+
+// structuredOutputInstructions asks for the extraction tool call after the markdown analysis
+func structuredOutputInstructions(pack PromptPack) string {
+	return `STRUCTURED DATA:
+After the markdown analysis, call the ` + extractionToolName + ` tool exactly once with the ` + pack.structuredSchema().records + ` of this page.
+Use empty strings/arrays when information is not on the page - never invent values.`
+}
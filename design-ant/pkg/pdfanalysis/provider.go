@@ -0,0 +1,283 @@
+package pdfanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Providers a model can run on, told apart by the model name
+const (
+	ProviderAnthropic = "anthropic"
+	ProviderGemini    = "gemini"
+)
+
+// providerModels is the model of a provider when the default model is
+// configured for another one
+var providerModels = map[string]string{
+	ProviderAnthropic: DefaultModel,
+	ProviderGemini:    "gemini-2.5-flash",
+}
+
+// providerKeys names the environment variable holding each provider's API key
+var providerKeys = map[string]string{
+	ProviderAnthropic: "ANTHROPIC_API_KEY",
+	ProviderGemini:    "GEMINI_API_KEY",
+}
+
+// Capabilities is what a provider takes and returns, as far as the pipeline
+// uses it. The pipeline asks for these rather than the provider's name:
+// pages go as PDF documents when the provider takes them and are rendered
+// as images otherwise, and chunks are held to the provider's size limits.
+type Capabilities struct {
+	Provider        string
+	PDFInput        bool  // Takes pages as PDF documents
+	ImageInput      bool  // Takes pages rendered as JPEG images, also used for pages too large as PDFs
+	TextInput       bool  // Takes the text layer of a page alone (-input-mode text and auto)
+	MaxRequestMB    int64 // Largest request, base64-encoded
+	MaxImageMB      int64 // Largest image of a request, base64-encoded
+	MaxImagePx      int   // Longest edge of an image (0 = no limit)
+	MaxOutputTokens int   // Longest answer requested
+	JSONMode        bool  // Returns structured data to a schema (-structured)
+	TokenCounting   bool  // Counts the tokens of a request before it is sent (-tpm pacing)
+}
+
+// providerCapabilities holds the capabilities of each provider
+var providerCapabilities = map[string]Capabilities{
+	ProviderAnthropic: {
+		Provider:        ProviderAnthropic,
+		PDFInput:        true,
+		ImageInput:      true,
+		TextInput:       true,
+		MaxRequestMB:    anthropicMaxRequestMB,
+		MaxImageMB:      anthropicMaxImageMB,
+		MaxImagePx:      anthropicMaxImagePx,
+		MaxOutputTokens: 8192,
+		JSONMode:        true, // Through tool use
+		TokenCounting:   true,
+	},
+	ProviderGemini: {
+		Provider:        ProviderGemini,
+		PDFInput:        true,
+		ImageInput:      true,
+		MaxRequestMB:    geminiMaxRequestMB,
+		MaxImageMB:      geminiMaxRequestMB,
+		MaxOutputTokens: 8192,
+	},
+}
+
+// ProviderCapabilities returns the capabilities of a provider, and false for
+// an unknown one
+func ProviderCapabilities(provider string) (Capabilities, bool) {
+	caps, ok := providerCapabilities[provider]
+	return caps, ok
+}
+
+// Capabilities returns the capabilities of the configured provider, or of
+// the provider serving the model when none is set
+func (c *Config) Capabilities() Capabilities {
+	if c.Provider == "" {
+		return providerCapabilities[ModelProvider(c.ModelName)]
+	}
+	return providerCapabilities[c.Provider]
+}
+
+// ModelProvider returns the provider serving a model
+func ModelProvider(model string) string {
+	if strings.HasPrefix(model, "gemini-") {
+		return ProviderGemini
+	}
+	return ProviderAnthropic
+}
+
+// checkProvider settles the provider and its model, and checks the options
+// against its capabilities. Gemini only runs the page analysis; the passes
+// built on Claude's text-only requests need the anthropic provider.
+func (c *Config) checkProvider() error {
+	if c.Provider == "" {
+		c.Provider = ModelProvider(c.ModelName)
+	}
+	if _, ok := providerModels[c.Provider]; !ok {
+		return fmt.Errorf("invalid provider %q: must be %s or %s", c.Provider, ProviderAnthropic, ProviderGemini)
+	}
+	if ModelProvider(c.ModelName) != c.Provider {
+		if c.ModelName != DefaultModel {
+			return fmt.Errorf("model %s does not run on provider %s", c.ModelName, c.Provider)
+		}
+		c.ModelName = providerModels[c.Provider]
+	}
+	caps := c.Capabilities()
+	for _, option := range []struct {
+		name string
+		set  bool
+	}{
+		{"-structured", c.Structured && !caps.JSONMode},
+		{"-input-mode " + c.InputMode, c.InputMode != InputModePDF && !caps.TextInput},
+		{"-two-stage", c.TwoStage && c.Provider != ProviderAnthropic},
+		{"-consolidate", c.Consolidate && c.Provider != ProviderAnthropic},
+		{"-summary", c.Summary && c.Provider != ProviderAnthropic},
+		{"-escalate-model", c.EscalateModel != "" && c.Provider != ProviderAnthropic},
+		{"-lang-mode translate", c.OutputLang != "" && c.LangMode == LangModeTranslate && c.Provider != ProviderAnthropic},
+	} {
+		if option.set {
+			return fmt.Errorf("%s needs the %s provider", option.name, ProviderAnthropic)
+		}
+	}
+	return nil
+}
+
+// AnalyzeChunkGemini sends a PDF chunk, or a page rendered as images, to the
+// Gemini generateContent API. Thinking tokens are billed as output and
+// counted as such.
+func AnalyzeChunkGemini(ctx context.Context, apiKey, model, chunkPath, prompt string) (string, int, int, error) {
+	content, err := ChunkContent(chunkPath, prompt)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	return sendGemini(ctx, apiKey, model, "", content)
+}
+
+// sendGemini posts message content to the generateContent API with a system
+// instruction (empty = none)
+func sendGemini(ctx context.Context, apiKey, model, system string, content []map[string]interface{}) (string, int, int, error) {
+	requestBody := map[string]interface{}{
+		"contents": []map[string]interface{}{{
+			"role":  "user",
+			"parts": geminiParts(content),
+		}},
+		"generationConfig": map[string]interface{}{"maxOutputTokens": providerCapabilities[ProviderGemini].MaxOutputTokens},
+	}
+	if system != "" {
+		requestBody["systemInstruction"] = map[string]interface{}{
+			"parts": []map[string]interface{}{{"text": system}},
+		}
+	}
+	reqBody, err := NewRequestBody(requestBody)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent", model)
+	header := http.Header{}
+	header.Set("x-goog-api-key", apiKey)
+	body, err := postModelRequest(ctx, MessageClient, url, reqBody, header, geminiBreaker)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	return parseGeminiResponse(body)
+}
+
+// EmbedGemini embeds text with the Gemini embedContent endpoint for the given
+// task type, e.g. RETRIEVAL_DOCUMENT or RETRIEVAL_QUERY. It is sent like the
+// page requests: captured, under a timeout, and held back by the breaker.
+func EmbedGemini(ctx context.Context, apiKey, model, taskType, text string) ([]float32, error) {
+	reqBody, err := NewRequestBody(map[string]interface{}{
+		"content":  map[string]interface{}{"parts": []map[string]string{{"text": text}}},
+		"taskType": taskType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:embedContent", model)
+	header := http.Header{}
+	header.Set("x-goog-api-key", apiKey)
+	body, err := postModelRequest(ctx, QuickClient, url, reqBody, header, geminiBreaker)
+	if err != nil {
+		return nil, err
+	}
+	var apiResponse struct {
+		Embedding struct {
+			Values []float32 `json:"values"`
+		} `json:"embedding"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return nil, fmt.Errorf("error parsing response: %v", err)
+	}
+	if len(apiResponse.Embedding.Values) == 0 {
+		return nil, fmt.Errorf("embedding API returned no values")
+	}
+	return apiResponse.Embedding.Values, nil
+}
+
+// geminiResponse is the parsed generateContent response
+type geminiResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []struct {
+				Text    string `json:"text"`
+				Thought bool   `json:"thought"`
+			} `json:"parts"`
+		} `json:"content"`
+		FinishReason string `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// geminiBlockingFinishReasons are the finish reasons of an answer withheld
+// by Gemini's filters rather than ended by the model
+var geminiBlockingFinishReasons = map[string]bool{
+	"SAFETY":             true,
+	"RECITATION":         true,
+	"BLOCKLIST":          true,
+	"PROHIBITED_CONTENT": true,
+	"SPII":               true,
+	"IMAGE_SAFETY":       true,
+}
+
+// parseGeminiResponse returns the answer text of the first candidate, without
+// thought parts, and the token counts. A blocked prompt, a filtered answer,
+// or a candidate without text is a *ResponseError, returned with the tokens
+// the response was billed for.
+func parseGeminiResponse(body []byte) (string, int, int, error) {
+	var apiResponse geminiResponse
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return "", 0, 0, &ResponseError{Provider: ProviderGemini, Reason: ResponseMalformed, Detail: err.Error()}
+	}
+	usage := apiResponse.UsageMetadata
+	inputTokens, outputTokens := usage.PromptTokenCount, usage.CandidatesTokenCount+usage.ThoughtsTokenCount
+	if reason := apiResponse.PromptFeedback.BlockReason; reason != "" {
+		return "", inputTokens, outputTokens, &ResponseError{Provider: ProviderGemini, Reason: ResponseBlocked, Detail: "prompt blocked, blockReason " + reason}
+	}
+	if len(apiResponse.Candidates) == 0 {
+		return "", inputTokens, outputTokens, &ResponseError{Provider: ProviderGemini, Reason: ResponseMalformed, Detail: "no candidates"}
+	}
+	candidate := apiResponse.Candidates[0]
+	var parts []string
+	for _, part := range candidate.Content.Parts {
+		if !part.Thought && part.Text != "" {
+			parts = append(parts, part.Text)
+		}
+	}
+	text := strings.Join(parts, "")
+	if geminiBlockingFinishReasons[candidate.FinishReason] {
+		return "", inputTokens, outputTokens, &ResponseError{Provider: ProviderGemini, Reason: ResponseBlocked, Detail: "answer blocked, finishReason " + candidate.FinishReason}
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", inputTokens, outputTokens, &ResponseError{Provider: ProviderGemini, Reason: ResponseEmpty, Detail: "no text, finishReason " + candidate.FinishReason}
+	}
+	return text, inputTokens, outputTokens, nil
+}
+
+// geminiParts converts the content blocks of a Messages API request into
+// Gemini parts: text stays text, documents and images become inline data
+func geminiParts(content []map[string]interface{}) []map[string]interface{} {
+	parts := make([]map[string]interface{}, 0, len(content))
+	for _, block := range content {
+		if source, ok := block["source"].(map[string]interface{}); ok {
+			parts = append(parts, map[string]interface{}{
+				"inline_data": map[string]interface{}{"mime_type": source["media_type"], "data": source["data"]},
+			})
+			continue
+		}
+		parts = append(parts, map[string]interface{}{"text": block["text"]})
+	}
+	return parts
+}
@@ -0,0 +1,151 @@
+package pdfanalysis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func FuzzParseGeminiResponse(f *testing.F) {
+	for _, seed := range []string{
+		`{"candidates": [{"content": {"parts": [{"text": "A bracket."}]}, "finishReason": "STOP"}], "usageMetadata": {"promptTokenCount": 1000, "candidatesTokenCount": 90, "thoughtsTokenCount": 10}}`,
+		`{"candidates": [{"content": {"parts": [{"text": "thinking", "thought": true}, {"text": "answer"}]}, "finishReason": "STOP"}]}`,
+		`{"candidates": [{"content": {"parts": [{"text": "thinking", "thought": true}]}, "finishReason": "MAX_TOKENS"}]}`,
+		`{"candidates": [{"content": {"parts": [{"text": "partial"}]}, "finishReason": "SAFETY"}]}`,
+		`{"candidates": [{"finishReason": "RECITATION"}]}`,
+		`{"promptFeedback": {"blockReason": "SAFETY"}}`,
+		`{"candidates": []}`,
+		`{"candidates": [null]}`,
+		`{"error": {"code": 429, "status": "RESOURCE_EXHAUSTED"}}`,
+		`{"candidates": [{"content": {"parts": [{"text": 1}]}}]}`,
+		`null`,
+		``,
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, body []byte) {
+		text, _, _, err := parseGeminiResponse(body)
+		if err != nil {
+			var respErr *ResponseError
+			if !errors.As(err, &respErr) || respErr.Provider != ProviderGemini {
+				t.Fatalf("error %v is not a Gemini *ResponseError", err)
+			}
+			switch respErr.Reason {
+			case ResponseBlocked, ResponseEmpty, ResponseMalformed:
+			default:
+				t.Fatalf("unexpected reason %q", respErr.Reason)
+			}
+			if text != "" {
+				t.Fatalf("text %q returned with an error", text)
+			}
+			return
+		}
+		if strings.TrimSpace(text) == "" {
+			t.Fatalf("blank text %q returned without an error", text)
+		}
+	})
+}
+
+func TestParseGeminiResponseUsage(t *testing.T) {
+	for _, body := range []string{
+		`{"promptFeedback": {"blockReason": "SAFETY"}, "usageMetadata": {"promptTokenCount": 1000}}`,
+		`{"candidates": [], "usageMetadata": {"promptTokenCount": 1000}}`,
+		`{"candidates": [{"finishReason": "SAFETY"}], "usageMetadata": {"promptTokenCount": 1000, "candidatesTokenCount": 5}}`,
+	} {
+		_, inputTokens, _, err := parseGeminiResponse([]byte(body))
+		if err == nil || inputTokens != 1000 {
+			t.Errorf("parseGeminiResponse(%s) = %d input tokens, %v; want 1000 with an error", body, inputTokens, err)
+		}
+	}
+}
+
+// geminiTransport answers generateContent requests with a rate limit first,
+// then an answer, failing the test for a request without a deadline
+type geminiTransport struct {
+	t        *testing.T
+	requests int
+}
+
+func (g *geminiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	io.Copy(io.Discard, req.Body)
+	req.Body.Close()
+	if _, ok := req.Context().Deadline(); !ok {
+		g.t.Error("request sent without a timeout")
+	}
+	g.requests++
+	status, header := http.StatusOK, http.Header{}
+	body := `{"candidates": [{"content": {"parts": [{"text": "A bracket."}]}, "finishReason": "STOP"}], "usageMetadata": {"promptTokenCount": 1000, "candidatesTokenCount": 100}}`
+	if g.requests == 1 {
+		status, body = http.StatusTooManyRequests, `{"error": {"code": 429, "status": "RESOURCE_EXHAUSTED"}}`
+		header.Set("retry-after", "30")
+	}
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+}
+
+func TestSendGemini(t *testing.T) {
+	transport := &geminiTransport{t: t}
+	messageClient := MessageClient
+	MessageClient = &http.Client{Transport: transport}
+	t.Cleanup(func() { MessageClient = messageClient })
+
+	dir := t.TempDir()
+	bucket := newTokenBucket(100000)
+	ctx := withRateLimiter(ContextWithCapture(context.Background(), dir, "page-001"), bucket)
+	content := []map[string]interface{}{{"type": "text", "text": "Describe the drawing."}}
+	_, _, _, err := sendGemini(ctx, "secret", "gemini-2.5-flash", "", content)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("first request: error %v, want a 429 *APIError", err)
+	}
+	if wait := time.Until(bucket.resumeAt); wait < 20*time.Second {
+		t.Errorf("rate limiter resumes in %v, want the retry-after of 30s", wait)
+	}
+	request, err := os.ReadFile(filepath.Join(dir, "page-001.request.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(request), "secret") || !strings.Contains(string(request), "Describe the drawing.") {
+		t.Errorf("captured request %s", request)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "page-001.response.json")); err != nil {
+		t.Error(err)
+	}
+
+	bucket.resumeAt = time.Time{}
+	text, inputTokens, outputTokens, err := sendGemini(ctx, "secret", "gemini-2.5-flash", "", content)
+	if err != nil || text != "A bracket." || inputTokens != 1000 || outputTokens != 100 {
+		t.Errorf("sendGemini = %q, %d, %d, %v", text, inputTokens, outputTokens, err)
+	}
+}
+
+// embedTransport answers embedContent requests with a fixed embedding
+type embedTransport struct{}
+
+func (embedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	io.Copy(io.Discard, req.Body)
+	req.Body.Close()
+	body := `{"embedding": {"values": [0.5, -0.25]}}`
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+}
+
+func TestEmbedGemini(t *testing.T) {
+	quickClient := QuickClient
+	QuickClient = &http.Client{Transport: embedTransport{}}
+	t.Cleanup(func() { QuickClient = quickClient })
+
+	dir := t.TempDir()
+	ctx := ContextWithCapture(context.Background(), dir, "embed-001")
+	values, err := EmbedGemini(ctx, "secret", "gemini-embedding-001", "RETRIEVAL_QUERY", "bracket")
+	if err != nil || len(values) != 2 || values[0] != 0.5 {
+		t.Errorf("EmbedGemini = %v, %v", values, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "embed-001.request.json")); err != nil {
+		t.Errorf("embedding request not captured: %v", err)
+	}
+}
@@ -0,0 +1,253 @@
+package pdfanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultTokensPerMinute is the input token rate limit of the default API tier
+const defaultTokensPerMinute = 400_000
+
+// fallbackPageTokens is reserved for a page whose token count could not be
+// determined up front; it is the conservative estimate for a PDF page
+const fallbackPageTokens = 80_000
+
+// maxInFlightRequests bounds concurrent page requests; the token bucket
+// decides how many of them may actually start
+const maxInFlightRequests = 16
+
+// tokenBucket is an input-tokens-per-minute limiter shared by all workers.
+// Requests reserve their pre-flight token count before they are sent and
+// settle the difference to the real count afterwards, so large pages slow
+// the run down and small pages let more requests through.
+type tokenBucket struct {
+	queue     sync.Mutex // Serializes waiters so large requests are not starved
+	mu        sync.Mutex
+	capacity  float64
+	tokens    float64 // May go negative after a request used more than it reserved
+	perSecond float64
+	last      time.Time
+	resumeAt  time.Time // Set from rate limit headers; no request starts before it
+}
+
+// newTokenBucket creates a full bucket for the given tokens per minute
+func newTokenBucket(tokensPerMinute int) *tokenBucket {
+	return &tokenBucket{
+		capacity:  float64(tokensPerMinute),
+		tokens:    float64(tokensPerMinute),
+		perSecond: float64(tokensPerMinute) / 60,
+		last:      time.Now(),
+	}
+}
+
+// refill adds the tokens accumulated since the last call; mu must be held
+func (b *tokenBucket) refill() {
+	now := time.Now()
+	b.tokens = min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.perSecond)
+	b.last = now
+}
+
+// wait blocks until n tokens are available and takes them. Requests larger
+// than the whole bucket wait for a full bucket instead of forever.
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	b.queue.Lock()
+	defer b.queue.Unlock()
+
+	need := min(float64(n), b.capacity)
+	for {
+		b.mu.Lock()
+		b.refill()
+		delay := time.Until(b.resumeAt)
+		if delay <= 0 && b.tokens >= need {
+			b.tokens -= need
+			b.mu.Unlock()
+			return nil
+		}
+		if delay <= 0 {
+			delay = time.Duration((need - b.tokens) / b.perSecond * float64(time.Second))
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// settle corrects a reservation once the real input token count is known
+func (b *tokenBucket) settle(reserved, actual int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	b.tokens = min(b.capacity, b.tokens-float64(actual-reserved))
+}
+
+// waitForReset blocks while the server has reported an exhausted rate limit
+func (b *tokenBucket) waitForReset(ctx context.Context) error {
+	b.mu.Lock()
+	delay := time.Until(b.resumeAt)
+	b.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+// rateLimitHeaders are the limits reported as anthropic-ratelimit-<name>-remaining and -reset
+var rateLimitHeaders = []string{"requests", "tokens", "input-tokens", "output-tokens"}
+
+// observe applies the rate limit headers of a response: a 429 with
+// retry-after or an exhausted limit holds back all requests until the
+// server's reset time, and the bucket never holds more input tokens than
+// the server says remain.
+func (b *tokenBucket) observe(status int, header http.Header) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	if retryAfter := ParseRetryAfter(header.Get("retry-after")); status == http.StatusTooManyRequests && retryAfter > 0 {
+		b.resumeAt = maxTime(b.resumeAt, now.Add(retryAfter))
+	}
+	for _, name := range rateLimitHeaders {
+		remaining, err := strconv.Atoi(header.Get("anthropic-ratelimit-" + name + "-remaining"))
+		if err != nil {
+			continue
+		}
+		if name == "input-tokens" {
+			b.refill()
+			b.tokens = min(b.tokens, float64(remaining))
+		}
+		if remaining > 0 {
+			continue
+		}
+		if reset, err := time.Parse(time.RFC3339, header.Get("anthropic-ratelimit-"+name+"-reset")); err == nil {
+			b.resumeAt = maxTime(b.resumeAt, reset)
+		}
+	}
+}
+
+// maxTime returns the later of two times
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// ParseRetryAfter reads a retry-after header given in seconds or as an HTTP
+// date; missing or invalid values are zero
+func ParseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// rateLimiterKey is the context key for the shared token bucket
+type rateLimiterKey struct{}
+
+// withRateLimiter attaches the shared token bucket to the context
+func withRateLimiter(ctx context.Context, bucket *tokenBucket) context.Context {
+	if bucket == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, rateLimiterKey{}, bucket)
+}
+
+// withoutRateLimiter returns ctx without its token bucket, for requests with
+// rate limits of their own
+func withoutRateLimiter(ctx context.Context) context.Context {
+	if rateLimiterFrom(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, rateLimiterKey{}, (*tokenBucket)(nil))
+}
+
+// rateLimiterFrom returns the token bucket of the context, or nil
+func rateLimiterFrom(ctx context.Context) *tokenBucket {
+	bucket, _ := ctx.Value(rateLimiterKey{}).(*tokenBucket)
+	return bucket
+}
+
+// countTokens asks the token counting endpoint for the input tokens of a
+// message. It is free and does not count against the message rate limit, so
+// it is sent past the token bucket, but it is captured and held back by the
+// circuit breaker like the request it counts. Extra request fields such as
+// tools are merged into the request body.
+func countTokens(ctx context.Context, apiKey, modelName string, content []map[string]interface{}, extra map[string]interface{}) (int, error) {
+	requestBody := map[string]interface{}{
+		"model": modelName,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": content},
+		},
+	}
+	for key, value := range extra {
+		requestBody[key] = value
+	}
+	reqBody, err := NewRequestBody(requestBody)
+	if err != nil {
+		return 0, err
+	}
+	header := http.Header{}
+	header.Set("x-api-key", apiKey)
+	header.Set("anthropic-version", "2023-06-01")
+	ctx = withCaptureSuffix(withoutRateLimiter(ctx), "count", 1)
+	body, err := postModelRequest(ctx, QuickClient, "https://api.anthropic.com/v1/messages/count_tokens", reqBody, header, anthropicBreaker)
+	if err != nil {
+		return 0, err
+	}
+	var counted struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	if err := json.Unmarshal(body, &counted); err != nil {
+		return 0, fmt.Errorf("error parsing response: %v", err)
+	}
+	return counted.InputTokens, nil
+}
+
+// preflightTokens counts the input tokens of a page request, falling back to
+// fallbackPageTokens for PDF pages and a character estimate for text pages.
+// Only providers with token counting are counted.
+func preflightTokens(ctx context.Context, config *Config, route PageRoute, path string, pageNumber int, prompt string) int {
+	if !config.Capabilities().TokenCounting {
+		return fallbackPageTokens
+	}
+	// Few-shot examples are reserved like PDF pages when they cannot be counted
+	fallback := fallbackPageTokens * (1 + len(config.Examples))
+	content, err := pageContent(config, route, path, pageNumber, prompt)
+	if err != nil {
+		return fallback
+	}
+	system := systemPrompt(config)
+	extra := systemField(system)
+	if config.Structured {
+		extra = structuredFields(config)
+	}
+	n, err := countTokens(ctx, config.APIKey, config.ModelName, content, extra)
+	if err != nil {
+		if route.Mode == InputModeText {
+			return (len(route.Text)+len(system)+len(prompt))/3 + fallbackPageTokens*len(config.Examples)
+		}
+		return fallback
+	}
+	return n
+}
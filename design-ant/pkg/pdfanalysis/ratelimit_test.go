@@ -0,0 +1,47 @@
+package pdfanalysis
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// countTransport answers token counting requests and keeps their bodies
+type countTransport struct {
+	bodies []string
+}
+
+func (c *countTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	req.Body.Close()
+	c.bodies = append(c.bodies, string(body))
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(`{"input_tokens": 1234}`)), Request: req}, nil
+}
+
+func TestPreflightTokens(t *testing.T) {
+	transport := &countTransport{}
+	quickClient := QuickClient
+	QuickClient = &http.Client{Transport: transport}
+	t.Cleanup(func() { QuickClient = quickClient })
+
+	dir := t.TempDir()
+	ctx := withRateLimiter(ContextWithCapture(context.Background(), dir, "page-001"), newTokenBucket(100000))
+	route := PageRoute{Mode: InputModeText, Text: "BRACKET 2X Ø8"}
+	for _, structured := range []bool{false, true} {
+		config := DefaultConfig()
+		config.Provider, config.APIKey, config.Structured = ProviderAnthropic, "secret", structured
+		if n := preflightTokens(ctx, config, route, "", 1, "Describe the drawing."); n != 1234 {
+			t.Errorf("structured %v: preflightTokens = %d, want the counted 1234", structured, n)
+		}
+	}
+	if len(transport.bodies) != 2 || strings.Contains(transport.bodies[0], extractionToolName) || !strings.Contains(transport.bodies[1], extractionToolName) {
+		t.Errorf("counted requests %q, want the extraction tool only with -structured", transport.bodies)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "page-001-count-1.request.json")); err != nil {
+		t.Errorf("token count not captured: %v", err)
+	}
+}
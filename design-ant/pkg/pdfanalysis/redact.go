@@ -0,0 +1,354 @@
+package pdfanalysis
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultMask replaces redacted text when a rule has no replacement
+const defaultMask = "[REDACTED]"
+
+// redactionPresets are built-in rules enabled with "preset:<name>" in a rules file
+var redactionPresets = map[string][]string{
+	"prices": {
+		`[$€£¥]\s?\d[\d.,]*`,
+		`\b\d[\d.,]*\s?(?:USD|EUR|GBP|CHF|JPY|CNY)\b`,
+	},
+	"emails": {
+		`\b[\w.+-]+@[\w-]+(?:\.[\w-]+)+\b`,
+	},
+}
+
+// redactionRule is one pattern with its replacement text
+type redactionRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// Redactor masks sensitive text in reports that are shared externally
+type Redactor struct {
+	rules []redactionRule
+	Count int
+}
+
+// LoadRedactor reads a rules file. Each non-empty line is one rule:
+//
+//	ACME Corp                 literal term, matched case-insensitively
+//	ACME Corp => [CUSTOMER]   literal term with its own replacement
+//	regex:PRJ-\d{4}           regular expression
+//	preset:prices             built-in rule set (prices, emails)
+//
+// Lines starting with # are comments.
+func LoadRedactor(path string) (*Redactor, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening redaction rules: %v", err)
+	}
+	defer file.Close()
+
+	r := &Redactor{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		replacement := defaultMask
+		if rule, repl, ok := strings.Cut(line, "=>"); ok {
+			line, replacement = strings.TrimSpace(rule), strings.TrimSpace(repl)
+		}
+
+		var patterns []string
+		switch {
+		case strings.HasPrefix(line, "preset:"):
+			name := strings.TrimPrefix(line, "preset:")
+			preset, ok := redactionPresets[name]
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: unknown preset %q", path, lineNumber, name)
+			}
+			patterns = preset
+		case strings.HasPrefix(line, "regex:"):
+			patterns = []string{strings.TrimPrefix(line, "regex:")}
+		default:
+			patterns = []string{`(?i)` + regexp.QuoteMeta(line)}
+		}
+
+		for _, p := range patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid pattern: %v", path, lineNumber, err)
+			}
+			r.rules = append(r.rules, redactionRule{pattern: re, replacement: replacement})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading redaction rules: %v", err)
+	}
+	if len(r.rules) == 0 {
+		return nil, fmt.Errorf("%s contains no redaction rules", path)
+	}
+	return r, nil
+}
+
+// Redact applies all rules to s in file order
+func (r *Redactor) Redact(s string) string {
+	for _, rule := range r.rules {
+		s = rule.pattern.ReplaceAllStringFunc(s, func(string) string {
+			r.Count++
+			return rule.replacement
+		})
+	}
+	return s
+}
+
+// RedactResult returns a copy of the result with every free-text and
+// structured field redacted. The input result is not modified.
+func (r *Redactor) RedactResult(result FullAnalysisResult) FullAnalysisResult {
+	out := result
+	// The document name often carries the customer or project code
+	out.PDFPath = r.Redact(filepath.Base(result.PDFPath))
+
+	out.Chunks = make([]ChunkAnalysis, len(result.Chunks))
+	for i, chunk := range result.Chunks {
+		chunk.Analysis = r.Redact(chunk.Analysis)
+		chunk.OriginalAnalysis = r.Redact(chunk.OriginalAnalysis)
+		chunk.Error = r.Redact(chunk.Error)
+		chunk.StructuredData = r.redactStructured(chunk.StructuredData)
+		compliance := make([]RuleResult, len(chunk.Compliance))
+		for j, check := range chunk.Compliance {
+			check.Message = r.Redact(check.Message)
+			compliance[j] = check
+		}
+		chunk.Compliance = compliance
+		validation := make([]ValidationResult, len(chunk.Validation))
+		for j, v := range chunk.Validation {
+			missing := make([]string, len(v.Missing))
+			for k, value := range v.Missing {
+				missing[k] = r.Redact(value)
+			}
+			v.Missing = missing
+			validation[j] = v
+		}
+		chunk.Validation = validation
+		if chunk.Unverified != nil {
+			unverified := make([]UnverifiedValue, len(chunk.Unverified))
+			for j, v := range chunk.Unverified {
+				v.Value = r.Redact(v.Value)
+				unverified[j] = v
+			}
+			chunk.Unverified = unverified
+		}
+		if chunk.Classification != nil {
+			classification := *chunk.Classification
+			classification.Summary = r.Redact(classification.Summary)
+			chunk.Classification = &classification
+		}
+		if chunk.Escalation != nil {
+			escalation := *chunk.Escalation
+			escalation.Reasons = make([]string, len(chunk.Escalation.Reasons))
+			for j, reason := range chunk.Escalation.Reasons {
+				escalation.Reasons[j] = r.Redact(reason)
+			}
+			escalation.Remaining = make([]string, len(chunk.Escalation.Remaining))
+			for j, reason := range chunk.Escalation.Remaining {
+				escalation.Remaining[j] = r.Redact(reason)
+			}
+			chunk.Escalation = &escalation
+		}
+		out.Chunks[i] = chunk
+	}
+	if result.Consolidated != nil {
+		consolidated := *result.Consolidated
+		consolidated.Analysis = r.Redact(consolidated.Analysis)
+		consolidated.Error = r.Redact(consolidated.Error)
+		consolidated.Levels = make([]ReduceLevel, len(result.Consolidated.Levels))
+		for i, level := range result.Consolidated.Levels {
+			summaries := make([]GroupSummary, len(level.Summaries))
+			for j, summary := range level.Summaries {
+				summary.Summary = r.Redact(summary.Summary)
+				summaries[j] = summary
+			}
+			consolidated.Levels[i] = ReduceLevel{Level: level.Level, Summaries: summaries}
+		}
+		out.Consolidated = &consolidated
+	}
+	if result.ExecutiveSummary != nil {
+		summary := *result.ExecutiveSummary
+		summary.Summary = r.Redact(summary.Summary)
+		summary.Error = r.Redact(summary.Error)
+		out.ExecutiveSummary = &summary
+	}
+
+	out.MasterBOM = make([]MasterBOMItem, len(result.MasterBOM))
+	for i, item := range result.MasterBOM {
+		for _, field := range []*string{&item.PartNumber, &item.Description, &item.Material, &item.Finish} {
+			*field = r.Redact(*field)
+		}
+		conflicts := make([]string, len(item.Conflicts))
+		for j, conflict := range item.Conflicts {
+			conflicts[j] = r.Redact(conflict)
+		}
+		item.Conflicts = conflicts
+		out.MasterBOM[i] = item
+	}
+
+	out.Discrepancies = make([]Discrepancy, len(result.Discrepancies))
+	for i, d := range result.Discrepancies {
+		d.Message = r.Redact(d.Message)
+		out.Discrepancies[i] = d
+	}
+
+	out.TitleBlockExceptions = make([]TitleBlockException, len(result.TitleBlockExceptions))
+	for i, e := range result.TitleBlockExceptions {
+		e.DrawingNumber = r.Redact(e.DrawingNumber)
+		out.TitleBlockExceptions[i] = e
+	}
+
+	out.Standards = make([]StandardReference, len(result.Standards))
+	for i, ref := range result.Standards {
+		ref.Designation = r.Redact(ref.Designation)
+		out.Standards[i] = ref
+	}
+
+	if result.AssemblyCost != nil {
+		cost := *result.AssemblyCost
+		cost.Lines = make([]AssemblyCostLine, len(result.AssemblyCost.Lines))
+		for i, line := range result.AssemblyCost.Lines {
+			line.PartNumber = r.Redact(line.PartNumber)
+			line.Description = r.Redact(line.Description)
+			cost.Lines[i] = line
+		}
+		out.AssemblyCost = &cost
+	}
+
+	out.Index = make([]IndexEntry, len(result.Index))
+	for i, entry := range result.Index {
+		entry.Value = r.Redact(entry.Value)
+		entry.Revision = r.Redact(entry.Revision)
+		out.Index[i] = entry
+	}
+	return out
+}
+
+// redactStructured copies and redacts the typed extraction fields
+func (r *Redactor) redactStructured(data StructuredData) StructuredData {
+	if data.Metadata != nil {
+		m := *data.Metadata
+		for _, field := range []*string{&m.DrawingNumber, &m.Title, &m.Revision, &m.DrawnBy, &m.CheckedBy,
+			&m.ApprovedBy, &m.Date, &m.Scale, &m.Projection, &m.Material} {
+			*field = r.Redact(*field)
+		}
+		data.Metadata = &m
+	}
+
+	items := make([]BOMItem, len(data.BOMItems))
+	for i, item := range data.BOMItems {
+		for _, field := range []*string{&item.ItemNumber, &item.PartNumber, &item.Description, &item.Material, &item.Finish} {
+			*field = r.Redact(*field)
+		}
+		items[i] = item
+	}
+	data.BOMItems = items
+
+	dims := make([]Dimension, len(data.Dimensions))
+	for i, d := range data.Dimensions {
+		for _, field := range []*string{&d.Feature, &d.Type, &d.Value, &d.Unit, &d.Tolerance} {
+			*field = r.Redact(*field)
+		}
+		dims[i] = d
+	}
+	data.Dimensions = dims
+
+	notes := make([]string, len(data.Notes))
+	for i, note := range data.Notes {
+		notes[i] = r.Redact(note)
+	}
+	data.Notes = notes
+
+	welds := make([]WeldSymbol, len(data.Welds))
+	for i, w := range data.Welds {
+		for _, field := range []*string{&w.Location, &w.Size, &w.Length, &w.Pitch, &w.Contour, &w.Process, &w.Note} {
+			*field = r.Redact(*field)
+		}
+		welds[i] = w
+	}
+	data.Welds = welds
+
+	finishes := make([]SurfaceFinish, len(data.SurfaceFinishes))
+	for i, f := range data.SurfaceFinishes {
+		for _, field := range []*string{&f.Feature, &f.Parameter, &f.Value, &f.Unit, &f.Lay, &f.Process, &f.Allowance} {
+			*field = r.Redact(*field)
+		}
+		finishes[i] = f
+	}
+	data.SurfaceFinishes = finishes
+	return r.redactPackSections(data)
+}
+
+// redactPackSections copies and redacts the sections of the prompt packs
+func (r *Redactor) redactPackSections(data StructuredData) StructuredData {
+	redact := func(fields ...*string) {
+		for _, field := range fields {
+			*field = r.Redact(*field)
+		}
+	}
+	instruments := make([]Instrument, len(data.Instruments))
+	for i, x := range data.Instruments {
+		redact(&x.Tag, &x.Function, &x.Loop, &x.Service, &x.Description)
+		instruments[i] = x
+	}
+	data.Instruments = instruments
+
+	lines := make([]PipingLine, len(data.Lines))
+	for i, x := range data.Lines {
+		redact(&x.LineNumber, &x.Size, &x.Service, &x.Spec, &x.Insulation, &x.From, &x.To)
+		lines[i] = x
+	}
+	data.Lines = lines
+
+	equipment := make([]Equipment, len(data.Equipment))
+	for i, x := range data.Equipment {
+		redact(&x.Tag, &x.Description, &x.Rating)
+		equipment[i] = x
+	}
+	data.Equipment = equipment
+
+	components := make([]ElectricalComponent, len(data.Components))
+	for i, x := range data.Components {
+		redact(&x.Designator, &x.Value, &x.PartNumber, &x.Description)
+		components[i] = x
+	}
+	data.Components = components
+
+	nets := make([]Net, len(data.Nets))
+	for i, x := range data.Nets {
+		nodes := make([]string, len(x.Nodes))
+		for j, node := range x.Nodes {
+			nodes[j] = r.Redact(node)
+		}
+		redact(&x.Name)
+		x.Nodes = nodes
+		nets[i] = x
+	}
+	data.Nets = nets
+
+	rooms := make([]Room, len(data.Rooms))
+	for i, x := range data.Rooms {
+		redact(&x.Number, &x.Name, &x.Area, &x.Level, &x.Finishes)
+		rooms[i] = x
+	}
+	data.Rooms = rooms
+
+	openings := make([]Opening, len(data.Openings))
+	for i, x := range data.Openings {
+		redact(&x.Mark, &x.Size, &x.Type, &x.FireRating)
+		openings[i] = x
+	}
+	data.Openings = openings
+	return data
+}
@@ -0,0 +1,222 @@
+package pdfanalysis
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"design-ant/internal/tempfiles"
+)
+
+// Limits of the Messages API for images
+const (
+	anthropicMaxImageMB = 5    // Per image, base64-encoded
+	anthropicMaxImagePx = 8000 // Longest edge
+)
+
+// renderDPIs are tried in order for a page that is too large as a PDF; the
+// first render that fits the limits is sent
+var renderDPIs = []float64{150, 100, 72}
+
+// Tiling is the last resort: the page is rendered at renderTileDPI and cut
+// into a grid of up to renderMaxTiles × renderMaxTiles images
+const (
+	renderTileDPI  = 150
+	renderMaxTiles = 4
+)
+
+// renderedPageImage is the file name of a page rendered as a single image
+const renderedPageImage = "page.jpg"
+
+// renderOversizedPage renders a single-page chunk as JPEG, for a page whose
+// PDF is too large for the API or a provider that takes no PDFs: the whole
+// page at the highest DPI in renderDPIs that fits the provider's limits,
+// otherwise as tiles. The returned chunk's Path is a directory of images,
+// which ChunkContent sends in place of the PDF. Canceling ctx stops between
+// renderings and removes the images written so far.
+func renderOversizedPage(ctx context.Context, src pdfSource, temp *tempfiles.Dir, chunk ChunkInfo, limits Limits, caps Capabilities) (ChunkInfo, error) {
+	doc, err := src.openFitz()
+	if err != nil {
+		return chunk, fmt.Errorf("error opening PDF: %v", err)
+	}
+	defer doc.Close()
+
+	maxImage := caps.MaxImageMB * BytesPerMB
+	maxRequest := caps.MaxRequestMB * BytesPerMB
+	maxEdge := caps.MaxImagePx
+	if limits.MaxChunkMB > 0 {
+		maxImage = min(maxImage, limits.MaxChunkMB*BytesPerMB)
+		maxRequest = min(maxRequest, limits.MaxChunkMB*BytesPerMB)
+	}
+	dir, err := temp.Sub(fmt.Sprintf("page_%d_render", chunk.StartPage+1))
+	if err != nil {
+		return chunk, err
+	}
+
+	for _, dpi := range renderDPIs {
+		if ctx.Err() != nil {
+			os.RemoveAll(dir)
+			return chunk, ctx.Err()
+		}
+		img, err := doc.ImageDPI(chunk.StartPage, dpi)
+		if err != nil {
+			return chunk, fmt.Errorf("error rendering page %d: %v", chunk.StartPage+1, err)
+		}
+		if maxEdge > 0 && longestEdge(img) > maxEdge {
+			continue
+		}
+		data, err := encodeJPEG(img)
+		if err != nil {
+			return chunk, err
+		}
+		if encodedSize(data) <= maxImage {
+			if err := os.WriteFile(filepath.Join(dir, renderedPageImage), data, 0644); err != nil {
+				return chunk, fmt.Errorf("error writing rendered page: %v", err)
+			}
+			chunk.Path = dir
+			chunk.Rendered = fmt.Sprintf("rendered at %.0f dpi", dpi)
+			return chunk, nil
+		}
+	}
+
+	img, err := doc.ImageDPI(chunk.StartPage, renderTileDPI)
+	if err != nil {
+		return chunk, fmt.Errorf("error rendering page %d: %v", chunk.StartPage+1, err)
+	}
+	for n := 2; n <= renderMaxTiles; n++ {
+		if ctx.Err() != nil {
+			os.RemoveAll(dir)
+			return chunk, ctx.Err()
+		}
+		tiles, err := tilePage(img, n, maxImage, maxRequest, maxEdge)
+		if err != nil {
+			return chunk, err
+		}
+		if tiles == nil {
+			continue
+		}
+		for name, data := range tiles {
+			if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+				return chunk, fmt.Errorf("error writing page tile: %v", err)
+			}
+		}
+		chunk.Path = dir
+		chunk.Rendered = fmt.Sprintf("%d×%d tiles at %d dpi", n, n, renderTileDPI)
+		return chunk, nil
+	}
+	return chunk, fmt.Errorf("page %d is too large to send even as %d×%d tiles", chunk.StartPage+1, renderMaxTiles, renderMaxTiles)
+}
+
+// tilePage cuts img into an n×n grid of JPEGs named tile-<row>-<col>.jpg.
+// It returns nil when a tile or the whole request would exceed the limits.
+func tilePage(img *image.RGBA, n int, maxImage, maxRequest int64, maxEdge int) (map[string][]byte, error) {
+	bounds := img.Bounds()
+	tiles := make(map[string][]byte)
+	var total int64
+	for row := 0; row < n; row++ {
+		for col := 0; col < n; col++ {
+			rect := image.Rect(
+				bounds.Min.X+bounds.Dx()*col/n, bounds.Min.Y+bounds.Dy()*row/n,
+				bounds.Min.X+bounds.Dx()*(col+1)/n, bounds.Min.Y+bounds.Dy()*(row+1)/n)
+			tile := img.SubImage(rect)
+			if maxEdge > 0 && longestEdge(tile) > maxEdge {
+				return nil, nil
+			}
+			data, err := encodeJPEG(tile)
+			if err != nil {
+				return nil, err
+			}
+			total += encodedSize(data)
+			if encodedSize(data) > maxImage || total > maxRequest {
+				return nil, nil
+			}
+			tiles[fmt.Sprintf("tile-%d-%d.jpg", row+1, col+1)] = data
+		}
+	}
+	return tiles, nil
+}
+
+// renderedContent builds the message content for a page rendered by
+// renderOversizedPage: one image, or the tiles in reading order, each
+// labeled with its position, followed by the prompt
+func renderedContent(dir, prompt string) ([]map[string]interface{}, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.jpg"))
+	if err != nil || len(names) == 0 {
+		return nil, fmt.Errorf("error reading rendered page: no images in %s", dir)
+	}
+	sort.Strings(names)
+
+	var content []map[string]interface{}
+	imageBlock := func(path string) map[string]interface{} {
+		return map[string]interface{}{
+			"type": "image",
+			"source": map[string]interface{}{
+				"type":       "base64",
+				"media_type": "image/jpeg",
+				"data":       FileData{Path: path},
+			},
+		}
+	}
+	if len(names) == 1 && filepath.Base(names[0]) == renderedPageImage {
+		content = append(content, imageBlock(names[0]))
+	} else {
+		for _, name := range names {
+			var row, col int
+			fmt.Sscanf(filepath.Base(name), "tile-%d-%d.jpg", &row, &col)
+			content = append(content,
+				map[string]interface{}{"type": "text", "text": fmt.Sprintf("Tile at row %d, column %d:", row, col)},
+				imageBlock(name))
+		}
+		prompt = "The images above are tiles of a single page, cut in a grid and labeled by row and column from the top left. " +
+			"Analyze them together as one page; details cut at tile edges continue in the neighboring tile.\n\n" + prompt
+	}
+	return append(content, map[string]interface{}{"type": "text", "text": prompt}), nil
+}
+
+// renderedSize returns the base64-encoded size of the images of a rendered page
+func renderedSize(dir string) (int64, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.jpg"))
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, name := range names {
+		info, err := os.Stat(name)
+		if err != nil {
+			return 0, err
+		}
+		total += int64(base64.StdEncoding.EncodedLen(int(info.Size())))
+	}
+	return total, nil
+}
+
+// encodeJPEG encodes an image at the quality of the annotated PDF
+func encodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, fmt.Errorf("error encoding page image: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// encodedSize returns the base64-encoded size of data
+func encodedSize(data []byte) int64 {
+	return int64(base64.StdEncoding.EncodedLen(len(data)))
+}
+
+// longestEdge returns the longer side of an image in pixels
+func longestEdge(img image.Image) int {
+	return max(img.Bounds().Dx(), img.Bounds().Dy())
+}
+
+// isRendered reports whether a chunk path is a page rendered as images
+func isRendered(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,44 @@
+package pdfanalysis
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ReproducibleTime is the time recorded by -reproducible runs: SOURCE_DATE_EPOCH
+// when set (the reproducible-builds convention), otherwise the Unix epoch
+func ReproducibleTime() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Unix(0, 0).UTC(), nil
+	}
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: must be Unix seconds", epoch)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// PinTimes replaces the wall-clock fields of a result, which differ on every
+// run, with at and zero durations. With everything else derived from the
+// chunks in page order, two runs over the same cached pages then write
+// byte-identical JSON.
+func PinTimes(result *FullAnalysisResult, at time.Time) {
+	zero := time.Duration(0).String()
+	for i := range result.Chunks {
+		result.Chunks[i].Timestamp = at
+		result.Chunks[i].ProcessingTime = zero
+	}
+	if c := result.Consolidated; c != nil {
+		c.Timestamp = at
+		c.ProcessingTime = zero
+	}
+	if s := result.ExecutiveSummary; s != nil {
+		s.Timestamp = at
+		s.ProcessingTime = zero
+	}
+	result.GeneratedAt = at
+	result.ProcessingTime = zero
+}
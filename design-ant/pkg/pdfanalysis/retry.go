@@ -0,0 +1,130 @@
+package pdfanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"design-ant/pkg/results"
+)
+
+// errorClass groups failed requests by how they should be retried
+type errorClass string
+
+// Error classes of failed API requests, recorded as ChunkAnalysis.ErrorClass.
+// Rate-limited and overloaded requests are retried patiently; too large,
+// invalid, and blocked requests fail again and are not retried.
+const (
+	ErrorRateLimited    errorClass = results.ErrorRateLimited
+	ErrorOverloaded     errorClass = results.ErrorOverloaded
+	ErrorServer         errorClass = results.ErrorServer
+	ErrorTimeout        errorClass = results.ErrorTimeout
+	ErrorNetwork        errorClass = results.ErrorNetwork
+	ErrorTooLarge       errorClass = results.ErrorTooLarge
+	ErrorInvalidRequest errorClass = results.ErrorInvalidRequest
+	ErrorBlocked        errorClass = results.ErrorBlocked
+	ErrorBadResponse    errorClass = results.ErrorBadResponse
+	ErrorCanceled       errorClass = results.ErrorCanceled
+	ErrorOther          errorClass = results.ErrorOther
+)
+
+// retryPolicy is how often and how patiently one error class is retried
+type retryPolicy struct {
+	attempts int           // Requests in total, including the first
+	base     time.Duration // First backoff; doubles with every retry
+	max      time.Duration // Cap for the backoff
+}
+
+// retryPolicies holds the policy of every retried class; classes without an
+// entry fail on the first attempt
+var retryPolicies = map[errorClass]retryPolicy{
+	ErrorRateLimited: {attempts: 3, base: 2 * time.Second, max: time.Minute},
+	ErrorOverloaded:  {attempts: 6, base: 5 * time.Second, max: 2 * time.Minute},
+	ErrorServer:      {attempts: 3, base: 2 * time.Second, max: 30 * time.Second},
+	ErrorTimeout:     {attempts: 2, base: 5 * time.Second, max: 5 * time.Second},
+	ErrorNetwork:     {attempts: 3, base: time.Second, max: 10 * time.Second},
+	ErrorBadResponse: {attempts: 2, base: 2 * time.Second, max: 2 * time.Second},
+}
+
+// ClassifyError sorts a failed request into its error class by status code
+// and, for Anthropic errors, by the error type in the response body; a
+// response without an answer by its reason
+func ClassifyError(err error) errorClass {
+	if errors.Is(err, context.Canceled) {
+		return ErrorCanceled
+	}
+	var respErr *ResponseError
+	if errors.As(err, &respErr) {
+		if respErr.Reason == ResponseRefused || respErr.Reason == ResponseBlocked {
+			return ErrorBlocked
+		}
+		return ErrorBadResponse
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch errorType := apiErr.errorType(); {
+		case apiErr.StatusCode == http.StatusTooManyRequests || errorType == "rate_limit_error":
+			return ErrorRateLimited
+		case apiErr.StatusCode == 529 || errorType == "overloaded_error":
+			return ErrorOverloaded
+		case apiErr.StatusCode == http.StatusRequestEntityTooLarge || errorType == "request_too_large":
+			return ErrorTooLarge
+		case apiErr.StatusCode == http.StatusBadRequest && strings.Contains(apiErr.Body, "prompt is too long"):
+			return ErrorTooLarge
+		case apiErr.StatusCode == http.StatusRequestTimeout:
+			return ErrorTimeout
+		case apiErr.StatusCode >= 500:
+			return ErrorServer
+		case apiErr.StatusCode >= 400:
+			return ErrorInvalidRequest
+		}
+		return ErrorOther
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ErrorTimeout
+		}
+		return ErrorNetwork
+	}
+	return ErrorOther
+}
+
+// RetryDelay decides whether a request that failed on attempt (1-based)
+// should be sent again and how long to wait first: the server's retry-after
+// when given, otherwise exponential backoff with jitter, so workers that
+// failed together do not retry together.
+func RetryDelay(err error, attempt int) (time.Duration, bool) {
+	policy, ok := retryPolicies[ClassifyError(err)]
+	if !ok || attempt >= policy.attempts {
+		return 0, false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter, true
+	}
+	delay := min(policy.base<<(attempt-1), policy.max)
+	return delay/2 + rand.N(delay/2+1), true
+}
+
+// errorType returns the error type of an Anthropic error body, such as
+// "overloaded_error", or "" when the body is not one
+func (e *APIError) errorType() string {
+	var body struct {
+		Error struct {
+			Type string `json:"type"`
+		} `json:"error"`
+	}
+	if json.Unmarshal([]byte(e.Body), &body) != nil {
+		return ""
+	}
+	return body.Error.Type
+}
@@ -0,0 +1,115 @@
+package pdfanalysis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// pageFingerprintDPI is low to keep fingerprinting fast; any visible change
+// to a page still changes its pixels
+const pageFingerprintDPI = 36
+
+// PageFingerprints hashes the rendered pixels and the text layer of every
+// page, so identical pages are recognized across runs and drawing revisions
+func PageFingerprints(pdfPath string, totalPages int) ([]string, error) {
+	return pageFingerprints(context.Background(), fileSource(pdfPath), totalPages)
+}
+
+// pageFingerprints hashes the pages of a source, stopping when ctx is canceled
+func pageFingerprints(ctx context.Context, src pdfSource, totalPages int) ([]string, error) {
+	doc, err := src.openFitz()
+	if err != nil {
+		return nil, fmt.Errorf("error opening PDF for fingerprinting: %v", err)
+	}
+	defer doc.Close()
+
+	hashes := make([]string, totalPages)
+	for i := 0; i < totalPages; i++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		img, err := doc.ImageDPI(i, pageFingerprintDPI)
+		if err != nil {
+			return nil, fmt.Errorf("error rendering page %d: %v", i+1, err)
+		}
+		text, err := doc.Text(i)
+		if err != nil {
+			return nil, fmt.Errorf("error reading text of page %d: %v", i+1, err)
+		}
+		h := sha256.New()
+		fmt.Fprintf(h, "%dx%d\x00", img.Bounds().Dx(), img.Bounds().Dy())
+		h.Write(img.Pix)
+		h.Write([]byte(strings.TrimSpace(text)))
+		hashes[i] = hex.EncodeToString(h.Sum(nil))
+	}
+	return hashes, nil
+}
+
+// reusedPage is an earlier page analysis with a description of where it came from
+type reusedPage struct {
+	chunk  ChunkAnalysis
+	source string
+}
+
+// reuseCache holds earlier page analyses by page fingerprint
+type reuseCache map[string]reusedPage
+
+// loadReuseCache collects the reusable single-page analyses of earlier result
+// files, then of the results given loaded. When several results contain the
+// same page, the first one wins.
+func loadReuseCache(config *Config) (reuseCache, error) {
+	var results []*Result
+	for _, filename := range config.ReuseFrom {
+		result, err := LoadResult(filename)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	results = append(results, config.ReuseResults...)
+	cache := make(reuseCache)
+	for _, result := range results {
+		for _, chunk := range result.Chunks {
+			if chunk.PageHash == "" || chunk.StartPage != chunk.EndPage || !reusable(config, chunk) {
+				continue
+			}
+			if _, ok := cache[chunk.PageHash]; !ok {
+				source := fmt.Sprintf("%s p. %d", filepath.Base(result.PDFPath), chunk.StartPage)
+				cache[chunk.PageHash] = reusedPage{chunk: chunk, source: source}
+			}
+		}
+	}
+	return cache, nil
+}
+
+// reusable reports whether an earlier analysis satisfies the current options;
+// one cut off at max_tokens never does
+func reusable(config *Config, chunk ChunkAnalysis) bool {
+	if chunk.Error != "" || chunk.Truncated || chunk.Language != config.OutputLang {
+		return false
+	}
+	if config.Structured {
+		data := chunk.StructuredData
+		return data.Metadata != nil || len(data.BOMItems) > 0 || len(data.Dimensions) > 0 || len(data.Notes) > 0
+	}
+	return true
+}
+
+// lookup returns a copy of the cached analysis for a page, renumbered for the
+// current document. Reused pages cost nothing in this run.
+func (c reuseCache) lookup(hash string, chunkNumber, page int) (ChunkAnalysis, bool) {
+	cached, ok := c[hash]
+	if !ok || hash == "" {
+		return ChunkAnalysis{}, false
+	}
+	chunk := cached.chunk
+	chunk.ChunkNumber = chunkNumber
+	chunk.StartPage, chunk.EndPage = page, page
+	markCopied(&chunk)
+	chunk.ReusedFrom = cached.source
+	return chunk, true
+}
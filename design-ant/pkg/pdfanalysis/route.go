@@ -0,0 +1,224 @@
+package pdfanalysis
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// Input modes decide how a page is submitted to the API
+const (
+	InputModePDF  = "pdf"  // Send the page as a PDF document (visual analysis)
+	InputModeText = "text" // Send only the extracted text layer (tokens only)
+	InputModeAuto = "auto" // Pick per page based on content stream analysis
+)
+
+// Thresholds for routing a page to text-only analysis
+const (
+	minTextChars  = 200 // Pages with less text are likely drawings
+	maxVectorOps  = 500 // CAD geometry produces thousands of path operators
+	maxInlineText = 100_000
+)
+
+// PageRoute records which submission path was chosen for a page and why
+type PageRoute struct {
+	Page         int    // 1-indexed page number
+	Mode         string // InputModePDF or InputModeText
+	Reason       string
+	TextOps      int
+	VectorOps    int
+	XObjects     int
+	InlineImages int
+	Text         string // Extracted text layer
+}
+
+// contentStats counts the operators of interest in a page content stream
+type contentStats struct {
+	TextOps      int
+	VectorOps    int
+	XObjects     int
+	InlineImages int
+}
+
+// ClassifyPages analyzes each page's content stream and decides whether
+// its text layer is enough or the full page has to be submitted
+func ClassifyPages(pdfPath string, totalPages int) ([]PageRoute, error) {
+	return classifyPages(context.Background(), fileSource(pdfPath), totalPages)
+}
+
+// classifyPages routes the pages of a source, stopping when ctx is canceled
+func classifyPages(ctx context.Context, src pdfSource, totalPages int) ([]PageRoute, error) {
+	file, closeFile, err := src.open()
+	if err != nil {
+		return nil, err
+	}
+	defer closeFile()
+
+	pdfCtx, err := api.ReadAndValidate(file, model.NewDefaultConfiguration())
+	if err != nil {
+		return nil, fmt.Errorf("error reading PDF structure: %v", err)
+	}
+
+	doc, err := src.openFitz()
+	if err != nil {
+		return nil, fmt.Errorf("error opening PDF for text extraction: %v", err)
+	}
+	defer doc.Close()
+
+	routes := make([]PageRoute, totalPages)
+	for i := 0; i < totalPages; i++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		route := PageRoute{Page: i + 1, Mode: InputModePDF}
+
+		content, err := pdfcpu.ExtractPageContent(pdfCtx, i+1)
+		if err != nil {
+			route.Reason = fmt.Sprintf("content stream unreadable: %v", err)
+			routes[i] = route
+			continue
+		}
+		data, err := io.ReadAll(content)
+		if err != nil {
+			route.Reason = fmt.Sprintf("content stream unreadable: %v", err)
+			routes[i] = route
+			continue
+		}
+
+		stats := scanContentStream(data)
+		route.TextOps = stats.TextOps
+		route.VectorOps = stats.VectorOps
+		route.XObjects = stats.XObjects
+		route.InlineImages = stats.InlineImages
+
+		text, err := doc.Text(i)
+		if err != nil {
+			route.Reason = fmt.Sprintf("text layer unreadable: %v", err)
+			routes[i] = route
+			continue
+		}
+		text = strings.TrimSpace(text)
+		route.Text = text
+
+		switch {
+		case stats.XObjects > 0 || stats.InlineImages > 0:
+			route.Reason = fmt.Sprintf("%d image/form objects", stats.XObjects+stats.InlineImages)
+		case stats.VectorOps > maxVectorOps:
+			route.Reason = fmt.Sprintf("%d vector operators (drawing geometry)", stats.VectorOps)
+		case len(text) < minTextChars:
+			route.Reason = fmt.Sprintf("only %d characters of text", len(text))
+		case len(text) > maxInlineText:
+			route.Reason = fmt.Sprintf("text layer too large (%d characters)", len(text))
+		default:
+			route.Mode = InputModeText
+			route.Reason = fmt.Sprintf("%d characters of text, %d vector operators", len(text), stats.VectorOps)
+		}
+		routes[i] = route
+	}
+	return routes, nil
+}
+
+// routeForChunk returns the route of a single-page chunk. Multi-page chunks
+// and runs without page classification always go through the PDF path.
+func routeForChunk(routes []PageRoute, chunk ChunkInfo) PageRoute {
+	if chunk.StartPage != chunk.EndPage || chunk.StartPage >= len(routes) {
+		return PageRoute{Page: chunk.StartPage + 1, Mode: InputModePDF}
+	}
+	return routes[chunk.StartPage]
+}
+
+// scanContentStream tokenizes a PDF content stream and counts operators.
+// Operands (strings, names, numbers, arrays, dictionaries) are skipped.
+func scanContentStream(data []byte) contentStats {
+	var stats contentStats
+	n := len(data)
+	for i := 0; i < n; {
+		c := data[i]
+		switch {
+		case isPDFWhitespace(c):
+			i++
+		case c == '%':
+			for i < n && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			i = skipLiteralString(data, i)
+		case c == '<':
+			if i+1 < n && data[i+1] == '<' {
+				i += 2
+			} else {
+				for i < n && data[i] != '>' {
+					i++
+				}
+				i++
+			}
+		case c == '>' || c == '[' || c == ']' || c == '{' || c == '}':
+			i++
+		case c == '/':
+			i++
+			for i < n && !isPDFWhitespace(data[i]) && !isPDFDelimiter(data[i]) {
+				i++
+			}
+		default:
+			start := i
+			for i < n && !isPDFWhitespace(data[i]) && !isPDFDelimiter(data[i]) {
+				i++
+			}
+			if i == start {
+				i++
+				continue
+			}
+			switch string(data[start:i]) {
+			case "Tj", "TJ", "'", "\"":
+				stats.TextOps++
+			case "m", "l", "c", "v", "y", "re":
+				stats.VectorOps++
+			case "Do":
+				stats.XObjects++
+			case "BI":
+				stats.InlineImages++
+			case "ID":
+				// Skip binary inline image data up to the EI operator
+				if end := bytes.Index(data[i:], []byte("EI")); end >= 0 {
+					i += end + 2
+				} else {
+					i = n
+				}
+			}
+		}
+	}
+	return stats
+}
+
+// skipLiteralString returns the index just past a (possibly nested) literal string
+func skipLiteralString(data []byte, i int) int {
+	depth := 0
+	for ; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return i
+}
+
+func isPDFWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
@@ -0,0 +1,241 @@
+package pdfanalysis
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// complianceRule is one line of a rules file
+type complianceRule struct {
+	text     string // The rule as written, used as its name in reports
+	field    string
+	operator string // required, matches, contains, or oneof
+	pattern  *regexp.Regexp
+	values   []string // Allowed values for oneof, the text for contains
+	message  string
+}
+
+// titleBlockRuleFields are the title block fields a rule can check
+var titleBlockRuleFields = map[string]func(*DrawingMetadata) string{
+	"drawing_number": func(m *DrawingMetadata) string { return m.DrawingNumber },
+	"title":          func(m *DrawingMetadata) string { return m.Title },
+	"revision":       func(m *DrawingMetadata) string { return m.Revision },
+	"drawn_by":       func(m *DrawingMetadata) string { return m.DrawnBy },
+	"checked_by":     func(m *DrawingMetadata) string { return m.CheckedBy },
+	"approved_by":    func(m *DrawingMetadata) string { return m.ApprovedBy },
+	"date":           func(m *DrawingMetadata) string { return m.Date },
+	"scale":          func(m *DrawingMetadata) string { return m.Scale },
+	"projection":     func(m *DrawingMetadata) string { return m.Projection },
+	"material":       func(m *DrawingMetadata) string { return m.Material },
+}
+
+// pageRuleFields are the other fields a rule can check; notes and analysis
+// pass when any value satisfies the rule
+var pageRuleFields = map[string]func(ChunkAnalysis) []string{
+	"notes":    func(c ChunkAnalysis) []string { return c.Notes },
+	"analysis": func(c ChunkAnalysis) []string { return []string{c.Analysis} },
+	"tolerance": func(c ChunkAnalysis) []string {
+		var values []string
+		for _, d := range c.Dimensions {
+			values = append(values, d.Tolerance)
+		}
+		return values
+	},
+}
+
+// loadRules reads a compliance rules file. Each non-empty line is one rule:
+//
+//	checked_by required                     field must be filled in
+//	revision matches ^[A-Z]{1,2}$           field must match a regular expression
+//	notes contains ISO 2768                 some value must contain the text (case-insensitive)
+//	projection oneof first angle, third angle
+//	tolerance required => Every dimension needs a tolerance
+//
+// An optional "=> message" replaces the generated failure message. Rules on
+// notes and analysis pass when any value satisfies them, tolerance rules must
+// hold for every dimension. Lines starting with # are comments.
+func loadRules(path string) ([]complianceRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening rules file: %v", err)
+	}
+	defer file.Close()
+
+	var rules []complianceRule
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := complianceRule{}
+		if text, message, ok := strings.Cut(line, "=>"); ok {
+			line, rule.message = strings.TrimSpace(text), strings.TrimSpace(message)
+		}
+		rule.text = line
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"<field> <operator> [argument]\"", path, lineNumber)
+		}
+		rule.field, rule.operator = strings.ToLower(fields[0]), strings.ToLower(fields[1])
+		_, titleBlock := titleBlockRuleFields[rule.field]
+		if _, ok := pageRuleFields[rule.field]; !ok && !titleBlock {
+			return nil, fmt.Errorf("%s:%d: unknown field %q", path, lineNumber, fields[0])
+		}
+		argument := ""
+		if len(fields) == 3 {
+			argument = strings.TrimSpace(fields[2])
+		}
+
+		switch rule.operator {
+		case "required":
+		case "matches":
+			if rule.pattern, err = regexp.Compile(argument); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid pattern: %v", path, lineNumber, err)
+			}
+		case "contains":
+			rule.pattern = regexp.MustCompile(`(?i)` + regexp.QuoteMeta(argument))
+			rule.values = []string{argument}
+		case "oneof":
+			for _, v := range strings.Split(argument, ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					rule.values = append(rule.values, v)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown operator %q: must be required, matches, contains, or oneof", path, lineNumber, fields[1])
+		}
+		if rule.operator != "required" && argument == "" {
+			return nil, fmt.Errorf("%s:%d: %s needs an argument", path, lineNumber, rule.operator)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading rules file: %v", err)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("%s contains no rules", path)
+	}
+	return rules, nil
+}
+
+// checkCompliance runs every rule against each analyzed page and stores the
+// results on the chunk. Failed pages are not checked. It returns the number
+// of checked pages and the number that passed all rules.
+func checkCompliance(chunks []ChunkAnalysis, rules []complianceRule) (checked, passed int) {
+	for i := range chunks {
+		chunks[i].Compliance = nil
+		if chunks[i].Error != "" {
+			continue
+		}
+		ok := true
+		for _, rule := range rules {
+			result := rule.check(chunks[i])
+			ok = ok && result.Passed
+			chunks[i].Compliance = append(chunks[i].Compliance, result)
+		}
+		checked++
+		if ok {
+			passed++
+		}
+	}
+	return checked, passed
+}
+
+// check evaluates the rule on one page
+func (r complianceRule) check(chunk ChunkAnalysis) RuleResult {
+	result := RuleResult{Rule: r.text, Passed: true}
+	switch get, titleBlock := titleBlockRuleFields[r.field]; {
+	case titleBlock && chunk.Metadata == nil:
+		result.Message = "no title block data on this page"
+	case titleBlock:
+		if value := get(chunk.Metadata); !r.accepts(value) {
+			result.Message = r.failure(value)
+		}
+	case r.field == "tolerance":
+		// Every dimension must satisfy the rule; pages without dimensions pass
+		for _, value := range pageRuleFields[r.field](chunk) {
+			if !r.accepts(value) {
+				result.Message = r.failure(value)
+				break
+			}
+		}
+	default:
+		matched := false
+		for _, value := range pageRuleFields[r.field](chunk) {
+			matched = matched || r.accepts(value)
+		}
+		if !matched {
+			result.Message = fmt.Sprintf("no %s %s", r.field, r.condition())
+		}
+	}
+	if result.Message != "" {
+		result.Passed = false
+		if r.message != "" {
+			result.Message = r.message
+		}
+	}
+	return result
+}
+
+// failure explains why a single value failed the rule
+func (r complianceRule) failure(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Sprintf("%s is empty", r.field)
+	}
+	return fmt.Sprintf("%s %q is not %s", r.field, value, r.condition())
+}
+
+// accepts reports whether a single value satisfies the rule
+func (r complianceRule) accepts(value string) bool {
+	value = strings.TrimSpace(value)
+	switch r.operator {
+	case "required":
+		return value != ""
+	case "oneof":
+		for _, v := range r.values {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	default:
+		return r.pattern.MatchString(value)
+	}
+}
+
+// condition phrases the rule's condition for failure messages
+func (r complianceRule) condition() string {
+	switch r.operator {
+	case "required":
+		return "filled in"
+	case "oneof":
+		return "one of " + strings.Join(r.values, ", ")
+	case "contains":
+		return "containing " + r.values[0]
+	default:
+		return "matching " + r.pattern.String()
+	}
+}
+
+// markdownCompliance renders a page's rule results as a checklist
+func markdownCompliance(results []RuleResult) string {
+	if len(results) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("**Compliance**\n\n")
+	for _, r := range results {
+		if r.Passed {
+			fmt.Fprintf(&b, "- [x] `%s`\n", r.Rule)
+		} else {
+			fmt.Fprintf(&b, "- [ ] `%s`: %s\n", r.Rule, EscapeInline(r.Message))
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
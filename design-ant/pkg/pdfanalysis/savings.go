@@ -0,0 +1,67 @@
+package pdfanalysis
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// markCopied zeroes the tokens and costs of a chunk copied from an earlier
+// analysis, recording them as saved instead. A copy of a copy keeps the
+// savings of the original analysis.
+func markCopied(chunk *ChunkAnalysis) {
+	if chunk.InputTokens > 0 || chunk.OutputTokens > 0 {
+		chunk.SavedInTokens, chunk.SavedOutTokens = chunk.InputTokens, chunk.OutputTokens
+		chunk.SavedCost = chunk.TotalCost
+	}
+	chunk.InputTokens, chunk.OutputTokens = 0, 0
+	chunk.InputCost, chunk.OutputCost, chunk.TotalCost = 0, 0, 0
+	chunk.Retries = 0
+	chunk.InputBreakdown = nil
+}
+
+// computeSavings adds up the savings of the copied chunks of a result; it
+// returns nil when no page was copied
+func computeSavings(result *FullAnalysisResult) *Savings {
+	var savings Savings
+	for _, chunk := range result.Chunks {
+		var line **SavingsLine
+		switch {
+		case chunk.DuplicateOf != 0:
+			line = &savings.Duplicates
+		case strings.HasPrefix(chunk.ReusedFrom, "cache: "):
+			line = &savings.Cache
+		case chunk.ReusedFrom != "":
+			line = &savings.Reuse
+		default:
+			continue
+		}
+		if *line == nil {
+			*line = &SavingsLine{}
+		}
+		(*line).Add(chunk)
+		savings.Total.Add(chunk)
+	}
+	if savings.Total.Pages == 0 {
+		return nil
+	}
+	savings.UncachedCost = result.TotalCost + savings.Total.Cost
+	return &savings
+}
+
+// WriteSavings writes the savings of a run to w, below the cost table
+func WriteSavings(w io.Writer, savings *Savings) {
+	if savings == nil {
+		return
+	}
+	line := func(label string, l *SavingsLine) {
+		if l != nil {
+			fmt.Fprintf(w, "  - %-18s %4d page(s), %d input + %d output tokens, $%.6f\n", label+":", l.Pages, l.InputTokens, l.OutputTokens, l.Cost)
+		}
+	}
+	fmt.Fprintf(w, "💸 %d page(s) copied instead of analyzed, saving $%.6f (the run would have cost $%.6f)\n",
+		savings.Total.Pages, savings.Total.Cost, savings.UncachedCost)
+	line("Page cache", savings.Cache)
+	line("Reused results", savings.Reuse)
+	line("Duplicate pages", savings.Duplicates)
+}
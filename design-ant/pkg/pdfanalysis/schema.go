@@ -0,0 +1,25 @@
+package pdfanalysis
+
+import (
+	"fmt"
+
+	"design-ant/pkg/results"
+)
+
+// CurrentSchemaVersion is the version of the FullAnalysisResult JSON written
+// by this build; see results.SchemaVersion for the history
+const CurrentSchemaVersion = results.SchemaVersion
+
+// LoadResult reads a result JSON file written by any supported version
+// (plain, gzip, or zstd compressed) and upgrades it to the current schema
+func LoadResult(filename string) (*FullAnalysisResult, error) {
+	data, err := readFileDecompressed(filename)
+	if err != nil {
+		return nil, err
+	}
+	result, err := results.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return result, nil
+}
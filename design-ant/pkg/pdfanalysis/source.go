@@ -0,0 +1,48 @@
+package pdfanalysis
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+// pdfSource is the document a run reads its pages from: a file on disk, or
+// a reader supplied by an embedding caller, which is read in place instead
+// of being copied to a temp file first
+type pdfSource struct {
+	path string      // File on disk, when r is nil
+	r    io.ReaderAt // Document held by the caller
+	size int64
+}
+
+// fileSource returns the source of a PDF on disk
+func fileSource(path string) pdfSource {
+	return pdfSource{path: path}
+}
+
+// readerSource returns the source of a PDF of size bytes read from r
+func readerSource(r io.ReaderAt, size int64) pdfSource {
+	return pdfSource{r: r, size: size}
+}
+
+// open returns the document for pdfcpu and a function that releases it
+func (s pdfSource) open() (io.ReadSeeker, func() error, error) {
+	if s.r != nil {
+		return io.NewSectionReader(s.r, 0, s.size), func() error { return nil }, nil
+	}
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening PDF: %v", err)
+	}
+	return file, file.Close, nil
+}
+
+// openFitz opens the document with MuPDF for rendering and text extraction
+func (s pdfSource) openFitz() (*fitz.Document, error) {
+	if s.r != nil {
+		return fitz.NewFromReader(io.NewSectionReader(s.r, 0, s.size))
+	}
+	return fitz.New(s.path)
+}
@@ -0,0 +1,125 @@
+package pdfanalysis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// DefaultStageTimeout bounds one page of an ExecStage without a Timeout
+const DefaultStageTimeout = time.Minute
+
+// PageStage is a custom step run on every analyzed page after extraction,
+// e.g. a company-specific BOM normalizer. Process changes the page in
+// place: it may rewrite the analysis or the structured data, or add its own
+// output to Extensions under its name. The stages of a run are called in
+// order by the workers, on several pages at once.
+//
+// Pages kept from a resumed run, reused from an earlier result, or copied
+// from an identical page already went through the stages and are not
+// processed again; pages found in the page cache are.
+type PageStage interface {
+	// Name identifies the stage in logs and traces
+	Name() string
+	// Process runs the stage on one page. On an error the page is kept as
+	// it was before the stage and the next stage still runs.
+	Process(ctx context.Context, page *ChunkAnalysis) error
+}
+
+// runStages runs the configured stages on an analyzed page
+func runStages(ctx context.Context, config *Config, page *ChunkAnalysis) {
+	if page.Error != "" {
+		return
+	}
+	for _, stage := range config.Stages {
+		if ctx.Err() != nil {
+			return
+		}
+		before := *page
+		_, span := startSpan(ctx, "stage", "page", page.StartPage, "stage", stage.Name())
+		err := stage.Process(ctx, page)
+		span.end(err)
+		if err != nil {
+			*page = before
+			logf(ctx, "  ⚠️  Page %d: stage %s failed, keeping the page as it was: %v\n", page.StartPage, stage.Name(), err)
+		}
+	}
+}
+
+// ExecStage runs a command for each page, so a stage can be written in any
+// language without building it into the engine: the page goes to the
+// command's stdin as JSON, as in the JSONL stream, and the page it prints
+// on stdout replaces it. Printing nothing keeps the page unchanged. A
+// nonzero exit fails the stage, with its stderr as the error.
+// The page numbers, token counts, and costs are kept from the input.
+type ExecStage struct {
+	Command string        // Run with the shell, with DESIGN_ANT_PAGE set
+	Timeout time.Duration // Limit for one page (0 = DefaultStageTimeout)
+}
+
+// NewExecStage returns a stage running command for each page
+func NewExecStage(command string) *ExecStage {
+	return &ExecStage{Command: command}
+}
+
+// Name returns the program the command runs, e.g. normalize-bom for
+// "./plugins/normalize-bom --site de"
+func (s *ExecStage) Name() string {
+	fields := strings.Fields(s.Command)
+	if len(fields) == 0 {
+		return "exec"
+	}
+	return filepath.Base(fields[0])
+}
+
+// Process runs the command on one page
+func (s *ExecStage) Process(ctx context.Context, page *ChunkAnalysis) error {
+	input, err := json.Marshal(page)
+	if err != nil {
+		return fmt.Errorf("error encoding page: %v", err)
+	}
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultStageTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", s.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", s.Command)
+	}
+	cmd.Env = append(os.Environ(), fmt.Sprintf("DESIGN_ANT_PAGE=%d", page.StartPage))
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("%v: %s", err, Truncate(message, 500))
+		}
+		return err
+	}
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return nil
+	}
+
+	var output ChunkAnalysis
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return fmt.Errorf("invalid page on stdout: %v", err)
+	}
+	output.ChunkNumber, output.StartPage, output.EndPage = page.ChunkNumber, page.StartPage, page.EndPage
+	output.InputTokens, output.OutputTokens = page.InputTokens, page.OutputTokens
+	output.InputCost, output.OutputCost, output.TotalCost = page.InputCost, page.OutputCost, page.TotalCost
+	*page = output
+	return nil
+}
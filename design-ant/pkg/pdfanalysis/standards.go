@@ -0,0 +1,134 @@
+package pdfanalysis
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// standardPattern recognizes the designations of one standards body. The
+// first group is the designation, the optional second group the edition.
+type standardPattern struct {
+	body    string
+	pattern *regexp.Regexp
+}
+
+// standardPatterns are tried in order; combined designations such as
+// "DIN EN ISO 4014" come first so their parts are not reported separately
+var standardPatterns = []standardPattern{
+	{"DIN", regexp.MustCompile(`\b(DIN\s+EN\s+ISO\s+\d{3,5}(?:-\d+)*)(?:\s*:\s*(\d{4}))?`)},
+	{"EN", regexp.MustCompile(`\b((?:BS\s+)?EN\s+ISO\s+\d{3,5}(?:-\d+)*)(?:\s*:\s*(\d{4}))?`)},
+	{"DIN", regexp.MustCompile(`\b(DIN\s+(?:EN\s+)?\d{3,5}(?:-\d+)*)(?:\s*:\s*(\d{4}))?`)},
+	{"ISO", regexp.MustCompile(`\b(ISO(?:/IEC)?\s*\d{3,5}(?:-\d+)*)(?:\s*:\s*(\d{4}))?`)},
+	{"IEC", regexp.MustCompile(`\b(IEC\s*\d{3,5}(?:-\d+)*)(?:\s*:\s*(\d{4}))?`)},
+	{"ASME", regexp.MustCompile(`\b((?:ASME|ANSI)(?:/ASME)?\s+(?:Y\d+\.\d+[A-Z]?|B\d+(?:\.\d+)+[A-Z]?))(?:\s*[-–]\s*(\d{4}))?`)},
+	{"MIL", regexp.MustCompile(`\b(MIL-(?:STD|SPEC|DTL|PRF|HDBK|[A-Z])-\d{2,6}[A-Z]?)(?:\s*\(?(?i:rev)\.?\s*([A-H])\)?\b)?`)},
+	{"ASTM", regexp.MustCompile(`\b(ASTM\s+[A-G]\d{1,4}(?:/[A-G]\d{1,4})?M?)(?:\s*[-–]\s*(\d{2,4}[a-z]?))?`)},
+	{"SAE", regexp.MustCompile(`\b((?:SAE\s+)?AMS\s*\d{4}[A-Z]?|SAE\s+J\d{3,4})\b`)},
+	{"AWS", regexp.MustCompile(`\b(AWS\s+[A-D]\d+\.\d+[A-Z]?)(?:\s*[:/]\s*(\d{4}))?`)},
+	{"JIS", regexp.MustCompile(`\b(JIS\s+[A-Z]\s?\d{4})(?:\s*:\s*(\d{4}))?`)},
+	{"BS", regexp.MustCompile(`\b(BS\s+\d{3,5}(?:-\d+)*)(?:\s*:\s*(\d{4}))?`)},
+}
+
+// extractStandards finds every cited standard in the page analyses, notes,
+// and BOM rows and returns them sorted by body and designation with the pages
+// that cite them. Designations are matched case-sensitively, since lower-case
+// "iso" is rarely a citation.
+func extractStandards(chunks []ChunkAnalysis) []StandardReference {
+	byKey := make(map[string]*StandardReference)
+	var refs []*StandardReference
+	for _, chunk := range chunks {
+		texts := append([]string{chunk.Analysis}, chunk.Notes...)
+		for _, row := range chunk.BOMItems {
+			texts = append(texts, row.Description, row.Material, row.Finish)
+		}
+		if m := chunk.Metadata; m != nil {
+			texts = append(texts, m.Material)
+		}
+		for _, text := range texts {
+			for _, found := range findStandards(text) {
+				key := strings.ToUpper(found.Designation)
+				ref, ok := byKey[key]
+				if !ok {
+					ref = &StandardReference{Body: found.Body, Designation: found.Designation}
+					byKey[key] = ref
+					refs = append(refs, ref)
+				}
+				ref.Pages = appendPage(ref.Pages, chunk.StartPage)
+				if len(found.Editions) > 0 && !containsString(ref.Editions, found.Editions[0]) {
+					ref.Editions = append(ref.Editions, found.Editions[0])
+				}
+			}
+		}
+	}
+
+	standards := make([]StandardReference, len(refs))
+	for i, ref := range refs {
+		sort.Ints(ref.Pages)
+		sort.Strings(ref.Editions)
+		standards[i] = *ref
+	}
+	sort.SliceStable(standards, func(i, j int) bool {
+		if standards[i].Body != standards[j].Body {
+			return standards[i].Body < standards[j].Body
+		}
+		return naturalLess(standards[i].Designation, standards[j].Designation)
+	})
+	return standards
+}
+
+// findStandards returns the citations in one text. Text matched by an
+// earlier pattern is not matched again.
+func findStandards(text string) []StandardReference {
+	var found []StandardReference
+	taken := make([]bool, len(text))
+	for _, p := range standardPatterns {
+		for _, m := range p.pattern.FindAllStringSubmatchIndex(text, -1) {
+			if taken[m[0]] || taken[m[1]-1] {
+				continue
+			}
+			for i := m[0]; i < m[1]; i++ {
+				taken[i] = true
+			}
+			ref := StandardReference{
+				Body:        p.body,
+				Designation: strings.Join(strings.Fields(text[m[2]:m[3]]), " "),
+			}
+			if len(m) > 5 && m[4] >= 0 {
+				ref.Editions = []string{text[m[4]:m[5]]}
+			}
+			found = append(found, ref)
+		}
+	}
+	return found
+}
+
+// digitRun finds the numbers compared by naturalLess
+var digitRun = regexp.MustCompile(`\d+`)
+
+// naturalLess orders designations so "ISO 286" comes before "ISO 1101"
+func naturalLess(a, b string) bool {
+	pad := func(s string) string {
+		return digitRun.ReplaceAllStringFunc(s, func(d string) string {
+			return strings.Repeat("0", max(0, 8-len(d))) + d
+		})
+	}
+	return pad(a) < pad(b)
+}
+
+// markdownStandards renders the cited standards as a markdown table; link formats a page reference
+func markdownStandards(standards []StandardReference, link func(page int) string) string {
+	var b strings.Builder
+	b.WriteString("| Body | Standard | Editions cited | Pages |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+	for _, s := range standards {
+		pages := make([]string, len(s.Pages))
+		for i, p := range s.Pages {
+			pages[i] = link(p)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", s.Body, EscapeInline(s.Designation),
+			EscapeInline(strings.Join(s.Editions, ", ")), strings.Join(pages, ", "))
+	}
+	return b.String()
+}
@@ -0,0 +1,166 @@
+package pdfanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ResultStore keeps the results of runs by name, {pdf-name}_analysis.json
+// for the command, together with the stream of pages of a run in progress
+type ResultStore interface {
+	// Save writes the result of a run, replacing an earlier one of the same name
+	Save(ctx context.Context, name string, result FullAnalysisResult) error
+	// Load reads a result. For a run that never saved one, e.g. because it
+	// crashed, the pages it appended are returned as an interrupted result.
+	Load(ctx context.Context, name string) (*FullAnalysisResult, error)
+	// List returns the names of the saved results, sorted
+	List(ctx context.Context) ([]string, error)
+	// AppendPage adds a finished page to the stream of a result. The first
+	// page a store appends for a name replaces the stream of an earlier run.
+	AppendPage(ctx context.Context, name string, page ChunkAnalysis) error
+}
+
+// StreamName returns the name of the page stream of a result,
+// {pdf-name}_analysis.jsonl for {pdf-name}_analysis.json(.gz)
+func StreamName(name string) string {
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".zst")
+	return strings.TrimSuffix(name, ".json") + ".jsonl"
+}
+
+// IsResultName reports whether a file name is that of a result written by
+// the command, {pdf-name}_analysis.json, .json.gz, or .json.zst
+func IsResultName(name string) bool {
+	return !strings.HasSuffix(name, ".jsonl") && strings.HasSuffix(StreamName(name), "_analysis.jsonl")
+}
+
+// ResultFromStream builds the interrupted result of a run from the pages it
+// streamed. The document is not part of the stream, so PDFPath is empty; a
+// page streamed twice keeps its last analysis.
+func ResultFromStream(pages []ChunkAnalysis) *FullAnalysisResult {
+	byPage := make(map[int]ChunkAnalysis)
+	for _, page := range pages {
+		byPage[page.StartPage] = page
+	}
+	chunks := make([]ChunkAnalysis, 0, len(byPage))
+	for _, page := range byPage {
+		chunks = append(chunks, page)
+	}
+	sortChunks(chunks)
+	result := &FullAnalysisResult{SchemaVersion: CurrentSchemaVersion, Chunks: chunks, Interrupted: true}
+	RecomputeTotals(result)
+	return result
+}
+
+// FileStore keeps results as files in a directory, the JSON result
+// compressed by its name's extension and the stream as JSON lines next to
+// it. Names that are absolute paths are used as they are.
+type FileStore struct {
+	Dir string
+
+	mu      sync.Mutex
+	streams map[string]*os.File // Streams started by this store
+}
+
+// NewFileStore returns a store of the result files in dir
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{Dir: dir, streams: make(map[string]*os.File)}
+}
+
+// path returns the file of a name
+func (s *FileStore) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(s.Dir, name)
+}
+
+// Save writes the result to its file through a temporary file
+func (s *FileStore) Save(ctx context.Context, name string, result FullAnalysisResult) error {
+	path := s.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return SaveJSONOutput(path, result)
+}
+
+// Load reads the result file, or its stream when there is none. A name
+// ending in .jsonl is read as a stream.
+func (s *FileStore) Load(ctx context.Context, name string) (*FullAnalysisResult, error) {
+	path := s.path(name)
+	if !strings.HasSuffix(path, ".jsonl") {
+		result, err := LoadResult(path)
+		if !errors.Is(err, os.ErrNotExist) {
+			return result, err
+		}
+		path = StreamName(path)
+	}
+	pages, err := loadChunkStream(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrResultNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ResultFromStream(pages), nil
+}
+
+// List returns the result files in the directory
+func (s *FileStore) List(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && IsResultName(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// AppendPage writes the page as one line of the stream file and flushes it
+// to disk, so a crash keeps every finished page
+func (s *FileStore) AppendPage(ctx context.Context, name string, page ChunkAnalysis) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := StreamName(s.path(name))
+	file := s.streams[path]
+	if file == nil {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		var err error
+		if file, err = os.Create(path); err != nil {
+			return err
+		}
+		s.streams[path] = file
+	}
+	if err := json.NewEncoder(file).Encode(page); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// Close closes the streams started by the store
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for path, file := range s.streams {
+		errs = append(errs, file.Close())
+		delete(s.streams, path)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,320 @@
+package pdfanalysis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// extractionToolName is the tool the model calls with the page's structured data
+const extractionToolName = "record_drawing_data"
+
+// maxExtractionRepairs is how often an invalid tool call is sent back for correction
+const maxExtractionRepairs = 2
+
+// dimensionTypes are the allowed values of Dimension.Type
+var dimensionTypes = []string{"linear", "diameter", "radius", "angle", "depth", "thread"}
+
+// extractionSchema is the structured data schema for Anthropic tool use
+const extractionSchema = `{
+  "type": "object",
+  "properties": {
+    "metadata": {
+      "type": "object",
+      "properties": {
+        "drawing_number": {"type": "string"},
+        "title": {"type": "string"},
+        "revision": {"type": "string"},
+        "drawn_by": {"type": "string"},
+        "checked_by": {"type": "string"},
+        "approved_by": {"type": "string"},
+        "date": {"type": "string"},
+        "scale": {"type": "string"},
+        "projection": {"type": "string"},
+        "material": {"type": "string"}
+      }
+    },
+    "bom_items": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "item_number": {"type": "string"},
+          "part_number": {"type": "string"},
+          "description": {"type": "string"},
+          "quantity": {"type": "number", "minimum": 0},
+          "material": {"type": "string"},
+          "finish": {"type": "string"}
+        },
+        "required": ["quantity"]
+      }
+    },
+    "dimensions": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "feature": {"type": "string"},
+          "type": {"type": "string", "enum": ["linear", "diameter", "radius", "angle", "depth", "thread"]},
+          "value": {"type": "string"},
+          "unit": {"type": "string"},
+          "tolerance": {"type": "string"}
+        },
+        "required": ["value"]
+      }
+    },
+    "notes": {"type": "array", "items": {"type": "string"}}
+  },
+  "required": ["metadata", "bom_items", "dimensions", "notes"]
+}`
+
+// extractionTool defines the extraction tool for the configured options
+func extractionTool(config *Config) map[string]interface{} {
+	pack := config.PromptPack.structuredSchema()
+	records := strings.ReplaceAll(strings.ReplaceAll(pack.records, "\n", " "), "EVERY", "every")
+	description := "Record the structured data of the drawing page: " + records + "."
+	schema := json.RawMessage(extractionSchema)
+	if pack != mechanicalSchema || config.Welds {
+		var extended map[string]interface{}
+		if err := json.Unmarshal(schema, &extended); err != nil {
+			panic(fmt.Sprintf("invalid extraction schema: %v", err))
+		}
+		if pack != mechanicalSchema {
+			addPackSchema(extended, pack)
+		}
+		if config.Welds {
+			addWeldSchema(extended)
+			description = strings.TrimSuffix(description, ".") + ", every weld symbol, and every surface finish callout."
+		}
+		schema, _ = json.Marshal(extended)
+	}
+	return map[string]interface{}{
+		"name":         extractionToolName,
+		"description":  description + " Use empty strings and arrays for information that is not on the page; never invent values.",
+		"input_schema": schema,
+	}
+}
+
+// addPackSchema replaces the sections the extraction tool schema requires
+// with those of a prompt pack and adds the pack's own sections
+func addPackSchema(schema map[string]interface{}, pack *packSchema) {
+	var properties map[string]interface{}
+	if err := json.Unmarshal([]byte(pack.properties), &properties); err != nil {
+		panic(fmt.Sprintf("invalid prompt pack schema: %v", err))
+	}
+	for name, property := range properties {
+		schema["properties"].(map[string]interface{})[name] = property
+	}
+	required := make([]interface{}, len(pack.required))
+	for i, name := range pack.required {
+		required[i] = name
+	}
+	schema["required"] = required
+}
+
+// toolExtraction is the outcome of a structured analysis: the markdown text,
+// the validated tool input, and the tokens of all turns including repairs
+type toolExtraction struct {
+	Analysis     string
+	Data         StructuredData
+	InputTokens  int
+	OutputTokens int
+	Repairs      int    // Tool calls sent back because they failed validation
+	Continued    int    // Continuation requests after the first turn hit max_tokens
+	Truncated    bool   // The first turn was still cut off after maxContinuations
+	DataError    string // Why no valid structured data was obtained; the analysis is still usable
+}
+
+// structuredFields returns the request fields of a -structured page request:
+// the extraction tool, left to the model's choice, and the system prompt
+func structuredFields(config *Config) map[string]interface{} {
+	extra := map[string]interface{}{
+		"tools":       []interface{}{extractionTool(config)},
+		"tool_choice": map[string]string{"type": "auto"},
+	}
+	if system := systemPrompt(config); system != "" {
+		extra["system"] = system
+	}
+	return extra
+}
+
+// analyzeStructured analyzes a page with the extraction tool available. The
+// model writes the markdown analysis and calls the tool; a call that fails
+// schema validation (or a missing call) is answered with the errors and the
+// tool is forced on the next turn, up to maxExtractionRepairs times. A first
+// turn that hits max_tokens is continued before its tool call is looked for.
+// API errors are returned with the tokens spent so far.
+func analyzeStructured(ctx context.Context, config *Config, route PageRoute, chunkPath string, pageNumber int, prompt string) (*toolExtraction, error) {
+	content, err := pageContent(config, route, chunkPath, pageNumber, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []map[string]interface{}{{"role": "user", "content": content}}
+	extra := structuredFields(config)
+	extraction := &toolExtraction{}
+	for turn := 0; ; turn++ {
+		resp, err := sendMessages(withCaptureSuffix(ctx, "repair", turn), config.APIKey, config.ModelName, messages, extra)
+		if err == nil && turn == 0 {
+			resp, extraction.Continued, err = continueAnswer(ctx, config.APIKey, config.ModelName, messages, extra, resp)
+		}
+		if resp != nil {
+			extraction.InputTokens += resp.Usage.InputTokens
+			extraction.OutputTokens += resp.Usage.OutputTokens
+		}
+		if err != nil {
+			return extraction, err
+		}
+		if turn == 0 {
+			extraction.Analysis = strings.TrimSpace(resp.text())
+			extraction.Truncated = resp.StopReason == "max_tokens"
+		}
+
+		// Find the tool call and validate it
+		var call *contentBlock
+		for i := range resp.Content {
+			if resp.Content[i].Type == "tool_use" && resp.Content[i].Name == extractionToolName {
+				call = &resp.Content[i]
+				break
+			}
+		}
+		var problem string
+		switch {
+		case call == nil && resp.StopReason == "max_tokens":
+			extraction.DataError = "response truncated before the tool call"
+			return extraction, nil
+		case call == nil:
+			problem = fmt.Sprintf("You did not call %s. Call it now with the structured data of this page.", extractionToolName)
+		default:
+			data, errs := validateExtraction(config, call.Input)
+			if len(errs) == 0 {
+				extraction.Data = data
+				return extraction, nil
+			}
+			problem = "The tool input failed schema validation:\n- " + strings.Join(errs, "\n- ") +
+				"\nCall " + extractionToolName + " again with the corrected data."
+		}
+		if turn == maxExtractionRepairs {
+			extraction.DataError = problem
+			return extraction, nil
+		}
+
+		// Send the problem back and force the tool on the next turn
+		extraction.Repairs++
+		messages = append(messages, map[string]interface{}{"role": "assistant", "content": resp.Content})
+		// Every tool call needs a result; the problem goes with the one that failed
+		var reply []interface{}
+		for _, block := range resp.Content {
+			if block.Type == "tool_use" {
+				result := "Ignored."
+				if call != nil && block.ID == call.ID {
+					result = problem
+				}
+				reply = append(reply, map[string]interface{}{"type": "tool_result", "tool_use_id": block.ID, "is_error": true, "content": result})
+			}
+		}
+		if call == nil {
+			reply = append(reply, map[string]interface{}{"type": "text", "text": problem})
+		}
+		messages = append(messages, map[string]interface{}{"role": "user", "content": reply})
+		extra["tool_choice"] = map[string]string{"type": "tool", "name": extractionToolName}
+	}
+}
+
+// validateExtraction decodes the tool input and checks it against the schema:
+// required sections, no unknown fields, correct types, non-negative
+// quantities, known dimension types, and a value on every dimension; with
+// -welds also the weld and surface finish rows
+func validateExtraction(config *Config, input json.RawMessage) (StructuredData, []string) {
+	var data StructuredData
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(input, &sections); err != nil {
+		return data, []string{fmt.Sprintf("input is not a JSON object: %v", err)}
+	}
+	var errs []string
+	pack := config.PromptPack.structuredSchema()
+	required := append([]string(nil), pack.required...)
+	if config.Welds {
+		required = append(required, "welds", "surface_finishes")
+	}
+	for _, key := range required {
+		if _, ok := sections[key]; !ok {
+			errs = append(errs, fmt.Sprintf("%s: required", key))
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&data); err != nil {
+		return data, append(errs, err.Error())
+	}
+	for i, item := range data.BOMItems {
+		if item.Quantity < 0 {
+			errs = append(errs, fmt.Sprintf("bom_items[%d].quantity: must not be negative", i))
+		}
+		if item.ItemNumber == "" && item.PartNumber == "" && item.Description == "" {
+			errs = append(errs, fmt.Sprintf("bom_items[%d]: needs an item_number, part_number, or description", i))
+		}
+	}
+	for i, d := range data.Dimensions {
+		if strings.TrimSpace(d.Value) == "" {
+			errs = append(errs, fmt.Sprintf("dimensions[%d].value: required", i))
+		}
+		if d.Type != "" && !containsString(dimensionTypes, d.Type) {
+			errs = append(errs, fmt.Sprintf("dimensions[%d].type: %q is not one of %s", i, d.Type, strings.Join(dimensionTypes, ", ")))
+		}
+	}
+	if pack.validate != nil {
+		errs = append(errs, pack.validate(data)...)
+	}
+	if config.Welds {
+		errs = append(errs, validateWelds(data)...)
+	}
+	if len(errs) > 0 {
+		return data, errs
+	}
+
+	if data.Metadata != nil && *data.Metadata == (DrawingMetadata{}) {
+		data.Metadata = nil
+	}
+	data.Notes = compactStrings(data.Notes)
+	return data, nil
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitJSONBlock returns the contents of the last ```json block in text and
+// the remaining text with the block removed
+func SplitJSONBlock(text string) (string, string, error) {
+	start := strings.LastIndex(text, "```json")
+	if start < 0 {
+		return "", text, fmt.Errorf("no json block in response")
+	}
+	body := text[start+len("```json"):]
+	end := strings.Index(body, "```")
+	if end < 0 {
+		return "", text, fmt.Errorf("unterminated json block (response may be truncated)")
+	}
+	return body[:end], strings.TrimSpace(text[:start] + body[end+len("```"):]), nil
+}
+
+// compactStrings drops empty entries the model emits for placeholder arrays
+func compactStrings(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,167 @@
+package pdfanalysis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// executiveSummaryBudgetChars caps the page text sent to the executive
+// summary pass; longer page analyses are shortened to share the budget
+const executiveSummaryBudgetChars = 120_000
+
+// generateExecutiveSummary runs the final pass with config.SummaryModel. It
+// reads the -consolidate summary when there is one, otherwise the page
+// analyses, plus the counts derived from the structured data so the totals
+// are exact. On failure the summary carries the error and the cost of the call.
+func generateExecutiveSummary(ctx context.Context, config *Config, result FullAnalysisResult) (*ExecutiveSummary, error) {
+	start := time.Now()
+	summary := &ExecutiveSummary{Model: config.SummaryModel}
+	fail := func(err error) (*ExecutiveSummary, error) {
+		summary.Error = err.Error()
+		summary.ProcessingTime = time.Since(start).String()
+		summary.Timestamp = time.Now()
+		return summary, err
+	}
+
+	var source string
+	if c := result.Consolidated; c != nil && c.Error == "" && strings.TrimSpace(c.Analysis) != "" {
+		source = fmt.Sprintf("<document_summary>\n%s\n</document_summary>", strings.TrimSpace(c.Analysis))
+	} else {
+		var sections []consolidationSection
+		for _, chunk := range result.Chunks {
+			if chunk.Error == "" && strings.TrimSpace(chunk.Analysis) != "" {
+				sections = append(sections, consolidationSection{startPage: chunk.StartPage, endPage: chunk.EndPage, text: chunk.Analysis})
+			}
+		}
+		if len(sections) == 0 {
+			return fail(fmt.Errorf("no successful page analyses to summarize"))
+		}
+		source = formatSections(shortenSections(sections, executiveSummaryBudgetChars))
+	}
+
+	prompt := executiveSummaryPrompt(documentFacts(result), source)
+	if style := styleInstructions(config); style != "" {
+		prompt += "\n\n" + style
+	}
+	if config.OutputLang != "" {
+		prompt += "\n\n" + OutputLanguageInstructions(config.OutputLang)
+	}
+	summaryConfig := *config
+	summaryConfig.ModelName = config.SummaryModel
+	text, inputTokens, outputTokens, err := SendWithRetry(ctx, &summaryConfig, prompt)
+
+	pricing := GetPricing(config.SummaryModel)
+	summary.InputTokens, summary.OutputTokens = inputTokens, outputTokens
+	summary.InputCost = float64(inputTokens) / 1_000_000 * pricing.InputPricePerMTokens
+	summary.OutputCost = float64(outputTokens) / 1_000_000 * pricing.OutputPricePerMTokens
+	summary.TotalCost = summary.InputCost + summary.OutputCost
+	if err != nil {
+		return fail(fmt.Errorf("error generating executive summary: %v", err))
+	}
+	if strings.TrimSpace(text) == "" {
+		return fail(fmt.Errorf("empty executive summary"))
+	}
+
+	summary.Summary = text
+	summary.ProcessingTime = time.Since(start).String()
+	summary.Timestamp = time.Now()
+	return summary, nil
+}
+
+// shortenSections truncates each section to an equal share of the budget
+// when all of them together are too long. Short sections give their unused
+// share to the others.
+func shortenSections(sections []consolidationSection, budget int) []consolidationSection {
+	if sectionsLength(sections) <= budget {
+		return sections
+	}
+	shortened := make([]consolidationSection, len(sections))
+	copy(shortened, sections)
+	remaining, left := budget, len(sections)
+	for _, s := range sections {
+		if share := remaining / left; len(s.text) <= share {
+			remaining -= len(s.text)
+			left--
+		}
+	}
+	share := max(remaining/max(left, 1), 1)
+	for i, s := range shortened {
+		if len(s.text) > share {
+			shortened[i].text = Truncate(s.text, share)
+		}
+	}
+	return shortened
+}
+
+// documentFacts lists the counts the model must not estimate itself
+func documentFacts(result FullAnalysisResult) string {
+	var b strings.Builder
+	failed := 0
+	for _, chunk := range result.Chunks {
+		if chunk.Error != "" {
+			failed++
+		}
+	}
+	fmt.Fprintf(&b, "- Pages: %d (%d failed to analyze)\n", result.TotalPages, failed)
+	if len(result.MasterBOM) > 0 {
+		total := 0.0
+		for _, item := range result.MasterBOM {
+			total += item.TotalQuantity
+		}
+		fmt.Fprintf(&b, "- Distinct parts in the BOM: %d\n", len(result.MasterBOM))
+		fmt.Fprintf(&b, "- Total part quantity: %g\n", total)
+	}
+	if len(result.Discrepancies) > 0 {
+		fmt.Fprintf(&b, "- Cross-page discrepancies:\n")
+		for _, d := range result.Discrepancies {
+			fmt.Fprintf(&b, "  - %s\n", d.Message)
+		}
+	}
+	if len(result.TitleBlockExceptions) > 0 {
+		fmt.Fprintf(&b, "- Pages with incomplete title blocks: %d\n", len(result.TitleBlockExceptions))
+	}
+	if len(result.Standards) > 0 {
+		designations := make([]string, len(result.Standards))
+		for i, s := range result.Standards {
+			designations[i] = s.Designation
+		}
+		fmt.Fprintf(&b, "- Standards cited: %s\n", strings.Join(designations, ", "))
+	}
+	if cost := result.AssemblyCost; cost != nil {
+		fmt.Fprintf(&b, "- Estimated material cost: %s (%d part(s) unpriced)\n", FormatMoney(cost.Total, cost.Currency), cost.Unpriced)
+	}
+	return b.String()
+}
+
+// executiveSummaryPrompt asks for the one-page overview
+func executiveSummaryPrompt(facts, source string) string {
+	return fmt.Sprintf(`The following is the analysis of an engineering drawing package.
+Write a one-page executive summary for a project manager or buyer who will not read the drawings.
+
+OUTPUT FORMAT - START DIRECTLY (NO INTRODUCTORY PHRASES):
+# Executive Summary
+
+1. **WHAT IT IS**: Two or three sentences on the product or assembly, its purpose, and drawing numbers
+2. **KEY COMPONENTS**: The five to ten most important assemblies or parts, one line each
+3. **PARTS**: Distinct parts and total quantity, taken from the facts below when given
+4. **CRITICAL NOTES**: Requirements that drive cost, lead time, or quality (materials, finishes, tolerances, tests, standards)
+5. **ATTENTION NEEDED**: Discrepancies, missing information, and failed pages
+
+RULES:
+- At most 400 words
+- Use the facts below exactly as given; do not recount parts from the analyses
+- Use exact part numbers and codes; reference pages as "p. N"
+
+<facts>
+%s</facts>
+
+%s`, facts, source)
+}
+
+// SaveExecutiveSummary writes the summary as a standalone markdown file
+func SaveExecutiveSummary(filename string, summary *ExecutiveSummary) error {
+	return os.WriteFile(filename, []byte(strings.TrimSpace(summary.Summary)+"\n"), 0644)
+}
@@ -0,0 +1,55 @@
+package pdfanalysis
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are available to user-provided output and prompt templates
+var templateFuncs = template.FuncMap{
+	"upper":     strings.ToUpper,
+	"lower":     strings.ToLower,
+	"trim":      strings.TrimSpace,
+	"join":      strings.Join,
+	"replace":   strings.ReplaceAll,
+	"pageRange": PageRangeLabel,
+	"money":     func(v float64) string { return fmt.Sprintf("$%.6f", v) },
+	"add":       func(a, b int) int { return a + b },
+	"costs":     costBreakdown,
+	"indent": func(spaces int, text string) string {
+		pad := strings.Repeat(" ", spaces)
+		return pad + strings.ReplaceAll(text, "\n", "\n"+pad)
+	},
+}
+
+// RenderTemplate applies a Go text/template file to the result and writes the output
+func RenderTemplate(templatePath, outputPath string, result FullAnalysisResult) error {
+	tmpl, err := template.New(filepath.Base(templatePath)).Funcs(templateFuncs).ParseFiles(templatePath)
+	if err != nil {
+		return fmt.Errorf("error parsing template: %v", err)
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.Execute(file, result); err != nil {
+		file.Close()
+		return fmt.Errorf("error executing template: %v", err)
+	}
+	return file.Close()
+}
+
+// TemplateOutputFilename derives the report name from the template name:
+// report.md.tmpl produces {pdf-name}_report.md, report.tmpl produces .txt
+func TemplateOutputFilename(pdfPath, templatePath string) string {
+	ext := filepath.Ext(strings.TrimSuffix(filepath.Base(templatePath), ".tmpl"))
+	if ext == "" {
+		ext = ".txt"
+	}
+	base := filepath.Base(pdfPath)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + "_report" + ext
+}
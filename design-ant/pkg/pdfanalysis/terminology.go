@@ -0,0 +1,58 @@
+package pdfanalysis
+
+import "fmt"
+
+// Terminology styles accepted by -terminology
+const (
+	TerminologyASME = "asme" // American drafting vocabulary (ASME Y14, ANSI)
+	TerminologyISO  = "iso"  // ISO drafting vocabulary (ISO 128, ISO 1101, ISO 7200)
+)
+
+// Unit orders accepted by -unit-order
+const (
+	UnitOrderMetricFirst   = "metric-first"
+	UnitOrderImperialFirst = "imperial-first"
+)
+
+// keepDrawnText is the rule shared by the terminology styles
+const keepDrawnText = "Quote notes, callouts, table cells, and title block fields exactly as drawn; only your own wording follows this vocabulary."
+
+// terminologyInstructions are the prompt sections of the terminology styles
+var terminologyInstructions = map[string]string{
+	TerminologyASME: `TERMINOLOGY (ASME):
+Use American drafting vocabulary (ASME Y14.5, Y14.100) in your own wording: bill of materials (BOM), feature control frame, basic dimension, MMC/LMC, surface finish, revision block, third-angle or first-angle projection.
+` + keepDrawnText,
+	TerminologyISO: `TERMINOLOGY (ISO):
+Use ISO drafting vocabulary (ISO 128, ISO 1101, ISO 7200, ISO 21920) in your own wording: parts list (item list) instead of bill of materials, tolerance frame instead of feature control frame, theoretically exact dimension (TED) instead of basic dimension, MMR/LMR instead of MMC/LMC, surface texture instead of surface finish, revision table instead of revision block, projection method instead of projection type.
+` + keepDrawnText,
+}
+
+// TerminologyInstructions is added to the prompts for -terminology
+func TerminologyInstructions(style string) string {
+	return terminologyInstructions[style]
+}
+
+// UnitOrderInstructions is added to the prompts for -unit-order. Values are
+// never converted by the model; -units does that for the structured data.
+func UnitOrderInstructions(order string) string {
+	first, second, example := "millimeter", "inch", "25.4 mm [1.000 in]"
+	if order == UnitOrderImperialFirst {
+		first, second, example = "inch", "millimeter", "1.000 in [25.4 mm]"
+	}
+	return fmt.Sprintf(`UNITS:
+Put %s values first. Where the drawing gives a value in both systems (dual dimensioning), write the %s value first and the %s value in brackets, e.g. %s.
+Write values given in one system only in that unit, with the unit; never convert them yourself.`, first, first, second, example)
+}
+
+// styleInstructions returns the -terminology and -unit-order sections for
+// the prompts of the document passes (consolidation, executive summary)
+func styleInstructions(config *Config) string {
+	var parts []string
+	if config.Terminology != "" {
+		parts = append(parts, TerminologyInstructions(config.Terminology))
+	}
+	if config.UnitOrder != "" {
+		parts = append(parts, UnitOrderInstructions(config.UnitOrder))
+	}
+	return joinPrompt(parts...)
+}
@@ -0,0 +1,70 @@
+package pdfanalysis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// titleBlockAuditFields are the fields every title block must have, in report order
+var titleBlockAuditFields = []struct {
+	label string
+	get   func(*DrawingMetadata) string
+}{
+	{"Revision", func(m *DrawingMetadata) string { return m.Revision }},
+	{"Drawn By", func(m *DrawingMetadata) string { return m.DrawnBy }},
+	{"Checked By", func(m *DrawingMetadata) string { return m.CheckedBy }},
+	{"Approved By", func(m *DrawingMetadata) string { return m.ApprovedBy }},
+	{"Date", func(m *DrawingMetadata) string { return m.Date }},
+}
+
+// placeholderValues are title block entries that mean the field was left blank
+var placeholderValues = map[string]bool{
+	"-": true, "--": true, "?": true, "n/a": true, "na": true, "none": true, "tbd": true, "xx": true, "xxx": true,
+}
+
+// auditTitleBlocks lists the analyzed pages with missing or placeholder
+// title block fields. Runs without any extracted title block (no -structured)
+// return nil, since there is nothing to audit.
+func auditTitleBlocks(chunks []ChunkAnalysis) []TitleBlockException {
+	audited := false
+	for _, chunk := range chunks {
+		audited = audited || chunk.Metadata != nil
+	}
+	if !audited {
+		return nil
+	}
+
+	var exceptions []TitleBlockException
+	for _, chunk := range chunks {
+		if chunk.Error != "" {
+			continue
+		}
+		exception := TitleBlockException{Page: chunk.StartPage}
+		if m := chunk.Metadata; m == nil {
+			exception.Missing = []string{"title block"}
+		} else {
+			exception.DrawingNumber = m.DrawingNumber
+			for _, field := range titleBlockAuditFields {
+				value := strings.TrimSpace(field.get(m))
+				if value == "" || placeholderValues[strings.ToLower(value)] {
+					exception.Missing = append(exception.Missing, field.label)
+				}
+			}
+		}
+		if len(exception.Missing) > 0 {
+			exceptions = append(exceptions, exception)
+		}
+	}
+	return exceptions
+}
+
+// markdownTitleBlockExceptions renders the exception report as a markdown table; link formats a page reference
+func markdownTitleBlockExceptions(exceptions []TitleBlockException, link func(page int) string) string {
+	var b strings.Builder
+	b.WriteString("| Page | Drawing | Missing |\n")
+	b.WriteString("| --- | --- | --- |\n")
+	for _, e := range exceptions {
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", link(e.Page), EscapeInline(e.DrawingNumber), strings.Join(e.Missing, ", "))
+	}
+	return b.String()
+}
@@ -0,0 +1,299 @@
+package pdfanalysis
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tracing follows the OpenTelemetry conventions without the SDK: spans of
+// the pipeline stages are exported as OTLP/JSON over HTTP to the collector
+// named by the standard OTEL_EXPORTER_OTLP_ENDPOINT variables, and API
+// requests carry a W3C traceparent header. Without an endpoint, spans are
+// not recorded and every span call is a no-op.
+
+// Span kinds of the OTLP data model
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+)
+
+// span is one timed operation of a trace
+type span struct {
+	traceID [16]byte
+	spanID  [8]byte
+	parent  [8]byte // Zero for the root span
+	name    string
+	kind    int
+	start   time.Time
+
+	mu    sync.Mutex
+	attrs map[string]interface{}
+	err   string
+}
+
+type spanKey struct{}
+
+// spanFrom returns the span of a context, or nil
+func spanFrom(ctx context.Context) *span {
+	s, _ := ctx.Value(spanKey{}).(*span)
+	return s
+}
+
+// startSpan starts a span as a child of the span in ctx, or a new trace.
+// It returns ctx unchanged and a nil span when tracing is off.
+func startSpan(ctx context.Context, name string, attrs ...interface{}) (context.Context, *span) {
+	return startSpanKind(ctx, name, spanKindInternal, attrs...)
+}
+
+// startSpanKind starts a span of the given kind; attrs are key, value pairs
+func startSpanKind(ctx context.Context, name string, kind int, attrs ...interface{}) (context.Context, *span) {
+	if traceExporter == nil {
+		return ctx, nil
+	}
+	s := &span{name: name, kind: kind, start: time.Now(), attrs: make(map[string]interface{})}
+	if parent := spanFrom(ctx); parent != nil {
+		s.traceID = parent.traceID
+		s.parent = parent.spanID
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	rand.Read(s.spanID[:])
+	for i := 0; i+1 < len(attrs); i += 2 {
+		s.attrs[fmt.Sprint(attrs[i])] = attrs[i+1]
+	}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// set records an attribute on the span
+func (s *span) set(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs[key] = value
+	s.mu.Unlock()
+}
+
+// end finishes the span, marking it failed when err is not nil, and queues
+// it for export
+func (s *span) end(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if err != nil {
+		s.err = err.Error()
+	}
+	s.mu.Unlock()
+	traceExporter.add(s, time.Now())
+}
+
+// traceparent returns the W3C trace context header of the span
+func (s *span) traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:]))
+}
+
+// otlpExporter batches finished spans and posts them to an OTLP/HTTP
+// collector every few seconds
+type otlpExporter struct {
+	url     string
+	headers map[string]string
+	service string
+
+	mu    sync.Mutex
+	spans []map[string]interface{}
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// traceExporter is the exporter of the run, nil when tracing is off
+var traceExporter *otlpExporter
+
+// traceFlushInterval is how often finished spans are posted
+const traceFlushInterval = 5 * time.Second
+
+// InitTracing turns tracing on when an OTLP endpoint is configured. The
+// returned function posts the remaining spans and must be called on exit.
+func InitTracing() func() {
+	url := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if url == "" {
+		base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if base == "" || os.Getenv("OTEL_TRACES_EXPORTER") == "none" {
+			return func() {}
+		}
+		url = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+	service := os.Getenv("OTEL_SERVICE_NAME")
+	if service == "" {
+		service = "design-ant"
+	}
+	e := &otlpExporter{url: url, headers: parseOTLPHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")), service: service, done: make(chan struct{})}
+	traceExporter = e
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(traceFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				e.flush()
+			case <-e.done:
+				e.flush()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(e.done)
+		e.wg.Wait()
+	}
+}
+
+// parseOTLPHeaders parses OTEL_EXPORTER_OTLP_HEADERS, e.g. "api-key=abc,tenant=x"
+func parseOTLPHeaders(s string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if key, value, ok := strings.Cut(pair, "="); ok {
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return headers
+}
+
+// add converts a finished span to its OTLP/JSON form and queues it
+func (e *otlpExporter) add(s *span, end time.Time) {
+	s.mu.Lock()
+	var attrs []map[string]interface{}
+	for key, value := range s.attrs {
+		attrs = append(attrs, otlpAttribute(key, value))
+	}
+	record := map[string]interface{}{
+		"traceId":           hex.EncodeToString(s.traceID[:]),
+		"spanId":            hex.EncodeToString(s.spanID[:]),
+		"name":              s.name,
+		"kind":              s.kind,
+		"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(end.UnixNano(), 10),
+		"attributes":        attrs,
+	}
+	if s.parent != [8]byte{} {
+		record["parentSpanId"] = hex.EncodeToString(s.parent[:])
+	}
+	if s.err != "" {
+		record["status"] = map[string]interface{}{"code": 2, "message": s.err} // STATUS_CODE_ERROR
+	}
+	s.mu.Unlock()
+
+	e.mu.Lock()
+	e.spans = append(e.spans, record)
+	e.mu.Unlock()
+}
+
+// otlpAttribute encodes one attribute as an OTLP AnyValue
+func otlpAttribute(key string, value interface{}) map[string]interface{} {
+	var v map[string]interface{}
+	switch value := value.(type) {
+	case int:
+		v = map[string]interface{}{"intValue": strconv.Itoa(value)}
+	case int64:
+		v = map[string]interface{}{"intValue": strconv.FormatInt(value, 10)}
+	case float64:
+		v = map[string]interface{}{"doubleValue": value}
+	case bool:
+		v = map[string]interface{}{"boolValue": value}
+	default:
+		v = map[string]interface{}{"stringValue": fmt.Sprint(value)}
+	}
+	return map[string]interface{}{"key": key, "value": v}
+}
+
+// flush posts the queued spans. Export failures are logged and the spans
+// dropped, so a missing collector never affects the run.
+func (e *otlpExporter) flush() {
+	e.mu.Lock()
+	spans := e.spans
+	e.spans = nil
+	e.mu.Unlock()
+	if len(spans) == 0 {
+		return
+	}
+
+	payload := map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": []interface{}{otlpAttribute("service.name", e.service)},
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": "design-ant"},
+				"spans": spans,
+			}},
+		}},
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequest("POST", e.url, bytes.NewReader(data))
+	if err != nil {
+		slog.Warn("Could not export traces", "url", e.url, "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+	// Not the traced clients: exporting must not create spans itself
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		slog.Warn("Could not export traces", "url", e.url, "error", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		slog.Warn("Trace collector rejected spans", "status", resp.StatusCode, "body", strings.TrimSpace(string(body)))
+	}
+}
+
+// tracingTransport records a client span for every API request and passes
+// the trace context on in the traceparent header. The span ends when the
+// response headers arrive; the APIs answer in one JSON body, so that is
+// close to the provider's latency.
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+func (t tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	_, s := startSpanKind(req.Context(), req.Method+" "+req.URL.Host, spanKindClient,
+		"http.request.method", req.Method, "server.address", req.URL.Host, "url.path", req.URL.Path)
+	if s == nil {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("traceparent", s.traceparent())
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		s.end(err)
+		return nil, err
+	}
+	s.set("http.response.status_code", resp.StatusCode)
+	if resp.StatusCode >= 400 {
+		s.end(fmt.Errorf("status %d", resp.StatusCode))
+	} else {
+		s.end(nil)
+	}
+	return resp, nil
+}
@@ -0,0 +1,268 @@
+package pdfanalysis
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"design-ant/pkg/results"
+)
+
+// Config holds application configuration
+type Config struct {
+	APIKey          string
+	ModelName       string
+	Provider        string            // API serving ModelName: anthropic or gemini (empty = told by the model name)
+	PromptPack      PromptPack        // Analysis prompt for the kind of drawings
+	PromptVars      map[string]string // Values for {{.Vars.name}} in the prompt template
+	SystemAddendum  string            // Conventions appended to the system prompt of the page requests (empty = none)
+	Examples        []Example         // Few-shot example pages sent ahead of every page (empty = none)
+	Context         []PageContext     // Supplemental text added to the prompt of all or some pages (empty = none)
+	PDFPath         string
+	SourcePath      string        // Original Office document or s3://, gs://, az:// URI when PDFPath is a local copy
+	InputMode       string        // pdf, text, or auto
+	StreamJSONL     bool          // Write each chunk result to a JSONL file as it completes
+	Structured      bool          // Request typed JSON data alongside the markdown analysis
+	Welds           bool          // Also extract weld symbols and surface finish callouts (implies Structured)
+	AnnotatedPDF    bool          // Write a review PDF interleaving original pages and analyses
+	CaptureDir      string        // Directory for raw request/response captures (empty = disabled)
+	EventsPath      string        // File progress events are appended to as JSON lines (empty = disabled)
+	TemplatePath    string        // Go text/template applied to the final result
+	TemplateOut     string        // Output path for the rendered template
+	Compression     string        // none, gzip, or zstd for the JSON result
+	LedgerPath      string        // Append-only run history (empty = disabled)
+	OutputLang      string        // Language code for the analysis text (empty = English)
+	LangMode        string        // prompt or translate
+	Terminology     string        // Drafting vocabulary of the analysis text: asme or iso (empty = not specified)
+	UnitOrder       string        // metric-first or imperial-first (empty = not specified)
+	TranslateModel  string        // Model used for the translation pass
+	Markdown        string        // Markdown export flavor (empty = disabled)
+	RedactRules     string        // Rules file for redacting shared reports (empty = disabled)
+	PriceList       string        // CSV or JSON prices keyed by part number for the assembly cost estimate (empty = disabled)
+	AlertWebhook    string        // URL posted to when the run's cost crosses an alert threshold (empty = disabled)
+	AlertThresholds []float64     // Dollar amounts that trigger an alert, ascending
+	Project         string        // Tag recorded in the ledger to attribute spend to a project (empty = untagged)
+	PricingFile     string        // JSON model prices overriding the embedded pricing.json (empty = embedded only)
+	EscalateModel   string        // Stronger model for pages whose output looks incomplete (empty = disabled)
+	ValidatorsPath  string        // Text-layer validators checked against the model output (empty = disabled)
+	Ground          bool          // Send the text layer with each page and move values not found on it out of the output
+	NeutralRetry    bool          // Send a refused or blocked page once more with neutral documentation instructions
+	RulesPath       string        // Compliance rules checked against each page's title block (empty = disabled)
+	QuestionsPath   string        // Questions answered from the relevant pages instead of a full analysis (empty = disabled)
+	Units           string        // Unit system dimensions are normalized to: metric or imperial (empty = off)
+	TwoStage        bool          // Classify each page with a cheap call before the specialized extraction
+	ClassifyModel   string        // Model used for the -two-stage classification call
+	Consolidate     bool          // Run a second-stage pass producing a whole-document summary
+	FanIn           int           // Maximum sections per reduce group (0 = limited by size only)
+	Summary         bool          // Generate a one-page executive summary after the page analyses
+	SummaryModel    string        // Model used for the executive summary
+	ReuseFrom       []string      // Earlier result files whose analyses are reused for identical pages
+	ReuseResults    []*Result     // Earlier results, already loaded, reused like those of ReuseFrom
+	CacheDir        string        // Local page cache reused across runs (empty = disabled)
+	OutputURI       string        // s3://, gs://, or az:// prefix the output files are uploaded to (empty = local only)
+	PostHook        string        // Shell command template run once the outputs of the document are written (empty = none)
+	PageHook        string        // Shell command template run after each page, with the page result on stdin (empty = none)
+	Stages          []PageStage   // Custom steps run on each analyzed page after extraction, in order (empty = none)
+	ResumeFrom      string        // Partial result of an interrupted run whose finished pages are kept (empty = disabled)
+	ResumeStore     ResultStore   // Store ResumeFrom names a result in (nil = ResumeFrom is a file)
+	StoreURI        string        // Directory, sqlite: database, or s3://, gs://, az:// prefix the JSON result and stream are kept in (empty = current directory)
+	TokensPerMinute int           // Input token rate limit that paces page requests (0 = fixed concurrency)
+	Workers         int           // Concurrent page requests (0 = 16 with -tpm, 4 without)
+	ChunkTimeout    time.Duration // Limit for one attempt of a page or text request, including repair turns
+	RunDeadline     time.Duration // Limit for the whole run; unfinished pages are left for -resume (0 = none)
+	MaxCost         float64       // Stop starting pages once the run has cost this many dollars (0 = no limit)
+	NoProgress      bool          // Print one line per page instead of the progress bar
+	Reproducible    bool          // Pin timestamps and durations in the outputs so identical inputs give identical files
+	Pages           []int         // Pages (1-based) to analyze (empty = all)
+	Limits          Limits
+}
+
+// DefaultModel analyzes pages unless another model is configured
+const DefaultModel = "claude-3-5-haiku-20241022" // Using cheapest model
+
+// DefaultConfig returns the configuration of a run without flags, with the
+// API key from ANTHROPIC_API_KEY
+func DefaultConfig() *Config {
+	return &Config{
+		APIKey:          os.Getenv("ANTHROPIC_API_KEY"),
+		ModelName:       DefaultModel,
+		PromptPack:      MechanicalPromptPack,
+		InputMode:       InputModePDF,
+		Compression:     CompressionNone,
+		LangMode:        LangModePrompt,
+		CacheDir:        defaultCacheDir(),
+		StreamJSONL:     true,
+		TokensPerMinute: defaultTokensPerMinute,
+		ChunkTimeout:    DefaultRequestTimeout,
+		Limits:          Limits{MaxChunkMB: anthropicMaxRequestMB},
+	}
+}
+
+// Validate checks the settings and fills in the ones implied by others,
+// e.g. -structured for -welds and the analysis model for the other passes
+func (c *Config) Validate() error {
+	if c.Welds || c.RulesPath != "" || c.PriceList != "" {
+		c.Structured = true
+	}
+	if err := c.checkProvider(); err != nil {
+		return err
+	}
+	if c.PromptPack.Analysis == nil {
+		c.PromptPack = MechanicalPromptPack
+	}
+	if err := c.checkPrompt(); err != nil {
+		return err
+	}
+	for _, context := range c.Context {
+		for _, page := range context.Pages {
+			if page < 1 {
+				return fmt.Errorf("invalid context page %d: pages start at 1", page)
+			}
+		}
+	}
+	if c.FanIn < 0 || c.FanIn == 1 {
+		return fmt.Errorf("invalid -fan-in %d: must be 0 or at least 2", c.FanIn)
+	}
+	if c.ChunkTimeout <= 0 {
+		return fmt.Errorf("invalid -chunk-timeout %v: must be positive", c.ChunkTimeout)
+	}
+	if c.RunDeadline < 0 {
+		return fmt.Errorf("invalid -run-deadline %v: must not be negative", c.RunDeadline)
+	}
+	if c.TokensPerMinute < 0 {
+		return fmt.Errorf("invalid -tpm %d: must not be negative", c.TokensPerMinute)
+	}
+	if c.Workers < 0 {
+		return fmt.Errorf("invalid -workers %d: must not be negative", c.Workers)
+	}
+	if c.MaxCost < 0 {
+		return fmt.Errorf("invalid -max-cost %v: must not be negative", c.MaxCost)
+	}
+	if c.Limits.MaxPages < 0 || c.Limits.MaxChunkMB < 0 || c.Limits.MaxTotalMB < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	switch c.InputMode {
+	case InputModePDF, InputModeText, InputModeAuto:
+	default:
+		return fmt.Errorf("invalid -input-mode %q: must be pdf, text, or auto", c.InputMode)
+	}
+	switch c.Compression {
+	case CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return fmt.Errorf("invalid -compress %q: must be none, gzip, or zstd", c.Compression)
+	}
+	if _, ok := MarkdownFlavors[c.Markdown]; c.Markdown != "" && !ok {
+		return fmt.Errorf("invalid -markdown %q: must be confluence, notion, or github", c.Markdown)
+	}
+	switch c.Units {
+	case "", UnitsMetric, UnitsImperial:
+	default:
+		return fmt.Errorf("invalid -units %q: must be metric or imperial", c.Units)
+	}
+	switch c.Terminology {
+	case "", TerminologyASME, TerminologyISO:
+	default:
+		return fmt.Errorf("invalid -terminology %q: must be asme or iso", c.Terminology)
+	}
+	switch c.UnitOrder {
+	case "", UnitOrderMetricFirst, UnitOrderImperialFirst:
+	default:
+		return fmt.Errorf("invalid -unit-order %q: must be metric-first or imperial-first", c.UnitOrder)
+	}
+	switch c.LangMode {
+	case LangModePrompt, LangModeTranslate:
+	default:
+		return fmt.Errorf("invalid -lang-mode %q: must be prompt or translate", c.LangMode)
+	}
+	if strings.EqualFold(c.OutputLang, "en") {
+		c.OutputLang = ""
+	}
+	if c.EscalateModel != "" && !c.Structured && c.ValidatorsPath == "" {
+		return fmt.Errorf("-escalate-model needs -structured or -validate to detect incomplete pages")
+	}
+	if c.TranslateModel == "" {
+		c.TranslateModel = c.ModelName
+	}
+	if c.ClassifyModel == "" {
+		c.ClassifyModel = c.ModelName
+	}
+	if c.SummaryModel == "" {
+		c.SummaryModel = c.ModelName
+	}
+	for _, stage := range c.Stages {
+		if exec, ok := stage.(*ExecStage); ok && strings.TrimSpace(exec.Command) == "" {
+			return fmt.Errorf("invalid -stage: empty command")
+		}
+	}
+	return nil
+}
+
+// Limits holds size guards enforced before a chunk is sent to the API
+type Limits struct {
+	MaxPages   int   // Maximum pages per document (0 = no limit)
+	MaxChunkMB int64 // Maximum base64-encoded size of one chunk
+	MaxTotalMB int64 // Maximum base64-encoded size of all chunks (0 = no limit)
+}
+
+// DocumentPath returns the path the user supplied, before any conversion
+func (c *Config) DocumentPath() string {
+	if c.SourcePath != "" {
+		return c.SourcePath
+	}
+	return c.PDFPath
+}
+
+// The result types are defined in package results, so tools that read the
+// JSON output can import them without the engine
+type (
+	FullAnalysisResult   = results.FullAnalysisResult
+	BuildInfo            = results.BuildInfo
+	PromptInfo           = results.PromptInfo
+	ChunkAnalysis        = results.ChunkAnalysis
+	StructuredData       = results.StructuredData
+	DrawingMetadata      = results.DrawingMetadata
+	BOMItem              = results.BOMItem
+	Dimension            = results.Dimension
+	NormalizedValue      = results.NormalizedValue
+	WeldSymbol           = results.WeldSymbol
+	SurfaceFinish        = results.SurfaceFinish
+	Instrument           = results.Instrument
+	PipingLine           = results.PipingLine
+	Equipment            = results.Equipment
+	ElectricalComponent  = results.ElectricalComponent
+	Net                  = results.Net
+	Room                 = results.Room
+	Opening              = results.Opening
+	TokenAttribution     = results.TokenAttribution
+	PageClassification   = results.PageClassification
+	Escalation           = results.Escalation
+	RuleResult           = results.RuleResult
+	ValidationResult     = results.ValidationResult
+	UnverifiedValue      = results.UnverifiedValue
+	BlockedStatus        = results.BlockedStatus
+	ConsolidatedAnalysis = results.ConsolidatedAnalysis
+	ReduceLevel          = results.ReduceLevel
+	GroupSummary         = results.GroupSummary
+	ExecutiveSummary     = results.ExecutiveSummary
+	MasterBOMItem        = results.MasterBOMItem
+	BOMOccurrence        = results.BOMOccurrence
+	Discrepancy          = results.Discrepancy
+	TitleBlockException  = results.TitleBlockException
+	IndexEntry           = results.IndexEntry
+	StandardReference    = results.StandardReference
+	AssemblyCost         = results.AssemblyCost
+	AssemblyCostLine     = results.AssemblyCostLine
+	Savings              = results.Savings
+	SavingsLine          = results.SavingsLine
+	PricingTable         = results.PricingTable
+	AnthropicPricing     = results.AnthropicPricing
+)
+
+// ChunkInfo holds information about a PDF chunk
+type ChunkInfo struct {
+	Path      string
+	StartPage int
+	EndPage   int
+	Rendered  string // How a page too large as PDF was rendered instead, e.g. "rendered at 100 dpi"
+}
@@ -0,0 +1,225 @@
+package pdfanalysis
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Unit systems accepted by -units
+const (
+	UnitsMetric   = "metric"
+	UnitsImperial = "imperial"
+)
+
+const mmPerInch = 25.4
+
+// unitAliases maps the spellings found on drawings to mm, in, or deg
+var unitAliases = map[string]string{
+	"mm": "mm", "millimeter": "mm", "millimeters": "mm", "millimetre": "mm", "millimetres": "mm",
+	"cm": "cm", "m": "m",
+	"in": "in", "inch": "in", "inches": "in", "\"": "in", "”": "in", "''": "in",
+	"deg": "deg", "°": "deg", "degree": "deg", "degrees": "deg",
+}
+
+var (
+	// numberPattern finds the first number of a value: 12, 0.500, .75, 1/2,
+	// 1 1/4, 12,5, or 1,250.5; parseNumber tells decimal commas from
+	// thousands separators
+	numberPattern = regexp.MustCompile(`(\d+\s+\d+/\d+|\d+/\d+|\d*\.\d+|\d+(?:,\d+)*(?:\.\d+)?)`)
+	// multiplierPattern matches a leading count such as "2X " or "4 × "
+	multiplierPattern = regexp.MustCompile(`(?i)^\s*\d+\s*[x×]\s+`)
+	// valueUnitPattern finds a unit written directly after the number
+	valueUnitPattern = regexp.MustCompile(`(?i)\d\s*(mm|cm|inch(?:es)?|in\b|"|”|''|°|deg\b)`)
+	// symmetricPattern matches ±0.1
+	symmetricPattern = regexp.MustCompile(`^(?:±|\+/-|\+-)\s*(\d*\.?\d+)`)
+	// deviationPattern matches signed deviations such as +0.02, -0, or -.005
+	deviationPattern = regexp.MustCompile(`([+-])\s*(\d*\.?\d+)`)
+)
+
+// normalizeUnits converts every dimension of the chunks to the unit system and
+// records the conversion on the dimension. Dimensions without a readable
+// number (threads, notes) are left alone. The unit of a dimension is taken
+// from its unit field, the value text, the most common unit of its page, or
+// the most common unit of the document, in that order. It returns the number
+// of normalized and converted dimensions.
+func normalizeUnits(chunks []ChunkAnalysis, system string) (normalized, converted int) {
+	target := "mm"
+	if system == UnitsImperial {
+		target = "in"
+	}
+
+	documentUnit := dominantUnit(chunks...)
+	for i := range chunks {
+		pageUnit := dominantUnit(chunks[i])
+		for j := range chunks[i].Dimensions {
+			d := &chunks[i].Dimensions[j]
+			d.Normalized = nil
+			if d.Type == "thread" {
+				continue
+			}
+			unit, source := explicitUnit(*d)
+			switch {
+			case unit != "":
+			case d.Type == "angle":
+				unit, source = "deg", "dimension"
+			case pageUnit != "":
+				unit, source = pageUnit, "page"
+			case documentUnit != "":
+				unit, source = documentUnit, "document"
+			default:
+				// Unlabeled drawings are assumed to follow the target system
+				unit, source = target, "document"
+			}
+
+			value, ok := parseNumber(d.Value)
+			if !ok {
+				continue
+			}
+			n := &NormalizedValue{SourceUnit: unit, UnitSource: source, Unit: unit}
+			factor := 1.0
+			if unit != "deg" {
+				mm := map[string]float64{"mm": 1, "cm": 10, "m": 1000, "in": mmPerInch}[unit]
+				if target == "mm" {
+					factor = mm
+				} else {
+					factor = mm / mmPerInch
+				}
+				n.Unit = target
+			}
+			n.Value = roundTo(value*factor, 4)
+			if upper, lower, ok := parseTolerance(d.Tolerance); ok {
+				upper, lower = roundTo(upper*factor, 5), roundTo(lower*factor, 5)
+				n.Upper, n.Lower = &upper, &lower
+			}
+			if n.Unit != unit {
+				n.Conversion = fmt.Sprintf("%s %s → %s %s", strconv.FormatFloat(value, 'f', -1, 64), unit,
+					strconv.FormatFloat(n.Value, 'f', -1, 64), n.Unit)
+				converted++
+			}
+			d.Normalized = n
+			normalized++
+		}
+	}
+	return normalized, converted
+}
+
+// explicitUnit returns the unit written on the dimension itself
+func explicitUnit(d Dimension) (string, string) {
+	if unit, ok := unitAliases[strings.ToLower(strings.TrimSpace(d.Unit))]; ok {
+		return unit, "dimension"
+	}
+	if m := valueUnitPattern.FindStringSubmatch(d.Value); m != nil {
+		if unit, ok := unitAliases[strings.ToLower(m[1])]; ok {
+			return unit, "value"
+		}
+	}
+	return "", ""
+}
+
+// dominantUnit returns the most common explicit length unit of the chunks,
+// or "" when no dimension names one
+func dominantUnit(chunks ...ChunkAnalysis) string {
+	counts := make(map[string]int)
+	for _, chunk := range chunks {
+		for _, d := range chunk.Dimensions {
+			if unit, _ := explicitUnit(d); unit != "" && unit != "deg" {
+				counts[unit]++
+			}
+		}
+	}
+	best := ""
+	for _, unit := range []string{"mm", "in", "cm", "m"} {
+		if counts[unit] > counts[best] {
+			best = unit
+		}
+	}
+	return best
+}
+
+// parseNumber reads the first number of a dimension value such as "Ø25",
+// "2X R5", "1 1/4", "12,5", or "1,250". Multiplier prefixes like "2X" are
+// skipped. A comma followed by exactly three digits separates thousands;
+// any other comma is a decimal comma.
+func parseNumber(value string) (float64, bool) {
+	value = multiplierPattern.ReplaceAllString(value, "")
+	m := numberPattern.FindString(value)
+	if m == "" {
+		return 0, false
+	}
+	if whole, frac, ok := strings.Cut(m, " "); ok {
+		w, err := strconv.ParseFloat(whole, 64)
+		f, ok := parseFraction(strings.TrimSpace(frac))
+		return w + f, err == nil && ok
+	}
+	if strings.Contains(m, "/") {
+		return parseFraction(m)
+	}
+	if strings.Contains(m, ",") {
+		m = replaceCommas(m)
+	}
+	v, err := strconv.ParseFloat(m, 64)
+	return v, err == nil
+}
+
+// replaceCommas drops the thousands separators of a number, as in 1,250 or
+// 12,500.75, or makes its first comma a decimal point, as in 12,5 or 0,500
+func replaceCommas(number string) string {
+	integer, _, _ := strings.Cut(number, ".")
+	groups := strings.Split(integer, ",")
+	thousands := len(groups[0]) <= 3 && groups[0][0] != '0'
+	for _, group := range groups[1:] {
+		thousands = thousands && len(group) == 3
+	}
+	if thousands {
+		return strings.ReplaceAll(number, ",", "")
+	}
+	// Anything after a second comma is not part of the number
+	first, rest, _ := strings.Cut(number, ",")
+	decimals, _, _ := strings.Cut(rest, ",")
+	return first + "." + decimals
+}
+
+// parseFraction parses "3/8"
+func parseFraction(s string) (float64, bool) {
+	num, den, ok := strings.Cut(s, "/")
+	n, err1 := strconv.ParseFloat(num, 64)
+	d, err2 := strconv.ParseFloat(den, 64)
+	if !ok || err1 != nil || err2 != nil || d == 0 {
+		return 0, false
+	}
+	return n / d, true
+}
+
+// parseTolerance reads "±0.1", "+0.02/-0", or "+0.1 -0.05" as upper and lower
+// deviations. Fit classes such as "H7" are not converted.
+func parseTolerance(tolerance string) (upper, lower float64, ok bool) {
+	tolerance = strings.TrimSpace(tolerance)
+	if m := symmetricPattern.FindStringSubmatch(tolerance); m != nil {
+		v, err := strconv.ParseFloat(m[1], 64)
+		return v, -v, err == nil
+	}
+	matches := deviationPattern.FindAllStringSubmatch(tolerance, -1)
+	if len(matches) != 2 {
+		return 0, 0, false
+	}
+	var devs [2]float64
+	for i, m := range matches {
+		v, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		if m[1] == "-" {
+			v = -v
+		}
+		devs[i] = v
+	}
+	return max(devs[0], devs[1]), min(devs[0], devs[1]), true
+}
+
+// roundTo rounds v to the given number of decimals to hide float noise
+func roundTo(v float64, decimals int) float64 {
+	f, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'f', decimals, 64), 64)
+	return f + 0 // no negative zero
+}
@@ -0,0 +1,33 @@
+package pdfanalysis
+
+import "testing"
+
+func TestParseNumber(t *testing.T) {
+	for value, want := range map[string]float64{
+		"Ø25":        25,
+		"2X R5":      5,
+		"1 1/4":      1.25,
+		"3/8":        0.375,
+		".75":        0.75,
+		"0.500":      0.5,
+		"12,5":       12.5,
+		"0,500":      0.5,
+		"1,250":      1250,
+		"1,250 mm":   1250,
+		"1,250.5":    1250.5,
+		"12,345,678": 12345678,
+		"1,2500":     1.25,
+		"R12,5, 3":   12.5,
+	} {
+		got, ok := parseNumber(value)
+		if !ok || got != want {
+			t.Errorf("parseNumber(%q) = %v, %v; want %v", value, got, ok, want)
+		}
+	}
+	if _, ok := parseNumber("M8x1.25 thread"); !ok {
+		t.Error("no number found in a thread")
+	}
+	if _, ok := parseNumber("see note"); ok {
+		t.Error("number found in a note")
+	}
+}
@@ -0,0 +1,252 @@
+package pdfanalysis
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// validationTargets are the output sections a validator can check
+var validationTargets = map[string]func(ChunkAnalysis) []string{
+	"analysis": func(c ChunkAnalysis) []string { return []string{c.Analysis} },
+	"bom": func(c ChunkAnalysis) []string {
+		var values []string
+		for _, row := range c.BOMItems {
+			values = append(values, row.ItemNumber, row.PartNumber, row.Description, row.Material, row.Finish)
+		}
+		return values
+	},
+	"metadata": func(c ChunkAnalysis) []string {
+		if m := c.Metadata; m != nil {
+			return []string{m.DrawingNumber, m.Title, m.Revision, m.DrawnBy, m.CheckedBy, m.ApprovedBy, m.Date, m.Scale, m.Projection, m.Material}
+		}
+		return nil
+	},
+	"dimensions": func(c ChunkAnalysis) []string {
+		var values []string
+		for _, d := range c.Dimensions {
+			values = append(values, d.Feature, d.Value+" "+d.Tolerance)
+		}
+		return values
+	},
+	"notes": func(c ChunkAnalysis) []string { return c.Notes },
+	"instruments": func(c ChunkAnalysis) []string {
+		var values []string
+		for _, x := range c.Instruments {
+			values = append(values, x.Tag, x.Service)
+		}
+		return values
+	},
+	"lines": func(c ChunkAnalysis) []string {
+		var values []string
+		for _, x := range c.Lines {
+			values = append(values, x.LineNumber)
+		}
+		return values
+	},
+	"equipment": func(c ChunkAnalysis) []string {
+		var values []string
+		for _, x := range c.Equipment {
+			values = append(values, x.Tag, x.Description)
+		}
+		return values
+	},
+	"components": func(c ChunkAnalysis) []string {
+		var values []string
+		for _, x := range c.Components {
+			values = append(values, x.Designator, x.Value, x.PartNumber)
+		}
+		return values
+	},
+	"nets": func(c ChunkAnalysis) []string {
+		var values []string
+		for _, x := range c.Nets {
+			values = append(values, x.Name)
+			values = append(values, x.Nodes...)
+		}
+		return values
+	},
+	"rooms": func(c ChunkAnalysis) []string {
+		var values []string
+		for _, x := range c.Rooms {
+			values = append(values, x.Number, x.Name)
+		}
+		return values
+	},
+	"openings": func(c ChunkAnalysis) []string {
+		var values []string
+		for _, x := range c.Openings {
+			values = append(values, x.Mark)
+		}
+		return values
+	},
+}
+
+// validator checks that every match of a pattern in the text layer appears in the output
+type validator struct {
+	name    string
+	pattern *regexp.Regexp
+	target  string // A key of validationTargets, or "any"
+}
+
+// loadValidators reads a validators file. Each non-empty line is one validator:
+//
+//	P\d{2} => bom                         every part number P01..P99 must be in the BOM rows
+//	part numbers: P\d{2} => bom           the same, with a name for reports
+//	DWG-\d{4} => analysis                 drawing references must be mentioned in the analysis
+//	ISO \d+ => any                        anywhere in the analysis or structured data
+//
+// Targets are analysis, bom, metadata, dimensions, notes, the sections of the
+// prompt packs (instruments, lines, equipment, components, nets, rooms,
+// openings), or any. When the
+// pattern has a capture group, the first group is the value to look for.
+// Lines starting with # are comments.
+func loadValidators(path string) ([]validator, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening validators: %v", err)
+	}
+	defer file.Close()
+
+	var validators []validator
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, "=>")
+		if i < 0 {
+			return nil, fmt.Errorf("%s:%d: expected \"<pattern> => <target>\"", path, lineNumber)
+		}
+		expr, target := strings.TrimSpace(line[:i]), strings.ToLower(strings.TrimSpace(line[i+2:]))
+		if _, ok := validationTargets[target]; !ok && target != "any" {
+			return nil, fmt.Errorf("%s:%d: unknown target %q: must be analysis, bom, metadata, dimensions, notes, a prompt pack section, or any", path, lineNumber, target)
+		}
+		name := expr
+		if label, rest, ok := strings.Cut(expr, ": "); ok {
+			name, expr = strings.TrimSpace(label), strings.TrimSpace(rest)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid pattern: %v", path, lineNumber, err)
+		}
+		validators = append(validators, validator{name: name, pattern: re, target: target})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading validators: %v", err)
+	}
+	if len(validators) == 0 {
+		return nil, fmt.Errorf("%s contains no validators", path)
+	}
+	return validators, nil
+}
+
+// PageTexts extracts the text layer of every page
+func PageTexts(pdfPath string, totalPages int) ([]string, error) {
+	return pageTexts(fileSource(pdfPath), totalPages)
+}
+
+// pageTexts extracts the text layer of every page of a source
+func pageTexts(src pdfSource, totalPages int) ([]string, error) {
+	doc, err := src.openFitz()
+	if err != nil {
+		return nil, fmt.Errorf("error opening PDF for text extraction: %v", err)
+	}
+	defer doc.Close()
+
+	texts := make([]string, totalPages)
+	for i := 0; i < totalPages; i++ {
+		text, err := doc.Text(i)
+		if err != nil {
+			return nil, fmt.Errorf("error reading text of page %d: %v", i+1, err)
+		}
+		texts[i] = text
+	}
+	return texts, nil
+}
+
+// validateOutput runs the validators against each analyzed page and stores
+// the failures on the chunk. Pages without a text layer (scans) and failed
+// pages are skipped. It returns the number of flagged pages.
+func validateOutput(chunks []ChunkAnalysis, texts []string, validators []validator) int {
+	flagged := 0
+	for i := range chunks {
+		chunk := &chunks[i]
+		chunk.Validation = nil
+		if chunk.Error != "" {
+			continue
+		}
+		var text strings.Builder
+		for page := chunk.StartPage; page <= chunk.EndPage && page <= len(texts); page++ {
+			text.WriteString(texts[page-1])
+			text.WriteString("\n")
+		}
+		for _, v := range validators {
+			if result := v.check(*chunk, text.String()); len(result.Missing) > 0 {
+				chunk.Validation = append(chunk.Validation, result)
+			}
+		}
+		if len(chunk.Validation) > 0 {
+			flagged++
+		}
+	}
+	return flagged
+}
+
+// check looks up every distinct match of the pattern in the page output
+func (v validator) check(chunk ChunkAnalysis, text string) ValidationResult {
+	result := ValidationResult{Validator: v.name, Target: v.target}
+
+	var output []string
+	if v.target == "any" {
+		for _, get := range validationTargets {
+			output = append(output, get(chunk)...)
+		}
+	} else {
+		output = validationTargets[v.target](chunk)
+	}
+	haystack := normalizeValidationText(strings.Join(output, "\n"))
+
+	seen := make(map[string]bool)
+	for _, m := range v.pattern.FindAllStringSubmatch(text, -1) {
+		value := m[0]
+		if len(m) > 1 && m[1] != "" {
+			value = m[1]
+		}
+		value = strings.Join(strings.Fields(value), " ")
+		key := normalizeValidationText(value)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result.Found++
+		if !strings.Contains(haystack, key) {
+			result.Missing = append(result.Missing, value)
+		}
+	}
+	return result
+}
+
+// normalizeValidationText folds case and whitespace so line breaks in the
+// text layer do not cause false alarms
+func normalizeValidationText(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), " "))
+}
+
+// markdownValidation renders a page's validation failures as a warning
+func markdownValidation(results []ValidationResult) string {
+	if len(results) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("> **Possibly incomplete:** values in the page text are missing from the output\n")
+	for _, r := range results {
+		fmt.Fprintf(&b, "> - %s (%s): %d of %d missing: %s\n", EscapeInline(r.Validator), r.Target,
+			len(r.Missing), r.Found, EscapeInline(strings.Join(r.Missing, ", ")))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
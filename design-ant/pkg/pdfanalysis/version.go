@@ -0,0 +1,38 @@
+package pdfanalysis
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// Version is the release of design-ant, set when building a release:
+//
+//	go build -ldflags "-X design-ant/pkg/pdfanalysis.Version=1.4.0"
+//
+// Builds without it are "dev", or the module version for go install.
+var Version = "dev"
+
+// Build returns the build of this binary, recorded in every result so
+// changes in extraction quality can be traced to a release
+var Build = sync.OnceValue(func() *BuildInfo {
+	build := &BuildInfo{Version: Version}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return build
+	}
+	build.GoVersion = info.GoVersion
+	if build.Version == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		build.Version = info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Commit = setting.Value
+		case "vcs.time":
+			build.CommitTime = setting.Value
+		case "vcs.modified":
+			build.Modified = setting.Value == "true"
+		}
+	}
+	return build
+})
@@ -0,0 +1,143 @@
+package pdfanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// weldTypes are the allowed values of WeldSymbol.Type
+var weldTypes = []string{
+	"fillet", "square_groove", "v_groove", "bevel_groove", "u_groove", "j_groove",
+	"flare_v", "flare_bevel", "plug", "slot", "spot", "seam", "stud", "surfacing",
+	"edge", "backing", "other",
+}
+
+// weldInstructions is the prompt pack for reading weld and surface finish symbols
+const weldInstructions = `WELD AND SURFACE FINISH SYMBOLS:
+Read EVERY weld symbol and EVERY surface finish callout on the page and include them in the tool call.
+Weld symbols (AWS A2.4 / ISO 2553):
+- type from the basic symbol: fillet, square_groove, v_groove, bevel_groove, u_groove, j_groove, flare_v, flare_bevel, plug, slot, spot, seam, stud, surfacing, edge, backing, or other
+- side: "arrow" if the symbol is below the reference line (AWS) / on the solid line (ISO), "other" if above / on the dashed line, "both" if on both sides
+- size left of the symbol (leg, or throat "a" / leg "z" in ISO), length and pitch right of it ("50-150" = length 50, pitch 150), with units
+- all_around is true for a circle at the arrow/reference line junction; field_weld is true for a flag
+- contour and finish symbols (flush, convex, concave, G/M/C), and the tail reference (process, specification) as process
+- one row per weld symbol; a symbol with welds on both sides and different sizes becomes two rows
+Surface finish (ISO 1302 / ASME Y14.36):
+- parameter and value exactly as written (Ra 3.2, Rz 16), unit µm unless the drawing uses µin
+- material_removal: "required" (bar over the check mark), "prohibited" (circle), or "any" (plain check mark)
+- lay direction symbol, process note, and machining allowance when present
+- include the general surface finish note of the title block as a row with feature "general"`
+
+// weldSchemaProperties are the tool schema properties added by -welds
+const weldSchemaProperties = `{
+  "welds": {
+    "type": "array",
+    "items": {
+      "type": "object",
+      "properties": {
+        "location": {"type": "string"},
+        "type": {"type": "string", "enum": ["fillet", "square_groove", "v_groove", "bevel_groove", "u_groove", "j_groove", "flare_v", "flare_bevel", "plug", "slot", "spot", "seam", "stud", "surfacing", "edge", "backing", "other"]},
+        "side": {"type": "string", "enum": ["arrow", "other", "both"]},
+        "size": {"type": "string"},
+        "length": {"type": "string"},
+        "pitch": {"type": "string"},
+        "all_around": {"type": "boolean"},
+        "field_weld": {"type": "boolean"},
+        "contour": {"type": "string"},
+        "process": {"type": "string"},
+        "note": {"type": "string"}
+      },
+      "required": ["type", "all_around", "field_weld"]
+    }
+  },
+  "surface_finishes": {
+    "type": "array",
+    "items": {
+      "type": "object",
+      "properties": {
+        "feature": {"type": "string"},
+        "parameter": {"type": "string"},
+        "value": {"type": "string"},
+        "unit": {"type": "string"},
+        "material_removal": {"type": "string", "enum": ["required", "prohibited", "any"]},
+        "lay": {"type": "string"},
+        "process": {"type": "string"},
+        "allowance": {"type": "string"}
+      },
+      "required": ["value"]
+    }
+  }
+}`
+
+// addWeldSchema extends the extraction tool schema with the weld and surface finish sections
+func addWeldSchema(schema map[string]interface{}) {
+	var properties map[string]interface{}
+	if err := json.Unmarshal([]byte(weldSchemaProperties), &properties); err != nil {
+		panic(fmt.Sprintf("invalid weld schema: %v", err))
+	}
+	for name, property := range properties {
+		schema["properties"].(map[string]interface{})[name] = property
+	}
+	schema["required"] = append(schema["required"].([]interface{}), "welds", "surface_finishes")
+}
+
+// validateWelds checks weld and surface finish rows beyond what decoding catches
+func validateWelds(data StructuredData) []string {
+	var errs []string
+	for i, w := range data.Welds {
+		if !containsString(weldTypes, w.Type) {
+			errs = append(errs, fmt.Sprintf("welds[%d].type: %q is not one of %s", i, w.Type, strings.Join(weldTypes, ", ")))
+		}
+		if w.Side != "" && !containsString([]string{"arrow", "other", "both"}, w.Side) {
+			errs = append(errs, fmt.Sprintf("welds[%d].side: %q must be arrow, other, or both", i, w.Side))
+		}
+	}
+	for i, f := range data.SurfaceFinishes {
+		if strings.TrimSpace(f.Value) == "" {
+			errs = append(errs, fmt.Sprintf("surface_finishes[%d].value: required", i))
+		}
+		if f.MaterialRemoval != "" && !containsString([]string{"required", "prohibited", "any"}, f.MaterialRemoval) {
+			errs = append(errs, fmt.Sprintf("surface_finishes[%d].material_removal: %q must be required, prohibited, or any", i, f.MaterialRemoval))
+		}
+	}
+	return errs
+}
+
+// markdownWelds renders a page's weld symbols and surface finishes as tables
+func markdownWelds(data StructuredData) string {
+	var b strings.Builder
+	if len(data.Welds) > 0 {
+		b.WriteString("**Weld symbols**\n\n")
+		b.WriteString("| Location | Type | Side | Size | Length | Pitch | All around | Field | Process / note |\n")
+		b.WriteString("| --- | --- | --- | --- | --- | --- | --- | --- | --- |\n")
+		for _, w := range data.Welds {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s | %s | %s |\n",
+				EscapeInline(w.Location), EscapeInline(w.Type), EscapeInline(w.Side), EscapeInline(w.Size),
+				EscapeInline(w.Length), EscapeInline(w.Pitch), yesNo(w.AllAround), yesNo(w.FieldWeld),
+				EscapeInline(strings.TrimSpace(w.Process+" "+w.Note)))
+		}
+		b.WriteString("\n")
+	}
+	if len(data.SurfaceFinishes) > 0 {
+		b.WriteString("**Surface finish**\n\n")
+		b.WriteString("| Feature | Roughness | Material removal | Lay | Process | Allowance |\n")
+		b.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+		for _, f := range data.SurfaceFinishes {
+			roughness := strings.TrimSpace(strings.Join([]string{f.Parameter, f.Value, f.Unit}, " "))
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n",
+				EscapeInline(f.Feature), EscapeInline(roughness), EscapeInline(f.MaterialRemoval),
+				EscapeInline(f.Lay), EscapeInline(f.Process), EscapeInline(f.Allowance))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// yesNo formats a flag for tables
+func yesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+	return ""
+}
@@ -0,0 +1,361 @@
+package pdfanalysis
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"design-ant/internal/tempfiles"
+	"design-ant/pkg/results"
+)
+
+// pageJob is one chunk in the analysis queue together with its retry state.
+// A job that failed with a retryable error goes back into the queue after
+// its backoff, so the worker is free for other pages in the meantime.
+type pageJob struct {
+	index          int
+	chunk          ChunkInfo
+	pageHash       string
+	route          PageRoute
+	cacheKey       string
+	contentKey     string // Identifies identical chunks of the run
+	classification *PageClassification
+	prompt         string // Built on the first attempt and reused for retries
+	started        time.Time
+	attempts       int            // Requests sent so far
+	retries        int            // Attempts that failed and were retried
+	retryIn        time.Duration  // Backoff before the next attempt
+	blocked        *BlockedStatus // Set by the first refused or blocked attempt
+	inputTokens    int            // Spent by all attempts so far, failed ones included
+	outputTokens   int
+}
+
+// page identifies the job's chunk to observers
+func (j *pageJob) page() ChunkAnalysis {
+	return results.NewChunk(j.index+1, j.chunk.StartPage+1, j.chunk.EndPage+1)
+}
+
+// pageQueue analyzes chunks with a fixed pool of workers
+type pageQueue struct {
+	config       *Config
+	routes       []PageRoute
+	fingerprints []string
+	reuse        reuseCache
+	resumed      map[int]ChunkAnalysis // Pages done by the interrupted run given to -resume
+	cache        *pageCache            // Local page cache (nil = disabled)
+	source       string                // Document name recorded with cache entries
+	document     pdfSource             // Read again to render pages the provider rejects as too large
+	temp         *tempfiles.Dir        // Where those pages are rendered
+	observer     ProgressObserver
+	id           Run // The run the observer is told the pages are of
+	total        int // Chunks planned
+}
+
+// run analyzes the chunks read from split with the given number of workers
+// and returns the results in chunk order, for every chunk received before
+// split was closed. Finished chunks are reported to onResult one at a time,
+// in completion order, from the calling goroutine. At most pipelineDepth
+// chunks per worker are taken from split before earlier ones finish.
+//
+// Chunks with identical content are sent once: the others wait for the first
+// one and copy its analysis, or are queued themselves if it failed.
+func (q *pageQueue) run(ctx context.Context, split <-chan ChunkInfo, workers int, onResult func(ChunkAnalysis)) []ChunkAnalysis {
+	total := q.total
+	// Both channels can hold every job, so requeueing and reporting never block
+	jobs := make(chan *pageJob, total)
+	finished := make(chan ChunkAnalysis, total)
+	for w := 0; w < min(workers, total); w++ {
+		go func() {
+			defer tempfiles.CleanupOnPanic()
+			for job := range jobs {
+				if result, done := q.process(ctx, job); done {
+					finished <- result
+				} else {
+					go q.requeue(ctx, jobs, job)
+				}
+			}
+		}()
+	}
+
+	results := make([]ChunkAnalysis, total)
+	sent := make(map[string]int)           // Content key -> index of the job whose result copies use
+	duplicates := make(map[int][]*pageJob) // Index of a sent job -> jobs waiting for it
+	received, pending := 0, 0
+	report := func(result ChunkAnalysis) {
+		results[result.ChunkNumber-1] = result
+		onResult(result)
+		pending--
+	}
+	copyResult := func(result ChunkAnalysis, job *pageJob) {
+		pagef(ctx, "  🪞 %s is identical to page %d, reusing its analysis\n", chunkLabel(job.page()), result.StartPage)
+		report(duplicateResult(result, job))
+	}
+
+	for split != nil || pending > 0 {
+		// Take no new chunks while the pipeline is full
+		input := split
+		if pending >= pipelineDepth*workers {
+			input = nil
+		}
+		select {
+		case chunk, ok := <-input:
+			if !ok {
+				split = nil
+				continue
+			}
+			job := &pageJob{index: received, chunk: chunk}
+			received++
+			pending++
+			if chunk.StartPage == chunk.EndPage && q.fingerprints != nil {
+				job.pageHash = q.fingerprints[chunk.StartPage]
+			}
+			if job.contentKey = contentKey(job); job.contentKey != "" {
+				if original, ok := sent[job.contentKey]; ok {
+					switch result := results[original]; {
+					case result.ChunkNumber == 0:
+						duplicates[original] = append(duplicates[original], job)
+						continue
+					case result.Error == "":
+						copyResult(result, job)
+						continue
+					}
+					// The original failed; this copy is sent instead
+				}
+				sent[job.contentKey] = job.index
+			}
+			jobs <- job
+
+		case result := <-finished:
+			report(result)
+			waiting := duplicates[result.ChunkNumber-1]
+			delete(duplicates, result.ChunkNumber-1)
+			if result.Error != "" && len(waiting) > 0 {
+				// Identical content may still succeed; send the next copy and
+				// let the others wait for that one
+				sent[waiting[0].contentKey] = waiting[0].index
+				duplicates[waiting[0].index] = waiting[1:]
+				jobs <- waiting[0]
+				continue
+			}
+			for _, job := range waiting {
+				copyResult(result, job)
+			}
+		}
+	}
+	close(jobs)
+	return results[:received]
+}
+
+// requeue puts a failed job back after its backoff, or right away when
+// the run is interrupted so the job is reported without another attempt
+func (q *pageQueue) requeue(ctx context.Context, jobs chan<- *pageJob, job *pageJob) {
+	select {
+	case <-time.After(job.retryIn):
+	case <-ctx.Done():
+	}
+	jobs <- job
+}
+
+// process runs one attempt of a job. It returns done=false when the attempt
+// failed with a retryable error and the job should be queued again after
+// job.retryIn.
+func (q *pageQueue) process(ctx context.Context, job *pageJob) (ChunkAnalysis, bool) {
+	config := q.config
+	pageNumber := job.chunk.StartPage + 1
+	if job.attempts == 0 {
+		job.started = time.Now()
+		if done, ok := q.resumed[pageNumber]; ok && (done.PageHash == "" || done.PageHash == job.pageHash) {
+			done.ChunkNumber = job.index + 1
+			pagef(ctx, "  ⏩ Page %d already analyzed by the interrupted run\n", pageNumber)
+			return done, true
+		}
+		if cached, ok := q.reuse.lookup(job.pageHash, job.index+1, pageNumber); ok {
+			cached.ProcessingTime = time.Since(job.started).String()
+			pagef(ctx, "  ♻️  Page %d unchanged, reusing analysis from %s\n", pageNumber, cached.ReusedFrom)
+			return cached, true
+		}
+		job.route = routeForChunk(q.routes, job.chunk)
+		job.cacheKey = cacheKey(config, job.pageHash, pageNumber, job.route)
+		if cached, ok := q.cache.lookup(job.cacheKey, job.index+1, pageNumber); ok {
+			// The cache keeps analyses as extracted, before the stages
+			runStages(ctx, config, &cached)
+			cached.ProcessingTime = time.Since(job.started).String()
+			pagef(ctx, "  💾 Page %d found in the page cache (%s)\n", pageNumber, strings.TrimPrefix(cached.ReusedFrom, "cache: "))
+			return cached, true
+		}
+		if ctx.Err() != nil {
+			// Kept, reused, and cached pages above cost nothing, so they still count
+			return interruptedChunk(job), true
+		}
+		q.observer.OnPageStart(q.id, q.total, job.page())
+
+		if config.TwoStage {
+			ctx := ContextWithCapture(ctx, config.CaptureDir, fmt.Sprintf("chunk-%03d-classify", job.index+1))
+			job.classification = classifyPage(ctx, config, job.route, job.chunk.Path, pageNumber)
+			if job.classification.Error != "" {
+				logf(ctx, "  ⚠️  Page %d: classification failed, using the generic prompt: %s\n", pageNumber, job.classification.Error)
+			} else {
+				pagef(ctx, "  🏷️  Page %d: %s\n", pageNumber, job.classification.Type)
+			}
+		}
+		job.prompt = buildPrompt(config, pageNumber, job.classification)
+	}
+
+	job.attempts++
+	attemptCtx := ContextWithCapture(ctx, config.CaptureDir, fmt.Sprintf("chunk-%03d-attempt-%d", job.index+1, job.attempts))
+	attemptCtx, span := startSpan(attemptCtx, "page", "page", pageNumber, "attempt", job.attempts, "input_mode", job.route.Mode)
+	pageConfig := config
+	if job.blocked != nil && job.blocked.NeutralRetry {
+		pageConfig = neutralConfig(config)
+	}
+	reply, err := analyzePage(attemptCtx, pageConfig, job.route, job.chunk.Path, pageNumber, job.prompt)
+	// Refused, blocked, and retried attempts are billed too
+	job.inputTokens += reply.InputTokens
+	job.outputTokens += reply.OutputTokens
+	inputTokens, outputTokens, extraction := job.inputTokens, job.outputTokens, reply.Extraction
+	span.set("input_tokens", reply.InputTokens)
+	span.set("output_tokens", reply.OutputTokens)
+	defer func() { span.end(err) }()
+	if err != nil && ctx.Err() == nil && ClassifyError(err) == ErrorTooLarge && job.route.Mode != InputModeText &&
+		job.chunk.StartPage == job.chunk.EndPage && job.chunk.Rendered == "" && config.Capabilities().ImageInput {
+		// The provider rejected the page PDF as too large; send it as images
+		_, renderSpan := startSpan(attemptCtx, "render", "page", pageNumber)
+		rendered, renderErr := renderOversizedPage(attemptCtx, q.document, q.temp, job.chunk, config.Limits, config.Capabilities())
+		renderSpan.end(renderErr)
+		if renderErr == nil {
+			logf(ctx, "  🖼️  Page %d: %s, sending it %s\n", pageNumber, ClassifyError(err), rendered.Rendered)
+			job.chunk = rendered
+			job.retryIn = 0
+			return ChunkAnalysis{}, false
+		}
+		if ctx.Err() == nil {
+			slog.Warn("Could not render page as images", "page", pageNumber, "error", renderErr)
+		}
+	}
+	if err != nil && ctx.Err() == nil && job.blocked == nil {
+		if job.blocked = blockedStatus(err); job.blocked != nil && config.NeutralRetry {
+			// Sent once more with the neutral prompt instead of failing
+			job.blocked.NeutralRetry = true
+			logf(ctx, "  🚫 Page %d: %s %s (%s), retrying with the neutral prompt\n", pageNumber, job.blocked.Provider, job.blocked.Reason, job.blocked.Detail)
+			job.retryIn = 0
+			return ChunkAnalysis{}, false
+		}
+	}
+	if err != nil && ctx.Err() == nil {
+		if delay, retry := RetryDelay(err, job.attempts); retry {
+			job.retries++
+			job.retryIn = delay
+			q.observer.OnRetry(q.id, job.page(), job.attempts, err, delay)
+			return ChunkAnalysis{}, false
+		}
+	}
+	if err != nil && ctx.Err() != nil {
+		return interruptedChunk(job), true
+	}
+
+	pricing := GetPricing(config.ModelName)
+	inputCost := float64(inputTokens) / 1_000_000 * pricing.InputPricePerMTokens
+	outputCost := float64(outputTokens) / 1_000_000 * pricing.OutputPricePerMTokens
+
+	result := ChunkAnalysis{
+		ChunkNumber:  job.index + 1,
+		StartPage:    pageNumber,
+		EndPage:      job.chunk.EndPage + 1,
+		Analysis:     reply.Analysis,
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+		InputCost:    inputCost,
+		OutputCost:   outputCost,
+		TotalCost:    inputCost + outputCost,
+		InputMode:    job.route.Mode,
+		RouteReason:  job.route.Reason,
+		Rendered:     job.chunk.Rendered,
+		Retries:      job.retries,
+		Continued:    reply.Continued,
+		Truncated:    reply.Truncated,
+		PageHash:     job.pageHash,
+	}
+	if c := job.classification; c != nil {
+		// The classification call is part of the page cost
+		classifyPricing := GetPricing(c.Model)
+		result.Classification = c
+		result.InputTokens += c.InputTokens
+		result.OutputTokens += c.OutputTokens
+		result.InputCost += float64(c.InputTokens) / 1_000_000 * classifyPricing.InputPricePerMTokens
+		result.OutputCost += float64(c.OutputTokens) / 1_000_000 * classifyPricing.OutputPricePerMTokens
+		result.TotalCost = result.InputCost + result.OutputCost
+	}
+
+	if err == nil {
+		requests := 1 + reply.Continued
+		if extraction != nil {
+			requests += extraction.Repairs
+		}
+		result.InputBreakdown = attributeTokens(ctx, pageConfig, job.prompt, reply.InputTokens, requests)
+		if reply.Truncated {
+			logf(ctx, "  ✂️  Page %d: answer still cut off at max_tokens after %d continuation(s)\n", pageNumber, reply.Continued)
+		} else if reply.Continued > 0 {
+			pagef(ctx, "  ✂️  Page %d: answer hit max_tokens, completed in %d continuation(s)\n", pageNumber, reply.Continued)
+		}
+	}
+
+	if err == nil && extraction != nil {
+		if extraction.Repairs > 0 {
+			pagef(ctx, "  🔧 Page %d: structured data repaired in %d extra turn(s)\n", pageNumber, extraction.Repairs)
+		}
+		if extraction.DataError != "" {
+			logf(ctx, "  ⚠️  Page %d: no valid structured data, keeping raw text only: %s\n", pageNumber, extraction.DataError)
+		} else {
+			result.StructuredData = extraction.Data
+		}
+	}
+
+	if err == nil && config.Ground {
+		// Before translation, which could reword the markers
+		if n := groundChunk(&result, job.route.Text); n > 0 {
+			pagef(ctx, "  🔎 Page %d: %d unverified value(s) moved out of the output\n", pageNumber, n)
+		}
+	}
+
+	if err == nil && config.OutputLang != "" {
+		if config.LangMode == LangModePrompt {
+			result.Language = config.OutputLang
+		} else {
+			// Translate after extraction so the structured data stays verbatim
+			ctx := ContextWithCapture(ctx, config.CaptureDir, fmt.Sprintf("chunk-%03d-translate", job.index+1))
+			if err := applyTranslation(ctx, config, &result); err != nil {
+				logf(ctx, "  ⚠️  Page %d: translation failed, keeping English analysis: %v\n", pageNumber, err)
+			}
+		}
+	}
+
+	if job.blocked != nil {
+		job.blocked.Recovered = err == nil
+		result.Blocked = job.blocked
+		if err == nil {
+			pagef(ctx, "  ✅ Page %d: analyzed with the neutral prompt\n", pageNumber)
+		}
+	}
+
+	result.Timestamp = time.Now()
+	if err != nil {
+		result.Error = err.Error()
+		result.ErrorClass = string(ClassifyError(err))
+	} else if reusable(config, result) {
+		if err := q.cache.store(job.cacheKey, fmt.Sprintf("%s p. %d", q.source, pageNumber), result); err != nil {
+			slog.Warn("Could not cache page", "page", pageNumber, "error", err)
+		}
+	}
+	runStages(ctx, config, &result)
+	result.ProcessingTime = time.Since(job.started).String()
+	return result, true
+}
+
+// interruptedChunk is the placeholder result of a job the interruption stopped
+func interruptedChunk(job *pageJob) ChunkAnalysis {
+	chunk := job.page()
+	chunk.Error = ErrInterrupted
+	return chunk
+}
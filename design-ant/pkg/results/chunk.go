@@ -0,0 +1,130 @@
+package results
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ChunkAnalysis represents analysis result for a PDF chunk
+type ChunkAnalysis struct {
+	ChunkNumber      int                        `json:"chunk_number"`
+	StartPage        int                        `json:"start_page"`
+	EndPage          int                        `json:"end_page"`
+	Analysis         string                     `json:"analysis"` // Raw markdown analysis, always kept as fallback
+	InputTokens      int                        `json:"input_tokens"`
+	OutputTokens     int                        `json:"output_tokens"`
+	InputCost        float64                    `json:"input_cost"`
+	OutputCost       float64                    `json:"output_cost"`
+	TotalCost        float64                    `json:"total_cost"`
+	ProcessingTime   string                     `json:"processing_time"`
+	InputMode        string                     `json:"input_mode,omitempty"`
+	RouteReason      string                     `json:"route_reason,omitempty"`
+	Rendered         string                     `json:"rendered,omitempty"`           // Sent as images because the page was too large as PDF
+	Retries          int                        `json:"retries,omitempty"`            // Failed attempts retried before the final one
+	Continued        int                        `json:"continued,omitempty"`          // Continuation requests after the answer hit max_tokens
+	Truncated        bool                       `json:"truncated,omitempty"`          // Still cut off at max_tokens after the continuations
+	PageHash         string                     `json:"page_hash,omitempty"`          // Fingerprint of the rendered page and its text layer
+	ReusedFrom       string                     `json:"reused_from,omitempty"`        // Earlier result the analysis was copied from
+	DuplicateOf      int                        `json:"duplicate_of,omitempty"`       // Identical page of this run the analysis was copied from
+	SavedInTokens    int                        `json:"saved_input_tokens,omitempty"` // Tokens of the original analysis of a copied page
+	SavedOutTokens   int                        `json:"saved_output_tokens,omitempty"`
+	InputBreakdown   *TokenAttribution          `json:"input_breakdown,omitempty"`   // Input tokens of the analysis split into document and prompt
+	SavedCost        float64                    `json:"saved_cost,omitempty"`        // What the original analysis of a copied page cost
+	Language         string                     `json:"language,omitempty"`          // Output language when not English
+	OriginalAnalysis string                     `json:"original_analysis,omitempty"` // English analysis before the translation pass
+	Error            string                     `json:"error,omitempty"`
+	ErrorClass       string                     `json:"error_class,omitempty"`    // Kind of failure, e.g. timeout or invalid request
+	Compliance       []RuleResult               `json:"compliance,omitempty"`     // Results of -rules on this page
+	Validation       []ValidationResult         `json:"validation,omitempty"`     // Text-layer values missing from the output (-validate)
+	Unverified       []UnverifiedValue          `json:"unverified,omitempty"`     // Values -ground removed from the output
+	Escalation       *Escalation                `json:"escalation,omitempty"`     // Rerun with -escalate-model
+	Blocked          *BlockedStatus             `json:"blocked,omitempty"`        // Refused by the model or blocked by a safety filter
+	Classification   *PageClassification        `json:"classification,omitempty"` // Page type from the -two-stage first call
+	Extensions       map[string]json.RawMessage `json:"extensions,omitempty"`     // Output of custom page stages, by stage name
+	Timestamp        time.Time                  `json:"timestamp"`
+	StructuredData
+}
+
+// NewChunk returns the chunk of a run covering startPage to endPage
+// (1-indexed), without an analysis yet
+func NewChunk(chunkNumber, startPage, endPage int) ChunkAnalysis {
+	return ChunkAnalysis{ChunkNumber: chunkNumber, StartPage: startPage, EndPage: endPage}
+}
+
+// TokenAttribution splits input tokens between the document and the prompt,
+// to show whether savings should come from smaller pages or shorter prompts
+type TokenAttribution struct {
+	DocumentTokens int     `json:"document_tokens"` // Page PDF, images, or text layer, with message framing and -structured repair turns
+	PromptTokens   int     `json:"prompt_tokens"`   // Instructions, and the extraction tool with -structured
+	DocumentCost   float64 `json:"document_cost"`
+	PromptCost     float64 `json:"prompt_cost"`
+}
+
+// Add sums another attribution into a
+func (a *TokenAttribution) Add(other TokenAttribution) {
+	a.DocumentTokens += other.DocumentTokens
+	a.PromptTokens += other.PromptTokens
+	a.DocumentCost += other.DocumentCost
+	a.PromptCost += other.PromptCost
+}
+
+// PageClassification is the result of the cheap first call of -two-stage
+type PageClassification struct {
+	Type         string  `json:"type"`
+	Summary      string  `json:"summary,omitempty"`
+	Model        string  `json:"model"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	TotalCost    float64 `json:"total_cost"` // Already included in the chunk cost
+	Error        string  `json:"error,omitempty"`
+}
+
+// Escalation records a page that was analyzed again with a stronger model
+// because the first output looked incomplete
+type Escalation struct {
+	FromModel    string   `json:"from_model"`
+	Model        string   `json:"model"`
+	Reasons      []string `json:"reasons"`             // Quality problems of the first output
+	Accepted     bool     `json:"accepted"`            // The escalated output replaced the first one
+	Remaining    []string `json:"remaining,omitempty"` // Quality problems of the escalated output
+	InputTokens  int      `json:"input_tokens"`
+	OutputTokens int      `json:"output_tokens"`
+	TotalCost    float64  `json:"total_cost"` // Already included in the chunk cost
+	Error        string   `json:"error,omitempty"`
+}
+
+// RuleResult is the outcome of one compliance rule on one page
+type RuleResult struct {
+	Rule    string `json:"rule"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"` // Why the rule failed
+}
+
+// BlockedStatus records a page the model refused or a provider's safety
+// filter blocked, and whether -neutral-retry recovered it. A page that stays
+// blocked also has error_class "blocked".
+type BlockedStatus struct {
+	Provider     string `json:"provider"`                // anthropic or gemini
+	Reason       string `json:"reason"`                  // refused or blocked
+	Detail       string `json:"detail"`                  // The provider's stop, finish, or block reason
+	NeutralRetry bool   `json:"neutral_retry,omitempty"` // Sent once more with the neutral prompt
+	Recovered    bool   `json:"recovered"`               // The neutral retry produced the analysis
+}
+
+// UnverifiedValue is a part number or dimension that -ground could not
+// verify against the page and moved out of its output
+type UnverifiedValue struct {
+	Value  string `json:"value"`
+	Field  string `json:"field"`  // Where it was, e.g. analysis or bom_items[3].part_number
+	Reason string `json:"reason"` // "marked by the model" or "not in the text layer"
+}
+
+// ValidationResult lists values a validator found in a page's text layer
+// that are missing from the model output. Pages with results likely lost
+// data and should be reviewed or rerun.
+type ValidationResult struct {
+	Validator string   `json:"validator"`
+	Target    string   `json:"target"`  // Output section that should contain the values
+	Found     int      `json:"found"`   // Distinct matches in the text layer
+	Missing   []string `json:"missing"` // Matches absent from the target
+}
@@ -0,0 +1,108 @@
+package results
+
+// MasterBOMItem is one part aggregated across all pages of the document
+type MasterBOMItem struct {
+	PartNumber    string          `json:"part_number,omitempty"`
+	Description   string          `json:"description,omitempty"`
+	TotalQuantity float64         `json:"total_quantity"`
+	Material      string          `json:"material,omitempty"`
+	Finish        string          `json:"finish,omitempty"`
+	Occurrences   []BOMOccurrence `json:"occurrences"`
+	Conflicts     []string        `json:"conflicts,omitempty"` // Differing materials, finishes, or descriptions
+}
+
+// BOMOccurrence records where a part was listed
+type BOMOccurrence struct {
+	Page       int     `json:"page"`
+	ItemNumber string  `json:"item_number,omitempty"`
+	Quantity   float64 `json:"quantity"`
+}
+
+// Pages returns the distinct pages the part appears on, in order
+func (item MasterBOMItem) Pages() []int {
+	var pages []int
+	for _, o := range item.Occurrences {
+		if len(pages) == 0 || pages[len(pages)-1] != o.Page {
+			pages = append(pages, o.Page)
+		}
+	}
+	return pages
+}
+
+// Discrepancy is a cross-page inconsistency flagged for human review
+type Discrepancy struct {
+	Kind    string `json:"kind"` // quantity, dimension, revision, or bom
+	Pages   []int  `json:"pages"`
+	Message string `json:"message"`
+}
+
+// TitleBlockException is a page whose title block lacks sign-off or revision
+// information, a recurring audit finding
+type TitleBlockException struct {
+	Page          int      `json:"page"`
+	DrawingNumber string   `json:"drawing_number,omitempty"`
+	Missing       []string `json:"missing"` // Field labels, or "title block" when none was found
+}
+
+// IndexEntry maps one identifier found in the document to the pages it appears on
+type IndexEntry struct {
+	Kind     string `json:"kind"` // part_number or drawing_number
+	Value    string `json:"value"`
+	Revision string `json:"revision,omitempty"` // Drawing revision from the title block
+	Pages    []int  `json:"pages"`
+}
+
+// StandardReference is one standard or specification cited in the document
+type StandardReference struct {
+	Body        string   `json:"body"`               // Issuing body: ISO, ASME, DIN, MIL, ...
+	Designation string   `json:"designation"`        // e.g. "ISO 2768-1" or "MIL-STD-810"
+	Editions    []string `json:"editions,omitempty"` // Years or revision letters as cited
+	Pages       []int    `json:"pages"`
+}
+
+// AssemblyCost is the estimated material cost of the master BOM, priced from
+// an external price list
+type AssemblyCost struct {
+	PriceList string             `json:"price_list"`         // File the prices were read from
+	Currency  string             `json:"currency,omitempty"` // Currency of the price list
+	Lines     []AssemblyCostLine `json:"lines"`
+	Total     float64            `json:"total"`              // Sum of the priced lines
+	Unpriced  int                `json:"unpriced,omitempty"` // Parts without a price match
+}
+
+// AssemblyCostLine is one master BOM part with its price
+type AssemblyCostLine struct {
+	PartNumber    string  `json:"part_number,omitempty"`
+	Description   string  `json:"description,omitempty"`
+	Quantity      float64 `json:"quantity"`
+	UnitPrice     float64 `json:"unit_price"`
+	ExtendedPrice float64 `json:"extended_price"`
+	Priced        bool    `json:"priced"` // False when the price list has no match
+}
+
+// Savings is what the pages copied instead of analyzed would have cost,
+// by where they were copied from. The run uses no prompt caching or batch
+// API, so copied pages are its only savings.
+type Savings struct {
+	Cache        *SavingsLine `json:"cache,omitempty"`      // Pages found in the -cache page cache
+	Reuse        *SavingsLine `json:"reuse,omitempty"`      // Pages copied from -reuse results
+	Duplicates   *SavingsLine `json:"duplicates,omitempty"` // Identical pages copied within the run
+	Total        SavingsLine  `json:"total"`
+	UncachedCost float64      `json:"uncached_cost"` // What the run would have cost with every page analyzed
+}
+
+// SavingsLine counts copied pages and the tokens and dollars they saved
+type SavingsLine struct {
+	Pages        int     `json:"pages"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+}
+
+// Add counts one copied chunk
+func (l *SavingsLine) Add(chunk ChunkAnalysis) {
+	l.Pages++
+	l.InputTokens += chunk.SavedInTokens
+	l.OutputTokens += chunk.SavedOutTokens
+	l.Cost += chunk.SavedCost
+}
@@ -0,0 +1,53 @@
+package results
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Values of ChunkAnalysis.ErrorClass, the kind of failure of a page
+const (
+	ErrorRateLimited    = "rate limited"      // 429: the provider's rate limit
+	ErrorOverloaded     = "overloaded"        // 529: the API was busy
+	ErrorServer         = "server error"      // Other 5xx
+	ErrorTimeout        = "timeout"           // Client timeout or 408
+	ErrorNetwork        = "network error"     // Connection refused, reset, or DNS failure
+	ErrorTooLarge       = "request too large" // 413 or a prompt over the context window
+	ErrorInvalidRequest = "invalid request"   // Other 4xx
+	ErrorBlocked        = "blocked"           // The model refused or a safety filter blocked the page
+	ErrorBadResponse    = "bad response"      // A response without text or one that could not be parsed
+	ErrorCanceled       = "canceled"          // The run was interrupted
+	ErrorOther          = "error"             // Anything else
+)
+
+// Errors matched by ChunkAnalysis.Err for the classes that have one
+var (
+	ErrRateLimited     = errors.New("rate limited")
+	ErrPayloadTooLarge = errors.New("payload too large")
+	ErrBlocked         = errors.New("blocked")
+)
+
+// classErrors maps error classes to their sentinel
+var classErrors = map[string]error{
+	ErrorRateLimited: ErrRateLimited,
+	ErrorTooLarge:    ErrPayloadTooLarge,
+	ErrorBlocked:     ErrBlocked,
+}
+
+// ClassError returns the sentinel of an error class, or nil when it has none
+func ClassError(class string) error {
+	return classErrors[class]
+}
+
+// Err returns the failure of a page as an error that matches the sentinel
+// of its class, e.g. errors.Is(chunk.Err(), ErrRateLimited), or nil when
+// the page was analyzed
+func (c ChunkAnalysis) Err() error {
+	if c.Error == "" {
+		return nil
+	}
+	if sentinel := ClassError(c.ErrorClass); sentinel != nil {
+		return fmt.Errorf("%s: %w", c.Error, sentinel)
+	}
+	return errors.New(c.Error)
+}
@@ -0,0 +1,182 @@
+// Package results defines the analysis results design-ant writes as JSON:
+// a FullAnalysisResult for the document with a ChunkAnalysis per page. The
+// JSON tags are stable within a schema version, so tools that read
+// {pdf-name}_analysis.json or the JSONL stream can import these types
+// instead of redefining them; Decode reads files of earlier versions too.
+package results
+
+import "time"
+
+// FullAnalysisResult represents the complete analysis result
+type FullAnalysisResult struct {
+	SchemaVersion        int                   `json:"schema_version"`
+	Build                *BuildInfo            `json:"build,omitempty"`  // design-ant build that wrote the result
+	Prompt               *PromptInfo           `json:"prompt,omitempty"` // Prompt pack the pages were analyzed with
+	PDFPath              string                `json:"pdf_path"`
+	TotalPages           int                   `json:"total_pages"`
+	TotalChunks          int                   `json:"total_chunks"`
+	Interrupted          bool                  `json:"interrupted,omitempty"` // Run was stopped early; Chunks holds the pages finished so far
+	Chunks               []ChunkAnalysis       `json:"chunks"`
+	Consolidated         *ConsolidatedAnalysis `json:"consolidated_analysis,omitempty"`
+	ExecutiveSummary     *ExecutiveSummary     `json:"executive_summary,omitempty"`
+	MasterBOM            []MasterBOMItem       `json:"master_bom,omitempty"`             // BOM aggregated across pages (-structured)
+	Discrepancies        []Discrepancy         `json:"discrepancies,omitempty"`          // Cross-page inconsistencies for review
+	TitleBlockExceptions []TitleBlockException `json:"title_block_exceptions,omitempty"` // Pages missing sign-off or revision fields
+	Index                []IndexEntry          `json:"index,omitempty"`                  // Part and drawing numbers with their pages
+	Standards            []StandardReference   `json:"standards,omitempty"`              // Standards and specifications cited, with their pages
+	AssemblyCost         *AssemblyCost         `json:"assembly_cost,omitempty"`          // Master BOM priced from -prices
+	Pricing              *PricingTable         `json:"pricing,omitempty"`                // Model prices the costs were computed with
+	TotalInputTokens     int                   `json:"total_input_tokens"`
+	TotalOutputTokens    int                   `json:"total_output_tokens"`
+	TotalInputCost       float64               `json:"total_input_cost"`
+	TotalOutputCost      float64               `json:"total_output_cost"`
+	TotalCost            float64               `json:"total_cost"`
+	DuplicateSavings     float64               `json:"duplicate_savings,omitempty"` // Cost avoided by copying identical pages within the run
+	InputBreakdown       *TokenAttribution     `json:"input_breakdown,omitempty"`   // Page input tokens split into document and prompt
+	Savings              *Savings              `json:"savings,omitempty"`           // Tokens and cost avoided by copying cached, reused, and duplicate pages
+	ProcessingTime       string                `json:"processing_time"`
+	GeneratedAt          time.Time             `json:"generated_at"`
+}
+
+// New returns the result of a document with the given chunks, stamped with
+// the current schema version and with its totals summed
+func New(pdfPath string, totalPages int, chunks []ChunkAnalysis) *FullAnalysisResult {
+	result := &FullAnalysisResult{
+		SchemaVersion: SchemaVersion,
+		PDFPath:       pdfPath,
+		TotalPages:    totalPages,
+		Chunks:        chunks,
+		GeneratedAt:   time.Now(),
+	}
+	result.SumTotals()
+	return result
+}
+
+// SumTotals sets the chunk count and the token and cost totals from the
+// chunks, the consolidated analysis, and the executive summary
+func (r *FullAnalysisResult) SumTotals() {
+	r.TotalChunks = len(r.Chunks)
+	r.TotalInputTokens, r.TotalOutputTokens = 0, 0
+	r.TotalInputCost, r.TotalOutputCost = 0, 0
+	for _, chunk := range r.Chunks {
+		r.TotalInputTokens += chunk.InputTokens
+		r.TotalOutputTokens += chunk.OutputTokens
+		r.TotalInputCost += chunk.InputCost
+		r.TotalOutputCost += chunk.OutputCost
+	}
+	if c := r.Consolidated; c != nil {
+		r.TotalInputTokens += c.InputTokens
+		r.TotalOutputTokens += c.OutputTokens
+		r.TotalInputCost += c.InputCost
+		r.TotalOutputCost += c.OutputCost
+	}
+	if s := r.ExecutiveSummary; s != nil {
+		r.TotalInputTokens += s.InputTokens
+		r.TotalOutputTokens += s.OutputTokens
+		r.TotalInputCost += s.InputCost
+		r.TotalOutputCost += s.OutputCost
+	}
+	r.TotalCost = r.TotalInputCost + r.TotalOutputCost
+}
+
+// BuildInfo identifies the design-ant build that wrote a result
+type BuildInfo struct {
+	Version    string `json:"version"`               // Release, e.g. 1.4.0, or "dev"
+	Commit     string `json:"commit,omitempty"`      // VCS revision the binary was built from
+	CommitTime string `json:"commit_time,omitempty"` // Time of that revision, RFC 3339
+	Modified   bool   `json:"modified,omitempty"`    // Built with uncommitted changes
+	GoVersion  string `json:"go_version,omitempty"`
+}
+
+// String returns the version with the short commit, e.g. "1.4.0 (3f2a9c1)"
+func (b BuildInfo) String() string {
+	s := b.Version
+	if b.Commit != "" {
+		commit := b.Commit
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+		if b.Modified {
+			commit += "+modified"
+		}
+		s += " (" + commit + ")"
+	}
+	return s
+}
+
+// PromptInfo identifies the prompt pack of a run, so results of prompt
+// versions can be told apart and compared
+type PromptInfo struct {
+	Pack    string `json:"pack"`              // Pack or template name, e.g. "mechanical"
+	Version string `json:"version,omitempty"` // Version the template declares
+	Hash    string `json:"hash"`              // SHA-256 of the template text, first 12 hex digits
+}
+
+// String returns the pack with its version and hash, e.g. "pid v2 (3f2a9c1b0d4e)"
+func (p PromptInfo) String() string {
+	s := p.Pack
+	if p.Version != "" {
+		s += " v" + p.Version
+	}
+	return s + " (" + p.Hash + ")"
+}
+
+// ConsolidatedAnalysis represents the final consolidated analysis
+type ConsolidatedAnalysis struct {
+	Analysis       string        `json:"analysis"`
+	InputTokens    int           `json:"input_tokens"`
+	OutputTokens   int           `json:"output_tokens"`
+	InputCost      float64       `json:"input_cost"`
+	OutputCost     float64       `json:"output_cost"`
+	TotalCost      float64       `json:"total_cost"`
+	ProcessingTime string        `json:"processing_time"`
+	Levels         []ReduceLevel `json:"levels,omitempty"` // Intermediate group summaries, lowest level first
+	Error          string        `json:"error,omitempty"`
+	Timestamp      time.Time     `json:"timestamp"`
+}
+
+// ReduceLevel holds the intermediate summaries produced by one reduce step
+type ReduceLevel struct {
+	Level     int            `json:"level"`
+	Summaries []GroupSummary `json:"summaries"`
+}
+
+// GroupSummary is the summary of a consecutive range of pages
+type GroupSummary struct {
+	StartPage    int     `json:"start_page"`
+	EndPage      int     `json:"end_page"`
+	Summary      string  `json:"summary"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	TotalCost    float64 `json:"total_cost"`
+}
+
+// ExecutiveSummary is a one-page overview for readers who will not go
+// through the page analyses: key components, part counts, critical notes
+type ExecutiveSummary struct {
+	Summary        string    `json:"summary"`
+	Model          string    `json:"model"`
+	InputTokens    int       `json:"input_tokens"`
+	OutputTokens   int       `json:"output_tokens"`
+	InputCost      float64   `json:"input_cost"`
+	OutputCost     float64   `json:"output_cost"`
+	TotalCost      float64   `json:"total_cost"`
+	ProcessingTime string    `json:"processing_time"`
+	Error          string    `json:"error,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// PricingTable is the content of a pricing file. Results record the table
+// they were priced with, so their costs can be audited after prices change.
+type PricingTable struct {
+	Source       string                      `json:"source"`            // "embedded" or the -pricing-file path
+	Updated      string                      `json:"updated,omitempty"` // When the prices were last checked, e.g. 2025-01-31
+	DefaultModel string                      `json:"default_model"`     // Prices used for models not in the table
+	Models       map[string]AnthropicPricing `json:"models"`
+}
+
+// AnthropicPricing holds pricing information for different models
+type AnthropicPricing struct {
+	InputPricePerMTokens  float64 `json:"input_per_mtok"`  // Price per million input tokens
+	OutputPricePerMTokens float64 `json:"output_per_mtok"` // Price per million output tokens
+}
@@ -0,0 +1,202 @@
+package results
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fullResult returns a result with every optional field set
+func fullResult() *FullAnalysisResult {
+	stamp := time.Date(2025, 3, 14, 9, 26, 53, 589793238, time.UTC)
+	upper, lower := 0.05, -0.05
+	chunk := ChunkAnalysis{
+		ChunkNumber: 1, StartPage: 1, EndPage: 1,
+		Analysis:    "## Bracket\nTwo holes, [unverified #1].",
+		InputTokens: 1500, OutputTokens: 400, InputCost: 0.0045, OutputCost: 0.006, TotalCost: 0.0105,
+		ProcessingTime: "2.5s", InputMode: "pdf", RouteReason: "vector drawing", Rendered: "png 150 dpi",
+		Retries: 1, Continued: 2, Truncated: true, PageHash: "ab12cd34", ReusedFrom: "old_analysis.json",
+		DuplicateOf: 3, SavedInTokens: 1200, SavedOutTokens: 300, SavedCost: 0.008,
+		InputBreakdown:   &TokenAttribution{DocumentTokens: 1000, PromptTokens: 500, DocumentCost: 0.003, PromptCost: 0.0015},
+		Language:         "de",
+		OriginalAnalysis: "## Bracket",
+		Error:            "response refused",
+		ErrorClass:       "blocked",
+		Compliance:       []RuleResult{{Rule: "revision present", Passed: false, Message: "no revision"}},
+		Validation:       []ValidationResult{{Validator: "part numbers", Target: "bom", Found: 3, Missing: []string{"P-1"}}},
+		Unverified:       []UnverifiedValue{{Value: "P-10234", Field: "analysis", Reason: "marked by the model"}},
+		Escalation:       &Escalation{FromModel: "haiku", Model: "sonnet", Reasons: []string{"no BOM rows"}, Accepted: true, Remaining: []string{"no notes"}, InputTokens: 10, OutputTokens: 5, TotalCost: 0.001, Error: "timeout"},
+		Blocked:          &BlockedStatus{Provider: "anthropic", Reason: "refused", Detail: "stop_reason refusal", NeutralRetry: true, Recovered: true},
+		Classification:   &PageClassification{Type: "part", Summary: "a bracket", Model: "haiku", InputTokens: 100, OutputTokens: 10, TotalCost: 0.0001, Error: "none"},
+		Extensions:       map[string]json.RawMessage{"cost-lookup": json.RawMessage(`{"price":12.5}`)},
+		Timestamp:        stamp,
+		StructuredData: StructuredData{
+			Metadata:        &DrawingMetadata{DrawingNumber: "D-100", Title: "Bracket", Revision: "B", Date: "2025-03-14", Scale: "1:2"},
+			BOMItems:        []BOMItem{{ItemNumber: "1", PartNumber: "P-10234", Description: "Bolt", Quantity: 4, Material: "steel"}},
+			Dimensions:      []Dimension{{Feature: "bore", Type: "diameter", Value: "25.4", Unit: "mm", Tolerance: "±0.05", Normalized: &NormalizedValue{Value: 25.4, Unit: "mm", Upper: &upper, Lower: &lower, SourceUnit: "mm", UnitSource: "dimension"}}},
+			Notes:           []string{"Break all edges"},
+			Welds:           []WeldSymbol{{Type: "fillet", Size: "5 mm", AllAround: true}},
+			SurfaceFinishes: []SurfaceFinish{{Parameter: "Ra", Value: "1.6", Unit: "µm"}},
+			Instruments:     []Instrument{{Tag: "FIC-101"}},
+			Lines:           []PipingLine{{LineNumber: `2"-P-1001-A1A`}},
+			Equipment:       []Equipment{{Tag: "P-101A"}},
+			Components:      []ElectricalComponent{{Designator: "R12", Value: "10k"}},
+			Nets:            []Net{{Name: "+24V", Nodes: []string{"U3.5"}}},
+			Rooms:           []Room{{Number: "101", Name: "Office"}},
+			Openings:        []Opening{{Mark: "D101", Kind: "door"}},
+		},
+	}
+	result := &FullAnalysisResult{
+		SchemaVersion: SchemaVersion,
+		Build:         &BuildInfo{Version: "1.4.0", Commit: "3f2a9c1b0d4e", CommitTime: "2025-03-01T10:00:00Z", Modified: true, GoVersion: "go1.24.0"},
+		Prompt:        &PromptInfo{Pack: "mechanical", Version: "2", Hash: "3f2a9c1b0d4e"},
+		PDFPath:       "bracket.pdf",
+		TotalPages:    1,
+		Interrupted:   true,
+		Chunks:        []ChunkAnalysis{chunk},
+		Consolidated: &ConsolidatedAnalysis{Analysis: "One bracket.", ProcessingTime: "1s", Error: "partial", Timestamp: stamp,
+			Levels: []ReduceLevel{{Level: 1, Summaries: []GroupSummary{{StartPage: 1, EndPage: 1, Summary: "bracket"}}}}},
+		ExecutiveSummary:     &ExecutiveSummary{Summary: "Bracket.", Model: "sonnet", ProcessingTime: "1s", Timestamp: stamp},
+		MasterBOM:            []MasterBOMItem{{PartNumber: "P-10234", TotalQuantity: 4, Occurrences: []BOMOccurrence{{Page: 1, ItemNumber: "1", Quantity: 4}}, Conflicts: []string{"material"}}},
+		Discrepancies:        []Discrepancy{{Kind: "quantity", Pages: []int{1, 2}, Message: "4 vs 6"}},
+		TitleBlockExceptions: []TitleBlockException{{Page: 1, Missing: []string{"checked by"}}},
+		Index:                []IndexEntry{{Kind: "part_number", Value: "P-10234", Pages: []int{1}}},
+		Standards:            []StandardReference{{Body: "ISO", Designation: "ISO 2768-1", Editions: []string{"1989"}, Pages: []int{1}}},
+		AssemblyCost:         &AssemblyCost{PriceList: "prices.csv", Currency: "EUR", Lines: []AssemblyCostLine{{PartNumber: "P-10234", Quantity: 4, UnitPrice: 0.5, ExtendedPrice: 2, Priced: true}}, Total: 2, Unpriced: 1},
+		Pricing:              &PricingTable{Source: "embedded", Updated: "2025-01-31", DefaultModel: "sonnet", Models: map[string]AnthropicPricing{"sonnet": {InputPricePerMTokens: 3, OutputPricePerMTokens: 15}}},
+		DuplicateSavings:     0.01,
+		InputBreakdown:       &TokenAttribution{DocumentTokens: 1000, PromptTokens: 500},
+		Savings:              &Savings{Cache: &SavingsLine{Pages: 1, Cost: 0.01}, Total: SavingsLine{Pages: 1, Cost: 0.01}, UncachedCost: 0.02},
+		ProcessingTime:       "3.5s",
+		GeneratedAt:          stamp,
+	}
+	result.SumTotals()
+	return result
+}
+
+func TestResultRoundTrip(t *testing.T) {
+	for name, result := range map[string]*FullAnalysisResult{
+		"full":    fullResult(),
+		"minimal": New("empty.pdf", 0, nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			result.GeneratedAt = result.GeneratedAt.UTC()
+			data, err := json.Marshal(result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded FullAnalysisResult
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(&decoded, result) {
+				t.Errorf("round trip changed the result:\n got %+v\nwant %+v", decoded, *result)
+			}
+			viaDecode, err := Decode(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(viaDecode, result) {
+				t.Errorf("Decode changed the result:\n got %+v\nwant %+v", *viaDecode, *result)
+			}
+		})
+	}
+}
+
+func TestChunkRoundTrip(t *testing.T) {
+	chunk := fullResult().Chunks[0]
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ChunkAnalysis
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, chunk) {
+		t.Errorf("round trip changed the chunk:\n got %+v\nwant %+v", decoded, chunk)
+	}
+
+	// The structured fields are embedded, not nested
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"bom_items", "metadata", "dimensions", "openings"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("%s missing from the chunk JSON", key)
+		}
+	}
+	if _, ok := fields["StructuredData"]; ok {
+		t.Error("structured data written as a nested object")
+	}
+}
+
+// jsonKeys returns the top-level keys v marshals to
+func jsonKeys(t *testing.T, v interface{}) []string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func TestOmitEmpty(t *testing.T) {
+	chunkKeys := []string{"analysis", "chunk_number", "end_page", "input_cost", "input_tokens", "output_cost",
+		"output_tokens", "processing_time", "start_page", "timestamp", "total_cost"}
+	if got := jsonKeys(t, NewChunk(1, 1, 1)); !slices.Equal(got, chunkKeys) {
+		t.Errorf("empty chunk keys = %v, want %v", got, chunkKeys)
+	}
+	resultKeys := []string{"chunks", "generated_at", "pdf_path", "processing_time", "schema_version", "total_chunks",
+		"total_cost", "total_input_cost", "total_input_tokens", "total_output_cost", "total_output_tokens", "total_pages"}
+	if got := jsonKeys(t, &FullAnalysisResult{}); !slices.Equal(got, resultKeys) {
+		t.Errorf("empty result keys = %v, want %v", got, resultKeys)
+	}
+
+	// Set optional fields are written, also when only their zero-able members are set
+	result := fullResult()
+	keys := jsonKeys(t, result)
+	for _, key := range []string{"build", "prompt", "interrupted", "consolidated_analysis", "executive_summary",
+		"master_bom", "discrepancies", "title_block_exceptions", "index", "standards", "assembly_cost", "pricing",
+		"duplicate_savings", "input_breakdown", "savings"} {
+		if !slices.Contains(keys, key) {
+			t.Errorf("%s missing from the full result JSON", key)
+		}
+	}
+	if got := jsonKeys(t, &PromptInfo{Pack: "mechanical", Hash: "3f2a9c1b0d4e"}); !slices.Equal(got, []string{"hash", "pack"}) {
+		t.Errorf("prompt info without a version has keys %v", got)
+	}
+}
+
+func TestTimeRoundTrip(t *testing.T) {
+	// Offsets and nanoseconds survive; the zero time stays zero
+	stamp := time.Date(2025, 3, 14, 9, 26, 53, 589793238, time.FixedZone("CET", 3600))
+	for _, when := range []time.Time{stamp, {}} {
+		data, err := json.Marshal(ChunkAnalysis{Timestamp: when})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded ChunkAnalysis
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Timestamp.Equal(when) || decoded.Timestamp.IsZero() != when.IsZero() {
+			t.Errorf("timestamp %v decoded as %v", when, decoded.Timestamp)
+		}
+		if _, offset := decoded.Timestamp.Zone(); !when.IsZero() && offset != 3600 {
+			t.Errorf("timestamp offset %d, want 3600", offset)
+		}
+	}
+}
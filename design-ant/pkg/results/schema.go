@@ -0,0 +1,75 @@
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SchemaVersion is the version of the FullAnalysisResult JSON written by this build.
+// Bump it whenever fields are renamed, removed, or change meaning, and add a migration.
+//
+//	1: original format (no schema_version field)
+//	2: per-chunk input_mode/route_reason and optional structured fields
+const SchemaVersion = 2
+
+// schemaMigrations upgrade a decoded result document from version N to N+1
+var schemaMigrations = map[int]func(doc map[string]interface{}) error{
+	1: migrateV1ToV2,
+}
+
+// Decode reads result JSON written by any supported version and upgrades it
+// to the current schema
+func Decode(data []byte) (*FullAnalysisResult, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid result JSON: %v", err)
+	}
+
+	version := 1
+	if v, ok := doc["schema_version"].(float64); ok {
+		version = int(v)
+	}
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("result uses schema version %d, this build only supports up to %d; upgrade the tool",
+			version, SchemaVersion)
+	}
+
+	for ; version < SchemaVersion; version++ {
+		migrate, ok := schemaMigrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration from schema version %d", version)
+		}
+		if err := migrate(doc); err != nil {
+			return nil, fmt.Errorf("migrating schema version %d: %v", version, err)
+		}
+		doc["schema_version"] = version + 1
+	}
+
+	upgraded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var result FullAnalysisResult
+	if err := json.Unmarshal(upgraded, &result); err != nil {
+		return nil, fmt.Errorf("invalid result JSON: %v", err)
+	}
+	return &result, nil
+}
+
+// migrateV1ToV2 marks chunks from before page routing as submitted via PDF
+func migrateV1ToV2(doc map[string]interface{}) error {
+	chunks, ok := doc["chunks"].([]interface{})
+	if !ok {
+		return fmt.Errorf("missing chunks array")
+	}
+	for _, c := range chunks {
+		chunk, ok := c.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("chunk is not an object")
+		}
+		if _, ok := chunk["input_mode"]; !ok {
+			chunk["input_mode"] = "pdf"
+		}
+	}
+	return nil
+}
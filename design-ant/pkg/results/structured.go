@@ -0,0 +1,170 @@
+package results
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// StructuredData holds the typed extraction for a chunk. All fields are
+// optional and only populated when structured output was requested and parsed.
+type StructuredData struct {
+	Metadata   *DrawingMetadata `json:"metadata,omitempty"`
+	BOMItems   []BOMItem        `json:"bom_items,omitempty"`
+	Dimensions []Dimension      `json:"dimensions,omitempty"`
+	Notes      []string         `json:"notes,omitempty"`
+
+	Welds           []WeldSymbol    `json:"welds,omitempty"`            // Only with -welds
+	SurfaceFinishes []SurfaceFinish `json:"surface_finishes,omitempty"` // Only with -welds
+
+	Instruments []Instrument          `json:"instruments,omitempty"` // Only with -prompt-pack pid
+	Lines       []PipingLine          `json:"lines,omitempty"`       // Only with -prompt-pack pid
+	Equipment   []Equipment           `json:"equipment,omitempty"`   // Only with -prompt-pack pid
+	Components  []ElectricalComponent `json:"components,omitempty"`  // Only with -prompt-pack electrical
+	Nets        []Net                 `json:"nets,omitempty"`        // Only with -prompt-pack electrical
+	Rooms       []Room                `json:"rooms,omitempty"`       // Only with -prompt-pack architectural
+	Openings    []Opening             `json:"openings,omitempty"`    // Only with -prompt-pack architectural
+}
+
+// DrawingMetadata holds title block information
+type DrawingMetadata struct {
+	DrawingNumber string `json:"drawing_number,omitempty"`
+	Title         string `json:"title,omitempty"`
+	Revision      string `json:"revision,omitempty"`
+	DrawnBy       string `json:"drawn_by,omitempty"`
+	CheckedBy     string `json:"checked_by,omitempty"`
+	ApprovedBy    string `json:"approved_by,omitempty"`
+	Date          string `json:"date,omitempty"`
+	Scale         string `json:"scale,omitempty"`
+	Projection    string `json:"projection,omitempty"`
+	Material      string `json:"material,omitempty"`
+}
+
+// BOMItem is a single bill of materials row
+type BOMItem struct {
+	ItemNumber  string  `json:"item_number,omitempty"`
+	PartNumber  string  `json:"part_number,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Quantity    float64 `json:"quantity"`
+	Material    string  `json:"material,omitempty"`
+	Finish      string  `json:"finish,omitempty"`
+}
+
+// Dimension is a single dimension callout as printed on the drawing
+type Dimension struct {
+	Feature   string `json:"feature,omitempty"`
+	Type      string `json:"type,omitempty"` // linear, diameter, radius, angle, depth, thread
+	Value     string `json:"value"`
+	Unit      string `json:"unit,omitempty"`
+	Tolerance string `json:"tolerance,omitempty"`
+
+	Normalized *NormalizedValue `json:"normalized,omitempty"` // Only with -units
+}
+
+// NormalizedValue is a dimension converted to the configured unit system.
+// Upper and lower are the tolerance limits as deviations from the nominal
+// value, in the same unit; both are nil when the tolerance could not be read.
+type NormalizedValue struct {
+	Value      float64  `json:"value"`
+	Unit       string   `json:"unit"` // mm, in, or deg
+	Upper      *float64 `json:"upper,omitempty"`
+	Lower      *float64 `json:"lower,omitempty"`
+	SourceUnit string   `json:"source_unit"`          // Unit as found on the drawing
+	UnitSource string   `json:"unit_source"`          // dimension, value, page, or document
+	Conversion string   `json:"conversion,omitempty"` // e.g. "1.25 in → 31.75 mm"
+}
+
+// String formats the value with its limits, e.g. "31.75 mm +0.05/-0.05"
+func (n NormalizedValue) String() string {
+	text := strconv.FormatFloat(n.Value, 'f', -1, 64) + " " + n.Unit
+	if n.Upper != nil && n.Lower != nil {
+		text += fmt.Sprintf(" %+g/%+g", *n.Upper, *n.Lower)
+	}
+	return text
+}
+
+// WeldSymbol is one weld symbol as drawn (AWS A2.4 / ISO 2553)
+type WeldSymbol struct {
+	Location  string `json:"location,omitempty"` // Joint or feature the arrow points to
+	Type      string `json:"type"`               // fillet, v_groove, plug, spot, ... or other
+	Side      string `json:"side,omitempty"`     // arrow, other, or both
+	Size      string `json:"size,omitempty"`     // Leg, throat (a), or depth, with unit
+	Length    string `json:"length,omitempty"`
+	Pitch     string `json:"pitch,omitempty"` // Center-to-center spacing of intermittent welds
+	AllAround bool   `json:"all_around"`      // Circle at the arrow/reference line junction
+	FieldWeld bool   `json:"field_weld"`      // Flag: weld made on site, not in the shop
+	Contour   string `json:"contour,omitempty"`
+	Process   string `json:"process,omitempty"` // Tail reference, e.g. "GMAW" or "ISO 4063-135"
+	Note      string `json:"note,omitempty"`
+}
+
+// SurfaceFinish is one surface texture callout (ISO 1302 / ASME Y14.36)
+type SurfaceFinish struct {
+	Feature         string `json:"feature,omitempty"`
+	Parameter       string `json:"parameter,omitempty"` // Ra, Rz, Rmax, ...
+	Value           string `json:"value"`
+	Unit            string `json:"unit,omitempty"`             // µm or µin
+	MaterialRemoval string `json:"material_removal,omitempty"` // required, prohibited, or any
+	Lay             string `json:"lay,omitempty"`              // =, ⊥, X, M, C, R, P
+	Process         string `json:"process,omitempty"`          // e.g. "ground", "polished"
+	Allowance       string `json:"allowance,omitempty"`        // Machining allowance
+}
+
+// Instrument is one instrument bubble of a P&ID (ISA-5.1)
+type Instrument struct {
+	Tag         string `json:"tag"`                   // As drawn, e.g. "FIC-101"
+	Function    string `json:"function,omitempty"`    // Identification letters, e.g. "FIC"
+	Loop        string `json:"loop,omitempty"`        // Loop number, e.g. "101"
+	Location    string `json:"location,omitempty"`    // field, panel, control_system, or local_panel
+	Service     string `json:"service,omitempty"`     // Line or equipment it measures or acts on
+	Description string `json:"description,omitempty"` // e.g. "flow indicating controller"
+}
+
+// PipingLine is one line number label of a P&ID
+type PipingLine struct {
+	LineNumber string `json:"line_number"`          // As drawn, e.g. 2"-P-1001-A1A-IH
+	Size       string `json:"size,omitempty"`       // Nominal size with unit
+	Service    string `json:"service,omitempty"`    // Fluid or service code, e.g. "P"
+	Spec       string `json:"spec,omitempty"`       // Piping class, e.g. "A1A"
+	Insulation string `json:"insulation,omitempty"` // Insulation or tracing code, e.g. "IH"
+	From       string `json:"from,omitempty"`
+	To         string `json:"to,omitempty"`
+}
+
+// Equipment is one equipment item of a P&ID, e.g. a pump or a vessel
+type Equipment struct {
+	Tag         string `json:"tag"` // e.g. "P-101A"
+	Description string `json:"description,omitempty"`
+	Rating      string `json:"rating,omitempty"` // Design data as drawn, e.g. "50 m³/h @ 4 bar"
+}
+
+// ElectricalComponent is one component of an electrical schematic
+type ElectricalComponent struct {
+	Designator  string `json:"designator"`      // Reference designator, e.g. R12, U3, K1
+	Value       string `json:"value,omitempty"` // Value or rating, e.g. "10k", "24 VDC"
+	PartNumber  string `json:"part_number,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Net is one net of an electrical schematic with the pins it connects
+type Net struct {
+	Name  string   `json:"name"`  // Net label or wire number, e.g. "+24V" or "W105"
+	Nodes []string `json:"nodes"` // Pins as designator.pin, e.g. "U3.5"
+}
+
+// Room is one room or space of an architectural sheet
+type Room struct {
+	Number   string `json:"number,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Area     string `json:"area,omitempty"` // With unit, as drawn
+	Level    string `json:"level,omitempty"`
+	Finishes string `json:"finishes,omitempty"` // Floor, wall, and ceiling finishes
+}
+
+// Opening is one door or window, from the plan tags or a schedule
+type Opening struct {
+	Mark       string `json:"mark"`                  // Tag, e.g. "D101" or "W3"
+	Kind       string `json:"kind"`                  // door or window
+	Size       string `json:"size,omitempty"`        // Width x height as drawn
+	Type       string `json:"type,omitempty"`        // Type code from the schedule
+	FireRating string `json:"fire_rating,omitempty"` // e.g. "EI 30" or "90 min"
+}
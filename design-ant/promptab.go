@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"design-ant/pkg/pdfanalysis"
+)
+
+// PromptABReport compares two versions of the analysis prompt on the same
+// sample pages, so a prompt change can be judged before it is rolled out
+type PromptABReport struct {
+	PDFPath     string                 `json:"pdf_path"`
+	Pages       []int                  `json:"pages"` // Sampled page numbers
+	Model       string                 `json:"model"`
+	A           PromptABSide           `json:"a"`
+	B           PromptABSide           `json:"b"`
+	Similarity  float64                `json:"similarity"` // Mean word overlap of the A and B outputs, 0-1
+	Comparison  []PromptABPage         `json:"comparison"`
+	GeneratedAt time.Time              `json:"generated_at"`
+	Build       *pdfanalysis.BuildInfo `json:"build,omitempty"` // design-ant build that ran the comparison
+}
+
+// PromptABSide is one prompt version's run over the sample pages
+type PromptABSide struct {
+	Source       string                  `json:"source"` // Pack name or template file given with -a or -b
+	Prompt       *pdfanalysis.PromptInfo `json:"prompt"`
+	FailedPages  int                     `json:"failed_pages,omitempty"`
+	CachedPages  int                     `json:"cached_pages,omitempty"` // Taken from the page cache at no cost
+	InputTokens  int                     `json:"input_tokens"`
+	OutputTokens int                     `json:"output_tokens"`
+	TotalCost    float64                 `json:"total_cost"`
+}
+
+// PromptABPage is the output of both prompt versions for one sample page
+type PromptABPage struct {
+	Page       int      `json:"page"`
+	A          string   `json:"a"`
+	B          string   `json:"b"`
+	AError     string   `json:"a_error,omitempty"`
+	BError     string   `json:"b_error,omitempty"`
+	Similarity float64  `json:"similarity"`
+	OnlyInA    []string `json:"only_in_a,omitempty"` // Values with digits, e.g. part numbers and dimensions, found by A only
+	OnlyInB    []string `json:"only_in_b,omitempty"`
+}
+
+// runPromptAB analyzes a sample of pages with two prompt versions and writes
+// their outputs side by side with tokens, cost, and the values only one of
+// them found
+func runPromptAB(args []string) error {
+	fs := flag.NewFlagSet("prompt-ab", flag.ContinueOnError)
+	promptA := fs.String("a", "", "prompt version A: a prompt pack name or template file (default: the prompt of the analysis flags)")
+	promptB := fs.String("b", "", "prompt version B: a prompt pack name or template file")
+	sample := fs.Int("n", 3, "number of pages to sample, spread evenly over the document")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go run . prompt-ab [-a old.tmpl] -b new.tmpl [-n 3] <pdf-file> [analysis flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || *promptB == "" || *sample < 1 {
+		fs.Usage()
+		return fmt.Errorf("missing PDF file or -b")
+	}
+	pdfPath := fs.Arg(0)
+	config, err := parseFlags(append(append([]string{}, fs.Args()[1:]...), pdfPath))
+	if err != nil {
+		return err
+	}
+	// Analyses copied from earlier results were made with another prompt
+	config.ReuseFrom, config.ResumeFrom = nil, ""
+
+	packA, err := promptVersion(config.PromptPack, *promptA)
+	if err != nil {
+		return fmt.Errorf("-a: %v", err)
+	}
+	packB, err := promptVersion(config.PromptPack, *promptB)
+	if err != nil {
+		return fmt.Errorf("-b: %v", err)
+	}
+	if packA.Hash == packB.Hash {
+		return fmt.Errorf("-a and -b are the same prompt (%s)", packA.Info())
+	}
+
+	totalPages, err := pdfanalysis.GetPageCount(pdfPath)
+	if err != nil {
+		return err
+	}
+	pages := samplePages(totalPages, *sample)
+	report := PromptABReport{PDFPath: pdfPath, Pages: pages, Model: config.ModelName, GeneratedAt: time.Now(), Build: pdfanalysis.Build()}
+	fmt.Printf("🆎 Prompt A/B: %s vs %s on page(s) %s of %s\n", packA.Info(), packB.Info(), joinInts(pages), filepath.Base(pdfPath))
+
+	ctx := context.Background()
+	var outputs [2]map[int]pdfanalysis.ChunkAnalysis
+	for i, pack := range []pdfanalysis.PromptPack{packA, packB} {
+		side := []*PromptABSide{&report.A, &report.B}[i]
+		side.Source = []string{*promptA, *promptB}[i]
+		if side.Source == "" {
+			side.Source = pack.Name
+		}
+		fmt.Printf("\n  🔄 %s: %s\n", []string{"A", "B"}[i], pack.Info())
+		run := *config
+		run.PromptPack = pack
+		run.Pages = pages
+		analyzer, err := pdfanalysis.New(pdfanalysis.WithConfig(run))
+		if err != nil {
+			return err
+		}
+		result, err := analyzer.AnalyzeFile(ctx, pdfPath)
+		if result == nil {
+			return err
+		}
+		if err != nil {
+			fmt.Printf("  ⚠️  Run stopped early: %v\n", err)
+		}
+		side.Prompt = result.Prompt
+		side.InputTokens, side.OutputTokens, side.TotalCost = result.TotalInputTokens, result.TotalOutputTokens, result.TotalCost
+		for _, chunk := range result.Chunks {
+			if chunk.Error != "" {
+				side.FailedPages++
+			}
+			if chunk.ReusedFrom != "" {
+				side.CachedPages++
+			}
+		}
+		outputs[i] = chunksByPage(result.Chunks)
+	}
+	comparePrompts(&report, outputs[0], outputs[1])
+
+	printPromptAB(os.Stdout, &report)
+	jsonFile := pdfanalysis.GenerateOutputFilename(pdfPath, "prompt-ab.json")
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(jsonFile, data, 0644); err != nil {
+		return fmt.Errorf("error writing %s: %v", jsonFile, err)
+	}
+	htmlFile := pdfanalysis.GenerateOutputFilename(pdfPath, "prompt-ab.html")
+	file, err := os.Create(htmlFile)
+	if err != nil {
+		return fmt.Errorf("error writing %s: %v", htmlFile, err)
+	}
+	defer file.Close()
+	if err := promptABPage.Execute(file, &report); err != nil {
+		return fmt.Errorf("error writing %s: %v", htmlFile, err)
+	}
+	fmt.Printf("\n💾 Comparison saved to: %s and %s (side by side)\n", jsonFile, htmlFile)
+	return nil
+}
+
+// promptVersion returns the pack named spec, or the prompt template file
+// spec with the structured sections of base; an empty spec is base itself
+func promptVersion(base pdfanalysis.PromptPack, spec string) (pdfanalysis.PromptPack, error) {
+	if spec == "" {
+		return base, nil
+	}
+	if pack, ok := pdfanalysis.PromptPacks[spec]; ok {
+		return pack, nil
+	}
+	custom, err := pdfanalysis.LoadPromptPack(spec)
+	if err != nil {
+		return pdfanalysis.PromptPack{}, err
+	}
+	base.Name, base.Version, base.Hash, base.Analysis = custom.Name, custom.Version, custom.Hash, custom.Analysis
+	return base, nil
+}
+
+// comparePrompts pairs the outputs of both versions by page and scores them
+func comparePrompts(report *PromptABReport, a, b map[int]pdfanalysis.ChunkAnalysis) {
+	var total float64
+	var scored int
+	for _, page := range report.Pages {
+		chunkA, chunkB := a[page], b[page]
+		comparison := PromptABPage{Page: page, A: chunkA.Analysis, B: chunkB.Analysis, AError: chunkA.Error, BError: chunkB.Error}
+		// A run that stopped early has no chunk for the pages it did not reach
+		if _, ok := a[page]; !ok {
+			comparison.AError = "not analyzed"
+		}
+		if _, ok := b[page]; !ok {
+			comparison.BError = "not analyzed"
+		}
+		if comparison.AError == "" && comparison.BError == "" {
+			comparison.Similarity = wordOverlap(chunkA.Analysis, chunkB.Analysis)
+			comparison.OnlyInA = valuesOnlyIn(chunkA.Analysis, chunkB.Analysis)
+			comparison.OnlyInB = valuesOnlyIn(chunkB.Analysis, chunkA.Analysis)
+			total += comparison.Similarity
+			scored++
+		}
+		report.Comparison = append(report.Comparison, comparison)
+	}
+	if scored > 0 {
+		report.Similarity = total / float64(scored)
+	}
+}
+
+// valuesOnlyIn returns the words with digits of text, such as part numbers,
+// dimensions, and tags, that other lacks, sorted
+func valuesOnlyIn(text, other string) []string {
+	values := func(s string) map[string]bool {
+		set := make(map[string]bool)
+		for _, word := range questionTerm.FindAllString(s, -1) {
+			if strings.IndexFunc(word, unicode.IsDigit) >= 0 {
+				set[word] = true
+			}
+		}
+		return set
+	}
+	otherValues := values(other)
+	var only []string
+	for value := range values(text) {
+		if !otherValues[value] {
+			only = append(only, value)
+		}
+	}
+	sort.Strings(only)
+	return only
+}
+
+// printPromptAB prints the totals of both versions and each page's similarity
+func printPromptAB(w io.Writer, report *PromptABReport) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, strings.Repeat("=", 80))
+	fmt.Fprintf(w, "%-14s %-32s %-32s\n", "", "A", "B")
+	fmt.Fprintln(w, strings.Repeat("-", 80))
+	row := func(label string, value func(PromptABSide) string) {
+		fmt.Fprintf(w, "%-14s %-32s %-32s\n", label, pdfanalysis.Truncate(value(report.A), 32), pdfanalysis.Truncate(value(report.B), 32))
+	}
+	row("Prompt", func(s PromptABSide) string { return s.Prompt.String() })
+	row("Failed pages", func(s PromptABSide) string { return fmt.Sprint(s.FailedPages) })
+	row("Cached pages", func(s PromptABSide) string { return fmt.Sprint(s.CachedPages) })
+	row("Input tokens", func(s PromptABSide) string { return fmt.Sprint(s.InputTokens) })
+	row("Output tokens", func(s PromptABSide) string { return fmt.Sprint(s.OutputTokens) })
+	row("Cost", func(s PromptABSide) string { return fmt.Sprintf("$%.4f", s.TotalCost) })
+	fmt.Fprintln(w, strings.Repeat("-", 80))
+	for _, page := range report.Comparison {
+		if page.AError != "" || page.BError != "" {
+			failed := "A and B"
+			if page.BError == "" {
+				failed = "A"
+			} else if page.AError == "" {
+				failed = "B"
+			}
+			fmt.Fprintf(w, "Page %d: failed with %s\n", page.Page, failed)
+			continue
+		}
+		fmt.Fprintf(w, "Page %d: similarity %.2f, %d value(s) only in A, %d only in B\n", page.Page, page.Similarity, len(page.OnlyInA), len(page.OnlyInB))
+	}
+	fmt.Fprintln(w, strings.Repeat("=", 80))
+	fmt.Fprintf(w, "Mean similarity: %.2f\n", report.Similarity)
+}
+
+// promptABPage is the side-by-side HTML view of a PromptABReport
+var promptABPage = template.Must(template.New("prompt-ab").Funcs(template.FuncMap{"join": strings.Join}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Prompt A/B: {{.PDFPath}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; table-layout: fixed; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 0.5em; vertical-align: top; text-align: left; }
+th:first-child, td:first-child { width: 10em; }
+.pages th:first-child, .pages td:first-child { width: auto; }
+pre { white-space: pre-wrap; font-size: 12px; margin: 0; }
+.only { color: #b00; font-size: 13px; }
+</style>
+</head>
+<body>
+<h1>Prompt A/B: {{.PDFPath}}</h1>
+<table>
+<tr><th></th><th>A</th><th>B</th></tr>
+<tr><td>Prompt</td><td>{{.A.Prompt}}</td><td>{{.B.Prompt}}</td></tr>
+<tr><td>Source</td><td>{{.A.Source}}</td><td>{{.B.Source}}</td></tr>
+<tr><td>Failed pages</td><td>{{.A.FailedPages}}</td><td>{{.B.FailedPages}}</td></tr>
+<tr><td>Cached pages</td><td>{{.A.CachedPages}}</td><td>{{.B.CachedPages}}</td></tr>
+<tr><td>Input tokens</td><td>{{.A.InputTokens}}</td><td>{{.B.InputTokens}}</td></tr>
+<tr><td>Output tokens</td><td>{{.A.OutputTokens}}</td><td>{{.B.OutputTokens}}</td></tr>
+<tr><td>Cost</td><td>${{printf "%.4f" .A.TotalCost}}</td><td>${{printf "%.4f" .B.TotalCost}}</td></tr>
+</table>
+<p>Model {{.Model}}, mean similarity {{printf "%.2f" .Similarity}}</p>
+{{range .Comparison}}
+<h2>Page {{.Page}}{{if not (or .AError .BError)}}, similarity {{printf "%.2f" .Similarity}}{{end}}</h2>
+<table class="pages">
+<tr><th>A</th><th>B</th></tr>
+<tr>
+<td>{{with .AError}}<p class="only">Failed: {{.}}</p>{{end}}{{with .OnlyInA}}<p class="only">Only in A: {{join . ", "}}</p>{{end}}<pre>{{.A}}</pre></td>
+<td>{{with .BError}}<p class="only">Failed: {{.}}</p>{{end}}{{with .OnlyInB}}<p class="only">Only in B: {{join . ", "}}</p>{{end}}<pre>{{.B}}</pre></td>
+</tr>
+</table>
+{{end}}
+</body>
+</html>
+`))
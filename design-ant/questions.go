@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"design-ant/internal/tempfiles"
+	"design-ant/pkg/pdfanalysis"
+)
+
+// maxQuestionPages caps the pages sent with one question
+const maxQuestionPages = 3
+
+// maxConcurrentQuestions limits parallel question requests, like page analysis
+const maxConcurrentQuestions = 4
+
+// minTextLayerWords is the word count below which a page counts as having no
+// searchable text layer (scans, pure drawings)
+const minTextLayerWords = 5
+
+// QuestionAnswer is the answer to one line of the -questions file
+type QuestionAnswer struct {
+	Question     string  `json:"question"`
+	Answer       string  `json:"answer"`
+	Found        bool    `json:"found"`           // False when the pages did not contain the answer
+	Pages        []int   `json:"pages,omitempty"` // Pages cited in the answer
+	Candidates   []int   `json:"candidates"`      // Pages sent to the model
+	Located      string  `json:"located"`         // How the candidates were chosen: text_search or no_text_layer
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	TotalCost    float64 `json:"total_cost"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// QuestionsResult is the output of a -questions run
+type QuestionsResult struct {
+	PDFPath           string                    `json:"pdf_path"`
+	TotalPages        int                       `json:"total_pages"`
+	Model             string                    `json:"model"`
+	Answers           []QuestionAnswer          `json:"answers"`
+	TotalInputTokens  int                       `json:"total_input_tokens"`
+	TotalOutputTokens int                       `json:"total_output_tokens"`
+	TotalCost         float64                   `json:"total_cost"`
+	Pricing           *pdfanalysis.PricingTable `json:"pricing,omitempty"` // Model prices the costs were computed with
+	ProcessingTime    string                    `json:"processing_time"`
+	GeneratedAt       time.Time                 `json:"generated_at"`
+	Build             *pdfanalysis.BuildInfo    `json:"build,omitempty"` // design-ant build that wrote the answers
+}
+
+var (
+	// questionTerm finds the words of a question; part numbers keep their dots and dashes
+	questionTerm = regexp.MustCompile(`[\p{L}\p{N}][\p{L}\p{N}.\-/]*[\p{L}\p{N}]|[\p{L}\p{N}]`)
+	// pageCitation finds "p. 3", "page 3", and "pp. 3" references in an answer
+	pageCitation = regexp.MustCompile(`(?i)\b(?:pp?|pages?)\.?\s*(\d+)`)
+)
+
+// questionStopWords are ignored when searching the text layer
+var questionStopWords = map[string]bool{
+	"a": true, "all": true, "an": true, "and": true, "any": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "can": true, "do": true, "does": true, "drawing": true, "for": true,
+	"from": true, "has": true, "have": true, "how": true, "in": true, "is": true, "it": true,
+	"its": true, "many": true, "much": true, "of": true, "on": true, "or": true, "page": true,
+	"should": true, "the": true, "there": true, "this": true, "to": true, "used": true, "what": true,
+	"when": true, "where": true, "which": true, "who": true, "with": true,
+}
+
+// loadQuestions reads one question per non-empty line; lines starting with # are comments
+func loadQuestions(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening questions: %v", err)
+	}
+	defer file.Close()
+
+	var questions []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			questions = append(questions, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading questions: %v", err)
+	}
+	if len(questions) == 0 {
+		return nil, fmt.Errorf("%s contains no questions", path)
+	}
+	return questions, nil
+}
+
+// runQuestions answers each question from the pages most likely to contain
+// the answer instead of analyzing the whole document. Candidate pages are
+// found by searching the text layer; when no page matches, pages without a
+// text layer are sent instead, since search cannot see them.
+func runQuestions(config *pdfanalysis.Config) (*QuestionsResult, error) {
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("ANTHROPIC_API_KEY not found in environment variables")
+	}
+	questions, err := loadQuestions(config.QuestionsPath)
+	if err != nil {
+		return nil, err
+	}
+	if config.PricingFile != "" {
+		if err := pdfanalysis.LoadPricingFile(config.PricingFile); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := os.Stat(config.PDFPath); os.IsNotExist(err) && !isRemoteURI(config.PDFPath) {
+		return nil, fmt.Errorf("PDF file not found: %s", config.PDFPath)
+	}
+	if err := checkStorage(config.PDFPath, config.OutputURI, config.StoreURI); err != nil {
+		return nil, err
+	}
+
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println("  DESIGN PDF QUESTIONS (ANTHROPIC)")
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("\n📄 Processing: %s\n", filepath.Base(config.PDFPath))
+
+	temp, err := tempfiles.New("pdf-questions")
+	if err != nil {
+		return nil, err
+	}
+	defer temp.Remove()
+
+	// Documents in cloud storage are copied into the temporary directory
+	if isRemoteURI(config.PDFPath) {
+		if err := fetchRemoteInput(context.Background(), config, temp.File("download")); err != nil {
+			return nil, err
+		}
+	}
+
+	if pdfanalysis.IsOfficeDocument(config.PDFPath) {
+		fmt.Printf("🔁 Converting %s to PDF with LibreOffice...\n", filepath.Ext(config.PDFPath))
+		pdfPath, err := pdfanalysis.ConvertToPDF(context.Background(), config.PDFPath, temp.File("converted"))
+		if err != nil {
+			return nil, fmt.Errorf("error converting document: %v", err)
+		}
+		if config.SourcePath == "" {
+			config.SourcePath = config.PDFPath
+		}
+		config.PDFPath = pdfPath
+	}
+	fmt.Printf("🤖 Model: %s\n", config.ModelName)
+
+	startTime := time.Now()
+	totalPages, err := pdfanalysis.GetPageCount(config.PDFPath)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("📊 Total pages: %d\n", totalPages)
+	if err := pdfanalysis.CheckPageLimit(config.Limits, totalPages); err != nil {
+		return nil, err
+	}
+	texts, err := pdfanalysis.PageTexts(config.PDFPath, totalPages)
+	if err != nil {
+		return nil, err
+	}
+	if config.CaptureDir != "" {
+		if err := os.MkdirAll(config.CaptureDir, 0755); err != nil {
+			return nil, fmt.Errorf("error creating capture directory: %v", err)
+		}
+	}
+	fmt.Printf("❓ %d question(s)\n", len(questions))
+	fmt.Println(strings.Repeat("-", 70))
+
+	// Candidate pages are extracted once and shared between questions
+	result := &QuestionsResult{PDFPath: config.DocumentPath(), TotalPages: totalPages, Model: config.ModelName, Pricing: pdfanalysis.PricingSnapshot()}
+	result.Answers = make([]QuestionAnswer, len(questions))
+	pagePaths := make(map[int]string)
+	for i, question := range questions {
+		answer := QuestionAnswer{Question: question}
+		answer.Candidates, answer.Located = locatePages(question, texts, maxQuestionPages)
+		for _, page := range answer.Candidates {
+			if _, ok := pagePaths[page]; ok {
+				continue
+			}
+			if pagePaths[page], err = pdfanalysis.ExtractPage(config.PDFPath, temp.Path(), page); err != nil {
+				return nil, err
+			}
+		}
+		result.Answers[i] = answer
+	}
+
+	pricing := pdfanalysis.GetPricing(config.ModelName)
+	semaphore := make(chan struct{}, maxConcurrentQuestions)
+	var wg sync.WaitGroup
+	for i := range result.Answers {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			answer := &result.Answers[index]
+			if len(answer.Candidates) == 0 {
+				answer.Answer = "NOT FOUND: no page mentions the terms of the question"
+				fmt.Printf("  ❔ Q%d: no candidate pages\n", index+1)
+				return
+			}
+			ctx := pdfanalysis.ContextWithCapture(context.Background(), config.CaptureDir, fmt.Sprintf("question-%02d", index+1))
+			err := answerQuestion(ctx, config, answer, pagePaths)
+			answer.TotalCost = float64(answer.InputTokens)/1_000_000*pricing.InputPricePerMTokens +
+				float64(answer.OutputTokens)/1_000_000*pricing.OutputPricePerMTokens
+			if err != nil {
+				answer.Error = err.Error()
+				fmt.Printf("  ❌ Q%d failed: %v\n", index+1, err)
+				return
+			}
+			fmt.Printf("  ✅ Q%d answered from %s, $%.6f\n", index+1, pageList(answer.Candidates), answer.TotalCost)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, answer := range result.Answers {
+		result.TotalInputTokens += answer.InputTokens
+		result.TotalOutputTokens += answer.OutputTokens
+		result.TotalCost += answer.TotalCost
+	}
+	result.ProcessingTime = time.Since(startTime).String()
+	result.GeneratedAt = time.Now()
+	result.Build = pdfanalysis.Build()
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println("  ANSWERS")
+	fmt.Println(strings.Repeat("=", 70))
+	for i, answer := range result.Answers {
+		fmt.Printf("\nQ%d: %s\n", i+1, answer.Question)
+		if answer.Error != "" {
+			fmt.Printf("  ❌ %s\n", answer.Error)
+			continue
+		}
+		fmt.Println(strings.TrimSpace(answer.Answer))
+	}
+	fmt.Println()
+	fmt.Printf("💰 Total: %d input tokens, %d output tokens, $%.6f\n", result.TotalInputTokens, result.TotalOutputTokens, result.TotalCost)
+
+	jsonFile := pdfanalysis.GenerateOutputFilename(config.DocumentPath(), "answers.json")
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err == nil {
+		err = os.WriteFile(jsonFile, data, 0644)
+	}
+	if err != nil {
+		slog.Warn("Could not save answers", "path", jsonFile, "error", err)
+	} else {
+		fmt.Printf("💾 Answers saved to: %s\n", jsonFile)
+	}
+	mdFile := pdfanalysis.GenerateOutputFilename(config.DocumentPath(), "answers.md")
+	if err := os.WriteFile(mdFile, []byte(markdownAnswers(result)), 0644); err != nil {
+		slog.Warn("Could not save answers markdown", "path", mdFile, "error", err)
+	} else {
+		fmt.Printf("💾 Answers markdown saved to: %s\n", mdFile)
+	}
+	uploadOutputs(context.Background(), config, startTime)
+	runPostHook(config, jsonFile, len(result.Answers), result.TotalCost, false)
+	return result, nil
+}
+
+// locatePages ranks pages by the question terms found in their text layer,
+// weighting rare terms higher, and returns up to limit pages in page order.
+// When no page matches, pages without a text layer are returned instead.
+func locatePages(question string, texts []string, limit int) ([]int, string) {
+	var terms []string
+	for _, term := range questionTerm.FindAllString(strings.ToLower(question), -1) {
+		if questionStopWords[term] || (len(term) < 3 && !strings.ContainsAny(term, "0123456789")) {
+			continue
+		}
+		// "housings" should find "housing"
+		if len(term) > 4 && strings.HasSuffix(term, "s") && !strings.HasSuffix(term, "ss") {
+			term = strings.TrimSuffix(term, "s")
+		}
+		terms = append(terms, term)
+	}
+
+	lower := make([]string, len(texts))
+	for i, text := range texts {
+		lower[i] = strings.ToLower(text)
+	}
+	type scored struct {
+		page  int
+		score float64
+	}
+	var ranked []scored
+	scores := make([]float64, len(texts))
+	for _, term := range terms {
+		var hits []int
+		for i, text := range lower {
+			if strings.Contains(text, term) {
+				hits = append(hits, i)
+			}
+		}
+		idf := math.Log(1 + float64(len(texts))/float64(max(len(hits), 1)))
+		for _, i := range hits {
+			scores[i] += idf
+		}
+	}
+	for i, score := range scores {
+		if score > 0 {
+			ranked = append(ranked, scored{page: i + 1, score: score})
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	var pages []int
+	located := "text_search"
+	for _, r := range ranked[:min(len(ranked), limit)] {
+		pages = append(pages, r.page)
+	}
+	if len(pages) == 0 {
+		located = "no_text_layer"
+		for i, text := range texts {
+			if len(strings.Fields(text)) < minTextLayerWords && len(pages) < limit {
+				pages = append(pages, i+1)
+			}
+		}
+	}
+	sort.Ints(pages)
+	return pages, located
+}
+
+// answerQuestion sends the candidate pages with the question and records the
+// answer, its cited pages, and the token counts on answer
+func answerQuestion(ctx context.Context, config *pdfanalysis.Config, answer *QuestionAnswer, pagePaths map[int]string) error {
+	var content []map[string]interface{}
+	for _, page := range answer.Candidates {
+		pageContent, err := pdfanalysis.ChunkContent(pagePaths[page], fmt.Sprintf("The document above is page %d.", page))
+		if err != nil {
+			return err
+		}
+		content = append(content, pageContent...)
+	}
+	prompt := questionPrompt(answer.Question, answer.Candidates)
+	if config.Terminology != "" {
+		prompt += "\n\n" + pdfanalysis.TerminologyInstructions(config.Terminology)
+	}
+	if config.UnitOrder != "" {
+		prompt += "\n\n" + pdfanalysis.UnitOrderInstructions(config.UnitOrder)
+	}
+	if config.OutputLang != "" {
+		prompt += "\n\n" + pdfanalysis.OutputLanguageInstructions(config.OutputLang)
+	}
+	content = append(content, map[string]interface{}{"type": "text", "text": prompt})
+
+	text, inputTokens, outputTokens, err := pdfanalysis.SendContentWithRetry(ctx, config, content)
+	answer.InputTokens, answer.OutputTokens = inputTokens, outputTokens
+	if err != nil {
+		return err
+	}
+	answer.Answer = strings.TrimSpace(text)
+	answer.Found = !strings.HasPrefix(strings.ToUpper(answer.Answer), "NOT FOUND")
+	answer.Pages = citedPages(answer.Answer, answer.Candidates)
+	return nil
+}
+
+// questionPrompt asks for a short, cited answer from the given pages
+func questionPrompt(question string, pages []int) string {
+	return fmt.Sprintf(`The documents above are pages %s of an engineering drawing package.
+Answer the question below using only these pages.
+
+RULES:
+- Answer in one to three sentences, quoting values, codes, and units exactly as written
+- Cite every page you used as "p. N"
+- If the pages do not contain the answer, reply with "NOT FOUND" followed by what the pages show instead
+
+QUESTION: %s`, pageList(pages), question)
+}
+
+// citedPages returns the candidate pages referenced in an answer, in page order
+func citedPages(answer string, candidates []int) []int {
+	var pages []int
+	for _, m := range pageCitation.FindAllStringSubmatch(answer, -1) {
+		page, err := strconv.Atoi(m[1])
+		if err == nil && containsInt(candidates, page) && !containsInt(pages, page) {
+			pages = append(pages, page)
+		}
+	}
+	sort.Ints(pages)
+	return pages
+}
+
+// containsInt reports whether values contains v
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// pageList formats pages as "p. 1, 4, 7"
+func pageList(pages []int) string {
+	parts := make([]string, len(pages))
+	for i, p := range pages {
+		parts[i] = strconv.Itoa(p)
+	}
+	return "p. " + strings.Join(parts, ", ")
+}
+
+// markdownAnswers renders the answers as a markdown report
+func markdownAnswers(result *QuestionsResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Questions: %s\n\n", filepath.Base(result.PDFPath))
+	fmt.Fprintf(&b, "| Questions | Pages | Total Cost | Generated |\n|---|---|---|---|\n| %d | %d | $%.6f | %s |\n\n",
+		len(result.Answers), result.TotalPages, result.TotalCost, result.GeneratedAt.Format("2006-01-02 15:04"))
+	for i, answer := range result.Answers {
+		fmt.Fprintf(&b, "## Q%d: %s\n\n", i+1, pdfanalysis.EscapeInline(answer.Question))
+		if answer.Error != "" {
+			fmt.Fprintf(&b, "> **Failed:** %s\n\n", pdfanalysis.EscapeInline(answer.Error))
+		} else {
+			b.WriteString(strings.TrimSpace(answer.Answer))
+			b.WriteString("\n\n")
+		}
+		if len(answer.Candidates) > 0 {
+			fmt.Fprintf(&b, "_Searched %s_\n\n", pageList(answer.Candidates))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisQueue keeps a queue on a Redis server, so runners on machines that
+// share no directory work it together. Each job is a JSON string under
+// design-ant:job:{id}, listed in the design-ant:jobs set; locks and runner
+// heartbeats are keys that expire after jobLockStale unless refreshed, so
+// the lock of a runner that is gone disappears by itself.
+type redisQueue struct {
+	client *redis.Client
+	addr   string
+	prefix string
+}
+
+// Scripts that change a lock only while the runner in ARGV[1] holds it, so a
+// runner whose lock expired and was taken over by another cannot refresh,
+// remove, or save over it. Each returns 1 when it did.
+var (
+	redisRefreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then return 0 end
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return 1`)
+	redisUnlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then return 0 end
+redis.call("DEL", KEYS[1])
+return 1`)
+	// KEYS are the lock, the job, and the jobs set; ARGV[2] and ARGV[3] are
+	// the job's JSON and ID
+	redisReleaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then return 0 end
+redis.call("SET", KEYS[2], ARGV[2])
+redis.call("SADD", KEYS[3], ARGV[3])
+redis.call("DEL", KEYS[1])
+return 1`)
+)
+
+// openRedisQueue connects to the Redis server of a -queue URL,
+// redis://[user:password@]host:port[/db] or rediss:// for TLS
+func openRedisQueue(uri string) (*redisQueue, error) {
+	options, err := redis.ParseURL(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -queue %q: %v", uri, err)
+	}
+	q := &redisQueue{client: redis.NewClient(options), addr: options.Addr, prefix: "design-ant:"}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := q.ping(ctx); err != nil {
+		q.client.Close()
+		return nil, fmt.Errorf("error connecting to the queue %s: %v", options.Addr, err)
+	}
+	return q, nil
+}
+
+// ping checks that the server answers
+func (q *redisQueue) ping(ctx context.Context) error {
+	return q.client.Ping(ctx).Err()
+}
+
+func (q *redisQueue) location() string {
+	return "redis " + q.addr
+}
+
+func (q *redisQueue) close() error {
+	return q.client.Close()
+}
+
+func (q *redisQueue) jobKey(id string) string {
+	return q.prefix + "job:" + id
+}
+
+func (q *redisQueue) lockKey(name string) string {
+	return q.prefix + "lock:" + name
+}
+
+func (q *redisQueue) runnerKey(runner string) string {
+	return q.prefix + "runner:" + runner
+}
+
+func (q *redisQueue) save(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, q.jobKey(job.ID), data, 0)
+		pipe.SAdd(ctx, q.prefix+"jobs", job.ID)
+		return nil
+	})
+	return err
+}
+
+func (q *redisQueue) load(id string) (*Job, error) {
+	data, err := q.client.Get(context.Background(), q.jobKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("job %s: %w", id, os.ErrNotExist)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("error parsing job %s: %v", id, err)
+	}
+	return &job, nil
+}
+
+func (q *redisQueue) list() ([]*Job, error) {
+	ctx := context.Background()
+	ids, err := q.client.SMembers(ctx, q.prefix+"jobs").Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = q.jobKey(id)
+	}
+	values, err := q.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	var jobs []*Job
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			// Listed but not yet written
+			continue
+		}
+		var job Job
+		if err := json.Unmarshal([]byte(data), &job); err != nil {
+			return nil, fmt.Errorf("error parsing job %s: %v", ids[i], err)
+		}
+		jobs = append(jobs, &job)
+	}
+	return jobs, nil
+}
+
+// claim locks each job picked in turn, then claims it if it is still
+// pending, since it may have finished between the listing and the lock
+func (q *redisQueue) claim(runner string, pick func(jobs []*Job) []*Job, start func(job *Job)) (*Job, error) {
+	jobs, err := q.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range pick(jobs) {
+		locked, err := q.lock(job.ID, runner)
+		if err != nil {
+			return nil, err
+		}
+		if !locked {
+			continue
+		}
+		current, err := q.load(job.ID)
+		if err != nil || current.Status != JobPending {
+			q.unlock(job.ID, runner)
+			continue
+		}
+		start(current)
+		if err := q.save(current); err != nil {
+			q.unlock(job.ID, runner)
+			return nil, err
+		}
+		return current, nil
+	}
+	return nil, nil
+}
+
+func (q *redisQueue) lock(name, runner string) (bool, error) {
+	return q.client.SetNX(context.Background(), q.lockKey(name), runner, jobLockStale).Result()
+}
+
+func (q *redisQueue) release(job *Job, runner string) (bool, error) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return false, err
+	}
+	keys := []string{q.lockKey(job.ID), q.jobKey(job.ID), q.prefix + "jobs"}
+	n, err := redisReleaseScript.Run(context.Background(), q.client, keys, runner, data, job.ID).Int()
+	return n == 1, err
+}
+
+func (q *redisQueue) refresh(name, runner string) error {
+	n, err := redisRefreshScript.Run(context.Background(), q.client, []string{q.lockKey(name)}, runner, jobLockStale.Milliseconds()).Int()
+	if err == nil && n == 0 {
+		return fmt.Errorf("lock %s: %w", name, errLockLost)
+	}
+	return err
+}
+
+func (q *redisQueue) unlock(name, runner string) {
+	redisUnlockScript.Run(context.Background(), q.client, []string{q.lockKey(name)}, runner)
+}
+
+// clearStale has nothing to remove, since a stale lock has expired
+func (q *redisQueue) clearStale(name string) bool {
+	return !q.fresh(name)
+}
+
+// fresh reports whether the lock exists, since it expires once stale
+func (q *redisQueue) fresh(name string) bool {
+	n, err := q.client.Exists(context.Background(), q.lockKey(name)).Result()
+	// A server that cannot be reached leaves locks alone
+	return err != nil || n > 0
+}
+
+func (q *redisQueue) beat(runner string) error {
+	return q.client.Set(context.Background(), q.runnerKey(runner), time.Now().Format(time.RFC3339), jobLockStale).Err()
+}
+
+func (q *redisQueue) endBeat(runner string) {
+	q.client.Del(context.Background(), q.runnerKey(runner))
+}
+
+func (q *redisQueue) runners() int {
+	ctx := context.Background()
+	active := 0
+	iter := q.client.Scan(ctx, 0, q.runnerKey("*"), 100).Iterator()
+	for iter.Next(ctx) {
+		active++
+	}
+	return active
+}
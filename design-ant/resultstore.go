@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"design-ant/internal/tempfiles"
+	"design-ant/pkg/pdfanalysis"
+	"design-ant/pkg/results"
+
+	_ "modernc.org/sqlite"
+)
+
+// openResultStore opens the store of -result-store: a directory, a SQLite
+// database as sqlite:path, or an s3://, gs://, or az:// prefix. Relative
+// paths resolve in dir, which is the store when uri is empty.
+func openResultStore(uri, dir string) (pdfanalysis.ResultStore, error) {
+	switch {
+	case isRemoteURI(uri):
+		return newObjectStore(uri)
+	case strings.HasPrefix(uri, "sqlite:"):
+		path := strings.TrimPrefix(uri, "sqlite:")
+		if path == "" {
+			return nil, fmt.Errorf("invalid result store %q: use sqlite:path/to/results.db", uri)
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		return openSQLiteStore(uri, path)
+	}
+	if !filepath.IsAbs(uri) {
+		uri = filepath.Join(dir, uri)
+	}
+	return pdfanalysis.NewFileStore(uri), nil
+}
+
+// closeResultStore closes the streams and staging files of a store
+func closeResultStore(store pdfanalysis.ResultStore) {
+	if closer, ok := store.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// absStoreURI makes the paths of a -result-store absolute, so jobs running
+// in other directories use the same store
+func absStoreURI(uri string) string {
+	switch {
+	case uri == "" || isRemoteURI(uri):
+		return uri
+	case strings.HasPrefix(uri, "sqlite:"):
+		return "sqlite:" + absPath(strings.TrimPrefix(uri, "sqlite:"))
+	}
+	return absPath(uri)
+}
+
+// resultName returns the name of a run's JSON result in its store
+func resultName(config *pdfanalysis.Config) string {
+	return pdfanalysis.GenerateOutputFilename(config.DocumentPath(), "json") + pdfanalysis.CompressionSuffix(config.Compression)
+}
+
+// resultLocation returns where the store of -result-store keeps a result or
+// stream, as shown in messages, the ledger, and hooks and accepted by
+// -resume: a path, a URI, or sqlite:path#name
+func resultLocation(config *pdfanalysis.Config, name string) string {
+	switch uri := config.StoreURI; {
+	case uri == "":
+		return name
+	case isRemoteURI(uri):
+		return strings.TrimSuffix(uri, "/") + "/" + name
+	case strings.HasPrefix(uri, "sqlite:"):
+		return uri + "#" + name
+	default:
+		return absPath(filepath.Join(uri, name))
+	}
+}
+
+// sqliteSchema creates the tables of a SQLite result store: the saved
+// results and the pages streamed by runs, keyed by the stream's name
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS results (
+	name TEXT PRIMARY KEY,
+	pdf_path TEXT NOT NULL,
+	saved_at TEXT NOT NULL,
+	result TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS pages (
+	stream TEXT NOT NULL,
+	start_page INTEGER NOT NULL,
+	page TEXT NOT NULL,
+	PRIMARY KEY (stream, start_page)
+);
+`
+
+// sqliteStore keeps results in a SQLite database, which several runners can
+// share on one machine; a writer waits up to 10 seconds for another's lock
+type sqliteStore struct {
+	uri  string // -result-store as given, the prefix of locations
+	path string
+	db   *sql.DB
+
+	mu      sync.Mutex
+	started map[string]bool // Streams started by this store
+}
+
+// openSQLiteStore opens the database of a sqlite: store, creating it and its
+// tables if needed
+func openSQLiteStore(uri, path string) (*sqliteStore, error) {
+	// The path is escaped, since a file: URI ends at ? and #
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?_pragma=busy_timeout(10000)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &sqliteStore{uri: uri, path: path, db: db, started: make(map[string]bool)}, nil
+}
+
+// Close closes the database
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+// query runs a query returning one text column
+func (s *sqliteStore) query(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", s.path, err)
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("%s: %v", s.path, err)
+		}
+		values = append(values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", s.path, err)
+	}
+	return values, nil
+}
+
+// name returns the name of a result given as a name or a location
+func (s *sqliteStore) name(ref string) string {
+	return strings.TrimPrefix(ref, s.uri+"#")
+}
+
+func (s *sqliteStore) Save(ctx context.Context, name string, result pdfanalysis.FullAnalysisResult) error {
+	result.SchemaVersion = pdfanalysis.CurrentSchemaVersion
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, "INSERT OR REPLACE INTO results VALUES (?, ?, ?, ?)",
+		s.name(name), result.PDFPath, time.Now().UTC().Format(time.RFC3339), string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %v", s.path, err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) Load(ctx context.Context, name string) (*pdfanalysis.FullAnalysisResult, error) {
+	name = s.name(name)
+	stream := name
+	if !strings.HasSuffix(name, ".jsonl") {
+		rows, err := s.query(ctx, "SELECT result FROM results WHERE name = ?", name)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) > 0 {
+			result, err := results.Decode([]byte(rows[0]))
+			if err != nil {
+				return nil, fmt.Errorf("%s#%s: %v", s.path, name, err)
+			}
+			return result, nil
+		}
+		stream = pdfanalysis.StreamName(name)
+	}
+
+	rows, err := s.query(ctx, "SELECT page FROM pages WHERE stream = ? ORDER BY start_page", stream)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: %s in %s", pdfanalysis.ErrResultNotFound, name, s.path)
+	}
+	pages := make([]pdfanalysis.ChunkAnalysis, len(rows))
+	for i, row := range rows {
+		if err := json.Unmarshal([]byte(row), &pages[i]); err != nil {
+			return nil, fmt.Errorf("%s#%s: %v", s.path, stream, err)
+		}
+	}
+	return pdfanalysis.ResultFromStream(pages), nil
+}
+
+func (s *sqliteStore) List(ctx context.Context) ([]string, error) {
+	return s.query(ctx, "SELECT name FROM results ORDER BY name")
+}
+
+// AppendPage adds the page to the stream of the result, removing the pages
+// of an earlier run with the first one
+func (s *sqliteStore) AppendPage(ctx context.Context, name string, page pdfanalysis.ChunkAnalysis) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := json.Marshal(page)
+	if err != nil {
+		return err
+	}
+	stream := pdfanalysis.StreamName(s.name(name))
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", s.path, err)
+	}
+	defer tx.Rollback()
+	if !s.started[stream] {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM pages WHERE stream = ?", stream); err != nil {
+			return fmt.Errorf("%s: %v", s.path, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT OR REPLACE INTO pages VALUES (?, ?, ?)", stream, page.StartPage, string(data)); err != nil {
+		return fmt.Errorf("%s: %v", s.path, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", s.path, err)
+	}
+	s.started[stream] = true
+	return nil
+}
+
+// streamUploadInterval is how often an object store uploads the stream of
+// a run in progress; the whole stream is uploaded each time
+const streamUploadInterval = 30 * time.Second
+
+// objectStore keeps results in cloud storage under a prefix, using the
+// storage clients of -output-uri. Results and streams are written to a local
+// staging directory and uploaded from there; objects cannot be appended to,
+// so the stream is uploaded again every streamUploadInterval and with the
+// result.
+type objectStore struct {
+	prefix  string         // URI without the trailing slash
+	temp    *tempfiles.Dir // Staging directory
+	staging *pdfanalysis.FileStore
+
+	mu       sync.Mutex
+	uploaded map[string]time.Time // Last upload of each stream
+}
+
+// newObjectStore returns the store of a cloud storage prefix
+func newObjectStore(uri string) (*objectStore, error) {
+	if strings.HasPrefix(uri, "az://") {
+		if _, _, _, err := azureBlob(uri); err != nil {
+			return nil, err
+		}
+	}
+	temp, err := tempfiles.New("design-ant-results")
+	if err != nil {
+		return nil, err
+	}
+	return &objectStore{
+		prefix:   strings.TrimSuffix(uri, "/"),
+		temp:     temp,
+		staging:  pdfanalysis.NewFileStore(filepath.Join(temp.Path(), "out")),
+		uploaded: make(map[string]time.Time),
+	}, nil
+}
+
+// uri returns the object of a name
+func (s *objectStore) uri(name string) string {
+	return s.prefix + "/" + name
+}
+
+// name returns the name of a result given as a name or a URI
+func (s *objectStore) name(ref string) string {
+	return strings.TrimPrefix(ref, s.prefix+"/")
+}
+
+// upload copies a staged file to its object
+func (s *objectStore) upload(ctx context.Context, name string) error {
+	if err := copyObject(ctx, s.uri(name), filepath.Join(s.staging.Dir, name), true); err != nil {
+		return fmt.Errorf("error uploading %s: %v", s.uri(name), err)
+	}
+	return nil
+}
+
+// Save uploads the result, and the final stream of a run that streamed one
+func (s *objectStore) Save(ctx context.Context, name string, result pdfanalysis.FullAnalysisResult) error {
+	name = s.name(name)
+	if err := s.staging.Save(ctx, name, result); err != nil {
+		return err
+	}
+	if err := s.upload(ctx, name); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stream := pdfanalysis.StreamName(name); !s.uploaded[stream].IsZero() {
+		return s.upload(ctx, stream)
+	}
+	return nil
+}
+
+// Load downloads the result, or its stream when there is none
+func (s *objectStore) Load(ctx context.Context, name string) (*pdfanalysis.FullAnalysisResult, error) {
+	name = s.name(name)
+	downloads := pdfanalysis.NewFileStore(filepath.Join(s.temp.Path(), "in"))
+	if err := os.MkdirAll(downloads.Dir, 0755); err != nil {
+		return nil, err
+	}
+	objects := []string{name}
+	if !strings.HasSuffix(name, ".jsonl") {
+		objects = append(objects, pdfanalysis.StreamName(name))
+	}
+	var errs []error
+	for _, object := range objects {
+		err := copyObject(ctx, s.uri(object), filepath.Join(downloads.Dir, object), false)
+		if err == nil {
+			return downloads.Load(ctx, object)
+		}
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("%w: %s (%v)", pdfanalysis.ErrResultNotFound, s.uri(name), errors.Join(errs...))
+}
+
+func (s *objectStore) List(ctx context.Context) ([]string, error) {
+	objects, err := listObjects(ctx, s.prefix)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, object := range objects {
+		if pdfanalysis.IsResultName(object) {
+			names = append(names, object)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// AppendPage stages the page and uploads the stream when it was last
+// uploaded streamUploadInterval ago, or never
+func (s *objectStore) AppendPage(ctx context.Context, name string, page pdfanalysis.ChunkAnalysis) error {
+	name = s.name(name)
+	if err := s.staging.AppendPage(ctx, name, page); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stream := pdfanalysis.StreamName(name)
+	if time.Since(s.uploaded[stream]) < streamUploadInterval {
+		return nil
+	}
+	s.uploaded[stream] = time.Now()
+	return s.upload(ctx, stream)
+}
+
+// Close removes the staging directory
+func (s *objectStore) Close() error {
+	s.staging.Close()
+	return s.temp.Remove()
+}
+
+// runResults lists the results in a store, or writes one to stdout
+func runResults(args []string) error {
+	fs := flag.NewFlagSet("results", flag.ContinueOnError)
+	uri := fs.String("store", "", "result store: a directory, sqlite:path/to/results.db, or an s3://, gs://, or az:// prefix (default: the current directory)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("usage: go run . results [-store uri] [name]")
+	}
+	if err := checkStorage(*uri); err != nil {
+		return err
+	}
+	store, err := openResultStore(*uri, ".")
+	if err != nil {
+		return err
+	}
+	defer closeResultStore(store)
+
+	ctx := context.Background()
+	if fs.NArg() == 0 {
+		names, err := store.List(ctx)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return nil
+	}
+	result, err := store.Load(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"design-ant/pkg/pdfanalysis"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	ctx := context.Background()
+	// Quotes and URI characters in the path and names are kept as they are
+	dir := filepath.Join(t.TempDir(), "it's #1?")
+	store, err := openResultStore("sqlite:results.db", dir)
+	if err == nil {
+		t.Fatal("opened a store in a missing directory")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	store, err = openResultStore("sqlite:results.db", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeResultStore(store)
+	if _, err := os.Stat(filepath.Join(dir, "results.db")); err != nil {
+		t.Error(err)
+	}
+
+	names := []string{"o'brien_analysis.json", "x'); DROP TABLE results; --_analysis.json"}
+	for _, name := range names {
+		result := pdfanalysis.FullAnalysisResult{PDFPath: name, TotalPages: 1,
+			Chunks: []pdfanalysis.ChunkAnalysis{{ChunkNumber: 1, StartPage: 1, EndPage: 1, Analysis: "A bracket."}}}
+		if err := store.Save(ctx, name, result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range names {
+		result, err := store.Load(ctx, "sqlite:results.db#"+name)
+		if err != nil || result.PDFPath != name || len(result.Chunks) != 1 {
+			t.Errorf("Load(%q) = %+v, %v", name, result, err)
+		}
+	}
+	listed, err := store.List(ctx)
+	if err != nil || !slices.Equal(listed, []string{names[0], names[1]}) {
+		t.Errorf("List = %q, %v", listed, err)
+	}
+
+	// A new run of a stream replaces the pages of the earlier one
+	for _, pages := range [][]int{{3, 1, 2}, {2}} {
+		other, err := openResultStore("sqlite:results.db", dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range pages {
+			page := pdfanalysis.ChunkAnalysis{ChunkNumber: n, StartPage: n, EndPage: n, Analysis: "A page."}
+			if err := other.AppendPage(ctx, "drawing_analysis.json", page); err != nil {
+				t.Fatal(err)
+			}
+		}
+		closeResultStore(other)
+	}
+	result, err := store.Load(ctx, "drawing_analysis.json")
+	if err != nil || len(result.Chunks) != 1 || result.Chunks[0].StartPage != 2 {
+		t.Errorf("streamed result = %+v, %v; want page 2 only", result, err)
+	}
+	if _, err := store.Load(ctx, "missing_analysis.json"); !errors.Is(err, pdfanalysis.ErrResultNotFound) {
+		t.Errorf("Load of a missing result = %v, want ErrResultNotFound", err)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// retentionInterval is how often the server looks for expired jobs
+const retentionInterval = time.Hour
+
+// retentionPolicy decides when the files of finished jobs are deleted
+type retentionPolicy struct {
+	after    time.Duration // Age of a finished job at which its files go (0 = never)
+	keepJSON bool          // Keep the JSON result, deleting the document and everything else
+}
+
+// isJSONResult reports whether path is a JSON result file, compressed or not
+func isJSONResult(path string) bool {
+	for _, suffix := range []string{"_analysis.json", "_analysis.json.gz", "_analysis.json.zst"} {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// purge deletes the files of a finished job: its uploaded document, its
+// outputs, and its log and progress events. Documents that were not uploaded
+// are the user's and stay. The job record stays too, marked purged, so its
+// cost still counts for its tenant.
+func (s *jobStore) purge(job *Job, keepJSON bool) error {
+	var kept []string
+	remove := func(path string) {
+		if keepJSON && isJSONResult(path) {
+			kept = append(kept, path)
+			return
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			slog.Warn("Could not delete job file", "job", job.ID, "path", path, "error", err)
+		}
+	}
+	for _, path := range job.Outputs {
+		remove(path)
+	}
+	if job.Upload != "" {
+		entries, _ := os.ReadDir(job.Upload)
+		for _, entry := range entries {
+			remove(filepath.Join(job.Upload, entry.Name()))
+		}
+		// Removed once nothing is kept in it
+		os.Remove(job.Upload)
+	}
+	os.Remove(s.logPath(job.ID))
+	os.Remove(s.eventsPath(job.ID))
+
+	job.Outputs = kept
+	job.PurgedAt = time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save(job)
+}
+
+// applyRetention purges the finished jobs older than the policy allows and
+// returns how many. A job that kept its JSON is not purged again.
+func (s *jobStore) applyRetention(policy retentionPolicy, now time.Time) (int, error) {
+	jobs, err := s.list()
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, job := range jobs {
+		if (job.Status != JobDone && job.Status != JobFailed) || !job.PurgedAt.IsZero() {
+			continue
+		}
+		if now.Sub(job.FinishedAt) < policy.after {
+			continue
+		}
+		if err := s.purge(job, policy.keepJSON); err != nil {
+			return purged, fmt.Errorf("error purging job %s: %v", job.ID, err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// runRetention applies the policy now and then every retentionInterval
+// until ctx is canceled
+func runRetention(ctx context.Context, store *jobStore, policy retentionPolicy) {
+	for {
+		purged, err := store.applyRetention(policy, time.Now())
+		if err != nil {
+			slog.Warn("Retention cleanup failed", "error", err)
+		}
+		if purged > 0 {
+			fmt.Printf("🧹 Retention: deleted the files of %d job(s) finished more than %v ago\n", purged, policy.after)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retentionInterval):
+		}
+	}
+}
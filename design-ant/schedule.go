@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Priority classes of jobs. A runner starts the queued jobs of a higher
+// class first.
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// priorityRank orders the classes, higher first; no class is normal
+var priorityRank = map[string]int{PriorityHigh: 2, PriorityNormal: 1, "": 1, PriorityLow: 0}
+
+// checkPriority rejects unknown priority classes
+func checkPriority(priority string) error {
+	if _, ok := priorityRank[priority]; !ok {
+		return fmt.Errorf("invalid priority %q: use high, normal, or low", priority)
+	}
+	return nil
+}
+
+// queuedAt is when the job last joined the queue: when it was added, or when
+// its last time slice ended. Jobs of a class start in this order, so sliced
+// jobs take turns with each other and with newer jobs.
+func (j *Job) queuedAt() time.Time {
+	if j.QueuedAt.IsZero() {
+		return j.CreatedAt
+	}
+	return j.QueuedAt
+}
+
+// schedule returns the pending jobs in the order a runner should try them:
+// by priority class, then by the time they joined the queue
+func schedule(jobs []*Job) []*Job {
+	var pending []*Job
+	for _, job := range jobs {
+		if job.Status == JobPending {
+			pending = append(pending, job)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		a, b := pending[i], pending[j]
+		if priorityRank[a.Priority] != priorityRank[b.Priority] {
+			return priorityRank[a.Priority] > priorityRank[b.Priority]
+		}
+		return a.queuedAt().Before(b.queuedAt())
+	})
+	return pending
+}
+
+// endSlice returns a job whose attempt stopped at its time slice to the back
+// of its class in the queue, resuming from the partial result it wrote
+func endSlice(job *Job, finished *progressEvent) {
+	job.Status = JobPending
+	job.Resume = finished.Output
+	job.QueuedAt = time.Now()
+	job.Slices++
+}
+
+// jobSlice returns the slice an attempt of the job runs for: none for a job
+// with its own -run-deadline, which ends it for good
+func jobSlice(job *Job, slice time.Duration) time.Duration {
+	if slice == 0 {
+		return 0
+	}
+	if config, err := parseFlags(job.Args); err != nil || config.RunDeadline > 0 {
+		return 0
+	}
+	return slice
+}
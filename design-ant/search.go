@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"design-ant/pkg/pdfanalysis"
+	"github.com/philippgille/chromem-go"
+)
+
+// maxPassageChars caps one embedded passage; page analyses are split on paragraphs
+const maxPassageChars = 2000
+
+// maxConcurrentEmbeds limits parallel embedding requests while indexing
+const maxConcurrentEmbeds = 4
+
+// defaultSearchIndexPath returns DESIGN_ANT_INDEX or an index directory next to the run ledger
+func defaultSearchIndexPath() string {
+	if path := os.Getenv("DESIGN_ANT_INDEX"); path != "" {
+		return path
+	}
+	return filepath.Join(filepath.Dir(defaultLedgerPath()), "index")
+}
+
+// openSearchCollection opens the persistent index and the collection for the
+// embedder. Each embedding model gets its own collection because vectors of
+// different models cannot be compared.
+func openSearchCollection(dbPath string, embed *embedder) (*chromem.Collection, error) {
+	db, err := chromem.NewPersistentDB(dbPath, true)
+	if err != nil {
+		return nil, fmt.Errorf("error opening index %s: %v", dbPath, err)
+	}
+	name := "analyses-" + strings.NewReplacer("/", "-", ".", "-").Replace(embed.name)
+	collection, err := db.GetOrCreateCollection(name, map[string]string{"embedder": embed.name}, embed.document)
+	if err != nil {
+		return nil, fmt.Errorf("error opening collection %s: %v", name, err)
+	}
+	return collection, nil
+}
+
+// runSemanticIndex embeds the analyses of result files into the local index.
+// Re-indexing a result file replaces its earlier passages.
+func runSemanticIndex(args []string) error {
+	fs := flag.NewFlagSet("index", flag.ContinueOnError)
+	dbPath := fs.String("db", defaultSearchIndexPath(), "index directory")
+	provider := fs.String("embedder", EmbedderGemini, "embedding provider: gemini or openai")
+	model := fs.String("embed-model", "", "embedding model (default depends on -embedder)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: go run . index [-db dir] [-embedder gemini|openai] <result.json>...")
+	}
+
+	embed, err := newEmbedder(*provider, *model)
+	if err != nil {
+		return err
+	}
+	collection, err := openSearchCollection(*dbPath, embed)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	for _, filename := range fs.Args() {
+		result, err := pdfanalysis.LoadResult(filename)
+		if err != nil {
+			return err
+		}
+		resultPath := absPath(filename)
+		if err := collection.Delete(ctx, map[string]string{"result": resultPath}, nil); err != nil {
+			return fmt.Errorf("error removing old passages of %s: %v", filename, err)
+		}
+
+		docs := searchDocuments(*result, resultPath)
+		if len(docs) == 0 {
+			fmt.Printf("⚠️  %s has no successful analyses to index\n", filename)
+			continue
+		}
+		fmt.Printf("🔄 Embedding %d passages from %s...\n", len(docs), filename)
+		if err := collection.AddDocuments(ctx, docs, maxConcurrentEmbeds); err != nil {
+			return fmt.Errorf("error indexing %s: %v", filename, err)
+		}
+	}
+	fmt.Printf("✅ Index %s now holds %d passages (%s)\n", *dbPath, collection.Count(), embed.name)
+	return nil
+}
+
+// searchDocuments splits the page analyses and the document summary of a
+// result into passages with page metadata
+func searchDocuments(result pdfanalysis.FullAnalysisResult, resultPath string) []chromem.Document {
+	document := filepath.Base(result.PDFPath)
+	var docs []chromem.Document
+	add := func(startPage, endPage int, drawing, text string) {
+		for i, passage := range splitPassages(text, maxPassageChars) {
+			docs = append(docs, chromem.Document{
+				ID: fmt.Sprintf("%s#%d-%d/%d", resultPath, startPage, endPage, i),
+				Metadata: map[string]string{
+					"result":     resultPath,
+					"document":   document,
+					"start_page": strconv.Itoa(startPage),
+					"end_page":   strconv.Itoa(endPage),
+					"drawing":    drawing,
+				},
+				Content: passage,
+			})
+		}
+	}
+
+	for _, chunk := range result.Chunks {
+		if chunk.Error != "" {
+			continue
+		}
+		drawing := ""
+		if chunk.Metadata != nil {
+			drawing = chunk.Metadata.DrawingNumber
+		}
+		add(chunk.StartPage, chunk.EndPage, drawing, chunk.Analysis)
+	}
+	if c := result.Consolidated; c != nil && c.Error == "" {
+		add(1, result.TotalPages, "", c.Analysis)
+	}
+	return docs
+}
+
+// splitPassages cuts text into passages of at most limit characters on
+// paragraph boundaries; a single longer paragraph becomes its own passage
+func splitPassages(text string, limit int) []string {
+	var passages []string
+	var current strings.Builder
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		if current.Len() > 0 && current.Len()+len(paragraph)+2 > limit {
+			passages = append(passages, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(paragraph)
+	}
+	if current.Len() > 0 {
+		passages = append(passages, current.String())
+	}
+	return passages
+}
+
+// runQuery retrieves the passages most relevant to a question and, unless
+// -answer=false, asks the model to answer from them with page citations
+func runQuery(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	dbPath := fs.String("db", defaultSearchIndexPath(), "index directory")
+	provider := fs.String("embedder", EmbedderGemini, "embedding provider the index was built with: gemini or openai")
+	model := fs.String("embed-model", "", "embedding model the index was built with")
+	k := fs.Int("k", 8, "number of passages to retrieve")
+	document := fs.String("doc", "", "only search this document (PDF file name)")
+	answer := fs.Bool("answer", true, "ask the model to answer from the retrieved passages")
+	modelName := fs.String("model", pdfanalysis.DefaultModel, "model used to answer")
+	pricingFile := fs.String("pricing-file", "", "read model prices from this JSON file instead of the built-in pricing.json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	question := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if question == "" || *k < 1 {
+		return fmt.Errorf("usage: go run . query [-db dir] [-k 8] [-doc name.pdf] [-answer=false] <question>")
+	}
+	if *pricingFile != "" {
+		if err := pdfanalysis.LoadPricingFile(*pricingFile); err != nil {
+			return err
+		}
+	}
+
+	embed, err := newEmbedder(*provider, *model)
+	if err != nil {
+		return err
+	}
+	collection, err := openSearchCollection(*dbPath, embed)
+	if err != nil {
+		return err
+	}
+	if collection.Count() == 0 {
+		return fmt.Errorf("index %s has no passages for %s; run 'go run . index' first", *dbPath, embed.name)
+	}
+
+	ctx := context.Background()
+	queryEmbedding, err := embed.query(ctx, question)
+	if err != nil {
+		return fmt.Errorf("error embedding question: %v", err)
+	}
+	var where map[string]string
+	if *document != "" {
+		where = map[string]string{"document": *document}
+	}
+	hits, err := collection.QueryEmbedding(ctx, queryEmbedding, min(*k, collection.Count()), where, nil)
+	if err != nil {
+		return fmt.Errorf("error querying index: %v", err)
+	}
+	if len(hits) == 0 {
+		fmt.Println("No matching passages found.")
+		return nil
+	}
+
+	fmt.Printf("🔍 %d relevant passages:\n", len(hits))
+	for i, hit := range hits {
+		fmt.Printf("  %d. %s (%.3f) %s\n", i+1, searchHitLabel(hit), hit.Similarity, pdfanalysis.Truncate(strings.Join(strings.Fields(hit.Content), " "), 80))
+	}
+	if !*answer {
+		return nil
+	}
+
+	apiKey := os.Getenv("ANTHROPIC_API_KEY")
+	if apiKey == "" {
+		return fmt.Errorf("ANTHROPIC_API_KEY not found in environment variables (use -answer=false to only list passages)")
+	}
+	content := []map[string]interface{}{
+		{
+			"type": "text",
+			"text": searchAnswerPrompt(question, hits),
+		},
+	}
+	text, inputTokens, outputTokens, err := pdfanalysis.SendMessage(ctx, apiKey, *modelName, content)
+	if err != nil {
+		return fmt.Errorf("error answering question: %v", err)
+	}
+	pricing := pdfanalysis.GetPricing(*modelName)
+	cost := float64(inputTokens)/1_000_000*pricing.InputPricePerMTokens + float64(outputTokens)/1_000_000*pricing.OutputPricePerMTokens
+
+	fmt.Println()
+	fmt.Println(strings.TrimSpace(text))
+	fmt.Println()
+	fmt.Printf("💰 %d input + %d output tokens, $%.6f\n", inputTokens, outputTokens, cost)
+	return nil
+}
+
+// searchHitLabel names the document and pages of a retrieved passage
+func searchHitLabel(hit chromem.Result) string {
+	start, _ := strconv.Atoi(hit.Metadata["start_page"])
+	end, _ := strconv.Atoi(hit.Metadata["end_page"])
+	label := hit.Metadata["document"] + ", " + pdfanalysis.PageRangeLabel(start, end)
+	if drawing := hit.Metadata["drawing"]; drawing != "" {
+		label += ", drawing " + drawing
+	}
+	return label
+}
+
+// searchAnswerPrompt asks for an answer grounded in the retrieved passages
+func searchAnswerPrompt(question string, hits []chromem.Result) string {
+	var b strings.Builder
+	for _, hit := range hits {
+		fmt.Fprintf(&b, "<excerpt source=%q>\n%s\n</excerpt>\n\n", searchHitLabel(hit), strings.TrimSpace(hit.Content))
+	}
+	return fmt.Sprintf(`The following excerpts come from analyses of engineering drawing packages.
+Answer the question using only these excerpts.
+
+RULES:
+- Cite the source of every fact as (document, page)
+- Use exact part numbers, drawing numbers, and values from the excerpts
+- If the excerpts do not contain the answer, say so instead of guessing
+
+%s
+QUESTION: %s`, b.String(), question)
+}
@@ -0,0 +1,753 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"design-ant/pkg/pdfanalysis"
+)
+
+// How often an event stream checks the job and its events file, and how
+// often it sends a comment so proxies keep an idle stream open
+const (
+	eventPollInterval = 500 * time.Millisecond
+	eventKeepAlive    = 15 * time.Second
+)
+
+// server is the HTTP API over a job store
+type server struct {
+	store         *jobStore
+	workDir       string         // Directory jobs submitted over HTTP run in
+	maxUpload     int64          // Largest uploaded document in bytes
+	maxJobWorkers int            // Most concurrent page requests of a submitted job
+	auth          *authenticator // nil = every request is allowed
+	readiness     readiness
+}
+
+// runServe serves the job queue over HTTP: jobs are submitted and looked up
+// as JSON and followed as server-sent events, and workers in this process
+// run them like 'jobs run -poll'
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "address to listen on")
+	grpcAddr := fs.String("grpc-addr", "", "also serve the gRPC service of proto/designant.proto on this address, e.g. 127.0.0.1:9090 (empty = off)")
+	dir := fs.String("dir", defaultJobsDir(), "jobs directory")
+	queue := fs.String("queue", os.Getenv("DESIGN_ANT_QUEUE"), "keep the jobs on this Redis server, redis://host:port[/db], so runners on several machines work one queue (default: jobs.db in -dir)")
+	workDir := fs.String("work-dir", ".", "directory submitted jobs run in; relative document paths resolve and results are written here")
+	workers := fs.Int("workers", 1, "jobs run at the same time (0 = only serve the queue, run 'jobs run' elsewhere)")
+	tpm := fs.Int("tpm", 0, "input tokens per minute shared by all runners of the directory; each job gets an even share (0 = each job's own -tpm)")
+	slice := fs.Duration("slice", 0, "run each job for at most this long, e.g. 5m, then requeue it to resume after the other queued jobs (0 = run jobs to the end)")
+	maxUploadMB := fs.Int("max-upload-mb", 512, "largest document accepted as an upload")
+	maxJobWorkers := fs.Int("max-job-workers", 16, "most concurrent page requests a submitted job may make; larger -workers and concurrency values are lowered to it")
+	retentionDays := fs.Int("retention-days", 0, "delete uploaded documents, outputs, and logs of jobs finished this many days ago (0 = keep)")
+	retainJSON := fs.Bool("retain-json", false, "with -retention-days, keep the JSON result of expired jobs and delete everything else")
+	results := fs.String("result-store", "", "keep the results of jobs in this directory, sqlite: database, or s3://, gs://, az:// prefix (default: each job's own, in its directory)")
+	tokensPath := fs.String("tokens", "", "JSON file of static bearer tokens with their roles and tenant")
+	oidcIssuer := fs.String("oidc-issuer", "", "accept bearer tokens signed by this OpenID Connect issuer")
+	oidcAudience := fs.String("oidc-audience", "", "audience tokens must be issued for (required with -oidc-issuer)")
+	oidcRoles := fs.String("oidc-roles-claim", "roles", "with -oidc-issuer, claim listing the caller's roles")
+	oidcTenant := fs.String("oidc-tenant-claim", "tenant", "with -oidc-issuer, claim naming the caller's tenant")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *workers < 0 || *tpm < 0 || *slice < 0 || *retentionDays < 0 || *maxUploadMB < 1 || *maxJobWorkers < 1 {
+		return fmt.Errorf("-workers, -tpm, -slice, and -retention-days must not be negative, -max-upload-mb and -max-job-workers must be positive")
+	}
+	wd, err := filepath.Abs(*workDir)
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("error locating the design-ant binary: %v", err)
+	}
+	if err := checkStorage(*results); err != nil {
+		return err
+	}
+	store, err := openJobStore(*dir, *queue)
+	if err != nil {
+		return err
+	}
+	defer store.close()
+	store.runner, store.results = runnerID(), absStoreURI(*results)
+	if err := requeueStale(store); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	s := &server{store: store, workDir: wd, maxUpload: int64(*maxUploadMB) << 20, maxJobWorkers: *maxJobWorkers}
+	if *tokensPath != "" || *oidcIssuer != "" {
+		s.auth = &authenticator{}
+		if *tokensPath != "" {
+			if s.auth.tokens, err = loadTokens(*tokensPath); err != nil {
+				return err
+			}
+		}
+		if *oidcIssuer != "" {
+			if s.auth.oidc, err = newOIDCVerifier(ctx, *oidcIssuer, *oidcAudience, *oidcRoles, *oidcTenant); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("🔐 Requiring bearer tokens: %d static token(s)", len(s.auth.tokens))
+		if *oidcIssuer != "" {
+			fmt.Printf(", OIDC from %s", *oidcIssuer)
+		}
+		fmt.Println()
+	} else {
+		for _, listen := range []string{*addr, *grpcAddr} {
+			if host, _, _ := net.SplitHostPort(listen); listen != "" && !isLoopback(host) {
+				slog.Warn("Serving without authentication on a non-loopback address; set -tokens or -oidc-issuer", "addr", listen)
+			}
+		}
+	}
+	httpServer := &http.Server{
+		Addr:    *addr,
+		Handler: s.routes(),
+		// Ends open event streams on shutdown
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	if *grpcAddr != "" {
+		listener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			return fmt.Errorf("error listening for gRPC: %v", err)
+		}
+		grpcServer := newGRPCServer(s)
+		go func() {
+			<-ctx.Done()
+			// Open streams only follow their jobs, which stay queued
+			grpcServer.Stop()
+		}()
+		go grpcServer.Serve(listener)
+		fmt.Printf("🌐 Serving gRPC on %s\n", *grpcAddr)
+	}
+	var wg sync.WaitGroup
+	if *workers > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			workQueue(ctx, store, exe, *workers, 2*time.Second, *tpm, *slice)
+		}()
+	}
+	if *retentionDays > 0 {
+		policy := retentionPolicy{after: time.Duration(*retentionDays) * 24 * time.Hour, keepJSON: *retainJSON}
+		go runRetention(ctx, store, policy)
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdown)
+	}()
+
+	fmt.Printf("🌐 Serving jobs in %s on http://%s with %d worker(s)\n", store.location(), *addr, *workers)
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	wg.Wait()
+	fmt.Println("⏹️  Server stopped; unfinished jobs stay queued")
+	return nil
+}
+
+// routes returns the handler of the API
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /jobs", s.require(roleRead, s.listJobs))
+	mux.HandleFunc("POST /jobs", s.require(roleSubmit, s.submitJob))
+	mux.HandleFunc("GET /jobs/{id}", s.require(roleRead, s.getJob))
+	mux.HandleFunc("GET /jobs/{id}/events", s.require(roleRead, s.streamEvents))
+	mux.HandleFunc("GET /jobs/{id}/result", s.require(roleRead, s.getResult))
+	mux.HandleFunc("DELETE /jobs/{id}", s.require(roleAdmin, s.purgeJob))
+	mux.HandleFunc("GET /tenants", s.require(roleRead, s.listTenants))
+	// Probes stay open so load balancers need no token
+	mux.HandleFunc("GET /healthz", s.healthz)
+	mux.HandleFunc("GET /readyz", s.readyz)
+	return mux
+}
+
+// requestTenant returns the tenant of a request, nil when it has none or no
+// tenants are configured. An authenticated caller acts for its token's
+// tenant; only admins, and every caller when auth is off, pick one with the
+// X-Tenant header.
+func (s *server) requestTenant(r *http.Request) (*Tenant, error) {
+	return s.callerTenant(r.Context(), r.Header.Get("X-Tenant"))
+}
+
+// callerTenant returns the tenant the caller of ctx acts for, given the
+// tenant it asked for (empty = none), as requestTenant does for X-Tenant
+func (s *server) callerTenant(ctx context.Context, name string) (*Tenant, error) {
+	tenants, err := s.store.tenants()
+	if err != nil || tenants == nil {
+		return nil, err
+	}
+	if p := principalFrom(ctx); p != nil && !p.has(roleAdmin) {
+		if p.tenant == "" {
+			return nil, fmt.Errorf("%s is not assigned a tenant", p.name)
+		}
+		name = p.tenant
+	}
+	if name == "" {
+		return nil, nil
+	}
+	tenant := tenants[name]
+	if tenant == nil {
+		return nil, fmt.Errorf("unknown tenant %q", name)
+	}
+	return tenant, nil
+}
+
+// visible reports whether a request for tenant may see a job; requests
+// without a tenant see all jobs
+func visible(job *Job, tenant *Tenant) bool {
+	return tenant == nil || job.Tenant == tenant.Name
+}
+
+// jobStatus is a job with its latest progress event
+type jobStatus struct {
+	*Job
+	Progress *progressEvent `json:"progress,omitempty"`
+}
+
+// writeJSON writes v as the response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeError writes an error response as {"error": "..."}
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// listJobs returns all jobs, or those in the state given by ?status=
+func (s *server) listJobs(w http.ResponseWriter, r *http.Request) {
+	tenant, err := s.requestTenant(r)
+	if err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	jobs, err := s.store.list()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	status := r.URL.Query().Get("status")
+	shown := []*Job{}
+	for _, job := range jobs {
+		if (status == "" || job.Status == status) && visible(job, tenant) {
+			shown = append(shown, job)
+		}
+	}
+	writeJSON(w, http.StatusOK, shown)
+}
+
+// jobRequest holds the form fields of POST /jobs
+type jobRequest struct {
+	Args        []string `json:"args"`
+	Concurrency int      `json:"concurrency"`
+	Budget      float64  `json:"budget"`
+	Priority    string   `json:"priority"`
+}
+
+// submitJob queues a job from a multipart form uploading the document, with
+// "args" the analysis flags as for 'jobs add' and "concurrency", "budget",
+// and "priority" fields. With tenants configured, the job is the X-Tenant's,
+// which must have budget left.
+func (s *server) submitJob(w http.ResponseWriter, r *http.Request) {
+	tenant, err := s.requestTenant(r)
+	if err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	if tenants, _ := s.store.tenants(); tenants != nil && tenant == nil {
+		writeError(w, http.StatusForbidden, fmt.Errorf("missing X-Tenant header"))
+		return
+	}
+	// Documents given as paths could name any file the server can read
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("upload the document as a multipart form with the file in \"document\""))
+		return
+	}
+	req, upload, err := s.receiveUpload(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if status, err := s.submit(w, req, upload, tenant); err != nil {
+		os.RemoveAll(upload)
+		writeError(w, status, err)
+	}
+}
+
+// httpFlags are the analysis flags a job submitted over HTTP may set, with
+// whether each is a boolean. The others name files, URIs, or commands on
+// this machine (-capture-dir, -output-uri, -context-file, -post-hook, ...),
+// so only its operator sets them.
+var httpFlags = map[string]bool{
+	"max-pages": false, "max-chunk-mb": false, "max-total-mb": false, "tpm": false, "workers": false,
+	"chunk-timeout": false, "run-deadline": false, "max-cost": false, "no-progress": true, "reproducible": true,
+	"input-mode": false, "structured": true, "welds": true, "units": false, "two-stage": true,
+	"prompt-pack": false, "prompt-var": false, "classify-model": false, "consolidate": true, "summary": true,
+	"summary-model": false, "fan-in": false, "annotated-pdf": true, "markdown": false, "escalate-model": false,
+	"neutral-retry": true, "ground": true, "compress": false, "output-lang": false, "lang-mode": false,
+	"terminology": false, "unit-order": false, "translate-model": false, "jsonl": true, "project": false,
+}
+
+// checkHTTPArgs checks that the analysis flags of a job submitted over HTTP
+// are all in httpFlags and that its document is the one uploaded to dir
+func checkHTTPArgs(args []string, dir string) error {
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			i++
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			break
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		isBool, ok := httpFlags[name]
+		if !ok {
+			return fmt.Errorf("-%s cannot be set over HTTP", name)
+		}
+		if !isBool && !hasValue {
+			i++
+		}
+	}
+	if documents := args[min(i, len(args)):]; len(documents) != 1 || filepath.Dir(filepath.Clean(documents[0])) != filepath.Clean(dir) {
+		return fmt.Errorf("the document must be the uploaded file, and flags must come before it")
+	}
+	return nil
+}
+
+// clampWorkers lowers the -workers values of checked HTTP job flags to max
+func clampWorkers(args []string, max int) []string {
+	clamped := append([]string{}, args...)
+	for i := 0; i < len(clamped); i++ {
+		arg := clamped[i]
+		if arg == "--" || len(arg) < 2 || arg[0] != '-' {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name != "workers" {
+			if !hasValue && !httpFlags[name] {
+				i++
+			}
+			continue
+		}
+		if !hasValue && i+1 < len(clamped) {
+			i++
+			value = clamped[i]
+		}
+		if n, err := strconv.Atoi(value); err == nil && n > max {
+			if hasValue {
+				clamped[i] = fmt.Sprintf("-workers=%d", max)
+			} else {
+				clamped[i] = strconv.Itoa(max)
+			}
+		}
+	}
+	return clamped
+}
+
+// submit checks and queues a job for the document uploaded to upload,
+// writing the created job on success and returning the HTTP status of a
+// failure
+func (s *server) submit(w http.ResponseWriter, req jobRequest, upload string, tenant *Tenant) (int, error) {
+	job, status, err := s.queueUpload(req, upload, tenant)
+	if err != nil {
+		return status, err
+	}
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	writeJSON(w, http.StatusCreated, job)
+	return 0, nil
+}
+
+// queueUpload checks and queues a job for the document uploaded to upload,
+// returning the HTTP status of a failure
+func (s *server) queueUpload(req jobRequest, upload string, tenant *Tenant) (*Job, int, error) {
+	if err := checkHTTPArgs(req.Args, upload); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	req.Args = clampWorkers(req.Args, s.maxJobWorkers)
+	req.Concurrency = min(req.Concurrency, s.maxJobWorkers)
+	job, err := newJob(upload, req.Args, req.Concurrency, req.Budget, req.Priority)
+	if err != nil {
+		// Without the usage text that follows flag errors
+		message, _, _ := strings.Cut(err.Error(), "\n")
+		return nil, http.StatusBadRequest, errors.New(message)
+	}
+	job.Upload = upload
+	if status, err := s.queue(job, tenant); err != nil {
+		return nil, status, err
+	}
+	return job, 0, nil
+}
+
+// receiveUpload stores the document of a multipart job request in a new
+// directory under uploads/ in the work directory and returns the request
+// with the document as its last argument. The form has a "document" file
+// and optional "args" (a JSON array of analysis flags), "concurrency",
+// "budget", and "priority" fields.
+func (s *server) receiveUpload(w http.ResponseWriter, r *http.Request) (jobRequest, string, error) {
+	var req jobRequest
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUpload+1<<20)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return req, "", fmt.Errorf("invalid upload: %v", err)
+	}
+	dir, err := s.newUpload()
+	if err != nil {
+		return req, "", err
+	}
+	document, err := readUploadForm(reader, dir, &req)
+	if err == nil && document == "" {
+		err = fmt.Errorf("missing document file")
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return req, "", err
+	}
+	req.Args = append(req.Args, document)
+	return req, dir, nil
+}
+
+// newUpload creates the directory of a new upload under uploads/ in the
+// work directory
+func (s *server) newUpload() (string, error) {
+	if err := os.MkdirAll(filepath.Join(s.workDir, "uploads"), 0755); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(filepath.Join(s.workDir, "uploads"), "upload-*")
+}
+
+// checkDocumentName rejects uploads that are not a PDF or Office document
+func checkDocumentName(name string) error {
+	if !strings.EqualFold(filepath.Ext(name), ".pdf") && !pdfanalysis.IsOfficeDocument(name) {
+		return fmt.Errorf("unsupported document %q: upload a PDF or Office document", name)
+	}
+	return nil
+}
+
+// readUploadForm reads the fields of an upload form into req and saves its
+// document in dir, returning the document's path
+func readUploadForm(reader *multipart.Reader, dir string, req *jobRequest) (string, error) {
+	var document string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return document, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid upload: %v", err)
+		}
+		if part.FormName() == "document" {
+			name := filepath.Base(part.FileName())
+			if err := checkDocumentName(name); err != nil {
+				return "", err
+			}
+			document = filepath.Join(dir, name)
+			file, err := os.Create(document)
+			if err != nil {
+				return "", err
+			}
+			_, err = io.Copy(file, part)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return "", fmt.Errorf("error receiving %s: %v", name, err)
+			}
+			continue
+		}
+		value, err := io.ReadAll(io.LimitReader(part, 1<<20))
+		if err != nil {
+			return "", fmt.Errorf("invalid upload: %v", err)
+		}
+		switch part.FormName() {
+		case "args":
+			err = json.Unmarshal(value, &req.Args)
+		case "concurrency":
+			req.Concurrency, err = strconv.Atoi(string(value))
+		case "budget":
+			req.Budget, err = strconv.ParseFloat(string(value), 64)
+		case "priority":
+			req.Priority = string(value)
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid %s field: %v", part.FormName(), err)
+		}
+	}
+}
+
+// queue saves a new job for tenant, checking the tenant's budget under the
+// store lock. It returns the HTTP status of a failure.
+func (s *server) queue(job *Job, tenant *Tenant) (int, error) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	if tenant != nil {
+		jobs, err := s.store.list()
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if usage := tenantUsage(jobs, tenant, time.Now()); usage.remaining() == 0 {
+			return http.StatusTooManyRequests, fmt.Errorf("monthly budget of $%.2f spent ($%.2f in %s)", tenant.Budget, usage.Spent, usage.Month)
+		}
+		job.Tenant = tenant.Name
+	}
+	if err := s.store.save(job); err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("error saving job: %v", err)
+	}
+	return 0, nil
+}
+
+// lookupJob loads the job named in the path, writing a 404 when there is
+// none or it belongs to another tenant
+func (s *server) lookupJob(w http.ResponseWriter, r *http.Request) *Job {
+	tenant, err := s.requestTenant(r)
+	if err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return nil
+	}
+	id := r.PathValue("id")
+	// IDs name files in the jobs directory
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no job %q", id))
+		return nil
+	}
+	job, err := s.store.load(id)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && !visible(job, tenant)) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no job %q", id))
+		return nil
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil
+	}
+	return job
+}
+
+// getJob returns a job with its latest progress
+func (s *server) getJob(w http.ResponseWriter, r *http.Request) {
+	job := s.lookupJob(w, r)
+	if job == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, jobStatus{Job: job, Progress: lastProgress(s.store.eventsPath(job.ID))})
+}
+
+// getResult returns the JSON result of a job from its result store, or the
+// pages streamed so far while it runs
+func (s *server) getResult(w http.ResponseWriter, r *http.Request) {
+	job := s.lookupJob(w, r)
+	if job == nil {
+		return
+	}
+	result, err := s.jobResult(r.Context(), job)
+	if errors.Is(err, pdfanalysis.ErrResultNotFound) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %s has no result yet", job.ID))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// jobConfig returns the analysis settings of a job, with the server's
+// -result-store unless the job gives its own
+func (s *server) jobConfig(job *Job) (*pdfanalysis.Config, error) {
+	args := job.Args
+	if s.store.results != "" {
+		args = append([]string{"-result-store", s.store.results}, args...)
+	}
+	return parseFlags(args)
+}
+
+// jobResult loads the result of a job from its result store, or the pages
+// streamed so far while it runs
+func (s *server) jobResult(ctx context.Context, job *Job) (*pdfanalysis.Result, error) {
+	config, err := s.jobConfig(job)
+	if err != nil {
+		return nil, err
+	}
+	store, err := openResultStore(config.StoreURI, job.Dir)
+	if err != nil {
+		return nil, err
+	}
+	defer closeResultStore(store)
+	return store.Load(ctx, resultName(config))
+}
+
+// listTenants returns the month's accounting of every tenant, or only the
+// X-Tenant's
+func (s *server) listTenants(w http.ResponseWriter, r *http.Request) {
+	tenant, err := s.requestTenant(r)
+	if err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	tenants, err := s.store.tenants()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	jobs, err := s.store.list()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	usage := []TenantUsage{}
+	for _, t := range tenants {
+		if tenant == nil || t.Name == tenant.Name {
+			usage = append(usage, tenantUsage(jobs, t, time.Now()))
+		}
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Tenant < usage[j].Tenant })
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// purgeJob deletes the files of a finished job now, as retention would,
+// including its JSON result
+func (s *server) purgeJob(w http.ResponseWriter, r *http.Request) {
+	job := s.lookupJob(w, r)
+	if job == nil {
+		return
+	}
+	if job.Status != JobDone && job.Status != JobFailed {
+		writeError(w, http.StatusConflict, fmt.Errorf("job %s is %s; only finished jobs can be purged", job.ID, job.Status))
+		return
+	}
+	if err := s.store.purge(job, false); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// lastProgress returns the last event of an events file, or nil
+func lastProgress(path string) *progressEvent {
+	line := lastLogLine(path)
+	if line == "" {
+		return nil
+	}
+	var event progressEvent
+	if json.Unmarshal([]byte(line), &event) != nil {
+		return nil
+	}
+	return &event
+}
+
+// streamEvents streams a job's progress as server-sent events: every event
+// of its events file as an event named after its type (start, page,
+// finished), and a status event with the job whenever its state changes.
+// The stream ends once the job is done or failed. Event IDs are offsets in
+// the events file, so a client reconnecting with Last-Event-ID continues
+// where it stopped.
+func (s *server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	job := s.lookupJob(w, r)
+	if job == nil {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+	var offset int64
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		offset, _ = strconv.ParseInt(id, 10, 64)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	status := ""
+	lastWrite := time.Now()
+	for {
+		// The job is read before the events, so the last events of a
+		// finished job are always sent before the stream ends
+		current, err := s.store.load(job.ID)
+		if err != nil {
+			return
+		}
+		var sent bool
+		offset, sent = sendEvents(w, s.store.eventsPath(job.ID), offset)
+		if current.Status != status {
+			status = current.Status
+			data, _ := json.Marshal(current)
+			fmt.Fprintf(w, "event: status\ndata: %s\n\n", data)
+			sent = true
+		}
+		if !sent && time.Since(lastWrite) >= eventKeepAlive {
+			fmt.Fprint(w, ": keep-alive\n\n")
+			sent = true
+		}
+		if sent {
+			flusher.Flush()
+			lastWrite = time.Now()
+		}
+		if status == JobDone || status == JobFailed {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(eventPollInterval):
+		}
+	}
+}
+
+// sendEvents writes the complete lines of an events file after offset as
+// server-sent events and returns the new offset
+func sendEvents(w io.Writer, path string, offset int64) (int64, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return offset, false
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return offset, false
+	}
+	sent := false
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// A partial line is read again once it is complete
+			return offset, sent
+		}
+		offset += int64(len(line))
+		var event progressEvent
+		if json.Unmarshal([]byte(line), &event) != nil {
+			slog.Warn("Skipping unreadable progress event", "path", path, "offset", offset)
+			continue
+		}
+		fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", offset, event.Type, strings.TrimSpace(line))
+		sent = true
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCheckHTTPArgs(t *testing.T) {
+	upload := filepath.Join(t.TempDir(), "uploads", "upload-1")
+	document := filepath.Join(upload, "pump.pdf")
+	tests := []struct {
+		name string
+		args []string
+		ok   bool
+	}{
+		{"document only", []string{document}, true},
+		{"allowed flags", []string{"-structured", "-markdown", "github", "-max-cost=2", "--ground", "-prompt-var", "customer=ACME", document}, true},
+		{"project", []string{"-project", "pump-line", document}, true},
+		{"bool flag with value", []string{"-summary=false", document}, true},
+		{"end of flags", []string{"-welds", "--", document}, true},
+		{"capture dir", []string{"-capture-dir", "/tmp", document}, false},
+		{"output uri", []string{"-output-uri=s3://bucket/x", document}, false},
+		{"context file", []string{"-context-file", "/etc/passwd", document}, false},
+		{"post hook", []string{"--post-hook", "rm -rf /", document}, false},
+		{"stage", []string{"-stage", "cat", document}, false},
+		{"result store", []string{"-result-store", "/srv", document}, false},
+		{"alert webhook", []string{"-alert-webhook", "http://169.254.169.254/", "-alert-at", "1", document}, false},
+		{"flag after allowed value", []string{"-markdown", "-capture-dir", document}, true},
+		{"other document", []string{"/srv/uploads/upload-2/secret.pdf", document}, false},
+		{"path outside upload", []string{filepath.Join(upload, "..", "upload-2", "secret.pdf")}, false},
+		{"cloud document", []string{"s3://bucket/pump.pdf"}, false},
+		{"flag after document", []string{document, "-capture-dir", "/tmp"}, false},
+		{"no document", []string{"-structured"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkHTTPArgs(tt.args, upload)
+			if (err == nil) != tt.ok {
+				t.Errorf("checkHTTPArgs(%q) = %v, want ok %v", tt.args, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestClampWorkers(t *testing.T) {
+	tests := []struct {
+		args, want []string
+	}{
+		{[]string{"-workers", "500", "pump.pdf"}, []string{"-workers", "16", "pump.pdf"}},
+		{[]string{"--workers=500", "pump.pdf"}, []string{"-workers=16", "pump.pdf"}},
+		{[]string{"-workers", "8", "pump.pdf"}, []string{"-workers", "8", "pump.pdf"}},
+		{[]string{"-markdown", "500", "-structured", "-workers=17", "pump.pdf"}, []string{"-markdown", "500", "-structured", "-workers=16", "pump.pdf"}},
+		{[]string{"-max-pages", "500", "pump.pdf"}, []string{"-max-pages", "500", "pump.pdf"}},
+	}
+	for _, tt := range tests {
+		if got := clampWorkers(tt.args, 16); !slices.Equal(got, tt.want) {
+			t.Errorf("clampWorkers(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sqliteQueueSchema creates the tables of a queue database: each job as
+// JSON, the locks with the runner holding them and when they were last
+// refreshed, and the runners' heartbeats. Times are Unix nanoseconds.
+const sqliteQueueSchema = `
+CREATE TABLE IF NOT EXISTS jobs (
+	id   TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS locks (
+	name      TEXT PRIMARY KEY,
+	runner    TEXT NOT NULL,
+	refreshed INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS runners (
+	runner TEXT PRIMARY KEY,
+	beat   INTEGER NOT NULL
+);
+`
+
+// sqliteQueue keeps a queue in jobs.db in the jobs directory. Every change
+// is a transaction that takes the database's write lock when it begins, so
+// the runners on one machine claim jobs one at a time.
+type sqliteQueue struct {
+	path string
+	db   *sql.DB
+}
+
+// openSQLiteQueue opens the queue database of a jobs directory, creating it
+// and moving in the jobs of the JSON files earlier versions kept there
+func openSQLiteQueue(dir string) (*sqliteQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "jobs.db")
+	// The path is escaped, since a file: URI ends at ? and #
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?_pragma=busy_timeout(10000)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	q := &sqliteQueue{path: path, db: db}
+	if _, err := db.Exec(sqliteQueueSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := q.importJobFiles(dir); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+// importJobFiles moves {id}.json job files into the database. Their lock
+// files are left behind, so a job they ran is requeued as stale.
+func (q *sqliteQueue) importJobFiles(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(paths) == 0 {
+		return err
+	}
+	var imported []string
+	err = q.update(func(tx *sql.Tx) error {
+		for _, path := range paths {
+			if filepath.Base(path) == "tenants.json" {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if errors.Is(err, os.ErrNotExist) {
+				// Imported by another runner opening the queue
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			var job Job
+			if err := json.Unmarshal(data, &job); err != nil {
+				return fmt.Errorf("error parsing job %s: %v", path, err)
+			}
+			if _, err := tx.Exec("INSERT OR IGNORE INTO jobs (id, data) VALUES (?, ?)", job.ID, string(data)); err != nil {
+				return err
+			}
+			imported = append(imported, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error importing job files into %s: %v", q.path, err)
+	}
+	for _, path := range imported {
+		os.Remove(path)
+	}
+	return nil
+}
+
+// update runs fn in a transaction, committing it when fn succeeds
+func (q *sqliteQueue) update(fn func(tx *sql.Tx) error) error {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (q *sqliteQueue) location() string {
+	return q.path
+}
+
+func (q *sqliteQueue) ping(ctx context.Context) error {
+	return q.db.PingContext(ctx)
+}
+
+func (q *sqliteQueue) close() error {
+	return q.db.Close()
+}
+
+// saveJob writes a job in tx
+func saveJob(tx *sql.Tx, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO jobs (id, data) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data", job.ID, string(data))
+	return err
+}
+
+// listJobs reads all jobs from the database or a transaction
+func listJobs(db interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}) ([]*Job, error) {
+	rows, err := db.Query("SELECT id, data FROM jobs")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var jobs []*Job
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		var job Job
+		if err := json.Unmarshal([]byte(data), &job); err != nil {
+			return nil, fmt.Errorf("error parsing job %s: %v", id, err)
+		}
+		jobs = append(jobs, &job)
+	}
+	return jobs, rows.Err()
+}
+
+func (q *sqliteQueue) save(job *Job) error {
+	return q.update(func(tx *sql.Tx) error { return saveJob(tx, job) })
+}
+
+func (q *sqliteQueue) load(id string) (*Job, error) {
+	var data string
+	err := q.db.QueryRow("SELECT data FROM jobs WHERE id = ?", id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("job %s: %w", id, os.ErrNotExist)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, fmt.Errorf("error parsing job %s: %v", id, err)
+	}
+	return &job, nil
+}
+
+func (q *sqliteQueue) list() ([]*Job, error) {
+	return listJobs(q.db)
+}
+
+// claim reads the jobs, picks one, locks it, and marks it running in one
+// transaction, so no other runner claims in between
+func (q *sqliteQueue) claim(runner string, pick func(jobs []*Job) []*Job, start func(job *Job)) (*Job, error) {
+	var claimed *Job
+	err := q.update(func(tx *sql.Tx) error {
+		jobs, err := listJobs(tx)
+		if err != nil {
+			return err
+		}
+		for _, job := range pick(jobs) {
+			result, err := tx.Exec("INSERT INTO locks (name, runner, refreshed) VALUES (?, ?, ?) ON CONFLICT (name) DO NOTHING",
+				job.ID, runner, time.Now().UnixNano())
+			if err != nil {
+				return err
+			}
+			if n, err := result.RowsAffected(); err != nil || n == 0 {
+				// Still locked by a runner that is gone until it is requeued
+				continue
+			}
+			start(job)
+			claimed = job
+			return saveJob(tx, job)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+func (q *sqliteQueue) release(job *Job, runner string) (bool, error) {
+	released := false
+	err := q.update(func(tx *sql.Tx) error {
+		result, err := tx.Exec("DELETE FROM locks WHERE name = ? AND runner = ?", job.ID, runner)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		released = true
+		return saveJob(tx, job)
+	})
+	return released && err == nil, err
+}
+
+func (q *sqliteQueue) lock(name, runner string) (bool, error) {
+	result, err := q.db.Exec("INSERT INTO locks (name, runner, refreshed) VALUES (?, ?, ?) ON CONFLICT (name) DO NOTHING",
+		name, runner, time.Now().UnixNano())
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (q *sqliteQueue) refresh(name, runner string) error {
+	result, err := q.db.Exec("UPDATE locks SET refreshed = ? WHERE name = ? AND runner = ?", time.Now().UnixNano(), name, runner)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("lock %s: %w", name, errLockLost)
+	}
+	return err
+}
+
+func (q *sqliteQueue) unlock(name, runner string) {
+	q.db.Exec("DELETE FROM locks WHERE name = ? AND runner = ?", name, runner)
+}
+
+func (q *sqliteQueue) clearStale(name string) bool {
+	_, err := q.db.Exec("DELETE FROM locks WHERE name = ? AND refreshed <= ?", name, time.Now().Add(-jobLockStale).UnixNano())
+	return err == nil && !q.fresh(name)
+}
+
+func (q *sqliteQueue) fresh(name string) bool {
+	var refreshed int64
+	err := q.db.QueryRow("SELECT refreshed FROM locks WHERE name = ?", name).Scan(&refreshed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	// A database that cannot be read leaves locks alone
+	return err != nil || time.Since(time.Unix(0, refreshed)) < jobLockStale
+}
+
+func (q *sqliteQueue) beat(runner string) error {
+	_, err := q.db.Exec("INSERT INTO runners (runner, beat) VALUES (?, ?) ON CONFLICT (runner) DO UPDATE SET beat = excluded.beat",
+		runner, time.Now().UnixNano())
+	return err
+}
+
+func (q *sqliteQueue) endBeat(runner string) {
+	q.db.Exec("DELETE FROM runners WHERE runner = ?", runner)
+}
+
+func (q *sqliteQueue) runners() int {
+	var active int
+	q.db.QueryRow("SELECT COUNT(*) FROM runners WHERE beat > ?", time.Now().Add(-jobLockStale).UnixNano()).Scan(&active)
+	return active
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"design-ant/pkg/pdfanalysis"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"google.golang.org/api/iterator"
+)
+
+// Cloud storage URI schemes accepted for input documents and -output-uri
+var remoteSchemes = []string{"s3://", "gs://", "az://"}
+
+// isRemoteURI reports whether path is an s3://, gs://, or az:// URI
+func isRemoteURI(path string) bool {
+	for _, scheme := range remoteSchemes {
+		if strings.HasPrefix(path, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// storageClient reads, writes, and lists the objects of one cloud provider,
+// streaming them without staging a copy
+type storageClient interface {
+	read(ctx context.Context, uri string, w io.Writer) error
+	write(ctx context.Context, uri string, r io.Reader) error
+	// list returns the names of the objects directly under a prefix URI
+	list(ctx context.Context, prefix string) ([]string, error)
+}
+
+// storageClients holds the client of each provider, and of each Azure
+// account, once its credentials were found
+var storageClients = struct {
+	sync.Mutex
+	clients map[string]storageClient
+}{clients: make(map[string]storageClient)}
+
+// openStorage returns the client for uri. Credentials come from each
+// provider's standard SDK chain: environment, shared config and profiles,
+// instance, workload, or managed identity, and the credentials of `aws sso
+// login`, `gcloud auth application-default login`, or `az login`.
+func openStorage(uri string) (storageClient, error) {
+	key := uri[:strings.Index(uri, "://")+3]
+	if key == "az://" {
+		account, _, _, err := azureBlob(uri)
+		if err != nil {
+			return nil, err
+		}
+		key += account
+	}
+	storageClients.Lock()
+	defer storageClients.Unlock()
+	if client := storageClients.clients[key]; client != nil {
+		return client, nil
+	}
+	// Only finding the credentials is bounded; the client is used without
+	// a deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var client storageClient
+	var err error
+	switch key[:5] {
+	case "s3://":
+		client, err = openS3(ctx)
+	case "gs://":
+		client, err = openGCS(ctx)
+	case "az://":
+		client, err = openAzure(ctx, strings.TrimPrefix(key, "az://"))
+	default:
+		return nil, fmt.Errorf("unsupported URI %q: use s3://, gs://, or az://", uri)
+	}
+	if err != nil {
+		return nil, err
+	}
+	storageClients.clients[key] = client
+	return client, nil
+}
+
+// checkStorage fails when there are no credentials for one of the URIs, so a
+// run or runner stops at startup instead of when it first reads or writes the
+// URI, after the pages are paid for
+func checkStorage(uris ...string) error {
+	for _, uri := range uris {
+		if isRemoteURI(uri) {
+			if _, err := openStorage(uri); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// bucketObject splits s3://bucket/key or gs://bucket/object
+func bucketObject(uri string) (bucket, object string, err error) {
+	bucket, object, _ = strings.Cut(uri[strings.Index(uri, "://")+3:], "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("invalid URI %q: use %sbucket/path", uri, uri[:5])
+	}
+	return bucket, object, nil
+}
+
+// s3Storage streams objects with the AWS SDK; uploads of unknown size go in
+// parts
+type s3Storage struct {
+	client   *s3.Client
+	uploader *manager.Uploader
+}
+
+func openS3(ctx context.Context) (*s3Storage, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error loading the AWS configuration: %v", err)
+	}
+	if cfg.Region == "" {
+		// Where S3 redirects requests for buckets elsewhere
+		cfg.Region = "us-east-1"
+	}
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		return nil, fmt.Errorf("no AWS credentials for s3://: %v", err)
+	}
+	client := s3.NewFromConfig(cfg)
+	return &s3Storage{client: client, uploader: manager.NewUploader(client)}, nil
+}
+
+func (s *s3Storage) read(ctx context.Context, uri string, w io.Writer) error {
+	bucket, key, err := bucketObject(uri)
+	if err != nil {
+		return err
+	}
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: &key})
+	if err != nil {
+		return err
+	}
+	defer out.Body.Close()
+	_, err = io.Copy(w, out.Body)
+	return err
+}
+
+func (s *s3Storage) write(ctx context.Context, uri string, r io.Reader) error {
+	bucket, key, err := bucketObject(uri)
+	if err != nil {
+		return err
+	}
+	_, err = s.uploader.Upload(ctx, &s3.PutObjectInput{Bucket: &bucket, Key: &key, Body: r})
+	return err
+}
+
+func (s *s3Storage) list(ctx context.Context, prefix string) ([]string, error) {
+	bucket, key, err := bucketObject(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		key += "/"
+	}
+	var names []string
+	pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{Bucket: &bucket, Prefix: &key, Delimiter: aws.String("/")})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			names = append(names, strings.TrimPrefix(aws.ToString(object.Key), key))
+		}
+	}
+	return names, nil
+}
+
+// gcsStorage streams objects with the Cloud Storage client
+type gcsStorage struct {
+	client *storage.Client
+}
+
+func openGCS(ctx context.Context) (*gcsStorage, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("no Google Cloud credentials for gs://: %v", err)
+	}
+	return &gcsStorage{client: client}, nil
+}
+
+func (s *gcsStorage) read(ctx context.Context, uri string, w io.Writer) error {
+	bucket, object, err := bucketObject(uri)
+	if err != nil {
+		return err
+	}
+	r, err := s.client.Bucket(bucket).Object(object).NewReader(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func (s *gcsStorage) write(ctx context.Context, uri string, r io.Reader) error {
+	bucket, object, err := bucketObject(uri)
+	if err != nil {
+		return err
+	}
+	// A canceled context discards the upload
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := s.client.Bucket(bucket).Object(object).NewWriter(ctx)
+	if _, err := io.Copy(w, r); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func (s *gcsStorage) list(ctx context.Context, prefix string) ([]string, error) {
+	bucket, object, err := bucketObject(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if object != "" {
+		object += "/"
+	}
+	var names []string
+	objects := s.client.Bucket(bucket).Objects(ctx, &storage.Query{Prefix: object, Delimiter: "/"})
+	for {
+		attrs, err := objects.Next()
+		if errors.Is(err, iterator.Done) {
+			return names, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Prefixes of deeper objects are listed with an empty name
+		if attrs.Name != "" {
+			names = append(names, strings.TrimPrefix(attrs.Name, object))
+		}
+	}
+}
+
+// azureStorage streams the blobs of one storage account
+type azureStorage struct {
+	client *azblob.Client
+}
+
+func openAzure(ctx context.Context, account string) (*azureStorage, error) {
+	credential, err := azidentity.NewDefaultAzureCredential(nil)
+	if err == nil {
+		// The chain is only tried when a token is needed
+		_, err = credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://storage.azure.com/.default"}})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no Azure credentials for az://: %v", err)
+	}
+	client, err := azblob.NewClient("https://"+account+".blob.core.windows.net/", credential, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &azureStorage{client: client}, nil
+}
+
+func (s *azureStorage) read(ctx context.Context, uri string, w io.Writer) error {
+	_, container, blob, err := azureBlob(uri)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.DownloadStream(ctx, container, blob, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func (s *azureStorage) write(ctx context.Context, uri string, r io.Reader) error {
+	_, container, blob, err := azureBlob(uri)
+	if err != nil {
+		return err
+	}
+	if blob == "" {
+		return fmt.Errorf("invalid Azure URI %q: use az://account/container/blob", uri)
+	}
+	_, err = s.client.UploadStream(ctx, container, blob, r, nil)
+	return err
+}
+
+func (s *azureStorage) list(ctx context.Context, prefix string) ([]string, error) {
+	_, container, blob, err := azureBlob(strings.TrimSuffix(prefix, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if blob != "" {
+		blob += "/"
+	}
+	var names []string
+	pages := s.client.NewListBlobsFlatPager(container, &azblob.ListBlobsFlatOptions{Prefix: &blob})
+	for pages.More() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Segment.BlobItems {
+			if rest := strings.TrimPrefix(*item.Name, blob); !strings.Contains(rest, "/") {
+				names = append(names, rest)
+			}
+		}
+	}
+	return names, nil
+}
+
+// azureBlob splits az://account/container/path/to/blob; the blob is empty
+// for a URI of the whole container
+func azureBlob(uri string) (account, container, blob string, err error) {
+	parts := strings.SplitN(strings.TrimPrefix(uri, "az://"), "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", fmt.Errorf("invalid Azure URI %q: use az://account/container/blob", uri)
+	}
+	if len(parts) == 3 {
+		blob = parts[2]
+	}
+	return parts[0], parts[1], blob, nil
+}
+
+// listObjects returns the names of the objects directly under a prefix URI
+func listObjects(ctx context.Context, prefix string) ([]string, error) {
+	client, err := openStorage(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return client.list(ctx, strings.TrimSuffix(prefix, "/"))
+}
+
+// copyObject streams an object into a local file, or a local file into an
+// object when upload is set
+func copyObject(ctx context.Context, uri, local string, upload bool) error {
+	client, err := openStorage(uri)
+	if err != nil {
+		return err
+	}
+	if upload {
+		file, err := os.Open(local)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return client.write(ctx, uri, file)
+	}
+	file, err := os.Create(local)
+	if err != nil {
+		return err
+	}
+	if err := client.read(ctx, uri, file); err != nil {
+		file.Close()
+		os.Remove(local)
+		return err
+	}
+	return file.Close()
+}
+
+// fetchRemoteInput downloads a remote input document into dir. The URI is
+// kept as the source path, so outputs are named after it and record it.
+func fetchRemoteInput(ctx context.Context, config *pdfanalysis.Config, dir string) error {
+	uri := config.PDFPath
+	fmt.Printf("☁️  Downloading %s...\n", uri)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating download directory: %v", err)
+	}
+	local := filepath.Join(dir, path.Base(uri))
+	if err := copyObject(ctx, uri, local, false); err != nil {
+		return fmt.Errorf("error downloading %s: %v", uri, err)
+	}
+	config.SourcePath = uri
+	config.PDFPath = local
+	return nil
+}
+
+// uploadOutputs copies the output files of this run, {name}_analysis.* and
+// {name}_report.* written since start, to -output-uri. A failed upload is a
+// warning; the local files are still there.
+func uploadOutputs(ctx context.Context, config *pdfanalysis.Config, start time.Time) {
+	if config.OutputURI == "" {
+		return
+	}
+	uploaded := 0
+	for _, file := range outputFiles(".", config.DocumentPath(), start) {
+		uri := strings.TrimSuffix(config.OutputURI, "/") + "/" + filepath.Base(file)
+		if err := copyObject(ctx, uri, file, true); err != nil {
+			slog.Warn("Could not upload output file", "path", file, "uri", uri, "error", err)
+			continue
+		}
+		uploaded++
+	}
+	fmt.Printf("☁️  Uploaded %d output file(s) to %s\n", uploaded, config.OutputURI)
+}
+
+// outputFiles returns the output files of a document in dir,
+// {name}_analysis.* and {name}_report.*, written since start
+func outputFiles(dir, document string, start time.Time) []string {
+	base := filepath.Base(document)
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	var files []string
+	for _, pattern := range []string{name + "_analysis.*", name + "_report.*"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() && !info.ModTime().Before(start.Add(-time.Second)) {
+				files = append(files, match)
+			}
+		}
+	}
+	return files
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckStorage(t *testing.T) {
+	if err := checkStorage("drawing.pdf", "", "sqlite:results.db"); err != nil {
+		t.Errorf("local paths: %v", err)
+	}
+
+	// No credentials in the environment, files, or instance metadata
+	missing := filepath.Join(t.TempDir(), "missing")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_CONFIG_FILE", missing)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", missing)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", missing)
+	for uri, provider := range map[string]string{"s3://drawings/a.pdf": "AWS", "gs://results/": "Google Cloud"} {
+		if err := checkStorage("drawing.pdf", uri); err == nil || !strings.Contains(err.Error(), "no "+provider+" credentials") {
+			t.Errorf("%s without credentials: error %v", uri, err)
+		}
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	if err := checkStorage("s3://drawings/a.pdf"); err != nil {
+		t.Errorf("s3:// with credentials: %v", err)
+	}
+	if err := checkStorage("az://account"); err == nil || !strings.Contains(err.Error(), "az://account/container") {
+		t.Errorf("az:// without a container: error %v", err)
+	}
+}
+
+func TestBucketObject(t *testing.T) {
+	for uri, want := range map[string][2]string{
+		"s3://drawings/2025/a.pdf": {"drawings", "2025/a.pdf"},
+		"gs://results":             {"results", ""},
+	} {
+		bucket, object, err := bucketObject(uri)
+		if err != nil || bucket != want[0] || object != want[1] {
+			t.Errorf("bucketObject(%q) = %q, %q, %v; want %q", uri, bucket, object, err, want)
+		}
+	}
+	if _, _, err := bucketObject("s3://"); err == nil {
+		t.Error("no error for a URI without a bucket")
+	}
+}
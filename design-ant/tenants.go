@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Tenant is a customer of a shared server with its own provider key,
+// monthly budget, and rate limits. Tenants are configured in tenants.json in
+// the jobs directory, so every runner of a shared queue applies the same
+// limits; without the file, jobs have no tenant and run with the runner's key.
+type Tenant struct {
+	Name      string  `json:"name"`
+	APIKeyEnv string  `json:"api_key_env"`              // Environment variable holding the tenant's Anthropic API key
+	Budget    float64 `json:"monthly_budget,omitempty"` // Dollars per calendar month across the tenant's jobs (0 = no limit)
+	TPM       int     `json:"tpm,omitempty"`            // Input tokens per minute of the tenant's key, split over its max_jobs
+	MaxJobs   int     `json:"max_jobs,omitempty"`       // Jobs of the tenant run at the same time (0 = no limit)
+}
+
+// tenantsPath returns the tenants file of a jobs directory
+func (s *jobStore) tenantsPath() string {
+	return filepath.Join(s.dir, "tenants.json")
+}
+
+// tenants reads the tenants by name; nil without a tenants file. It is read
+// on every use, so edits apply to the next job without a restart.
+func (s *jobStore) tenants() (map[string]*Tenant, error) {
+	data, err := os.ReadFile(s.tenantsPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []*Tenant
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("error parsing tenants %s: %v", s.tenantsPath(), err)
+	}
+	tenants := make(map[string]*Tenant, len(list))
+	for _, tenant := range list {
+		if tenant.Name == "" || tenant.APIKeyEnv == "" {
+			return nil, fmt.Errorf("error parsing tenants %s: every tenant needs a name and an api_key_env", s.tenantsPath())
+		}
+		if tenant.Budget < 0 || tenant.TPM < 0 || tenant.MaxJobs < 0 {
+			return nil, fmt.Errorf("error parsing tenants %s: limits of %s must not be negative", s.tenantsPath(), tenant.Name)
+		}
+		tenants[tenant.Name] = tenant
+	}
+	return tenants, nil
+}
+
+// TenantUsage is the accounting of one tenant for a calendar month
+type TenantUsage struct {
+	Tenant  string         `json:"tenant"`
+	Month   string         `json:"month"`
+	Spent   float64        `json:"spent"`            // Dollars of the tenant's jobs finished this month
+	Budget  float64        `json:"budget,omitempty"` // Monthly budget (0 = no limit)
+	Running int            `json:"running"`
+	Jobs    map[string]int `json:"jobs"` // Jobs by state
+}
+
+// tenantUsage adds up the jobs of a tenant for the month of now
+func tenantUsage(jobs []*Job, tenant *Tenant, now time.Time) TenantUsage {
+	usage := TenantUsage{Tenant: tenant.Name, Month: ledgerMonth(now), Budget: tenant.Budget, Jobs: map[string]int{}}
+	for _, job := range jobs {
+		if job.Tenant != tenant.Name {
+			continue
+		}
+		usage.Jobs[job.Status]++
+		if job.Status == JobRunning {
+			usage.Running++
+		}
+		if !job.FinishedAt.IsZero() && ledgerMonth(job.FinishedAt) == usage.Month {
+			usage.Spent += job.Cost
+		}
+	}
+	return usage
+}
+
+// remaining returns the dollars left of the tenant's budget this month, or
+// -1 when it has no budget
+func (u TenantUsage) remaining() float64 {
+	if u.Budget <= 0 {
+		return -1
+	}
+	if u.Spent >= u.Budget {
+		return 0
+	}
+	return u.Budget - u.Spent
+}
+
+// tenantLimits are the flags a tenant's job is run with, and the key
+type tenantLimits struct {
+	apiKey  string
+	maxCost float64 // 0 = no limit
+	tpm     int     // 0 = no limit
+}
+
+// limitsFor checks that a tenant can run another job and returns the
+// limits to run it with. A job is capped at the tenant's remaining budget,
+// so jobs of a tenant running at the same time can together go over it by
+// at most what each of them spends.
+func limitsFor(tenant *Tenant, usage TenantUsage) (tenantLimits, error) {
+	limits := tenantLimits{apiKey: os.Getenv(tenant.APIKeyEnv)}
+	if limits.apiKey == "" {
+		return limits, fmt.Errorf("no API key for tenant %s: %s is not set", tenant.Name, tenant.APIKeyEnv)
+	}
+	switch remaining := usage.remaining(); {
+	case remaining == 0:
+		return limits, fmt.Errorf("tenant %s has spent its monthly budget of $%.2f ($%.2f spent in %s)", tenant.Name, tenant.Budget, usage.Spent, usage.Month)
+	case remaining > 0:
+		limits.maxCost = remaining
+	}
+	if tenant.TPM > 0 {
+		limits.tpm = tenant.TPM / max(tenant.MaxJobs, 1)
+	}
+	return limits, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"design-ant/pkg/pdfanalysis"
+)
+
+// Subfolders of a watched directory that processed documents are moved to
+const (
+	watchDoneDir   = "done"
+	watchFailedDir = "failed"
+)
+
+// watchedFile is a document seen in the watched directory, analyzed once its
+// size has stopped changing, i.e. the scanner or copy has finished writing it
+type watchedFile struct {
+	size    int64
+	stable  int  // Polls in a row with the same size
+	handled bool // Analyzed already
+}
+
+// runWatch analyzes every document dropped into a directory with the same
+// analysis flags, writes the results to an output folder, and moves each
+// document to done/ or failed/. The directory is polled, so it also works on
+// network shares that do not deliver change events.
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	outDir := fs.String("out", "", "folder the results are written to (default: results/ in the watched directory)")
+	poll := fs.Duration("poll", 5*time.Second, "how often the directory is checked for new documents")
+	settle := fs.Int("settle", 2, "polls a new file's size must stay unchanged before it is analyzed")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go run . watch [flags] <directory> [analysis flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return fmt.Errorf("missing directory to watch")
+	}
+	if *poll <= 0 || *settle < 1 {
+		return fmt.Errorf("-poll must be positive and -settle at least 1")
+	}
+	dir, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("cannot watch %s: not a directory", fs.Arg(0))
+	}
+	profile := fs.Args()[1:]
+	// The profile is checked once up front, with a placeholder document
+	if _, err := parseFlags(append(append([]string{}, profile...), "document.pdf")); err != nil {
+		return err
+	}
+	if *outDir == "" {
+		*outDir = filepath.Join(dir, "results")
+	}
+	if *outDir, err = filepath.Abs(*outDir); err != nil {
+		return err
+	}
+	for _, sub := range []string{*outDir, filepath.Join(dir, watchDoneDir), filepath.Join(dir, watchFailedDir)} {
+		if err := os.MkdirAll(sub, 0755); err != nil {
+			return fmt.Errorf("error creating %s: %v", sub, err)
+		}
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("error locating the design-ant binary: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("👀 Watching %s every %v; results go to %s\n", dir, *poll, *outDir)
+	if len(profile) > 0 {
+		fmt.Printf("⚙️  Analysis flags: %s\n", strings.Join(profile, " "))
+	}
+
+	seen := make(map[string]*watchedFile)
+	for {
+		for _, path := range watchCandidates(dir, seen, *settle) {
+			if ctx.Err() != nil {
+				break
+			}
+			processWatched(ctx, exe, path, *outDir, profile)
+			if ctx.Err() == nil {
+				// Not analyzed again should it stay behind, e.g. when it could not be moved
+				seen[path].handled = true
+			}
+		}
+		select {
+		case <-ctx.Done():
+			fmt.Println("⏹️  Stopped watching")
+			return nil
+		case <-time.After(*poll):
+		}
+	}
+}
+
+// watchCandidates records the size of every document in dir and returns the
+// ones whose size has stayed the same for settle polls, by name
+func watchCandidates(dir string, seen map[string]*watchedFile, settle int) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		pdfanalysis.Logf("⚠️  Could not read %s: %v\n", dir, err)
+		return nil
+	}
+	present := make(map[string]bool)
+	var ready []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~$") {
+			continue
+		}
+		if !strings.EqualFold(filepath.Ext(name), ".pdf") && !pdfanalysis.IsOfficeDocument(name) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		present[path] = true
+		file := seen[path]
+		switch {
+		case file == nil:
+			seen[path] = &watchedFile{size: info.Size()}
+		case file.handled:
+		case file.size != info.Size():
+			file.size, file.stable = info.Size(), 0
+		default:
+			file.stable++
+			if file.stable >= settle && info.Size() > 0 {
+				ready = append(ready, path)
+			}
+		}
+	}
+	// Files removed before they were analyzed are forgotten
+	for path := range seen {
+		if !present[path] {
+			delete(seen, path)
+		}
+	}
+	sort.Strings(ready)
+	return ready
+}
+
+// processWatched analyzes one document and moves it to done/ or failed/ next
+// to it. The analysis output is kept in {name}.log in the output folder.
+func processWatched(ctx context.Context, exe, path, outDir string, profile []string) {
+	name := filepath.Base(path)
+	fmt.Printf("▶️  %s\n", name)
+	logPath := filepath.Join(outDir, strings.TrimSuffix(name, filepath.Ext(name))+".log")
+	args := append(append([]string{"-no-progress"}, profile...), path)
+	header := fmt.Sprintf("=== %s, %s", name, time.Now().Format(time.RFC3339))
+	err := runAnalysisProcess(ctx, exe, outDir, args, nil, logPath, header)
+	if ctx.Err() != nil {
+		// Left in place, so it is analyzed again on the next start
+		fmt.Printf("⏸️  %s interrupted; it stays in the watched folder\n", name)
+		return
+	}
+
+	target := watchDoneDir
+	if err != nil {
+		target = watchFailedDir
+		fmt.Printf("❌ %s failed: %v (log: %s)\n", name, err, logPath)
+	} else {
+		fmt.Printf("✅ %s done (log: %s)\n", name, logPath)
+	}
+	dest := filepath.Join(filepath.Dir(path), target, name)
+	if _, err := os.Stat(dest); err == nil {
+		// Keep the earlier copy of a document dropped in twice
+		ext := filepath.Ext(name)
+		dest = filepath.Join(filepath.Dir(dest), fmt.Sprintf("%s-%s%s", strings.TrimSuffix(name, ext), time.Now().Format("20060102-150405"), ext))
+	}
+	if err := os.Rename(path, dest); err != nil {
+		pdfanalysis.Logf("⚠️  Could not move %s to %s/: %v\n", name, target, err)
+	}
+}
@@ -0,0 +1,110 @@
+// Package geminicost turns the token usage reported by the Gemini API into
+// costs, for the Gemini-based tools of this repository.
+package geminicost
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// Pricing holds pricing information for a Gemini model
+type Pricing struct {
+	InputPricePerMTokens  float64 // Price per million input tokens (text, image, and PDF)
+	OutputPricePerMTokens float64 // Price per million output tokens, thinking included
+}
+
+// ModelPricing holds the paid-tier prices of the Gemini models, for prompts
+// up to 200k tokens
+var ModelPricing = map[string]Pricing{
+	"gemini-2.5-flash-lite": {
+		InputPricePerMTokens:  0.10, // $0.10 per million input tokens
+		OutputPricePerMTokens: 0.40, // $0.40 per million output tokens
+	},
+	"gemini-2.5-flash": {
+		InputPricePerMTokens:  0.30,
+		OutputPricePerMTokens: 2.50,
+	},
+	"gemini-2.5-pro": {
+		InputPricePerMTokens:  1.25,
+		OutputPricePerMTokens: 10.00,
+	},
+	"gemini-2.0-flash": {
+		InputPricePerMTokens:  0.10,
+		OutputPricePerMTokens: 0.40,
+	},
+	"gemini-2.0-flash-lite": {
+		InputPricePerMTokens:  0.075,
+		OutputPricePerMTokens: 0.30,
+	},
+}
+
+// GetPricing returns pricing for a given model name
+func GetPricing(modelName string) Pricing {
+	if pricing, ok := ModelPricing[modelName]; ok {
+		return pricing
+	}
+	// Default to Flash-Lite pricing if model not found
+	return ModelPricing["gemini-2.5-flash-lite"]
+}
+
+// Usage is the token count and cost of one or more requests
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+	InputCost    float64
+	OutputCost   float64
+	TotalCost    float64
+}
+
+// UsageOf reads the usage metadata of a response and prices it for the
+// model. Thinking tokens are billed as output. A response without metadata
+// counts as zero.
+func UsageOf(modelName string, resp *genai.GenerateContentResponse) Usage {
+	if resp == nil || resp.UsageMetadata == nil {
+		return Usage{}
+	}
+	meta := resp.UsageMetadata
+	pricing := GetPricing(modelName)
+	u := Usage{
+		InputTokens:  int(meta.PromptTokenCount + meta.ToolUsePromptTokenCount),
+		OutputTokens: int(meta.CandidatesTokenCount + meta.ThoughtsTokenCount),
+	}
+	u.InputCost = float64(u.InputTokens) / 1_000_000 * pricing.InputPricePerMTokens
+	u.OutputCost = float64(u.OutputTokens) / 1_000_000 * pricing.OutputPricePerMTokens
+	u.TotalCost = u.InputCost + u.OutputCost
+	return u
+}
+
+// Add adds the usage of another request
+func (u *Usage) Add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.InputCost += other.InputCost
+	u.OutputCost += other.OutputCost
+	u.TotalCost += other.TotalCost
+}
+
+// String formats the usage for a per-page console line
+func (u Usage) String() string {
+	return fmt.Sprintf("%d input tokens, %d output tokens, $%.6f", u.InputTokens, u.OutputTokens, u.TotalCost)
+}
+
+// PrintPricing prints the model's prices, as shown before a run
+func PrintPricing(modelName string) {
+	pricing := GetPricing(modelName)
+	fmt.Printf("💰 Model Pricing: $%.3f/M input, $%.2f/M output\n", pricing.InputPricePerMTokens, pricing.OutputPricePerMTokens)
+}
+
+// PrintSummary prints the totals of a run in the format of design-ant
+func PrintSummary(total Usage) {
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Println("💰 COST SUMMARY")
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("Total Input Tokens:  %d\n", total.InputTokens)
+	fmt.Printf("Total Output Tokens: %d\n", total.OutputTokens)
+	fmt.Printf("Total Input Cost:    $%.6f\n", total.InputCost)
+	fmt.Printf("Total Output Cost:   $%.6f\n", total.OutputCost)
+	fmt.Printf("Total Cost:          $%.6f\n", total.TotalCost)
+}
@@ -0,0 +1,285 @@
+// Package geminiquota paces Gemini requests to the rate limits of an API
+// tier, so the free tier's per-minute quotas are not exceeded, and reports
+// an exhausted daily quota as its own condition that a later run can resume.
+package geminiquota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+
+	"llm-pdf-app/geminicost"
+)
+
+// Profile holds the rate limits of one model on one API tier. Zero means no limit.
+type Profile struct {
+	RPM int // Requests per minute
+	TPM int // Input tokens per minute
+	RPD int // Requests per day, reset at midnight Pacific time
+}
+
+// Tiers of the Gemini API
+const (
+	TierFree = "free"
+	TierPaid = "paid" // Tier 1, billing enabled
+)
+
+// Profiles holds the published limits per tier and model
+var Profiles = map[string]map[string]Profile{
+	TierFree: {
+		"gemini-2.5-flash-lite": {RPM: 15, TPM: 250_000, RPD: 1_000},
+		"gemini-2.5-flash":      {RPM: 10, TPM: 250_000, RPD: 250},
+		"gemini-2.5-pro":        {RPM: 5, TPM: 250_000, RPD: 100},
+		"gemini-2.0-flash":      {RPM: 15, TPM: 1_000_000, RPD: 200},
+		"gemini-2.0-flash-lite": {RPM: 30, TPM: 1_000_000, RPD: 200},
+	},
+	TierPaid: {
+		"gemini-2.5-flash-lite": {RPM: 4_000, TPM: 4_000_000},
+		"gemini-2.5-flash":      {RPM: 1_000, TPM: 1_000_000, RPD: 10_000},
+		"gemini-2.5-pro":        {RPM: 150, TPM: 2_000_000, RPD: 10_000},
+		"gemini-2.0-flash":      {RPM: 2_000, TPM: 4_000_000},
+		"gemini-2.0-flash-lite": {RPM: 4_000, TPM: 4_000_000},
+	},
+}
+
+// GetProfile returns the limits of a model on a tier. Models missing from
+// the table get the strictest limits of the tier.
+func GetProfile(tier, modelName string) (Profile, error) {
+	models, ok := Profiles[tier]
+	if !ok {
+		return Profile{}, fmt.Errorf("invalid tier %q: must be %s or %s", tier, TierFree, TierPaid)
+	}
+	if profile, ok := models[modelName]; ok {
+		return profile, nil
+	}
+	var strictest Profile
+	for _, profile := range models {
+		if strictest.RPM == 0 || profile.RPM < strictest.RPM {
+			strictest = profile
+		}
+	}
+	return strictest, nil
+}
+
+// ErrDailyQuota is returned once the requests per day of the model are used
+// up. Pages not sent because of it can be sent by a run on the next day.
+var ErrDailyQuota = errors.New("daily quota exhausted")
+
+// IsDailyQuota reports whether err is ErrDailyQuota or a 429 from the API for
+// a per-day quota
+func IsDailyQuota(err error) bool {
+	if errors.Is(err, ErrDailyQuota) {
+		return true
+	}
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 429 {
+		return false
+	}
+	return strings.Contains(fmt.Sprint(apiErr.Details), "PerDay")
+}
+
+// pacific is the time zone in which daily quotas reset
+var pacific = loadPacific()
+
+func loadPacific() *time.Location {
+	if loc, err := time.LoadLocation("America/Los_Angeles"); err == nil {
+		return loc
+	}
+	return time.FixedZone("PST", -8*60*60)
+}
+
+// NextReset returns when the daily quotas reset next
+func NextReset(now time.Time) time.Time {
+	local := now.In(pacific)
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, pacific)
+}
+
+// dailyState is the requests counted today, kept across runs
+type dailyState struct {
+	Day      string         `json:"day"` // Pacific date, e.g. 2025-01-31
+	Requests map[string]int `json:"requests"`
+}
+
+// sent is one request of the last minute
+type sent struct {
+	at     time.Time
+	tokens int
+}
+
+// Pacer holds requests back until they fit the limits of a profile. It is
+// safe for concurrent use.
+type Pacer struct {
+	profile   Profile
+	modelName string
+	statePath string
+
+	mu        sync.Mutex
+	window    []sent
+	state     dailyState
+	exhausted bool
+}
+
+// DefaultStatePath returns the file in which the daily request counts are
+// kept, in the user config directory
+func DefaultStatePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "gemini-quota.json"
+	}
+	return filepath.Join(dir, "llm-pdf-app", "gemini-quota.json")
+}
+
+// NewPacer creates a pacer for a model. Requests counted by earlier runs on
+// the same day are read from statePath; an empty path keeps no state.
+func NewPacer(profile Profile, modelName, statePath string) *Pacer {
+	p := &Pacer{profile: profile, modelName: modelName, statePath: statePath}
+	p.state.Requests = make(map[string]int)
+	if data, err := os.ReadFile(statePath); err == nil {
+		var state dailyState
+		if json.Unmarshal(data, &state) == nil && state.Requests != nil {
+			p.state = state
+		}
+	}
+	return p
+}
+
+// Wait blocks until a request of about tokens input tokens fits the
+// per-minute limits and counts it. It returns ErrDailyQuota without waiting
+// when the requests per day are used up.
+func (p *Pacer) Wait(ctx context.Context, tokens int) error {
+	for {
+		p.mu.Lock()
+		now := time.Now()
+		p.rollDay(now)
+		if p.exhausted || (p.profile.RPD > 0 && p.state.Requests[p.modelName] >= p.profile.RPD) {
+			p.exhausted = true
+			p.mu.Unlock()
+			return ErrDailyQuota
+		}
+
+		// Forget requests older than a minute
+		keep := p.window[:0]
+		for _, s := range p.window {
+			if now.Sub(s.at) < time.Minute {
+				keep = append(keep, s)
+			}
+		}
+		p.window = keep
+		used := 0
+		for _, s := range p.window {
+			used += s.tokens
+		}
+
+		fitsRPM := p.profile.RPM == 0 || len(p.window) < p.profile.RPM
+		fitsTPM := p.profile.TPM == 0 || len(p.window) == 0 || used+tokens <= p.profile.TPM
+		if fitsRPM && fitsTPM {
+			p.window = append(p.window, sent{at: now, tokens: tokens})
+			p.state.Requests[p.modelName]++
+			p.save()
+			p.mu.Unlock()
+			return nil
+		}
+		// The oldest request leaving the window makes room
+		delay := time.Minute - now.Sub(p.window[0].at)
+		p.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Exhausted marks the daily quota as used up, e.g. after the API reported it
+// with a 429, so later Wait calls fail at once
+func (p *Pacer) Exhausted() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.exhausted = true
+	if p.profile.RPD > 0 {
+		p.state.Requests[p.modelName] = max(p.state.Requests[p.modelName], p.profile.RPD)
+		p.save()
+	}
+}
+
+// Used returns the requests counted today and the daily limit (0 = none)
+func (p *Pacer) Used() (int, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rollDay(time.Now())
+	return p.state.Requests[p.modelName], p.profile.RPD
+}
+
+// rollDay resets the daily counts when the Pacific date has changed
+func (p *Pacer) rollDay(now time.Time) {
+	day := now.In(pacific).Format("2006-01-02")
+	if p.state.Day != day {
+		p.state = dailyState{Day: day, Requests: make(map[string]int)}
+		p.exhausted = false
+	}
+}
+
+// save writes the daily counts; a failure only loses the count across runs
+func (p *Pacer) save() {
+	if p.statePath == "" {
+		return
+	}
+	data, err := json.Marshal(p.state)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(p.statePath), 0755); err != nil {
+		return
+	}
+	os.WriteFile(p.statePath, data, 0644)
+}
+
+// Page is a page summarized by an earlier run, kept so a run stopped by the
+// daily quota can be resumed without sending it again
+type Page struct {
+	Number  int              `json:"page"`
+	Summary string           `json:"summary"`
+	Usage   geminicost.Usage `json:"usage"`
+}
+
+// ProgressPath returns the progress file of a PDF, {pdf-name}_progress.json
+// in the working directory
+func ProgressPath(pdfPath string) string {
+	base := filepath.Base(pdfPath)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + "_progress.json"
+}
+
+// LoadProgress reads the pages of a progress file by page number
+func LoadProgress(path string) (map[int]Page, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading progress file: %v", err)
+	}
+	var pages []Page
+	if err := json.Unmarshal(data, &pages); err != nil {
+		return nil, fmt.Errorf("error parsing progress file %s: %v", path, err)
+	}
+	byNumber := make(map[int]Page, len(pages))
+	for _, page := range pages {
+		byNumber[page.Number] = page
+	}
+	return byNumber, nil
+}
+
+// SaveProgress writes the summarized pages to a progress file
+func SaveProgress(path string, pages []Page) error {
+	data, err := json.MarshalIndent(pages, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
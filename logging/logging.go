@@ -0,0 +1,37 @@
+// Package logging sets up the structured logger shared by the Gemini tools.
+// Diagnostics (warnings and errors) go through log/slog to stderr with
+// key-value fields; the tools' reports stay on stdout.
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Setup installs the default logger, configured by LOG_LEVEL (debug, info,
+// warn, or error; default info) and LOG_FORMAT (text or json; default
+// text). Calls to the standard log package go through it as well.
+func Setup() *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(os.Getenv("LOG_LEVEL"))); err != nil {
+		level = slog.LevelInfo
+	}
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if strings.EqualFold(os.Getenv("LOG_FORMAT"), "json") {
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, options)
+	}
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// Fatal logs err and exits with status 1. Only a main calls it; everything
+// else returns its errors.
+func Fatal(err error) {
+	slog.Error(err.Error())
+	os.Exit(1)
+}
@@ -3,8 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
@@ -12,30 +13,43 @@ import (
 	"github.com/gen2brain/go-fitz"
 	"github.com/joho/godotenv"
 	"google.golang.org/genai"
+
+	"llm-pdf-app/geminicost"
+	"llm-pdf-app/logging"
 )
 
+const modelName = "gemini-2.5-flash-lite"
+
 type PageData struct {
 	PageNumber int
 	Text       string
 }
 
 func main() {
+	logging.Setup()
+	if err := run(os.Args[1:]); err != nil {
+		logging.Fatal(err)
+	}
+}
+
+// run summarizes every page of the PDF in one request
+func run(args []string) error {
 	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error: Could not load .env file. Make sure it exists!")
+		return fmt.Errorf("could not load .env file, make sure it exists")
 	}
 
 	apiKey := os.Getenv("GEMINI_API_KEY")
 	if apiKey == "" {
-		log.Fatal("Error: GEMINI_API_KEY not found in .env file")
+		return fmt.Errorf("GEMINI_API_KEY not found in .env file")
 	}
 
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: go run main.go <pdf-file>")
+	if len(args) < 1 {
+		return fmt.Errorf("usage: go run main.go <pdf-file>")
 	}
 
-	pdfPath := os.Args[1]
+	pdfPath := args[0]
 	if _, err := os.Stat(pdfPath); os.IsNotExist(err) {
-		log.Fatalf("Error: PDF file not found: %s", pdfPath)
+		return fmt.Errorf("PDF file not found: %s", pdfPath)
 	}
 
 	fmt.Printf("📄 Processing PDF: %s\n", pdfPath)
@@ -43,38 +57,20 @@ func main() {
 
 	doc, err := fitz.New(pdfPath)
 	if err != nil {
-		log.Fatalf("Error opening PDF: %v", err)
+		return fmt.Errorf("error opening PDF: %v", err)
 	}
-	defer doc.Close()
-
 	totalPages := doc.NumPage()
-	fmt.Printf("📊 Total pages: %d\n\n", totalPages)
+	doc.Close()
+	fmt.Printf("📊 Total pages: %d\n", totalPages)
+	geminicost.PrintPricing(modelName)
+	fmt.Println()
 
 	fmt.Println("🔄 Extracting text from pages (using goroutines)...")
 	startTime := time.Now()
-	pages := make([]PageData, totalPages)
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-
-	for i := 0; i < totalPages; i++ {
-		wg.Add(1)
-		go func(pageIndex int) {
-			defer wg.Done()
-			text, err := doc.Text(pageIndex)
-			if err != nil {
-				log.Printf("Warning: Error on page %d: %v", pageIndex+1, err)
-				text = ""
-			}
-			mu.Lock()
-			pages[pageIndex] = PageData{
-				PageNumber: pageIndex + 1,
-				Text:       strings.TrimSpace(text),
-			}
-			mu.Unlock()
-			fmt.Printf("✅ Page %d: Text extracted\n", pageIndex+1)
-		}(i)
+	pages, err := extractPages(pdfPath, totalPages)
+	if err != nil {
+		return fmt.Errorf("error opening PDF: %v", err)
 	}
-	wg.Wait()
 
 	fmt.Printf("\n⏱️  Text extraction completed in: %v\n", time.Since(startTime))
 	fmt.Printf("📝 All %d pages extracted concurrently using goroutines!\n\n", totalPages)
@@ -92,27 +88,83 @@ func main() {
 	promptBuilder.WriteString("Please format your response as:\nPage 1: [summary]\nPage 2: [summary]\n...")
 
 	apiStartTime := time.Now()
-	summary, err := callGeminiAPI(apiKey, promptBuilder.String())
+	// One client for the whole run; it keeps its connections between calls
+	ctx := context.Background()
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: apiKey})
+	if err != nil {
+		return fmt.Errorf("error creating Gemini client: %v", err)
+	}
+	summary, usage, err := callGeminiAPI(ctx, client, promptBuilder.String())
 	if err != nil {
-		log.Fatalf("❌ API Error: %v", err)
+		return fmt.Errorf("API error: %v", err)
 	}
-	fmt.Printf("✅ API call completed in: %v\n\n", time.Since(apiStartTime))
+	fmt.Printf("✅ API call completed in: %v (%s)\n\n", time.Since(apiStartTime), usage)
 
 	fmt.Println("==================================================")
 	fmt.Println("📋 SUMMARY")
 	fmt.Println("==================================================")
 	fmt.Println(summary)
+	fmt.Println()
+	geminicost.PrintSummary(usage)
+	return nil
 }
 
-func callGeminiAPI(apiKey, prompt string) (string, error) {
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: apiKey})
-	if err != nil {
-		return "", fmt.Errorf("error creating Gemini client: %v", err)
+// extractPages extracts the text of every page with a pool of workers. A
+// fitz.Document is not safe for concurrent use, so each worker opens its
+// own handle instead of sharing one.
+func extractPages(pdfPath string, totalPages int) ([]PageData, error) {
+	workers := min(runtime.NumCPU(), totalPages)
+	docs := make([]*fitz.Document, 0, workers)
+	defer func() {
+		for _, doc := range docs {
+			doc.Close()
+		}
+	}()
+	for i := 0; i < workers; i++ {
+		doc, err := fitz.New(pdfPath)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	pageIndexes := make(chan int)
+	go func() {
+		for i := 0; i < totalPages; i++ {
+			pageIndexes <- i
+		}
+		close(pageIndexes)
+	}()
+
+	pages := make([]PageData, totalPages)
+	var wg sync.WaitGroup
+	for _, doc := range docs {
+		wg.Add(1)
+		go func(doc *fitz.Document) {
+			defer wg.Done()
+			for pageIndex := range pageIndexes {
+				text, err := doc.Text(pageIndex)
+				if err != nil {
+					slog.Warn("Could not extract page text", "page", pageIndex+1, "error", err)
+					text = ""
+				}
+				// Each page index is written by exactly one worker
+				pages[pageIndex] = PageData{
+					PageNumber: pageIndex + 1,
+					Text:       strings.TrimSpace(text),
+				}
+				fmt.Printf("✅ Page %d: Text extracted\n", pageIndex+1)
+			}
+		}(doc)
 	}
-	result, err := client.Models.GenerateContent(ctx, "gemini-2.5-flash-lite", genai.Text(prompt), nil)
+	wg.Wait()
+	return pages, nil
+}
+
+func callGeminiAPI(ctx context.Context, client *genai.Client, prompt string) (string, geminicost.Usage, error) {
+	result, err := client.Models.GenerateContent(ctx, modelName, genai.Text(prompt), nil)
 	if err != nil {
-		return "", fmt.Errorf("error calling Gemini API: %v", err)
+		return "", geminicost.Usage{}, fmt.Errorf("error calling Gemini API: %v", err)
 	}
-	return result.Text(), nil
+	return result.Text(), geminicost.UsageOf(modelName, result), nil
 }